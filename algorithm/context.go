@@ -0,0 +1,146 @@
+package algorithm
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"log"
+)
+
+// ContextVersion is version of Context.
+// フィールドの追加では上げない。既存のフィールドの意味を変える場合に上げる
+const ContextVersion = 1
+
+// ConfigAccessor is accessor of algorithm config file
+type ConfigAccessor interface {
+	GetConfigDir() (string)
+	// Load is load <configDir>/<algorithm name>.(yaml|toml|json)
+	Load(data interface{}) (error)
+}
+
+// Context is runtime context of algorithm instance. it is created for each instance and passed to every call
+type Context struct {
+	Version       int
+	Name          string
+	AlgorithmName string
+	// 取引所内取引の場合のみ
+	Exchange      exchange.Exchange
+	// 取引所内取引の場合はExchangeだけが入る
+	Exchanges     map[string]exchange.Exchange
+	Notifier      *notifier.Notifier
+	Logger        *log.Logger
+	Clock         clock.Clock
+	Config        ConfigAccessor
+	State         StateStore
+	Positions     PositionLedger
+}
+
+type InternalTradeAlgorithmV2 interface {
+	GetName() (string)
+	Initialize(ctx *Context) (error)
+	Update(ctx *Context, currencyPair string) (error)
+	Finalize(ctx *Context) (error)
+}
+
+type ExternalTradeAlgorithmV2 interface {
+	GetName() (string)
+	Initialize(ctx *Context) (error)
+	Update(ctx *Context) (error)
+	Finalize(ctx *Context) (error)
+}
+
+// internalTradeAlgorithmAdapter makes InternalTradeAlgorithm work as InternalTradeAlgorithmV2
+type internalTradeAlgorithmAdapter struct {
+	algorithm InternalTradeAlgorithm
+}
+
+func (i *internalTradeAlgorithmAdapter) GetName() (string) {
+	return i.algorithm.GetName()
+}
+
+func (i *internalTradeAlgorithmAdapter) Initialize(ctx *Context) (error) {
+	return i.algorithm.Initialize(ctx.Exchange, ctx.Notifier)
+}
+
+func (i *internalTradeAlgorithmAdapter) Update(ctx *Context, currencyPair string) (error) {
+	return i.algorithm.Update(currencyPair, ctx.Exchange, ctx.Notifier)
+}
+
+func (i *internalTradeAlgorithmAdapter) Finalize(ctx *Context) (error) {
+	return i.algorithm.Finalize(ctx.Exchange, ctx.Notifier)
+}
+
+func (i *internalTradeAlgorithmAdapter) RestoreState(store StateStore) (error) {
+	statefulAlgorithm, ok := i.algorithm.(StatefulAlgorithm)
+	if !ok {
+		return nil
+	}
+	return statefulAlgorithm.RestoreState(store)
+}
+
+func (i *internalTradeAlgorithmAdapter) SnapshotState(store StateStore) (error) {
+	statefulAlgorithm, ok := i.algorithm.(StatefulAlgorithm)
+	if !ok {
+		return nil
+	}
+	return statefulAlgorithm.SnapshotState(store)
+}
+
+// NewInternalTradeAlgorithmAdapter is wrap InternalTradeAlgorithm as InternalTradeAlgorithmV2
+func NewInternalTradeAlgorithmAdapter(algorithm InternalTradeAlgorithm) (InternalTradeAlgorithmV2) {
+	return &internalTradeAlgorithmAdapter{
+		algorithm: algorithm,
+	}
+}
+
+// externalTradeAlgorithmAdapter makes ExternalTradeAlgorithm work as ExternalTradeAlgorithmV2
+type externalTradeAlgorithmAdapter struct {
+	algorithm ExternalTradeAlgorithm
+}
+
+func (e *externalTradeAlgorithmAdapter) GetName() (string) {
+	return e.algorithm.GetName()
+}
+
+func (e *externalTradeAlgorithmAdapter) Initialize(ctx *Context) (error) {
+	return e.algorithm.Initialize(ctx.Exchanges, ctx.Notifier)
+}
+
+func (e *externalTradeAlgorithmAdapter) Update(ctx *Context) (error) {
+	return e.algorithm.Update(ctx.Exchanges, ctx.Notifier)
+}
+
+func (e *externalTradeAlgorithmAdapter) Finalize(ctx *Context) (error) {
+	return e.algorithm.Finalize(ctx.Exchanges, ctx.Notifier)
+}
+
+func (e *externalTradeAlgorithmAdapter) RestoreState(store StateStore) (error) {
+	statefulAlgorithm, ok := e.algorithm.(StatefulAlgorithm)
+	if !ok {
+		return nil
+	}
+	return statefulAlgorithm.RestoreState(store)
+}
+
+func (e *externalTradeAlgorithmAdapter) SnapshotState(store StateStore) (error) {
+	statefulAlgorithm, ok := e.algorithm.(StatefulAlgorithm)
+	if !ok {
+		return nil
+	}
+	return statefulAlgorithm.SnapshotState(store)
+}
+
+func (e *externalTradeAlgorithmAdapter) GetTriggers() (*Triggers) {
+	triggeredAlgorithm, ok := e.algorithm.(TriggeredAlgorithm)
+	if !ok {
+		return nil
+	}
+	return triggeredAlgorithm.GetTriggers()
+}
+
+// NewExternalTradeAlgorithmAdapter is wrap ExternalTradeAlgorithm as ExternalTradeAlgorithmV2
+func NewExternalTradeAlgorithmAdapter(algorithm ExternalTradeAlgorithm) (ExternalTradeAlgorithmV2) {
+	return &externalTradeAlgorithmAdapter{
+		algorithm: algorithm,
+	}
+}
@@ -0,0 +1,23 @@
+package algorithm
+
+// Position is holding and profit of algorithm instance for exchange and currency pair
+type Position struct {
+	Exchange      string  `json:"exchange"`
+	CurrencyPair  string  `json:"currencyPair"`
+	// 買いは正、売りは負
+	Amount        float64 `json:"amount"`
+	AveragePrice  float64 `json:"averagePrice"`
+	RealizedPnL   float64 `json:"realizedPnl"`
+	// LastPriceで評価した含み損益
+	UnrealizedPnL float64 `json:"unrealizedPnl"`
+	LastPrice     float64 `json:"lastPrice"`
+	Fees          float64 `json:"fees"`
+	Fills         int64   `json:"fills"`
+}
+
+// PositionLedger is accessor of positions of algorithm instance
+type PositionLedger interface {
+	GetPositions() ([]*Position, error)
+	// GetPosition returns empty position if algorithm has no fill
+	GetPosition(exchangeName string, currencyPair string) (*Position, error)
+}
@@ -1,22 +1,85 @@
 package algorithm
 
+import (
+	"sync"
+)
+
 var registeredAlgorithms map[string]*registeredAlgorithm = make(map[string]*registeredAlgorithm)
+// pluginの再読み込みで登録と参照が並行する
+var registeredAlgorithmsMutex = new(sync.Mutex)
 
 type InternalTradeAlgorithmNewFunc func(configDir string) (InternalTradeAlgorithm, error)
 type ExternalTradeAlgorithmNewFunc func(configDir string) (ExternalTradeAlgorithm, error)
+type InternalTradeAlgorithmV2NewFunc func(configDir string) (InternalTradeAlgorithmV2, error)
+type ExternalTradeAlgorithmV2NewFunc func(configDir string) (ExternalTradeAlgorithmV2, error)
 
 type registeredAlgorithm struct {
-	InternalTradeAlgorithmNewFunc InternalTradeAlgorithmNewFunc
-	ExternalTradeAlgorithmNewFunc ExternalTradeAlgorithmNewFunc
+	InternalTradeAlgorithmNewFunc   InternalTradeAlgorithmNewFunc
+	ExternalTradeAlgorithmNewFunc   ExternalTradeAlgorithmNewFunc
+	InternalTradeAlgorithmV2NewFunc InternalTradeAlgorithmV2NewFunc
+	ExternalTradeAlgorithmV2NewFunc ExternalTradeAlgorithmV2NewFunc
+}
+
+// HasInternalTradeAlgorithm is check that algorithm supports internal trade
+func (r *registeredAlgorithm) HasInternalTradeAlgorithm() (bool) {
+	return r.InternalTradeAlgorithmV2NewFunc != nil || r.InternalTradeAlgorithmNewFunc != nil
+}
+
+// HasExternalTradeAlgorithm is check that algorithm supports external trade
+func (r *registeredAlgorithm) HasExternalTradeAlgorithm() (bool) {
+	return r.ExternalTradeAlgorithmV2NewFunc != nil || r.ExternalTradeAlgorithmNewFunc != nil
+}
+
+// NewInternalTradeAlgorithm is create internal trade algorithm. old algorithm is wrapped by adapter
+func (r *registeredAlgorithm) NewInternalTradeAlgorithm(configDir string) (InternalTradeAlgorithmV2, error) {
+	if r.InternalTradeAlgorithmV2NewFunc != nil {
+		return r.InternalTradeAlgorithmV2NewFunc(configDir)
+	}
+	alg, err := r.InternalTradeAlgorithmNewFunc(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return NewInternalTradeAlgorithmAdapter(alg), nil
+}
+
+// NewExternalTradeAlgorithm is create external trade algorithm. old algorithm is wrapped by adapter
+func (r *registeredAlgorithm) NewExternalTradeAlgorithm(configDir string) (ExternalTradeAlgorithmV2, error) {
+	if r.ExternalTradeAlgorithmV2NewFunc != nil {
+		return r.ExternalTradeAlgorithmV2NewFunc(configDir)
+	}
+	alg, err := r.ExternalTradeAlgorithmNewFunc(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return NewExternalTradeAlgorithmAdapter(alg), nil
 }
 
 func RegisterAlgorithm(name string, internalTradeAlgorithmNewFunc InternalTradeAlgorithmNewFunc, externalTradeAlgorithmNewFunc ExternalTradeAlgorithmNewFunc) {
+	registeredAlgorithmsMutex.Lock()
+	defer registeredAlgorithmsMutex.Unlock()
 	registeredAlgorithms[name] = &registeredAlgorithm{
 		InternalTradeAlgorithmNewFunc: internalTradeAlgorithmNewFunc,
 		ExternalTradeAlgorithmNewFunc: externalTradeAlgorithmNewFunc,
 	}
 }
 
+// RegisterAlgorithmV2 is register algorithm that takes Context
+func RegisterAlgorithmV2(name string, internalTradeAlgorithmNewFunc InternalTradeAlgorithmV2NewFunc, externalTradeAlgorithmNewFunc ExternalTradeAlgorithmV2NewFunc) {
+	registeredAlgorithmsMutex.Lock()
+	defer registeredAlgorithmsMutex.Unlock()
+	registeredAlgorithms[name] = &registeredAlgorithm{
+		InternalTradeAlgorithmV2NewFunc: internalTradeAlgorithmNewFunc,
+		ExternalTradeAlgorithmV2NewFunc: externalTradeAlgorithmNewFunc,
+	}
+}
+
+// GetRegisterdAlgoriths is get copy of registered algorithms
 func GetRegisterdAlgoriths() (map[string]*registeredAlgorithm) {
-	return registeredAlgorithms
+	registeredAlgorithmsMutex.Lock()
+	defer registeredAlgorithmsMutex.Unlock()
+	algorithms := make(map[string]*registeredAlgorithm, len(registeredAlgorithms))
+	for name, registeredAlgorithm := range registeredAlgorithms {
+		algorithms[name] = registeredAlgorithm
+	}
+	return algorithms
 }
@@ -0,0 +1,22 @@
+package algorithm
+
+// StateStore is namespaced key/value store to keep algorithm state across restarts
+type StateStore interface {
+	// Get returns nil if key is not found
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) (error)
+	Delete(key string) (error)
+	Keys() ([]string, error)
+	// GetJSON returns false if key is not found
+	GetJSON(key string, value interface{}) (bool, error)
+	PutJSON(key string, value interface{}) (error)
+}
+
+// StatefulAlgorithm is optional interface of InternalTradeAlgorithm and ExternalTradeAlgorithm.
+// Algorithms can also keep the store and write to it in Update so that state survives crash.
+type StatefulAlgorithm interface {
+	// RestoreState is called before Initialize
+	RestoreState(store StateStore) (error)
+	// SnapshotState is called after Finalize
+	SnapshotState(store StateStore) (error)
+}
@@ -0,0 +1,58 @@
+package algorithm
+
+import (
+	"strings"
+)
+
+const (
+	// トリガーを宣言していない取引所間取引アルゴリズムの実行間隔 (ミリ秒)
+	DefaultTriggerInterval = 500
+)
+
+// BoardTrigger is exchange and currency pair whose board change wakes algorithm. empty means any
+type BoardTrigger struct {
+	Exchange     string `json:"exchange"     yaml:"exchange"     toml:"exchange"`
+	CurrencyPair string `json:"currencyPair" yaml:"currencyPair" toml:"currencyPair"`
+}
+
+// Match is check that board change of exchange and currency pair matches trigger
+func (b *BoardTrigger) Match(exchangeName string, currencyPair string) (bool) {
+	if b.Exchange != "" && !strings.EqualFold(b.Exchange, exchangeName) {
+		return false
+	}
+	if b.CurrencyPair != "" && !strings.EqualFold(b.CurrencyPair, currencyPair) {
+		return false
+	}
+	return true
+}
+
+// Triggers is conditions that wake external trade algorithm
+type Triggers struct {
+	// ミリ秒、0なら定期的には起こさない
+	Interval int             `json:"interval" yaml:"interval" toml:"interval"`
+	Boards   []*BoardTrigger `json:"boards"   yaml:"boards"   toml:"boards"`
+	// 自分の注文が約定したら起こす
+	Fills    bool            `json:"fills"    yaml:"fills"    toml:"fills"`
+}
+
+// MatchBoard is check that board change of exchange and currency pair matches any board trigger
+func (t *Triggers) MatchBoard(exchangeName string, currencyPair string) (bool) {
+	for _, boardTrigger := range t.Boards {
+		if boardTrigger.Match(exchangeName, currencyPair) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDefaultTriggers is create triggers for algorithms that do not declare triggers
+func NewDefaultTriggers() (*Triggers) {
+	return &Triggers{
+		Interval: DefaultTriggerInterval,
+	}
+}
+
+// TriggeredAlgorithm is optional interface of ExternalTradeAlgorithm and ExternalTradeAlgorithmV2 to declare triggers
+type TriggeredAlgorithm interface {
+	GetTriggers() (*Triggers)
+}
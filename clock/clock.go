@@ -0,0 +1,80 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is source of current time. algorithms should use it instead of time package
+type Clock interface {
+	Now() (time.Time)
+	Since(t time.Time) (time.Duration)
+	After(d time.Duration) (<-chan time.Time)
+	Sleep(d time.Duration)
+	NewTicker(d time.Duration) (Ticker)
+}
+
+// Ticker is ticker created by Clock
+type Ticker interface {
+	C() (<-chan time.Time)
+	Stop()
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (s *systemTicker) C() (<-chan time.Time) {
+	return s.ticker.C
+}
+
+func (s *systemTicker) Stop() {
+	s.ticker.Stop()
+}
+
+type systemClock struct {
+}
+
+func (s *systemClock) Now() (time.Time) {
+	return time.Now()
+}
+
+func (s *systemClock) Since(t time.Time) (time.Duration) {
+	return time.Since(t)
+}
+
+func (s *systemClock) After(d time.Duration) (<-chan time.Time) {
+	return time.After(d)
+}
+
+func (s *systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (s *systemClock) NewTicker(d time.Duration) (Ticker) {
+	return &systemTicker{
+		ticker: time.NewTicker(d),
+	}
+}
+
+// NewSystemClock is create clock of system time
+func NewSystemClock() (Clock) {
+	return &systemClock{}
+}
+
+var defaultClock Clock = NewSystemClock()
+var defaultClockMutex = new(sync.Mutex)
+
+// Default is get clock that is used by components created after this call
+func Default() (Clock) {
+	defaultClockMutex.Lock()
+	defer defaultClockMutex.Unlock()
+	return defaultClock
+}
+
+// SetDefault is replace default clock. it should be called before creating integrator, exchanges and robot
+func SetDefault(clock Clock) {
+	defaultClockMutex.Lock()
+	defer defaultClockMutex.Unlock()
+	defaultClock = clock
+}
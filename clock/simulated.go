@@ -0,0 +1,157 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type waiter struct {
+	deadline time.Time
+	period   time.Duration
+	c        chan time.Time
+	stopped  bool
+}
+
+type simulatedTicker struct {
+	clock  *SimulatedClock
+	waiter *waiter
+}
+
+func (s *simulatedTicker) C() (<-chan time.Time) {
+	return s.waiter.c
+}
+
+func (s *simulatedTicker) Stop() {
+	s.clock.mutex.Lock()
+	defer s.clock.mutex.Unlock()
+	s.waiter.stopped = true
+	s.clock.removeWaiter(s.waiter)
+}
+
+// SimulatedClock is clock that moves only when Advance or Set is called.
+// it is used by backtests and tests to drive time deterministically
+type SimulatedClock struct {
+	now         time.Time
+	waiters     []*waiter
+	mutex       *sync.Mutex
+	waitersCond *sync.Cond
+}
+
+func (s *SimulatedClock) Now() (time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.now
+}
+
+func (s *SimulatedClock) Since(t time.Time) (time.Duration) {
+	return s.Now().Sub(t)
+}
+
+func (s *SimulatedClock) addWaiter(w *waiter) {
+	s.waiters = append(s.waiters, w)
+	s.waitersCond.Broadcast()
+}
+
+func (s *SimulatedClock) removeWaiter(w *waiter) {
+	for idx, v := range s.waiters {
+		if v == w {
+			s.waiters = append(s.waiters[:idx], s.waiters[idx+1:]...)
+			return
+		}
+	}
+}
+
+func (s *SimulatedClock) After(d time.Duration) (<-chan time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- s.now
+		return c
+	}
+	s.addWaiter(&waiter{
+		deadline: s.now.Add(d),
+		c:        c,
+	})
+	return c
+}
+
+func (s *SimulatedClock) Sleep(d time.Duration) {
+	<-s.After(d)
+}
+
+func (s *SimulatedClock) NewTicker(d time.Duration) (Ticker) {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	w := &waiter{
+		deadline: s.now.Add(d),
+		period:   d,
+		c:        make(chan time.Time, 1),
+	}
+	s.addWaiter(w)
+	return &simulatedTicker{
+		clock:  s,
+		waiter: w,
+	}
+}
+
+// Set is move clock to t and fire timers whose deadline has come in order of deadline
+func (s *SimulatedClock) Set(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for {
+		sort.SliceStable(s.waiters, func(i, j int) (bool) {
+			return s.waiters[i].deadline.Before(s.waiters[j].deadline)
+		})
+		if len(s.waiters) == 0 || s.waiters[0].deadline.After(t) {
+			break
+		}
+		w := s.waiters[0]
+		if w.deadline.After(s.now) {
+			s.now = w.deadline
+		}
+		if w.period > 0 {
+			// time.Tickerと同じく受け取られていなければ捨てる
+			select {
+			case w.c <- s.now:
+			default:
+			}
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			w.c <- s.now
+			s.waiters = s.waiters[1:]
+		}
+	}
+	if t.After(s.now) {
+		s.now = t
+	}
+}
+
+// Advance is move clock forward by d
+func (s *SimulatedClock) Advance(d time.Duration) {
+	s.Set(s.Now().Add(d))
+}
+
+// BlockUntil is wait until n timers, sleepers or tickers are waiting on the clock
+func (s *SimulatedClock) BlockUntil(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for len(s.waiters) < n {
+		s.waitersCond.Wait()
+	}
+}
+
+// NewSimulatedClock is create simulated clock that starts at now
+func NewSimulatedClock(now time.Time) (*SimulatedClock) {
+	mutex := new(sync.Mutex)
+	return &SimulatedClock{
+		now:         now,
+		waiters:     make([]*waiter, 0),
+		mutex:       mutex,
+		waitersCond: sync.NewCond(mutex),
+	}
+}
@@ -0,0 +1,80 @@
+package clocktest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSimulatedClockAfter(t *testing.T) {
+	c := clock.NewSimulatedClock(start)
+	after1 := c.After(time.Second)
+	after2 := c.After(2 * time.Second)
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-after1:
+		t.Fatalf("timer fired before deadline")
+	default:
+	}
+	c.Advance(2 * time.Second)
+	fired1 := <-after1
+	fired2 := <-after2
+	if !fired1.Equal(start.Add(time.Second)) || !fired2.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("unexpected fired time (fired1 = %v, fired2 = %v)", fired1, fired2)
+	}
+	if !c.Now().Equal(start.Add(2500 * time.Millisecond)) {
+		t.Fatalf("unexpected now (%v)", c.Now())
+	}
+}
+
+func TestSimulatedClockSleep(t *testing.T) {
+	c := clock.NewSimulatedClock(start)
+	done := make(chan time.Time)
+	go func() {
+		c.Sleep(time.Minute)
+		done <- c.Now()
+	}()
+	// sleepが始まるまで待ってから進める
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	now := <-done
+	if !now.Equal(start.Add(time.Minute)) {
+		t.Fatalf("unexpected wake up time (%v)", now)
+	}
+}
+
+func TestSimulatedClockTicker(t *testing.T) {
+	c := clock.NewSimulatedClock(start)
+	ticker := c.NewTicker(time.Second)
+	c.Advance(time.Second)
+	tick := <-ticker.C()
+	if !tick.Equal(start.Add(time.Second)) {
+		t.Fatalf("unexpected tick (%v)", tick)
+	}
+	// 受け取られなかったtickは捨てられる
+	c.Advance(3 * time.Second)
+	<-ticker.C()
+	select {
+	case tick := <-ticker.C():
+		t.Fatalf("unexpected tick (%v)", tick)
+	default:
+	}
+	ticker.Stop()
+	c.Advance(time.Second)
+	select {
+	case tick := <-ticker.C():
+		t.Fatalf("tick after stop (%v)", tick)
+	default:
+	}
+}
+
+func TestDefaultClock(t *testing.T) {
+	defer clock.SetDefault(clock.NewSystemClock())
+	c := clock.NewSimulatedClock(start)
+	clock.SetDefault(c)
+	if !clock.Default().Now().Equal(start) {
+		t.Fatalf("default clock is not replaced")
+	}
+}
@@ -1,5 +1,9 @@
 package exchange
 
+import (
+	"github.com/AutomaticCoinTrader/ACT/clock"
+)
+
 type OrderAction string
 
 const (
@@ -29,6 +33,8 @@ type TradesCursor interface {
 
 // トレードコンテキストが更新されるたびに呼ばれる
 type StreamingCallback func(currencyPair string, ex Exchange) (error)
+// ストリーミングが再接続するたびに呼ばれる
+type ReconnectCallback func(currencyPair string, ex Exchange)
 type RetryCallback func(price *float64, amount *float64, errMsg string, retryCallbackData interface{}) (bool)
 
 type Exchange interface {
@@ -55,3 +61,13 @@ type Exchange interface {
 	StartStreamings() (error)
 	StopStreamings() (error)
 }
+
+// ReconnectNotifier is implemented by exchanges which notify reconnections of streaming
+type ReconnectNotifier interface {
+	SetReconnectCallback(reconnectCallback ReconnectCallback)
+}
+
+// ClockSetter is implemented by exchanges which wait for rate limits and retries with clock
+type ClockSetter interface {
+	SetClock(clock clock.Clock)
+}
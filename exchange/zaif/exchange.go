@@ -2,18 +2,21 @@ package zaif
 
 import (
 	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/clock"
 	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"strings"
 	"sync"
 	"strconv"
 	"math"
-	"log"
 )
 
 const (
 	exchangeName = "zaif"
 )
 
+var exchangeLogger = logger.Get("exchange").WithField(logger.FieldExchange, exchangeName)
+
 type BoardCursor struct {
 	index  int
 	values [][]float64
@@ -94,12 +97,12 @@ func (o *OrderHistoryCursor) Next() (int64, string, exchange.OrderAction, float6
 	}
 	id, err := strconv.ParseInt(key, 10, 64)
 	if err != nil {
-		log.Printf("can not parse id (exchange = %v, reason = %v)", exchangeName, err)
+		exchangeLogger.Errorf("can not parse id (exchange = %v, reason = %v)", exchangeName, err)
 		return 0, "", exchange.OrderActUnkown, 0, 0, 0, false
 	}
 	ts, err := strconv.ParseInt(value.Timestamp, 10, 64)
 	if err != nil {
-		log.Printf("can not parse timestamp (exchange = %v, reason = %v)", exchangeName, err)
+		exchangeLogger.Errorf("can not parse timestamp (exchange = %v, reason = %v)", exchangeName, err)
 		return 0, "", exchange.OrderActUnkown, 0, 0, 0, false
 	}
 	return id, value.CurrencyPair, action, value.Price, value.Amount, ts, true
@@ -160,12 +163,12 @@ func (o *ActiveOrderCursor) Next() (int64, string, exchange.OrderAction, float64
 	}
 	id, err := strconv.ParseInt(key, 10, 64)
 	if err != nil {
-		log.Printf("can not parse id (exchange = %v, reason = %v)", exchangeName, err)
+		exchangeLogger.Errorf("can not parse id (exchange = %v, reason = %v)", exchangeName, err)
 		return 0, "", exchange.OrderActUnkown, 0, 0, 0, false
 	}
 	ts, err := strconv.ParseInt(value.Timestamp, 10, 64)
 	if err != nil {
-		log.Printf("can not parse timestamp (exchange = %v, reason = %v)", exchangeName, err)
+		exchangeLogger.Errorf("can not parse timestamp (exchange = %v, reason = %v)", exchangeName, err)
 		return 0, "", exchange.OrderActUnkown, 0, 0, 0, false
 	}
 	return id, value.CurrencyPair, action, value.Price, value.Amount, ts, true
@@ -479,6 +482,18 @@ func (e *Exchange) Initialize(streamingCallback exchange.StreamingCallback) (err
 	return nil
 }
 
+// SetReconnectCallback is set callback called when streaming is reconnected. it should be called before StartStreamings
+func (e *Exchange) SetReconnectCallback(reconnectCallback exchange.ReconnectCallback) {
+	e.requester.SetReconnectCallback(func(currencyPair string) {
+		reconnectCallback(currencyPair, e)
+	})
+}
+
+// SetClock is set clock used by rate limiters and retry waits. it should be called before StartStreamings
+func (e *Exchange) SetClock(clock clock.Clock) {
+	e.requester.SetClock(clock)
+}
+
 // Finalize is finalize exchage
 func (e *Exchange) Finalize() (error) {
 	return nil
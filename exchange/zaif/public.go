@@ -4,11 +4,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/gorilla/websocket"
 	"github.com/AutomaticCoinTrader/ACT/utility"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"path"
 	"fmt"
 	"net/http"
 	"encoding/json"
-	"log"
 	"time"
 )
 
@@ -35,8 +36,8 @@ func (r *Requester) Currencies(currency string) (*PublicCurrenciesResponse, *uti
 			return newRes, res, resBody, err
 		}, request)
 		if err != nil {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry currencies (currency = %v, err: %v)", currency, err)
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry currencies (currency = %v, err: %v)", currency, err)
 			continue
 		}
 		return newRes.(*PublicCurrenciesResponse), request, response, err
@@ -74,8 +75,8 @@ func (r *Requester) CurrencyPairs(currencyPair string) (*PublicCurrencyPairsResp
 			return newRes, res, resBody, err
 		}, request)
 		if err != nil {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry currency pairs (currency pair = %v, err: %v)", currencyPair, err)
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry currency pairs (currency pair = %v, err: %v)", currencyPair, err)
 			continue
 		}
 		return newRes.(*PublicCurrencyPairsResponse), request, response, err
@@ -101,8 +102,8 @@ func (r *Requester) LastPrice(currencyPair string) (*PublicLastPriceResponse, *u
 			return newRes, res, resBody, err
 		}, request)
 		if err != nil {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry last price (currency pair = %v, err: %v)", currencyPair, err)
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry last price (currency pair = %v, err: %v)", currencyPair, err)
 			continue
 		}
 		return newRes.(*PublicLastPriceResponse), request, response, err
@@ -134,8 +135,8 @@ func (r *Requester) Ticker(currencyPair string) (*PublicTickerResponse, *utility
 			return newRes, res, resBody, err
 		}, request)
 		if err != nil {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry ticker (currency pair = %v, err: %v)", currencyPair, err)
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry ticker (currency pair = %v, err: %v)", currencyPair, err)
 			continue
 		}
 		return newRes.(*PublicTickerResponse), request, response, err
@@ -169,8 +170,8 @@ func (r *Requester) Trades(currencyPair string) (*PublicTradesResponse, *utility
 			return newRes, res, resBody, err
 		}, request)
 		if err != nil {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry terades (currency pair = %v, err: %v)", currencyPair, err)
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry terades (currency pair = %v, err: %v)", currencyPair, err)
 			continue
 		}
 		return newRes.(*PublicTradesResponse), request, response, err
@@ -207,8 +208,8 @@ func (r *Requester) Depth(currencyPair string) (*PublicDepthReaponse, *utility.H
 	for {
 		newRes, request, response, err := r.DepthNoRetry(currencyPair)
 		if err != nil {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry depth (currency pair = %v, err: %v)", currencyPair, err)
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry depth (currency pair = %v, err: %v)", currencyPair, err)
 			continue
 		}
 		return newRes, request, response, err
@@ -250,19 +251,20 @@ func (r Requester) streamingCallback(conn *websocket.Conn, userCallbackData inte
 	if err != nil {
 		return errors.Wrap(err, "can not read message of streaming")
 	}
+	metrics.IncWebsocketMessage(exchangeName, streaminCallbackData.currencyPair)
 	if messageType != websocket.TextMessage {
-		log.Printf("unsupported message type (message type = %v, message = %v)", messageType, message)
+		exchangeLogger.Errorf("unsupported message type (message type = %v, message = %v)", messageType, message)
 		return nil
 	}
 	newRes := new(StreamingResponse)
 	err = json.Unmarshal(message, newRes)
 	if err != nil {
-		log.Printf("can not unmarshal message of streaming (%v)", message)
+		exchangeLogger.Errorf("can not unmarshal message of streaming (%v)", message)
 		return nil
 	}
 	err = streaminCallbackData.callback(streaminCallbackData.currencyPair, newRes, streaminCallbackData.callbackData)
 	if err != nil {
-		log.Printf("call back error of streaming (%v)", err)
+		exchangeLogger.Errorf("call back error of streaming (%v)", err)
 		return nil
 	}
 	return nil
@@ -274,14 +276,14 @@ func (r Requester) StreamingStart(currencyPair string, callback StreamingCallbac
 	if ok {
 		return errors.Errorf("already exists streaming (currency pair = %v)", currencyPair)
 	}
-	log.Printf("start streaming (currency pair = %v)", currencyPair)
+	exchangeLogger.WithField(logger.FieldCurrencyPair, currencyPair).Infof("start streaming (currency pair = %v)", currencyPair)
 	requestURL := "wss://ws.zaif.jp/stream?currency_pair=" + currencyPair
 	streaminCallbackData := &streaminCallbackData{
 		currencyPair: currencyPair,
 		callback:     callback,
 		callbackData: callbackData,
 	}
-	newClient := utility.NewWSClient(r.readBufSize, r.writeBufSize, r.retry, r.retryWait)
+	newClient := r.newWSClient(currencyPair)
 	err := newClient.Start(r.streamingCallback, streaminCallbackData, requestURL, nil)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("can not start streaming (url = %v)", requestURL))
@@ -294,7 +296,7 @@ func (r Requester) StreamingStart(currencyPair string, callback StreamingCallbac
 func (r Requester) StreamingStop(currencyPair string) {
 	client, ok := r.wsClients[currencyPair]
 	if !ok {
-		log.Printf("not found streaming (currency pair = %v)", currencyPair)
+		exchangeLogger.Warnf("not found streaming (currency pair = %v)", currencyPair)
 		return
 	}
 	client.Stop()
@@ -314,19 +316,20 @@ func (r Requester) proxyStreamingCallback(conn *websocket.Conn, userCallbackData
 	if err != nil {
 		return errors.Wrap(err, "can not read message of proxy streaming")
 	}
+	metrics.IncWebsocketMessage(exchangeName, proxyStreaminCallbackData.currencyPair)
 	if messageType != websocket.TextMessage {
-		log.Printf("unsupported message type (message type = %v, message = %v)", messageType, message)
+		exchangeLogger.Errorf("unsupported message type (message type = %v, message = %v)", messageType, message)
 		return nil
 	}
 	newRes := new(PublicDepthReaponse)
 	err = json.Unmarshal(message, newRes)
 	if err != nil {
-		log.Printf("can not unmarshal message of proxy streaming (%v)", message)
+		exchangeLogger.Errorf("can not unmarshal message of proxy streaming (%v)", message)
 		return nil
 	}
 	err = proxyStreaminCallbackData.callback(proxyStreaminCallbackData.currencyPair, newRes, proxyStreaminCallbackData.callbackData)
 	if err != nil {
-		log.Printf("call back error of proxy streaming (%v)", err)
+		exchangeLogger.Errorf("call back error of proxy streaming (%v)", err)
 		return nil
 	}
 	return nil
@@ -338,14 +341,14 @@ func (r Requester) ProxyStreamingStart(addrPort string, currencyPair string, cal
 	if ok {
 		return errors.Errorf("already exists proxy streaming (currency pair = %v)", currencyPair)
 	}
-	log.Printf("start proxy streaming (currency pair = %v)", currencyPair)
+	exchangeLogger.WithField(logger.FieldCurrencyPair, currencyPair).Infof("start proxy streaming (currency pair = %v)", currencyPair)
 	requestURL := fmt.Sprintf("ws://%v/%v", addrPort, currencyPair)
 	proxyStreaminCallbackData := &proxyStreaminCallbackData{
 		currencyPair: currencyPair,
 		callback:     callback,
 		callbackData: callbackData,
 	}
-	newClient := utility.NewWSClient(r.readBufSize, r.writeBufSize, r.retry, r.retryWait)
+	newClient := r.newWSClient(currencyPair)
 	err := newClient.Start(r.proxyStreamingCallback, proxyStreaminCallbackData, requestURL, nil)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("can not start proxy streaming (url = %v)", requestURL))
@@ -359,7 +362,7 @@ func (r Requester) ProxyStreamingStop(addrPort string, currencyPair string) {
 	clientId := addrPort + "@" + currencyPair
 	client, ok := r.proxyWsClients[clientId]
 	if !ok {
-		log.Printf("not found proxy streaming (currency pair = %v)", currencyPair)
+		exchangeLogger.Warnf("not found proxy streaming (currency pair = %v)", currencyPair)
 		return
 	}
 	client.Stop()
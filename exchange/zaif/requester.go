@@ -3,6 +3,8 @@ package zaif
 import (
 	"github.com/pkg/errors"
 	"github.com/AutomaticCoinTrader/ACT/utility"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
 	"net/url"
 	"crypto/hmac"
 	"crypto/sha512"
@@ -13,8 +15,8 @@ import (
 	"net/http"
 	"encoding/json"
 	"sync"
-	"log"
 	"net"
+	"strings"
 )
 
 type RequesterKey struct {
@@ -39,6 +41,8 @@ type Requester struct {
 	publicApiHistoryMutex *sync.Mutex
 	tradeApiHistory       []int64
 	tradeApiHistoryMutex  *sync.Mutex
+	clock                 clock.Clock
+	reconnectCallback     func(currencyPair string)
 }
 
 type urlBuilder int
@@ -82,13 +86,14 @@ func (b urlBuilder) getURL() (string) {
 }
 
 func (r *Requester) MakePublicRequest(resource string, params string) (*utility.HTTPRequest) {
+	waitStart := r.clock.Now()
 	r.publicApiHistoryMutex.Lock()
 	for {
 		// waitを入れる処理
 		// ４０３ Forbiddenを緩和する
 		lastIdx := -1
 		var lastTs int64 = 0
-		now := time.Now()
+		now := r.clock.Now()
 		for idx, ts := range r.publicApiHistory {
 			if ts > now.UnixNano()-time.Second.Nanoseconds() {
 				// １秒以内のものになったらbreak
@@ -109,12 +114,13 @@ func (r *Requester) MakePublicRequest(resource string, params string) (*utility.
 			break
 		}
 		if lastTs > 0 {
-			time.Sleep(time.Duration(lastTs-(now.UnixNano()-time.Second.Nanoseconds())) * time.Nanosecond)
+			r.clock.Sleep(time.Duration(lastTs-(now.UnixNano()-time.Second.Nanoseconds())) * time.Nanosecond)
 		} else {
-			log.Print("called trade api with no wait")
+			exchangeLogger.Debugf("called trade api with no wait")
 		}
 	}
 	r.publicApiHistoryMutex.Unlock()
+	metrics.ObserveRateLimitWait(exchangeName, "public", r.clock.Since(waitStart))
 	u := Public.buildURL(resource)
 	if params != "" {
 		u += "?" + params
@@ -122,19 +128,22 @@ func (r *Requester) MakePublicRequest(resource string, params string) (*utility.
 	headers := make(map[string]string)
 	headers["Connection"] = "close"
 	return &utility.HTTPRequest{
-		Headers: headers,
-		URL:     u,
+		Headers:  headers,
+		URL:      u,
+		// 通貨ペアを除いたapi名
+		Endpoint: strings.SplitN(resource, "/", 2)[0],
 	}
 }
 
 func (r *Requester) makeTradeRequest(method string, params string) (*utility.HTTPRequest) {
+	waitStart := r.clock.Now()
 	r.tradeApiHistoryMutex.Lock()
 	for {
 		// waitを入れる処理
 		// ４０３ Forbiddenを緩和する
 		lastIdx := -1
 		var lastTs int64 = 0
-		now := time.Now()
+		now := r.clock.Now()
 		for idx, ts := range r.tradeApiHistory {
 			if ts > now.UnixNano()-time.Second.Nanoseconds() {
 				// １秒以内のものになったらbreak
@@ -155,12 +164,13 @@ func (r *Requester) makeTradeRequest(method string, params string) (*utility.HTT
 			break
 		}
 		if lastTs > 0 {
-			time.Sleep(time.Duration(lastTs-(now.UnixNano()-time.Second.Nanoseconds())) * time.Nanosecond)
+			r.clock.Sleep(time.Duration(lastTs-(now.UnixNano()-time.Second.Nanoseconds())) * time.Nanosecond)
 		} else {
-			log.Print("called trade api with no wait")
+			exchangeLogger.Debugf("called trade api with no wait")
 		}
 	}
 	r.tradeApiHistoryMutex.Unlock()
+	metrics.ObserveRateLimitWait(exchangeName, "trade", r.clock.Since(waitStart))
 	u := Trade.getURL()
 	values := url.Values{}
 	values.Set("nonce", r.getNonce())
@@ -184,15 +194,17 @@ func (r *Requester) makeTradeRequest(method string, params string) (*utility.HTT
 	headers["Sign"] = sign
 	//log.Printf("key = %v, sign = %v", key, sign)
 	return &utility.HTTPRequest{
-		URL:     u,
-		Headers: headers,
-		Body:    body,
+		URL:      u,
+		Headers:  headers,
+		Body:     body,
+		Endpoint: method,
 	}
 }
 
 func (r *Requester) getNonce() (string) {
 	nonceMutex.Lock()
 	defer nonceMutex.Unlock()
+	// nonceは取引所に送るものなので実際の時刻を使う
 	now := time.Now()
 	seq += 1
 	return strconv.FormatInt(now.Unix(), 10) + "." + fmt.Sprintf("%06d", now.Nanosecond()/1000) + fmt.Sprintf("%03d", seq%1000)
@@ -218,6 +230,32 @@ func (r *Requester) getHttpClient() (*utility.HTTPClient) {
 	return httpClient
 }
 
+// SetClock is set clock used by rate limiters and retry waits
+func (r *Requester) SetClock(clock clock.Clock) {
+	r.clock = clock
+	for _, httpClient := range r.httpClients {
+		httpClient.SetClock(clock)
+	}
+}
+
+// SetReconnectCallback is set callback called when streaming is reconnected
+func (r *Requester) SetReconnectCallback(reconnectCallback func(currencyPair string)) {
+	r.reconnectCallback = reconnectCallback
+}
+
+func (r *Requester) newWSClient(currencyPair string) (*utility.WSClient) {
+	newClient := utility.NewWSClient(r.readBufSize, r.writeBufSize, r.retry, r.retryWait)
+	newClient.SetClock(r.clock)
+	newClient.SetMetricsLabels(exchangeName, currencyPair)
+	if r.reconnectCallback != nil {
+		reconnectCallback := r.reconnectCallback
+		newClient.SetReconnectCallback(func() {
+			reconnectCallback(currencyPair)
+		})
+	}
+	return newClient
+}
+
 // NewRequester is create requester
 func NewRequester(keys []*RequesterKey, bindAddresses []string, retry int, retryWait, timeout int, readBufSize int, writeBufSize int) (*Requester, error) {
 	requester := &Requester{
@@ -237,6 +275,7 @@ func NewRequester(keys []*RequesterKey, bindAddresses []string, retry int, retry
 		publicApiHistoryMutex: new(sync.Mutex),
 		tradeApiHistory:       make([]int64, 0, tradeApiGurdCount),
 		tradeApiHistoryMutex:  new(sync.Mutex),
+		clock:                 clock.Default(),
 	}
 	if bindAddresses == nil || len(bindAddresses) == 0 {
 		requester.httpClients = append(requester.httpClients, utility.NewHTTPClient(retry, retryWait, timeout, nil), )
@@ -249,6 +288,9 @@ func NewRequester(keys []*RequesterKey, bindAddresses []string, retry int, retry
 			requester.httpClients = append(requester.httpClients, utility.NewHTTPClient(retry, retryWait, timeout, localAddr))
 		}
 	}
+	for _, httpClient := range requester.httpClients {
+		httpClient.SetName(exchangeName)
+	}
 	return requester, nil
 }
 
@@ -9,8 +9,9 @@ import (
 	"time"
 	"strconv"
 	"net/http"
+	"github.com/AutomaticCoinTrader/ACT/clock"
 	"github.com/AutomaticCoinTrader/ACT/exchange"
-	"log"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"net/url"
 )
 
@@ -205,15 +206,15 @@ type TradeCommonResponse struct {
 	Error   string `json:"error"`
 }
 
-func (t TradeCommonResponse) needRetry() (bool) {
+func (t TradeCommonResponse) needRetry(c clock.Clock) (bool) {
 	if t.Success == 0 {
-		log.Printf(" error message (%v)", t.Error)
+		exchangeLogger.Warnf(" error message (%v)", t.Error)
 		if t.Error == "order is too new" {
 			return false
 		} else if t.Error == "order not found" {
 			return false
 		} else if t.Error == "time wait restriction, please try later." {
-			time.Sleep(restrictionWait * time.Millisecond)
+			c.Sleep(restrictionWait * time.Millisecond)
 		}
 		return true
 	}
@@ -270,9 +271,9 @@ func (r *Requester) GetInfo() (*TradeGetInfoResponse, *utility.HTTPRequest, *htt
 			newRes := new(TradeGetInfoResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeGetInfoResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry get info (err: %v)", err)
+		if err != nil || newRes.(*TradeGetInfoResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry get info (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeGetInfoResponse), request, response, err
@@ -327,9 +328,9 @@ func (r *Requester) GetInfo2() (*TradeGetInfo2Response, *utility.HTTPRequest, *h
 			newRes := new(TradeGetInfo2Response)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeGetInfo2Response).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry get info 2 (err: %v)", err)
+		if err != nil || newRes.(*TradeGetInfo2Response).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry get info 2 (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeGetInfo2Response), request, response, err
@@ -358,9 +359,9 @@ func (r *Requester) GetPersonalInfo() (*TradeGetPersonalInfoResponse, *utility.H
 			newRes := new(TradeGetPersonalInfoResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeGetPersonalInfoResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry get personal info (err: %v)", err)
+		if err != nil || newRes.(*TradeGetPersonalInfoResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry get personal info (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeGetPersonalInfoResponse), request, response, err
@@ -394,9 +395,9 @@ func (r *Requester) GetIDInfo() (*TradeGetIDInfoResponse, *utility.HTTPRequest,
 			newRes := new(TradeGetIDInfoResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeGetIDInfoResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry get id info (err: %v)", err)
+		if err != nil || newRes.(*TradeGetIDInfoResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry get id info (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeGetIDInfoResponse), request, response, err
@@ -461,9 +462,9 @@ func (r *Requester) TradeHistory(tradeHistoryParams *TradeHistoryParams) (*Trade
 			newRes := new(TradeHistoryResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeHistoryResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry get trade history (err: %v)", err)
+		if err != nil || newRes.(*TradeHistoryResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry get trade history (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeHistoryResponse), request, response, err
@@ -524,9 +525,9 @@ func (r *Requester) TradeActiveOrder(tradeActiveOrderParams *TradeActiveOrderPar
 			newRes := new(TradeActiveOrderResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeActiveOrderResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry active order (err: %v)", err)
+		if err != nil || newRes.(*TradeActiveOrderResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry active order (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeActiveOrderResponse), request, response, err
@@ -551,9 +552,9 @@ func (r *Requester) TradeActiveOrderBoth(tradeActiveOrderParams *TradeActiveOrde
 			newRes := new(TradeActiveOrderBothResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeActiveOrderBothResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry active order both (err: %v)", err)
+		if err != nil || newRes.(*TradeActiveOrderBothResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry active order both (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeActiveOrderBothResponse), request, response, err
@@ -608,6 +609,7 @@ type TradeResponse struct {
 }
 
 func (r *Requester) tradeBase(tradeParams *TradeParams, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (*TradeResponse, *utility.HTTPRequest, *http.Response, error) {
+	tradeLogger := exchangeLogger.WithField(logger.FieldCurrencyPair, tradeParams.CurrencyPair)
 	for {
 		tradeParams.fixupPriceAndAmount(r)
 		params := make(url.Values)
@@ -619,7 +621,7 @@ func (r *Requester) tradeBase(tradeParams *TradeParams, retryCallback exchange.R
 			params.Add("limit", strconv.FormatFloat(tradeParams.Limit, 'f', r.getPricePrec(tradeParams.CurrencyPair), 64))
 		}
 		request := r.makeTradeRequest("trade", params.Encode())
-		log.Printf("try trade action = %v, currency pair = %v, price = %v, amount = %v, params = %v", tradeParams.Action, tradeParams.CurrencyPair, tradeParams.Price, tradeParams.Amount, params.Encode())
+		tradeLogger.Infof("try trade action = %v, currency pair = %v, price = %v, amount = %v, params = %v", tradeParams.Action, tradeParams.CurrencyPair, tradeParams.Price, tradeParams.Amount, params.Encode())
 		newRes, response, err := r.unmarshal(func(request *utility.HTTPRequest) (interface{}, *http.Response, []byte, error) {
 			httpClient := r.getHttpClient()
 			res, resBody, err := httpClient.DoRequest(utility.HTTPMethdoPOST, request, true)
@@ -629,7 +631,7 @@ func (r *Requester) tradeBase(tradeParams *TradeParams, retryCallback exchange.R
 			newRes := new(TradeResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeResponse).needRetry() {
+		if err != nil || newRes.(*TradeResponse).needRetry(r.clock) {
 			var errMsg string
 			if err != nil {
 				errMsg = err.Error()
@@ -644,11 +646,11 @@ func (r *Requester) tradeBase(tradeParams *TradeParams, retryCallback exchange.R
 					return newRes.(*TradeResponse), request, response, nil
 				}
 			}
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry trade action = %v, currency pair = %v", tradeParams.Action, tradeParams.CurrencyPair)
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			tradeLogger.Warnf("retry trade action = %v, currency pair = %v", tradeParams.Action, tradeParams.CurrencyPair)
 			continue
 		}
-		log.Printf("trade done action = %v, currency pair = %v, price = %v, amount = %v", tradeParams.Action, tradeParams.CurrencyPair, tradeParams.Price, tradeParams.Amount)
+		tradeLogger.Infof("trade done action = %v, currency pair = %v, price = %v, amount = %v", tradeParams.Action, tradeParams.CurrencyPair, tradeParams.Price, tradeParams.Amount)
 		return newRes.(*TradeResponse), request, response, err
 	}
 }
@@ -712,9 +714,9 @@ func (r *Requester) TradeCancelOrder(tradeCancelOrderParams *TradeCancelOrderPar
 			newRes := new(TradeCancelOrderResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeCancelOrderResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry cancel (err: %v)", err)
+		if err != nil || newRes.(*TradeCancelOrderResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.With(logger.Fields{logger.FieldCurrencyPair: tradeCancelOrderParams.CurrencyPair, logger.FieldOrderID: tradeCancelOrderParams.OrderId}).Warnf("retry cancel (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeCancelOrderResponse), request, response, err
@@ -787,9 +789,9 @@ func (r *Requester) TradeWithdraw(tradeWithdrawParams *TradeWithdrawParams) (*Tr
 			newRes := new(TradeWithdrawResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeWithdrawResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry widthrow (err: %v)", err)
+		if err != nil || newRes.(*TradeWithdrawResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry widthrow (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeWithdrawResponse), request, response, err
@@ -812,7 +814,7 @@ type TradeDepositHistoryParams struct {
 func (r *Requester) NewTradeDepositHistoryParams() (*TradeDepositHistoryParams) {
 	return &TradeDepositHistoryParams{
 		EndID: math.MaxInt64,
-		End:   time.Now().Unix(),
+		End:   r.clock.Now().Unix(),
 	}
 }
 
@@ -847,9 +849,9 @@ func (r *Requester) TradeDepositHistory(tradeDepositHistoryParams *TradeDepositH
 			newRes := new(TradeDepositHistoryResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeDepositHistoryResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry deposit (err: %v)", err)
+		if err != nil || newRes.(*TradeDepositHistoryResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry deposit (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeDepositHistoryResponse), request, response, err
@@ -872,7 +874,7 @@ type TradeWithdrawHistoryParams struct {
 func (r *Requester) NewTradeWithdrawHistoryParams() (*TradeWithdrawHistoryParams) {
 	return &TradeWithdrawHistoryParams{
 		EndID: math.MaxInt64,
-		End:   time.Now().Unix(),
+		End:   r.clock.Now().Unix(),
 	}
 }
 
@@ -907,9 +909,9 @@ func (r *Requester) TradeWithdrawHistory(tradeWithdrawHistoryParams *TradeWithdr
 			newRes := new(TradeWithdrawHistoryResponse)
 			return newRes, res, resBody, err
 		}, request)
-		if err != nil || newRes.(*TradeWithdrawHistoryResponse).needRetry() {
-			time.Sleep(time.Duration(r.retryWait) * time.Millisecond)
-			log.Printf("retry width history (err: %v)", err)
+		if err != nil || newRes.(*TradeWithdrawHistoryResponse).needRetry(r.clock) {
+			r.clock.Sleep(time.Duration(r.retryWait) * time.Millisecond)
+			exchangeLogger.Warnf("retry width history (err: %v)", err)
 			continue
 		}
 		return newRes.(*TradeWithdrawHistoryResponse), request, response, err
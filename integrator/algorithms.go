@@ -0,0 +1,69 @@
+package integrator
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/AutomaticCoinTrader/ACT/robot"
+	"net/http"
+	"fmt"
+)
+
+func (i *Integrator) apiGetAlgorithms(context *gin.Context) {
+	apiOK(context, i.robot.GetAlgorithmStats())
+}
+
+func (i *Integrator) apiGetAlgorithm(context *gin.Context) {
+	stats, err := i.robot.GetAlgorithmStatsByName(context.Param("name"))
+	if err != nil {
+		apiAbort(context, http.StatusNotFound, apiErrorNotFound, err.Error())
+		return
+	}
+	apiOK(context, stats)
+}
+
+func (i *Integrator) apiPauseAlgorithm(context *gin.Context) {
+	name := context.Param("name")
+	err := i.robot.PauseAlgorithm(name)
+	if err != nil {
+		apiAbort(context, http.StatusNotFound, apiErrorNotFound, err.Error())
+		return
+	}
+	i.apiGetAlgorithm(context)
+}
+
+func (i *Integrator) apiResumeAlgorithm(context *gin.Context) {
+	name := context.Param("name")
+	err := i.robot.ResumeAlgorithm(name)
+	if err != nil {
+		apiAbort(context, http.StatusNotFound, apiErrorNotFound, err.Error())
+		return
+	}
+	i.apiGetAlgorithm(context)
+}
+
+func (i *Integrator) apiReconfigureAlgorithm(context *gin.Context) {
+	config := make(map[string]interface{})
+	err := context.ShouldBindJSON(&config)
+	if err != nil {
+		apiAbort(context, http.StatusBadRequest, apiErrorBadRequest, fmt.Sprintf("invalid algorithm config (reason = %v)", err))
+		return
+	}
+	result, err := i.robot.ReconfigureAlgorithm(context.Param("name"), config)
+	if err != nil {
+		reconfigureError, ok := robot.GetReconfigureError(err)
+		if !ok {
+			apiAbort(context, http.StatusInternalServerError, apiErrorInternal, err.Error())
+			return
+		}
+		switch reconfigureError.Kind {
+		case robot.ReconfigureErrorNotFound:
+			apiAbort(context, http.StatusNotFound, apiErrorNotFound, err.Error())
+		case robot.ReconfigureErrorInitialize:
+			// アルゴリズムが新しい設定を受け付けなかった
+			apiAbort(context, http.StatusBadRequest, apiErrorBadRequest, err.Error())
+		default:
+			apiAbort(context, http.StatusInternalServerError, apiErrorInternal, err.Error())
+		}
+		return
+	}
+	apiOK(context, result)
+}
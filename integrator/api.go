@@ -0,0 +1,340 @@
+package integrator
+
+import (
+	"github.com/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"net/http"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	apiVersionPath      = "/api/v1"
+	defaultHistoryCount = 100
+)
+
+const (
+	apiErrorBadRequest    = "badRequest"
+	apiErrorNotFound      = "notFound"
+	apiErrorExchangeError = "exchangeError"
+	apiErrorInternal      = "internalError"
+)
+
+// apiError is error envelope of rest api
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiExchange struct {
+	Name          string   `json:"name"`
+	CurrencyPairs []string `json:"currencyPairs"`
+}
+
+type apiBoard struct {
+	Exchange     string      `json:"exchange"`
+	CurrencyPair string      `json:"currencyPair"`
+	// [価格, 数量]
+	Asks         [][]float64 `json:"asks"`
+	Bids         [][]float64 `json:"bids"`
+}
+
+type apiTicker struct {
+	Exchange     string  `json:"exchange"`
+	CurrencyPair string  `json:"currencyPair"`
+	LastPrice    float64 `json:"lastPrice"`
+	BestAsk      float64 `json:"bestAsk"`
+	BestBid      float64 `json:"bestBid"`
+}
+
+type apiTrade struct {
+	Time   int64   `json:"time"`
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+	Type   string  `json:"type"`
+}
+
+type apiOrder struct {
+	Exchange     string               `json:"exchange"`
+	CurrencyPair string               `json:"currencyPair"`
+	OrderID      int64                `json:"orderId"`
+	Action       exchange.OrderAction `json:"action"`
+	Price        float64              `json:"price"`
+	Amount       float64              `json:"amount"`
+	Timestamp    int64                `json:"timestamp"`
+}
+
+func apiOK(context *gin.Context, data interface{}) {
+	context.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func apiAbort(context *gin.Context, status int, code string, message string) {
+	// 監査ログに残す
+	context.Error(errors.New(message))
+	context.AbortWithStatusJSON(status, gin.H{"error": &apiError{Code: code, Message: message}})
+}
+
+func (i *Integrator) setupAPIRouting(engine *gin.Engine) {
+	api := engine.Group(apiVersionPath)
+	api.GET("/exchanges", i.requireRole(roleViewer), i.apiGetExchanges)
+	api.GET("/exchanges/:exchange", i.requireRole(roleViewer), i.apiGetExchange)
+	api.GET("/exchanges/:exchange/funds", i.requireRole(roleViewer), i.apiGetFunds)
+	api.GET("/exchanges/:exchange/orders", i.requireRole(roleViewer), i.apiGetActiveOrders)
+	api.GET("/exchanges/:exchange/orders/history", i.requireRole(roleViewer), i.apiGetOrderHistory)
+	api.POST("/exchanges/:exchange/orders", i.requireRole(roleTrader), i.audit, i.apiPlaceOrder)
+	api.DELETE("/exchanges/:exchange/orders/:orderId", i.requireRole(roleTrader), i.audit, i.apiCancelOrder)
+	api.GET("/exchanges/:exchange/pairs/:pair/board", i.requireRole(roleViewer), i.apiGetBoard)
+	api.GET("/exchanges/:exchange/pairs/:pair/ticker", i.requireRole(roleViewer), i.apiGetTicker)
+	api.GET("/exchanges/:exchange/pairs/:pair/trades", i.requireRole(roleViewer), i.apiGetTrades)
+	api.GET("/algorithms", i.requireRole(roleViewer), i.apiGetAlgorithms)
+	api.GET("/algorithms/:name", i.requireRole(roleViewer), i.apiGetAlgorithm)
+	api.POST("/algorithms/:name/pause", i.requireRole(roleTrader), i.audit, i.apiPauseAlgorithm)
+	api.POST("/algorithms/:name/resume", i.requireRole(roleTrader), i.audit, i.apiResumeAlgorithm)
+	api.PUT("/algorithms/:name/config", i.requireRole(roleAdmin), i.audit, i.apiReconfigureAlgorithm)
+	engine.NoRoute(func(context *gin.Context) {
+		if !strings.HasPrefix(context.Request.URL.Path, apiVersionPath+"/") {
+			return
+		}
+		apiAbort(context, http.StatusNotFound, apiErrorNotFound, fmt.Sprintf("not found (path = %v)", context.Request.URL.Path))
+	})
+}
+
+// apiExchangeParam is get exchange of path parameter. it aborts request if not found
+func (i *Integrator) apiExchangeParam(context *gin.Context) (exchange.Exchange, bool) {
+	name := context.Param("exchange")
+	ex, ok := i.getExchange(name)
+	if !ok {
+		apiAbort(context, http.StatusNotFound, apiErrorNotFound, fmt.Sprintf("not found exchange (exchange = %v)", name))
+		return nil, false
+	}
+	return ex, true
+}
+
+// apiCurrencyPairParam is get exchange and currency pair of path parameters. it aborts request if not found
+func (i *Integrator) apiCurrencyPairParam(context *gin.Context) (exchange.Exchange, string, bool) {
+	ex, ok := i.apiExchangeParam(context)
+	if !ok {
+		return nil, "", false
+	}
+	currencyPair := context.Param("pair")
+	for _, p := range ex.GetCurrencyPairs() {
+		if p == currencyPair {
+			return ex, currencyPair, true
+		}
+	}
+	apiAbort(context, http.StatusNotFound, apiErrorNotFound, fmt.Sprintf("not found currency pair (exchange = %v, currency pair = %v)", ex.GetName(), currencyPair))
+	return nil, "", false
+}
+
+func apiIntQuery(context *gin.Context, key string, defaultValue int64) (int64, bool) {
+	value := context.Query(key)
+	if value == "" {
+		return defaultValue, true
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		apiAbort(context, http.StatusBadRequest, apiErrorBadRequest, fmt.Sprintf("invalid %v (value = %v)", key, value))
+		return 0, false
+	}
+	return n, true
+}
+
+func newAPIExchange(ex exchange.Exchange) (*apiExchange) {
+	currencyPairs := append([]string(nil), ex.GetCurrencyPairs()...)
+	sort.Strings(currencyPairs)
+	return &apiExchange{
+		Name:          ex.GetName(),
+		CurrencyPairs: currencyPairs,
+	}
+}
+
+func (i *Integrator) apiGetExchanges(context *gin.Context) {
+	exchanges := make([]*apiExchange, 0)
+	for _, ex := range i.getExchanges() {
+		exchanges = append(exchanges, newAPIExchange(ex))
+	}
+	apiOK(context, exchanges)
+}
+
+func (i *Integrator) apiGetExchange(context *gin.Context) {
+	ex, ok := i.apiExchangeParam(context)
+	if !ok {
+		return
+	}
+	apiOK(context, newAPIExchange(ex))
+}
+
+func (i *Integrator) apiGetFunds(context *gin.Context) {
+	ex, ok := i.apiExchangeParam(context)
+	if !ok {
+		return
+	}
+	funds, err := ex.GetFunds()
+	if err != nil {
+		apiAbort(context, http.StatusBadGateway, apiErrorExchangeError, fmt.Sprintf("can not get funds (exchange = %v, reason = %v)", ex.GetName(), err))
+		return
+	}
+	metrics.SetBalances(ex.GetName(), funds)
+	apiOK(context, funds)
+}
+
+func newAPIOrders(ex exchange.Exchange, orderCursor exchange.OrderCursor) ([]*apiOrder) {
+	orders := make([]*apiOrder, 0, orderCursor.Len())
+	for {
+		orderID, currencyPair, action, price, amount, timestamp, ok := orderCursor.Next()
+		if !ok {
+			break
+		}
+		orders = append(orders, &apiOrder{
+			Exchange:     ex.GetName(),
+			CurrencyPair: currencyPair,
+			OrderID:      orderID,
+			Action:       action,
+			Price:        price,
+			Amount:       amount,
+			Timestamp:    timestamp,
+		})
+	}
+	return orders
+}
+
+func (i *Integrator) apiGetActiveOrders(context *gin.Context) {
+	ex, ok := i.apiExchangeParam(context)
+	if !ok {
+		return
+	}
+	orderCursor, err := ex.GetActiveOrderCursor()
+	if err != nil {
+		apiAbort(context, http.StatusBadGateway, apiErrorExchangeError, fmt.Sprintf("can not get active orders (exchange = %v, reason = %v)", ex.GetName(), err))
+		return
+	}
+	apiOK(context, newAPIOrders(ex, orderCursor))
+}
+
+func (i *Integrator) apiGetOrderHistory(context *gin.Context) {
+	ex, ok := i.apiExchangeParam(context)
+	if !ok {
+		return
+	}
+	count, ok := apiIntQuery(context, "count", defaultHistoryCount)
+	if !ok {
+		return
+	}
+	orderCursor, err := ex.GetOrderHistoryCursor(count)
+	if err != nil {
+		apiAbort(context, http.StatusBadGateway, apiErrorExchangeError, fmt.Sprintf("can not get order history (exchange = %v, reason = %v)", ex.GetName(), err))
+		return
+	}
+	apiOK(context, newAPIOrders(ex, orderCursor))
+}
+
+func limitBoard(values [][]float64, depth int64) ([][]float64) {
+	if values == nil {
+		return make([][]float64, 0)
+	}
+	if depth > 0 && int64(len(values)) > depth {
+		return values[:depth]
+	}
+	return values
+}
+
+func newAPIBoard(ex exchange.Exchange, currencyPair string, depth int64) (*apiBoard, error) {
+	// ストリーミングで受け取った板を返す
+	sellBoardCursor, buyBoardCursor, err := ex.GetSellBuyBoardCursor(currencyPair)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not get board (exchange = %v, currency pair = %v)", ex.GetName(), currencyPair)
+	}
+	return &apiBoard{
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Asks:         limitBoard(sellBoardCursor.All(), depth),
+		Bids:         limitBoard(buyBoardCursor.All(), depth),
+	}, nil
+}
+
+func (i *Integrator) apiGetBoard(context *gin.Context) {
+	ex, currencyPair, ok := i.apiCurrencyPairParam(context)
+	if !ok {
+		return
+	}
+	depth, ok := apiIntQuery(context, "depth", 0)
+	if !ok {
+		return
+	}
+	board, err := newAPIBoard(ex, currencyPair, depth)
+	if err != nil {
+		apiAbort(context, http.StatusBadGateway, apiErrorExchangeError, err.Error())
+		return
+	}
+	apiOK(context, board)
+}
+
+func newAPITicker(ex exchange.Exchange, currencyPair string) (*apiTicker, error) {
+	lastPrice, err := ex.GetLastPrice(currencyPair)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not get last price (exchange = %v, currency pair = %v)", ex.GetName(), currencyPair)
+	}
+	sellBoardCursor, buyBoardCursor, err := ex.GetSellBuyBoardCursor(currencyPair)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not get board (exchange = %v, currency pair = %v)", ex.GetName(), currencyPair)
+	}
+	ticker := &apiTicker{
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		LastPrice:    lastPrice,
+	}
+	if price, _, ok := sellBoardCursor.Next(); ok {
+		ticker.BestAsk = price
+	}
+	if price, _, ok := buyBoardCursor.Next(); ok {
+		ticker.BestBid = price
+	}
+	return ticker, nil
+}
+
+func (i *Integrator) apiGetTicker(context *gin.Context) {
+	ex, currencyPair, ok := i.apiCurrencyPairParam(context)
+	if !ok {
+		return
+	}
+	ticker, err := newAPITicker(ex, currencyPair)
+	if err != nil {
+		apiAbort(context, http.StatusBadGateway, apiErrorExchangeError, err.Error())
+		return
+	}
+	apiOK(context, ticker)
+}
+
+func (i *Integrator) apiGetTrades(context *gin.Context) {
+	ex, currencyPair, ok := i.apiCurrencyPairParam(context)
+	if !ok {
+		return
+	}
+	limit, ok := apiIntQuery(context, "limit", 0)
+	if !ok {
+		return
+	}
+	tradesCursor, err := ex.GetTradesCursor(currencyPair)
+	if err != nil {
+		apiAbort(context, http.StatusBadGateway, apiErrorExchangeError, fmt.Sprintf("can not get trades (exchange = %v, currency pair = %v, reason = %v)", ex.GetName(), currencyPair, err))
+		return
+	}
+	trades := make([]*apiTrade, 0, tradesCursor.Len())
+	for limit == 0 || int64(len(trades)) < limit {
+		time, price, amount, tradeType, ok := tradesCursor.Next()
+		if !ok {
+			break
+		}
+		trades = append(trades, &apiTrade{
+			Time:   time,
+			Price:  price,
+			Amount: amount,
+			Type:   tradeType,
+		})
+	}
+	apiOK(context, trades)
+}
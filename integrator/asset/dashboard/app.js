@@ -0,0 +1,274 @@
+(function () {
+  'use strict';
+
+  var tokenKey = 'act.dashboard.token';
+  var exchanges = [];
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  function el(tag, text, className) {
+    var e = document.createElement(tag);
+    if (text !== undefined && text !== null) {
+      e.textContent = String(text);
+    }
+    if (className) {
+      e.className = className;
+    }
+    return e;
+  }
+
+  function row(values) {
+    var tr = el('tr');
+    values.forEach(function (v) {
+      var td = el('td');
+      if (v instanceof Node) {
+        td.appendChild(v);
+      } else {
+        td.textContent = v === undefined || v === null ? '' : String(v);
+      }
+      tr.appendChild(td);
+    });
+    return tr;
+  }
+
+  function clear(e) {
+    while (e.firstChild) {
+      e.removeChild(e.firstChild);
+    }
+  }
+
+  function showMessage(text, isError) {
+    var message = $('message');
+    message.textContent = text;
+    message.className = isError ? 'error' : '';
+  }
+
+  // トークンを入力しなければブラウザでログインしたユーザーで呼ぶ
+  function callAPI(method, url, body) {
+    var headers = {};
+    if ($('token').value !== '') {
+      headers['Authorization'] = 'Bearer ' + $('token').value;
+    }
+    var init = {method: method, headers: headers, credentials: 'same-origin'};
+    if (body !== undefined) {
+      headers['Content-Type'] = 'application/json';
+      init.body = JSON.stringify(body);
+    }
+    return fetch(url, init).then(function (response) {
+      return response.json().then(function (json) {
+        if (json.error) {
+          throw new Error(json.error.code + ': ' + json.error.message);
+        }
+        return json.data;
+      });
+    });
+  }
+
+  function button(label, onClick) {
+    var b = el('button', label);
+    b.type = 'button';
+    b.addEventListener('click', onClick);
+    return b;
+  }
+
+  function renderBoard(pair) {
+    var div = el('div', null, 'board');
+    div.appendChild(el('h3', pair.currencyPair + '  最終価格 ' + pair.lastPrice));
+    var table = el('table');
+    table.appendChild(row(['', '価格', '数量']));
+    pair.asks.slice().reverse().forEach(function (v) {
+      var tr = row(['売', v[0], v[1]]);
+      tr.className = 'ask';
+      table.appendChild(tr);
+    });
+    pair.bids.forEach(function (v) {
+      var tr = row(['買', v[0], v[1]]);
+      tr.className = 'bid';
+      table.appendChild(tr);
+    });
+    div.appendChild(table);
+    return div;
+  }
+
+  function renderExchanges(snapshot) {
+    var container = $('exchanges');
+    var funds = $('funds');
+    var orders = $('orders');
+    clear(container);
+    clear(funds);
+    clear(orders);
+    snapshot.exchanges.forEach(function (ex) {
+      container.appendChild(el('h3', ex.name));
+      ex.pairs.forEach(function (pair) {
+        container.appendChild(renderBoard(pair));
+      });
+      if (!ex.account) {
+        return;
+      }
+      funds.appendChild(el('h3', ex.name));
+      ex.account.errors.forEach(function (e) {
+        funds.appendChild(el('div', e, 'error'));
+      });
+      var table = el('table');
+      Object.keys(ex.account.funds || {}).sort().forEach(function (currency) {
+        table.appendChild(row([currency, ex.account.funds[currency]]));
+      });
+      funds.appendChild(table);
+      ex.account.orders.forEach(function (order) {
+        orders.appendChild(row([order.exchange, order.currencyPair, order.orderId, order.action, order.price, order.amount,
+          button('取消', function () {
+            var url = '/api/v1/exchanges/' + encodeURIComponent(order.exchange) + '/orders/' + order.orderId +
+              '?currencyPair=' + encodeURIComponent(order.currencyPair);
+            callAPI('DELETE', url).then(function () {
+              showMessage('注文を取り消しました (' + order.orderId + ')', false);
+            }).catch(function (e) {
+              showMessage(e.message, true);
+            });
+          })]));
+      });
+    });
+  }
+
+  function renderPositions(snapshot) {
+    var tbody = $('positions');
+    clear(tbody);
+    Object.keys(snapshot.positions || {}).sort().forEach(function (name) {
+      snapshot.positions[name].forEach(function (p) {
+        tbody.appendChild(row([name, p.exchange, p.currencyPair, p.amount, p.averagePrice, p.realizedPnl, p.unrealizedPnl]));
+      });
+    });
+  }
+
+  function renderAlgorithms(snapshot) {
+    var tbody = $('algorithms');
+    clear(tbody);
+    snapshot.algorithms.forEach(function (a) {
+      var action = a.paused ? 'resume' : 'pause';
+      var tr = row([a.name, a.algorithm, a.exchange, a.status, a.updates, a.errors, a.lastError,
+        button(a.paused ? '再開' : '一時停止', function () {
+          callAPI('POST', '/api/v1/algorithms/' + encodeURIComponent(a.name) + '/' + action).then(function () {
+            showMessage(a.name + ' を' + (a.paused ? '再開' : '一時停止') + 'しました', false);
+          }).catch(function (e) {
+            showMessage(e.message, true);
+          });
+        })]);
+      if (a.status !== 'running') {
+        tr.className = a.status === 'paused' ? 'paused' : 'error';
+      }
+      tbody.appendChild(tr);
+    });
+  }
+
+  function renderKillSwitch(snapshot) {
+    var killSwitch = $('killswitch');
+    if (snapshot.killSwitch && snapshot.killSwitch.killed) {
+      var reason = snapshot.killSwitch.lastResult ? snapshot.killSwitch.lastResult.reason : '';
+      killSwitch.textContent = 'キルスイッチ作動中 ' + reason;
+      killSwitch.className = 'killed';
+    } else {
+      killSwitch.textContent = 'キルスイッチ 待機中';
+      killSwitch.className = '';
+    }
+  }
+
+  function renderNotifications(snapshot) {
+    var ul = $('notifications');
+    clear(ul);
+    snapshot.notifications.slice().reverse().forEach(function (n) {
+      var li = el('li', new Date(n.time).toLocaleString() + ' ' + n.subject);
+      li.appendChild(el('pre', n.body));
+      ul.appendChild(li);
+    });
+  }
+
+  function renderOrderForm(snapshot) {
+    var names = snapshot.exchanges.map(function (ex) {
+      return ex.name + ':' + ex.pairs.map(function (p) { return p.currencyPair; }).join(',');
+    }).join(';');
+    if (names === exchanges.join(';')) {
+      return;
+    }
+    exchanges = names === '' ? [] : names.split(';');
+    var select = $('order-exchange');
+    clear(select);
+    snapshot.exchanges.forEach(function (ex) {
+      var option = el('option', ex.name);
+      option.value = ex.name;
+      select.appendChild(option);
+    });
+    updatePairs(snapshot);
+    select.onchange = function () {
+      updatePairs(snapshot);
+    };
+  }
+
+  function updatePairs(snapshot) {
+    var name = $('order-exchange').value;
+    var select = $('order-pair');
+    clear(select);
+    snapshot.exchanges.forEach(function (ex) {
+      if (ex.name !== name) {
+        return;
+      }
+      ex.pairs.forEach(function (p) {
+        var option = el('option', p.currencyPair);
+        option.value = p.currencyPair;
+        select.appendChild(option);
+      });
+    });
+  }
+
+  function render(snapshot) {
+    $('updated').textContent = new Date(snapshot.time).toLocaleString();
+    renderExchanges(snapshot);
+    renderPositions(snapshot);
+    renderAlgorithms(snapshot);
+    renderKillSwitch(snapshot);
+    renderNotifications(snapshot);
+    renderOrderForm(snapshot);
+  }
+
+  function connect() {
+    var scheme = location.protocol === 'https:' ? 'wss://' : 'ws://';
+    var ws = new WebSocket(scheme + location.host + '/dashboard/ws');
+    var connection = $('connection');
+    ws.onopen = function () {
+      connection.textContent = '接続中';
+      connection.className = 'connected';
+    };
+    ws.onmessage = function (event) {
+      var message = JSON.parse(event.data);
+      if (message.type === 'snapshot') {
+        render(message.data);
+      }
+    };
+    ws.onclose = function () {
+      connection.textContent = '未接続';
+      connection.className = 'disconnected';
+      // 再接続する
+      setTimeout(connect, 3000);
+    };
+  }
+
+  $('token').value = localStorage.getItem(tokenKey) || '';
+  $('token').addEventListener('change', function () {
+    localStorage.setItem(tokenKey, $('token').value);
+  });
+  $('order-form').addEventListener('submit', function (event) {
+    event.preventDefault();
+    var url = '/api/v1/exchanges/' + encodeURIComponent($('order-exchange').value) + '/orders';
+    callAPI('POST', url, {
+      currencyPair: $('order-pair').value,
+      action: $('order-action').value,
+      price: parseFloat($('order-price').value),
+      amount: parseFloat($('order-amount').value)
+    }).then(function (order) {
+      showMessage('注文しました (注文ID = ' + order.orderId + ', 価格 = ' + order.price + ', 数量 = ' + order.amount + ')', false);
+    }).catch(function (e) {
+      showMessage(e.message, true);
+    });
+  });
+  connect();
+})();
@@ -0,0 +1,121 @@
+package integrator
+
+import (
+	"github.com/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"encoding/json"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// 記録するリクエストボディの上限
+const maxAuditBodySize = 64 * 1024
+
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Role       string    `json:"role"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Body       string    `json:"body,omitempty"`
+	Status     int       `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// auditLogger is write who did what through http server
+type auditLogger struct {
+	file   *os.File
+	mutex  *sync.Mutex
+	logger *logger.Logger
+}
+
+func (a *auditLogger) write(entry *auditEntry) {
+	a.logger.Infof("%v %v (user = %v, role = %v, remote addr = %v, status = %v, error = %v)",
+		entry.Method, entry.Path, entry.User, entry.Role, entry.RemoteAddr, entry.Status, entry.Error)
+	if a.file == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		a.logger.Errorf("can not marshal audit entry (reason = %v)", err)
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	_, err = a.file.Write(append(data, '\n'))
+	if err != nil {
+		a.logger.Errorf("can not write audit log (reason = %v)", err)
+	}
+}
+
+func (a *auditLogger) close() (error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+func newAuditLogger(auditLogFile string) (*auditLogger, error) {
+	a := &auditLogger{
+		mutex:  new(sync.Mutex),
+		logger: logger.Get("audit"),
+	}
+	if auditLogFile == "" {
+		return a, nil
+	}
+	file, err := os.OpenFile(auditLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not open audit log file (path = %v)", auditLogFile)
+	}
+	a.file = file
+	return a, nil
+}
+
+func newAuditEntry(context *gin.Context, user string, r role) (*auditEntry) {
+	return &auditEntry{
+		Time:       time.Now(),
+		User:       user,
+		Role:       r.String(),
+		RemoteAddr: context.ClientIP(),
+		Method:     context.Request.Method,
+		Path:       context.Request.URL.RequestURI(),
+	}
+}
+
+// audit is middleware that records requests changing state. it should be placed after requireRole
+func (i *Integrator) audit(context *gin.Context) {
+	r, _ := context.Get(roleContextKey)
+	userRole, _ := r.(role)
+	entry := newAuditEntry(context, context.GetString(userContextKey), userRole)
+	if context.Request.Body != nil {
+		body, err := ioutil.ReadAll(io.LimitReader(context.Request.Body, maxAuditBodySize))
+		if err == nil {
+			entry.Body = string(body)
+		}
+		// ハンドラーが読めるように戻す
+		context.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), context.Request.Body))
+	}
+	context.Next()
+	entry.Status = context.Writer.Status()
+	if len(context.Errors) > 0 {
+		entry.Error = context.Errors.Last().Error()
+	}
+	i.auditLogger.write(entry)
+}
+
+func (i *Integrator) auditDenied(context *gin.Context, user string, r role, status int, reason string) {
+	entry := newAuditEntry(context, user, r)
+	entry.Status = status
+	entry.Error = reason
+	i.auditLogger.write(entry)
+}
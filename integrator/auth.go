@@ -0,0 +1,213 @@
+package integrator
+
+import (
+	"github.com/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"crypto/subtle"
+	"net/http"
+	"fmt"
+	"strings"
+)
+
+const (
+	apiErrorUnauthorized = "unauthorized"
+	apiErrorForbidden    = "forbidden"
+	// 認証したユーザー名とロールをgin.Contextに入れるキー
+	userContextKey = "user"
+	roleContextKey = "role"
+	// 認証情報を設定していない場合のユーザー名
+	anonymousUser = "anonymous"
+	authRealm     = "ACT"
+)
+
+type role int
+
+const (
+	roleNone role = iota
+	// 参照だけできる
+	roleViewer
+	// 手動トレードとアルゴリズムの一時停止、再開ができる
+	roleTrader
+	// アルゴリズムの再読み込み、設定の変更、キルスイッチができる
+	roleAdmin
+)
+
+var roleNames = map[role]string{
+	roleViewer: "viewer",
+	roleTrader: "trader",
+	roleAdmin:  "admin",
+}
+
+func (r role) String() (string) {
+	name, ok := roleNames[r]
+	if !ok {
+		return "none"
+	}
+	return name
+}
+
+func parseRole(name string, defaultRole role) (role, error) {
+	if name == "" {
+		return defaultRole, nil
+	}
+	for r, n := range roleNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	return roleNone, errors.Errorf("unexpected role (role = %v)", name)
+}
+
+type apiToken struct {
+	User  string `json:"user"  yaml:"user"  toml:"user"`
+	Token string `json:"token" yaml:"token" toml:"token"`
+	// viewer, trader, admin
+	Role  string `json:"role"  yaml:"role"  toml:"role"`
+}
+
+type userConfig struct {
+	User         string `json:"user"         yaml:"user"         toml:"user"`
+	// bcryptでハッシュしたパスワード
+	PasswordHash string `json:"passwordHash" yaml:"passwordHash" toml:"passwordHash"`
+	// viewer, trader, admin
+	Role         string `json:"role"         yaml:"role"         toml:"role"`
+}
+
+type principal struct {
+	user  string
+	token []byte
+	hash  []byte
+	role  role
+}
+
+// authenticator is check tokens and basic auth of http requests
+type authenticator struct {
+	tokens []*principal
+	users  map[string]*principal
+}
+
+func (a *authenticator) configured() (bool) {
+	return len(a.tokens) > 0 || len(a.users) > 0
+}
+
+func (a *authenticator) authenticate(request *http.Request) (*principal, error) {
+	authorization := request.Header.Get("Authorization")
+	if strings.HasPrefix(authorization, "Bearer ") {
+		token := []byte(strings.TrimPrefix(authorization, "Bearer "))
+		for _, p := range a.tokens {
+			if subtle.ConstantTimeCompare(p.token, token) == 1 {
+				return p, nil
+			}
+		}
+		return nil, errors.New("invalid token")
+	}
+	user, password, ok := request.BasicAuth()
+	if ok {
+		p, ok := a.users[user]
+		if !ok {
+			return nil, errors.Errorf("invalid user or password (user = %v)", user)
+		}
+		err := bcrypt.CompareHashAndPassword(p.hash, []byte(password))
+		if err != nil {
+			return nil, errors.Errorf("invalid user or password (user = %v)", user)
+		}
+		return p, nil
+	}
+	if authorization != "" {
+		return nil, errors.New("unsupported authorization")
+	}
+	return nil, nil
+}
+
+func newAuthenticator(config *serverConfig) (*authenticator, error) {
+	a := &authenticator{
+		tokens: make([]*principal, 0),
+		users:  make(map[string]*principal),
+	}
+	if config == nil {
+		return a, nil
+	}
+	addToken := func(t *apiToken, defaultRole role) (error) {
+		if t.Token == "" {
+			return errors.Errorf("empty token (user = %v)", t.User)
+		}
+		r, err := parseRole(t.Role, defaultRole)
+		if err != nil {
+			return errors.Wrapf(err, "invalid token (user = %v)", t.User)
+		}
+		a.tokens = append(a.tokens, &principal{user: t.User, token: []byte(t.Token), role: r})
+		return nil
+	}
+	for _, t := range config.Tokens {
+		err := addToken(t, roleViewer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// tradeTokensは以前の設定との互換のため。ロールを省略するとtrader
+	for _, t := range config.TradeTokens {
+		err := addToken(t, roleTrader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range config.Users {
+		if u.User == "" {
+			return nil, errors.New("empty user name")
+		}
+		if _, ok := a.users[u.User]; ok {
+			return nil, errors.Errorf("duplicate user (user = %v)", u.User)
+		}
+		_, err := bcrypt.Cost([]byte(u.PasswordHash))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid password hash (user = %v)", u.User)
+		}
+		r, err := parseRole(u.Role, roleViewer)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid user (user = %v)", u.User)
+		}
+		a.users[u.User] = &principal{user: u.User, hash: []byte(u.PasswordHash), role: r}
+	}
+	return a, nil
+}
+
+// requireRole is middleware that allows requests of users who have the role or higher
+func (i *Integrator) requireRole(required role) (gin.HandlerFunc) {
+	return func(context *gin.Context) {
+		if !i.authenticator.configured() {
+			// 認証情報を設定していなければ参照だけできる
+			if required > roleViewer {
+				i.auditDenied(context, anonymousUser, roleViewer, http.StatusForbidden, "no credential is configured")
+				apiAbort(context, http.StatusForbidden, apiErrorForbidden, "no credential is configured")
+				return
+			}
+			context.Set(userContextKey, anonymousUser)
+			context.Set(roleContextKey, roleViewer)
+			context.Next()
+			return
+		}
+		p, err := i.authenticator.authenticate(context.Request)
+		if err != nil {
+			i.auditDenied(context, "", roleNone, http.StatusUnauthorized, err.Error())
+			context.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", authRealm))
+			apiAbort(context, http.StatusUnauthorized, apiErrorUnauthorized, err.Error())
+			return
+		}
+		if p == nil {
+			// ブラウザにはbasic認証のダイアログを出させる
+			context.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", authRealm))
+			apiAbort(context, http.StatusUnauthorized, apiErrorUnauthorized, "no credential")
+			return
+		}
+		if p.role < required {
+			message := fmt.Sprintf("%v role is required (user = %v, role = %v)", required, p.user, p.role)
+			i.auditDenied(context, p.user, p.role, http.StatusForbidden, message)
+			apiAbort(context, http.StatusForbidden, apiErrorForbidden, message)
+			return
+		}
+		context.Set(userContextKey, p.user)
+		context.Set(roleContextKey, p.role)
+		context.Next()
+	}
+}
@@ -0,0 +1,285 @@
+// Code generated by go-bindata.
+// sources:
+// asset/dashboard/app.js
+// asset/dashboard/index.html
+// asset/dashboard/style.css
+// DO NOT EDIT!
+
+package integrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func bindataRead(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, gz)
+	clErr := gz.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+	if clErr != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type asset struct {
+	bytes []byte
+	info  os.FileInfo
+}
+
+type bindataFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi bindataFileInfo) Name() string {
+	return fi.name
+}
+func (fi bindataFileInfo) Size() int64 {
+	return fi.size
+}
+func (fi bindataFileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+func (fi bindataFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+func (fi bindataFileInfo) IsDir() bool {
+	return false
+}
+func (fi bindataFileInfo) Sys() interface{} {
+	return nil
+}
+
+var _assetDashboardAppJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xad\x59\x5b\x6f\xdc\xc6\x15\x7e\xf7\xaf\x98\x00\x81\xc9\x85\xd7\x5c\x07\x79\x93\x21\x04\xbe\x28\x80\x1a\xd7\x16\x22\x17\x7d\x10\xf4\xc0\x5d\x8e\xb4\xb4\xb8\xe4\x82\x33\x5c\x59\x71\x04\x44\x2b\xa4\x09\xe2\xb6\x46\x0a\xc4\x45\x5a\x03\xe9\x2d\x49\x93\xc2\xa8\x91\xa0\x6e\x0a\xa4\xfe\x33\xb4\x94\xe6\x5f\xf4\x9c\xb9\x71\x86\x9c\xd5\xa5\xa8\x1e\x56\xe4\xcc\x99\x73\xce\x9c\xcb\x37\xe7\x0c\xc3\xad\x2a\x1f\xf1\xb4\xc8\x49\xd8\x23\x0f\x2e\x10\x12\x54\x8c\x12\xc6\xcb\x74\xc4\x83\xab\x17\x60\x60\x16\x97\x84\x17\x3b\x34\x7f\x8b\xee\x91\x65\x12\xc4\x23\x1e\x25\x31\x1b\x0f\x8b\xb8\x4c\x22\x31\x03\x84\x92\x8e\xde\x1f\x8d\xe3\x7c\x9b\x32\x20\xdc\xd8\x14\xcb\x0d\xff\x57\xc3\x34\x91\x22\x08\x29\x29\xaf\xca\x9c\x24\xc5\xa8\x9a\xd0\x9c\x47\xdb\x94\xaf\x64\x14\x1f\xaf\xef\xad\x26\x48\x88\x1c\xf7\x9d\xf5\x34\x0b\x79\xbc\xdd\x27\x9c\xde\xe7\x7d\x32\xca\x62\xc6\x6e\xc7\x13\xaa\x59\x0a\xf1\x20\xd6\xf0\x1c\x95\x34\xe6\x54\xb1\xc5\xa5\x82\x27\x21\xe9\x16\x09\x91\x07\x79\x65\x79\x99\x54\x79\x42\xb7\xd2\x9c\x26\xe4\xe2\x45\x62\x46\xf3\x2a\xcb\x34\x5f\x42\x68\x84\x13\x37\x8a\x9c\x03\x23\x90\xb0\x0e\xc6\xc9\xb7\x05\x0f\xc5\x72\xdf\x30\xee\xa8\x85\xcb\xcd\x20\x2c\x36\xcf\xf6\x4a\x65\x0e\xda\xdd\x74\x59\xec\x86\xb3\x38\xab\x28\xb3\xf7\xc9\x4b\xe0\x04\xf6\x08\x78\x19\x28\x15\x24\x51\xb4\x55\x94\x2b\xf1\x68\x1c\x36\x5e\x9d\x35\x9a\x88\xa5\x89\x5e\x9a\xe8\xa5\x52\xf3\x19\x49\x73\xc6\xe3\x7c\x44\x8b\x2d\x72\xbb\x48\xac\x1d\x10\x58\x15\xc5\xd3\x29\xcd\x93\x1b\xe3\x34\x4b\x80\xa7\x5e\xb9\x0f\xbc\x20\x5e\x1c\x4a\xd7\x5a\x33\xb2\xec\xd8\xf9\xdd\x77\xd5\x10\x1a\x99\xbc\x41\x82\x80\x2c\x69\x93\x5a\x7c\xd5\x7f\x5e\x3a\x82\x79\xa2\x2d\xae\xfe\x2b\xcb\xf1\xb2\x6b\xba\x51\x46\xe3\x32\x34\xdb\xd8\x05\x06\x94\x84\x34\xda\x4a\x4b\xc6\x05\x3b\xdb\x47\x25\x9d\x14\x33\x2a\xa5\x38\x34\x8d\x9f\x5c\xf6\x6c\x5c\xec\xfe\x94\x32\x16\x6f\xd3\x50\x86\x64\xca\x56\xca\xb2\x28\x6d\x47\x4d\x24\x01\x98\xe1\xd5\x30\x50\x2f\xda\xec\xea\xb5\x65\x2e\x7c\x73\xe7\xed\xe8\x51\x22\xd0\x6c\x14\x1f\xd0\x76\x41\x60\xf6\x3e\x18\x90\xfa\xf0\xc3\xfa\xf0\xfb\x7a\xfe\xf7\xfa\xf0\xdb\x7a\xfe\x9b\xa3\xf7\x3f\x3f\xfa\xe8\xf7\xf5\xc1\x6f\xeb\x83\xaf\xeb\x83\x8f\xeb\xf9\x2f\xeb\x83\x67\xf5\xe1\xe3\xfa\xf0\xab\x7a\xfe\x45\x3d\x7f\x5e\x1f\x7c\x59\x1f\x3e\xad\xe7\xcf\xea\xf9\x5f\x70\x09\x52\x7e\x56\x1f\x7e\x21\x98\x3c\xc7\xdf\x83\x2f\x8f\x3e\x86\xdf\xe7\x8e\x6d\xe3\x2c\xbb\xb6\xb6\x1a\x4e\x28\x1f\x17\x49\x9f\x54\x65\xd6\x27\xc3\x22\xd9\xb3\x37\x3f\xa6\x71\x42\x4b\x84\x82\x07\xfb\x4d\xea\x81\x21\x24\x66\xf4\x22\x11\xb3\x22\xe1\x82\xa0\xf1\x85\x5a\xb6\x11\x5c\xab\x80\x79\x99\xbe\x13\xa3\xc8\x60\x13\xb1\xe7\x3a\xb8\x94\x96\x24\x20\x97\x48\x9b\x8f\x9d\x4f\x28\x3d\xcd\x53\xb4\xe7\x03\xa9\xe2\x12\xd1\xaa\x2a\xf6\x4b\xfa\x01\xa0\xa4\xa4\x09\x18\x3f\x8d\x33\x18\x0d\x18\x58\xfa\x32\x88\xdd\x4e\xf3\xc0\x52\x1b\x37\xe7\x22\x86\x47\x63\xe5\xc6\xcb\x77\xf7\xa6\x54\x2a\x0c\xc1\x9b\xa5\x23\xb1\x83\xc1\x3d\x56\x48\xa4\x14\x3c\x41\xbd\x48\x30\x5d\x26\x3f\x59\xbf\x73\x3b\x62\x22\x05\xd2\xad\x3d\x21\xaa\xe7\x81\x87\x2d\xca\x21\xb3\x85\xa9\x71\x75\x2f\xe2\x63\x9a\x5b\x99\x5e\x52\x36\x2d\x72\x66\x25\xae\x5a\xa8\x27\x22\xd4\x20\xec\xac\xc3\x51\x3b\xd9\x71\xbb\x38\x16\x51\x3b\x9c\x55\x3e\x8e\x01\x91\x48\x4e\x77\x89\x08\x44\x8b\x2e\x1a\x01\x66\x80\x5f\x82\x25\xe1\x1e\x6b\x42\x05\xb2\xc9\xed\x26\xbb\x8d\x86\x82\x3a\x89\x79\x6c\xf2\xdf\xc9\x73\x37\xf3\x86\x15\xe7\xb0\x8f\x2c\x1e\x52\x30\x45\x91\xdf\x00\x0b\xef\xd8\x81\x37\x54\x10\x27\x09\x83\x3e\x11\xa4\x8a\xe3\x30\xe2\xe0\x1d\xf4\x8d\x9a\xd6\xc3\x71\x92\xac\xcc\xc0\x7b\xb7\x52\x06\x4e\xa4\x65\x18\x8c\x90\x71\xd0\x88\x70\x20\x67\xe8\x01\x6b\xc0\x29\x5a\x5e\xc7\x73\x31\x9c\xc6\xa9\x83\x04\x49\x3a\x53\x5a\xc1\x13\xf0\x44\xf4\xeb\x83\x0e\x48\xac\x11\x01\x66\x1c\xb4\x43\xea\xf1\xeb\x40\x8c\xcc\xa2\x51\x55\x82\x80\xd1\xde\x1a\xbc\xa0\x9d\x09\x39\x7e\xf2\xde\x0f\xff\x98\xbf\x7c\xf1\xc7\xe3\x3f\x7c\x2f\x8c\x2e\xe8\x00\x2e\xf8\x1a\x1c\xdf\xb4\x67\x8e\x06\x80\xfd\x78\x98\x51\x8d\xfc\xf8\xac\x65\x8a\x17\x47\x2a\x1e\x39\x1b\x01\x48\x0d\x24\x67\x7c\x3a\xfe\xe4\xd9\x8f\x1f\x3c\x0a\x36\x35\x4b\x21\x28\x66\x3b\x2c\x62\x60\x19\x0a\x31\x55\xd2\x19\xa4\x00\x3e\x9d\x7e\x04\xe1\xe9\x25\xa5\x1c\xfd\xf9\x1b\x60\x3f\xdb\xb8\xb2\x89\xbf\xaf\x6d\x6e\x9a\x20\x01\xe0\xb7\x81\x2f\x00\x61\x26\x77\xba\x3a\xf3\xb2\x75\x2e\x08\x05\x87\x69\xc2\xce\xa5\xce\x7f\xbe\xf9\xe7\x19\xd5\x01\xd6\xe7\x51\xa7\xed\x59\xb1\xc4\x8d\x27\x20\x59\x14\x51\x2b\xba\xaa\x0a\x59\x1e\x4f\xe1\xe0\xe1\x76\x64\x8d\x00\x74\x62\x00\xa4\x52\x9e\x32\xa6\x04\x0b\x2c\xf7\x03\xc3\x84\xc9\x79\xf1\x68\xcf\x15\xa5\x42\x69\x98\x94\xcf\x7a\x56\x9e\xa0\x86\xbf\x33\x2a\xd8\x38\x23\x72\xad\x1a\xd2\x8a\x46\x46\x1d\x8f\x23\xe8\xfd\xc6\x13\x46\x8a\x3f\x03\xe8\xfd\x28\xc7\xaa\xca\x38\x04\x06\xd0\xc7\x3e\xb6\x76\xea\x2d\x66\xdd\x49\xd6\x5e\x1b\x7b\x24\x12\xbe\x02\x92\xe2\xd1\xa8\xa8\x72\x6e\x33\x95\x5e\x6b\x97\x2b\xc2\x2a\x67\xdf\x81\xe2\x2b\x71\xd2\x6b\x21\x5b\xa4\x9f\xb9\x84\x13\xda\xd7\x15\x81\x6f\x1f\x27\x22\x00\x21\x77\x86\xf7\x28\x14\xf5\x3b\x74\x8f\x85\x96\x56\x32\x68\xa0\x5e\x7b\xb0\xdf\x8b\x58\x51\x72\x6f\x72\x6b\x58\x72\xca\x45\x3f\xa8\x68\xd2\x3e\x69\x4b\x31\x53\x9b\x9b\x3e\xfd\xbb\x3b\xb7\x13\xc8\x31\xa5\x8c\x42\x8f\x9e\x62\xc2\x56\x52\x51\x76\xb4\x14\xe3\x26\x6e\xfb\x92\xce\x41\x5f\x3d\x26\x7e\x57\x13\xfd\x1a\x0b\x49\xfa\x6d\x8a\x10\x6c\xa6\x26\xa8\x5c\xdf\x3a\x48\xd5\x21\x16\x1c\x3d\x7a\x7c\xfc\xfc\x43\xf0\x60\xbb\x0f\x6b\xfe\xd0\x7b\x70\xea\x23\xec\x0c\xe2\x69\x3a\x98\xbd\x36\x30\x59\x35\x40\xd8\x07\xbd\xe0\xec\xfd\xd9\xdb\xab\x37\x8a\x09\x1c\xf4\xd8\xee\xb8\x9b\xe8\xe1\x79\x31\x90\x1b\x16\x2b\x1c\xfd\xc9\x25\x47\x1c\xb4\x80\x6f\xd8\xbb\x5d\x3e\x51\x84\x4d\x69\x9d\xf0\x22\xef\x54\x91\x18\xdc\x5c\xb9\xb5\x72\x77\x25\x10\x65\x62\xa7\xfa\x68\xef\x96\x38\x95\x75\x70\xfc\xed\x5f\x8f\x1f\x7f\x80\x95\xec\xa3\xc7\xf5\xfc\x23\xb0\x95\x28\x51\x5f\xc8\x42\x95\x84\x9e\xdd\x90\xa0\x87\xf6\x84\x72\x8e\xb6\x34\x82\x38\x86\x5a\x6c\x71\x86\x75\xe5\x53\x5d\xbd\x40\xdf\x59\x56\x5d\x7e\xf6\xfb\x7e\xcf\x13\xbc\xbe\x02\x46\x42\xcf\x5a\xc1\x52\x7c\xf5\xa3\x3a\x57\xb5\x21\x80\xf2\x54\x13\xba\xb8\xcc\xad\x3a\xd1\xce\x60\x03\xbd\x66\xdd\xa9\x39\x9c\x3b\x0d\x6b\x97\xc1\x06\x12\x6c\xfa\xa0\xd6\xc9\x7a\x54\xa8\x9b\x4f\xb8\x16\x4a\x18\x2b\xa3\xa6\xad\x6c\x9a\xea\xfc\xc0\x27\x28\x23\xc0\xdc\x6b\x32\x7b\xa6\x50\x57\xc4\x59\xfa\x0e\x4d\xd6\xf2\x0c\x5f\xab\xdc\x1a\x38\x97\xb9\xaf\x65\xdb\x50\xd5\xf3\xf1\xe4\x54\x7b\xc7\x86\x72\xb1\xc1\x8d\x8d\x1a\x62\x8f\x79\x62\xb7\xd2\x90\x00\x01\x42\x62\x38\xbb\x2a\x06\xcd\x30\xf4\x71\x50\x9c\x57\x13\x2a\x1a\x39\x31\x18\x5c\xf5\xd6\x26\x71\x24\xed\x18\x37\x12\xf1\xa5\x31\x6a\x0c\x1d\x44\xcc\x2b\x86\x4f\xd5\x14\x6a\x69\x2a\x1e\xe5\xd1\x82\x4f\x58\x17\x8a\xa2\xbd\x81\x21\x05\x42\xb6\x3a\x47\xbf\xf8\xd5\x8f\x8f\x1f\x0a\x75\x5e\x7e\xf7\xde\xf1\xa7\xf3\xa3\x83\x27\xc7\x4f\xff\x74\x02\x40\x99\x34\x5f\xbb\xb3\x7e\x17\x8b\x45\x0d\x52\x8d\x69\x16\xa1\x94\xdc\x94\x44\x27\x24\x91\x06\x3a\x15\x22\xec\x04\x95\x2c\x44\x3d\x0c\x20\x81\x4c\xce\xb4\x1d\x21\xd3\x46\x12\x2f\x62\x9c\x05\x2f\xce\x8a\x16\x36\x56\x20\x52\xd8\x35\x86\xf6\x9d\xec\x85\xcb\x2a\xcf\xa1\x15\x0c\x9c\xdc\x72\xeb\x4f\xb3\x00\x2f\x52\x64\xdc\x24\x01\xee\x57\x3f\x2e\xe9\x72\xa0\x73\xa1\xd2\x49\xd2\x56\xb1\xea\xcb\x9d\xb7\xd2\x2c\x5b\xdf\x4d\xd1\x12\xbe\xdc\xd9\x31\xd3\x32\x81\xf0\x9d\x89\xf7\xc0\xba\x73\x33\x29\x63\x91\x5f\xbc\x48\x3c\xc3\xe2\xd1\x6e\xb0\x51\x08\xe4\x3d\x13\xc9\xe3\x5b\x80\xb1\xfd\x36\x24\x52\xc6\xc1\x08\x27\x13\x44\x8a\x91\xbe\x38\xc1\x3f\x8b\xd0\xbd\x8d\x09\xea\xf9\xd3\xfa\xf0\x6f\xf5\xfc\x5f\xe2\x7a\xe4\xb0\x3e\x3c\x78\xf9\xef\x27\x47\x0f\x3f\x79\xf9\xdd\x53\xd1\x71\x49\x66\x1e\x3e\x4e\xb7\x20\xf7\xa3\xc4\xb5\xae\xcc\xce\x23\x9b\x1c\xbd\x78\xff\xf8\xab\xcf\x40\x78\x70\x9a\xc8\x60\xd1\xbd\x95\xf4\xe8\xed\x82\xa7\x5b\xea\x62\xc2\x0f\x88\x55\x26\x9d\x99\xdb\x94\x2e\x20\x56\x59\x1b\x0d\x1d\xe2\x33\xf5\x86\xb9\xeb\xe5\x2c\x55\x25\x6a\x96\x62\x93\x4c\x77\xc9\x4d\x40\xb2\x30\x8f\x78\x0a\x40\x11\xf1\xe2\x56\x01\x78\x43\xd5\x7d\xa1\xc8\x62\xe1\x87\x3c\x62\x95\x38\x03\x4d\x5e\x65\x69\xa7\x54\x9e\x96\x14\x99\x8a\x6b\x97\xe6\xe8\xa8\x32\x87\x30\x4b\x4f\x4d\x87\x3b\x58\x69\xbc\x59\x94\x13\xaf\xe1\x10\x8f\x98\x1d\xa7\x4d\x0b\x34\x89\xa7\x0b\xda\x1f\x7d\x03\x7c\xdf\xc0\xd9\x92\xc0\x4c\xdd\xe5\xb8\x2b\xf1\xd8\xd5\x4b\xdc\xc3\xf4\x2a\x62\xd6\xbd\x22\x85\xca\xb2\x1f\x98\x8d\xa8\x91\xab\x76\x3a\x2a\x35\x01\x3f\x1a\xfd\x0c\x59\x5b\x31\xfb\x36\xca\xbe\xe3\x6f\x98\x04\x08\x3f\x1b\x9b\x90\x56\x62\x2c\x62\xd3\x2c\xe5\x96\x48\xb4\x0c\xa3\x19\x78\xc8\xea\x34\x2f\x6b\x5e\x6e\x5c\x49\xba\xff\xb5\x93\x14\x3d\xed\x54\x9d\xb4\xe8\x76\xf9\x62\xb5\x60\xda\xf3\x72\x42\x5d\x43\x2e\xeb\x69\x3d\x2b\xb5\x70\x62\x43\x2e\x68\xf5\xf6\xf2\xb0\x45\xe3\x5b\x89\xa4\x74\x97\x2c\x8a\x5c\x6a\x0e\x32\x7c\x87\xda\x09\x0c\xf6\xbb\x21\xe8\xa5\x6e\x85\x9f\xdf\xc4\xf6\x3d\xa9\xdf\x1d\x18\x6b\xff\x4f\x57\x60\x9c\xe9\x90\x16\xdf\x55\x9c\x3a\x73\x51\x13\x7d\x52\x6b\x6f\xaf\x5e\xec\xe8\xe9\xa2\xb6\xa4\xe5\xf1\x56\xee\x18\xb2\xd3\x5c\x7f\x96\x7a\xb3\xe3\x1c\xb0\xb2\xf4\x5d\x02\xae\x70\xa1\xde\xe0\x9c\xb1\xb0\x1f\xee\xf4\x85\xd1\x82\x4b\x21\x7b\xda\xd3\x5d\xd8\xd3\xbe\x6a\xd8\x9e\xf7\x9d\xf8\xf6\xfc\x82\xf3\xc3\x26\xf1\xa0\xa4\xe7\x23\x4d\x91\xe7\x60\xea\xd0\x8e\x60\x36\x1a\x53\x11\xc3\x59\x21\x05\x40\x2f\x5d\xf0\x62\x54\x64\x12\x69\xc6\x9c\x4f\xd9\x92\x28\x77\x76\x19\x5b\x1a\x0c\x44\xb9\xb3\x2b\x9e\x9a\xe8\xde\x65\xca\xb0\x3f\xa7\xc3\xf5\x62\xb4\x43\x79\xa8\x18\x5f\x6a\x18\x8f\x0b\xc6\x45\xf5\x69\x3e\x68\x0e\x76\x9d\xcb\x31\xa5\x9f\x8c\x31\xf0\x60\xf3\xae\xa9\x76\x19\xe4\x77\x01\x81\xb2\x20\xbb\x9b\x15\xed\xf3\xfd\xf8\xd7\x9f\xff\xf0\xfc\x77\xf6\x61\x6e\xd1\x3a\x87\xb9\x1a\x6f\x4a\x08\x4b\x74\xf3\x89\xc9\xca\x41\xbc\xc7\x76\x11\xb1\x21\x13\x5f\x1b\xa6\x31\x1e\xc8\x82\x4e\xdc\xbb\x3b\xe5\xa8\xf9\x32\x25\x2e\xca\xd1\xe4\xda\x83\x81\x9b\xbd\x22\xce\x35\xb5\xc3\x66\xbf\xa3\xe7\x28\x2b\x18\x3d\xbf\x8d\x9e\x7c\x2d\xcd\x74\xaa\x8d\x92\x94\xb5\xcd\x24\x3e\x88\x41\x07\x20\x39\xd4\x07\x9f\xd6\xf3\x87\x06\xdc\xf9\x5d\x48\xb1\xa2\xe2\xa1\x5a\xd5\x27\xaf\x5f\xb9\x72\xa5\x83\xbb\x9d\xcf\x55\x32\x2c\xb3\x75\x5e\x60\x9f\x8a\x5f\xae\x57\x39\x9d\x84\xfa\x0b\x79\x0f\x9b\x6d\x59\x7d\x59\x4b\x3d\xdf\x16\x24\x28\xfb\xda\x2a\x87\x3f\x6b\xf1\xef\x77\x34\x92\x59\xd5\x53\x12\x25\x90\x03\x72\x4e\xbc\x62\xa1\x4a\x9a\xa4\xdc\x11\xeb\x04\x8b\x8c\x88\x69\x29\xfe\xdf\xa4\x5b\x31\x54\xcc\xa1\x95\x0f\xe7\xbd\x87\x5a\x78\x0c\xd9\x57\x52\xca\x5d\xed\x46\x52\x7c\xe9\x32\x21\x62\x01\xf5\x52\xfb\xc4\x92\x2c\x75\x5f\x2b\xbb\x48\x8b\x28\x56\x09\xeb\x92\x89\x0b\xba\x25\x22\x32\xe1\xcd\xac\x88\x2d\x65\xc5\x94\xd1\xd4\xf0\x15\x97\x14\xfe\x15\x72\xce\x2c\xd1\x75\x57\xab\x91\x6d\xdd\x41\xfa\x2e\xba\x9c\xcb\x2d\x39\xb6\x7a\x13\x4d\xee\xbb\xe7\xea\x13\xf5\xf1\xc7\x9e\x17\xca\xcb\x59\xf9\xd9\xc6\x99\x95\x8a\xfa\x2e\xc9\x4e\x6e\x76\x4f\x6f\x73\xf7\xad\x40\x34\xb0\x7e\xf5\xc2\x7e\x0f\x7f\xff\x0b\x87\x0d\xd3\x8f\x67\x22\x00\x00")
+
+func assetDashboardAppJsBytes() ([]byte, error) {
+	return bindataRead(
+		_assetDashboardAppJs,
+		"asset/dashboard/app.js",
+	)
+}
+
+func assetDashboardAppJs() (*asset, error) {
+	bytes, err := assetDashboardAppJsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "asset/dashboard/app.js", size: 8807, mode: os.FileMode(420), modTime: time.Unix(1792427330, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _assetDashboardIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9d\x56\x4b\x6f\xd4\x30\x10\xbe\xf7\x57\x18\x9f\xe0\x40\x03\x9c\x38\x64\x23\xa1\xc2\x81\x13\x1c\xb8\x70\xf4\x26\xde\xc6\xd4\x79\x28\x76\x80\xbd\xed\x26\x40\xd9\x56\xa5\xe5\xd0\x0a\x95\x45\x94\x47\xa5\xd2\x4a\x05\xb5\x3c\x04\x54\xf4\xc7\x98\x24\xed\xbf\xc0\x8e\x77\xb3\xdb\x76\x4b\x17\x0e\x51\xe2\x99\xf1\x37\xdf\x3c\x3c\x8e\x79\xee\xfa\xad\xa9\x3b\x77\x6f\xdf\x00\x2e\xf7\xa8\x35\x61\xaa\x17\xa0\xc8\x9f\xae\xc1\x7b\x08\x2a\x01\x46\x8e\x7c\x79\x98\x23\x60\xbb\x28\x62\x98\xd7\x60\xcc\x1b\x17\xaf\x2a\x2d\x27\x9c\x62\xeb\xda\xd4\x1d\x20\xd2\x96\x48\x53\x91\x7c\x13\xe9\xba\x48\xbb\x22\xdd\x13\x69\xc7\x34\xb4\xc1\x84\x49\x89\x3f\x03\x22\x4c\x6b\x90\xf1\x26\xc5\xcc\xc5\x98\x43\xe0\x46\xb8\x51\x83\x86\x83\x98\x5b\x0f\x50\xe4\x18\x88\x49\x7c\xa3\x34\x99\xb4\x19\x53\x2e\x8c\x1e\x83\x7a\xe0\x34\x7b\x7c\x70\x64\x4d\x00\x60\xba\x97\x95\x67\x69\x70\xb9\x5c\xb2\x10\xf9\x80\x38\x35\x68\x07\xbe\x8f\x6d\x4e\x02\x1f\x02\x9b\x4a\xc8\x1a\x74\x08\xeb\x49\xb1\x03\xad\xbc\xbb\x99\x3f\x5b\x2f\xbe\xae\x9a\x86\xda\x74\x74\x77\x1c\x3a\xa8\xb4\x1a\xa5\x9c\x21\x94\xb2\x07\x84\xdb\xee\x40\xaf\x19\x2a\x4e\xa6\x87\x48\x6f\x83\xf6\xaf\xbe\x15\xd1\x2b\x56\xfe\x6a\x5f\xb4\x37\xf2\x6e\xab\xf8\x92\xfc\xfe\xf5\x26\x5f\xdb\x93\xdb\xae\xf4\xf4\x0e\xb9\x5f\xa2\xe3\x87\x32\xc3\xfe\x34\x66\x0a\x5c\x0a\x4b\x28\x63\x08\x6b\x04\xee\xf6\xfc\xe1\xd6\x8b\x11\x58\x8d\xd8\x77\xfe\x01\x47\x24\x6f\x45\xf2\x51\xa4\x4f\x44\xf2\x4e\xa4\x2b\xa2\xbd\x99\xef\x6e\xe4\x2b\xb3\x43\xc8\x1c\xd5\x55\x29\x01\xd0\xab\xb2\x2c\x26\x8f\xe4\xe3\x5a\xd9\xe2\x4a\xb6\xb7\x9c\x77\x5a\xb2\xe2\x6e\x29\x39\x6c\xad\x1e\xec\x6c\x88\x74\x55\x22\x57\x42\x8d\x79\xf3\x7a\x25\xc8\xde\xed\x1c\xec\x7c\xab\x96\xfd\xcc\xf4\xcd\x97\x3f\x1d\xce\x2e\x56\x4b\xfd\x61\x28\x97\x86\x76\x5f\x91\x51\xcd\x51\x86\x1d\x44\xb2\x12\x65\xdc\x5c\x37\x4c\xa9\x37\x2a\xee\x67\xa4\x21\xfb\xf9\xa3\x78\xd6\x19\x33\x68\x91\xbc\x17\xe9\xae\x48\xbe\x8b\x64\x5f\x7f\x0c\xe2\x1a\x33\x1f\x47\x03\xcc\xbe\xef\x66\xaf\x66\x8f\x25\x21\xdb\x7e\x5d\x2c\xfe\xca\x97\x16\x8a\x97\x73\x03\xe1\xd2\x96\x68\xef\x0f\x0b\xff\x9e\x96\x30\x60\x44\x45\xfa\xff\x99\x51\x0d\x92\x6e\x89\xe4\xb3\x48\x37\x45\xf2\x43\xa4\x6b\xe3\xb6\xc6\xd2\x42\xd6\x59\xa8\x98\x8f\xc2\x39\x35\x69\xc5\xdc\xd7\xfc\xf1\xfc\x20\x5d\x2f\x3f\xe7\x2b\x9f\x86\xa0\x64\x3e\x3f\xc8\x49\x33\x30\x28\xcf\xd7\x49\xf9\x38\x29\x42\x74\x3a\x88\x08\x77\xbd\xff\xcf\x51\xde\x99\xcf\xe6\x97\x4f\x1c\x9c\x46\x10\x79\x83\xe6\xbc\xa8\x96\xb0\x22\x40\x51\x1d\x53\x4b\xa4\x4f\xd5\xc4\x54\x27\x70\x17\x9c\x2f\xba\xed\x62\x79\x3d\x5b\xfc\x78\x01\x98\xc4\x0f\x63\x0e\x78\x33\xc4\xb2\x8a\x72\x96\x3d\x90\x20\xb0\x44\xe3\xc1\x0c\x96\x13\x0e\xc5\x3c\xb0\x03\x2f\xa4\x98\x4b\x93\xa0\xd1\x50\xf4\x35\xea\x51\x1f\x55\x7a\x15\x73\x2a\xb9\x0f\x71\xea\x4f\x9e\x72\xaa\x95\xca\x53\x40\x86\xdb\x78\x04\x4e\x88\x48\x74\x26\x86\x3e\xf4\x3d\x19\x18\x81\x82\xf4\xf0\xb6\x2a\x13\x69\x14\x84\x4a\x06\xee\x23\x1a\xcb\x30\xeb\x71\x13\x5a\x12\x44\xb4\x1f\x99\x86\x56\xfd\xc5\x5a\x3a\xa0\x50\xb9\x15\xc9\xdc\x49\xf3\x8a\x6d\x9f\xe5\x48\xd2\xfa\x54\x1e\xad\x87\x1f\x7b\x75\x1c\xc1\xe1\xf8\x23\x62\x63\x08\x18\xc7\xa1\xec\x28\xbf\x09\x81\x47\xfc\x1a\xbc\x74\x5a\x4d\xf4\x00\x38\x0b\x15\x79\x41\xec\xf3\xb1\x60\xeb\x31\xe7\x32\x70\x8d\xc4\xe2\xba\x47\x38\xb4\xfa\x1d\xa9\x95\xfd\xae\x56\x7d\x78\xec\xd2\xf0\x30\x63\x48\x77\xc1\x98\xd7\x4f\xb7\x75\xb0\xff\x5c\xb4\xb7\x65\x5f\x14\xaf\xd7\x87\x9a\x3e\xa6\x25\xa2\x1f\x70\xd2\x20\x36\xaa\x86\x4f\x4c\x8f\xc1\x9a\x86\xbe\x35\x4d\x66\x47\x24\xe4\x80\x45\xf6\x88\x5f\x02\x14\x86\x93\xf7\x4a\x00\x6d\xa6\xf6\xf5\xfe\x08\x0c\xfd\xeb\xf2\x07\xf1\x94\xe2\x24\xcb\x08\x00\x00")
+
+func assetDashboardIndexHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_assetDashboardIndexHtml,
+		"asset/dashboard/index.html",
+	)
+}
+
+func assetDashboardIndexHtml() (*asset, error) {
+	bytes, err := assetDashboardIndexHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "asset/dashboard/index.html", size: 2251, mode: os.FileMode(420), modTime: time.Unix(1792427330, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _assetDashboardStyleCss = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8d\x54\xdb\x8e\xdb\x20\x10\x7d\xcf\x57\x20\xad\x2a\xed\x4a\x61\xe5\xd8\xb9\xad\xf3\x35\x18\x06\x1b\x05\x03\x02\xd2\x64\x5b\xf5\xdf\x3b\x18\x3b\x1b\x36\xaa\x54\xf9\xc1\x66\x2e\xe7\xcc\x99\x19\xdc\x59\xf1\x49\x7e\xaf\x08\x19\x99\xef\x95\x69\x49\x75\xc2\x83\xb4\x26\x52\xc9\x46\xa5\x3f\x5b\x12\x98\x09\x34\x80\x57\xf2\xee\x0a\xea\x17\xb4\x64\xd3\xb8\x5b\x32\x71\xab\xad\x6f\xc9\x4b\x5d\xd7\xe9\xd8\x31\x7e\xee\xbd\xbd\x18\x81\x36\xb9\x4d\xcf\x69\xf5\x67\xb5\x1a\x80\x09\xf0\x13\x99\x50\xc1\x69\x86\xd8\x52\xc3\x04\xc1\xb4\xea\x0d\x55\x11\xc6\xd0\x12\x0e\x26\x82\x4f\xe6\x9e\x39\xa4\xd9\x67\x1a\xc7\x84\x50\xa6\x6f\xc9\xd1\xdd\xee\xc6\x85\x5b\x4a\xf9\xc4\xdd\x34\xcd\x23\xf1\xb0\xf9\x87\xd0\x59\xcd\x31\x21\x62\xf8\xc8\x94\x29\xab\xec\xbd\x12\x53\x39\xf8\xa6\x58\x23\x5a\x23\x50\xa4\xbe\x8c\x06\xeb\xf5\xe0\x80\xc5\x57\x76\x89\x96\x4a\x15\xd7\x64\x54\x66\x64\xb7\xd7\xed\xb1\x72\xb7\x35\xd9\x48\xff\xf6\xf6\xa5\xa6\xfe\xa6\x26\x1b\x90\x37\x00\x8f\xca\x66\xea\x52\xeb\x9c\x52\x36\x76\x16\x6c\x3d\x6a\x43\x14\x8c\x0b\x56\x2b\x41\x5e\x84\x10\x59\x76\x5d\xea\xc5\xe7\x98\x81\x1e\x55\x6f\x67\xf6\xa1\x29\xa2\x13\x6f\x45\xb6\xcf\xf1\xcd\x1c\x1f\x59\xa7\x61\x4a\xb9\x2a\x11\x07\xf4\x54\xd5\x8f\xaf\x8a\x52\x77\x34\x73\x01\x53\x96\xaf\x9c\x36\xac\x49\x14\xa5\x46\x94\x47\xe6\x71\x46\xb8\x45\x3a\x2d\x03\xb6\x55\xf5\x43\x7c\x40\xec\x6c\x8c\x76\x2c\xa4\x02\x2c\xa8\xad\x54\x3e\x44\xca\x07\xa5\x45\x62\x78\x3c\x4f\x6c\x8f\xc8\x1a\x64\x9c\xf2\xde\x3b\xcb\xbc\x28\x87\xad\x8c\x56\x06\x68\xa7\x2d\x3f\x9f\x8a\xfe\xa5\x39\xe4\xc6\x24\xfb\x4f\xf0\x51\x71\xa6\x17\xd0\x68\x5d\xc6\x64\xe1\x3c\x21\x2e\xab\xc9\xab\xe6\xa3\xee\x66\x3e\x25\x0a\x5f\x7d\x60\xb0\xaf\xb2\x8f\x5b\x63\x70\x05\xa0\x8c\x38\x48\xc1\x0f\x32\x47\x60\x8d\xf7\xa0\x35\x79\x3f\x2b\xad\xa7\x0f\xf0\xde\xfa\x22\x4b\xca\x43\x77\x98\x39\x1d\xbb\x84\x6f\xa0\xb0\x67\x75\xc3\x27\xb7\xb4\x7e\x24\x9a\x75\xa0\xff\xb7\x0d\xc7\x3c\x2e\x92\xcb\x7e\x31\x36\x2a\x89\x8d\x48\xbb\x1b\x9e\x6f\xd8\x3c\x65\x9a\x7a\xbe\xdc\xe5\xe7\x34\xe7\xa1\x48\xad\xa7\xf5\x9b\x97\xe2\x3a\xe0\x8f\x81\x06\xc7\x38\x6e\x13\x46\xd2\xab\x67\xee\xf1\xf2\xef\x76\xbb\x04\xfa\x17\x5c\xd0\x1d\x6d\xcc\x04\x00\x00")
+
+func assetDashboardStyleCssBytes() ([]byte, error) {
+	return bindataRead(
+		_assetDashboardStyleCss,
+		"asset/dashboard/style.css",
+	)
+}
+
+func assetDashboardStyleCss() (*asset, error) {
+	bytes, err := assetDashboardStyleCssBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "asset/dashboard/style.css", size: 1228, mode: os.FileMode(420), modTime: time.Unix(1792427066, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+// Asset loads and returns the asset for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func Asset(name string) ([]byte, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("Asset %s can't read by error: %v", name, err)
+		}
+		return a.bytes, nil
+	}
+	return nil, fmt.Errorf("Asset %s not found", name)
+}
+
+// MustAsset is like Asset but panics when Asset would return an error.
+// It simplifies safe initialization of global variables.
+func MustAsset(name string) []byte {
+	a, err := Asset(name)
+	if err != nil {
+		panic("asset: Asset(" + name + "): " + err.Error())
+	}
+
+	return a
+}
+
+// AssetInfo loads and returns the asset info for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func AssetInfo(name string) (os.FileInfo, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("AssetInfo %s can't read by error: %v", name, err)
+		}
+		return a.info, nil
+	}
+	return nil, fmt.Errorf("AssetInfo %s not found", name)
+}
+
+// AssetNames returns the names of the assets.
+func AssetNames() []string {
+	names := make([]string, 0, len(_bindata))
+	for name := range _bindata {
+		names = append(names, name)
+	}
+	return names
+}
+
+// _bindata is a table, holding each asset generator, mapped to its name.
+var _bindata = map[string]func() (*asset, error){
+	"asset/dashboard/app.js":     assetDashboardAppJs,
+	"asset/dashboard/index.html": assetDashboardIndexHtml,
+	"asset/dashboard/style.css":  assetDashboardStyleCss,
+}
+
+// AssetDir returns the file names below a certain
+// directory embedded in the file by go-bindata.
+// For example if you run go-bindata on data/... and data contains the
+// following hierarchy:
+//     data/
+//       foo.txt
+//       img/
+//         a.png
+//         b.png
+// then AssetDir("data") would return []string{"foo.txt", "img"}
+// AssetDir("data/img") would return []string{"a.png", "b.png"}
+// AssetDir("foo.txt") and AssetDir("notexist") would return an error
+// AssetDir("") will return []string{"data"}.
+func AssetDir(name string) ([]string, error) {
+	node := _bintree
+	if len(name) != 0 {
+		cannonicalName := strings.Replace(name, "\\", "/", -1)
+		pathList := strings.Split(cannonicalName, "/")
+		for _, p := range pathList {
+			node = node.Children[p]
+			if node == nil {
+				return nil, fmt.Errorf("Asset %s not found", name)
+			}
+		}
+	}
+	if node.Func != nil {
+		return nil, fmt.Errorf("Asset %s not found", name)
+	}
+	rv := make([]string, 0, len(node.Children))
+	for childName := range node.Children {
+		rv = append(rv, childName)
+	}
+	return rv, nil
+}
+
+type bintree struct {
+	Func     func() (*asset, error)
+	Children map[string]*bintree
+}
+
+var _bintree = &bintree{nil, map[string]*bintree{
+	"asset": &bintree{nil, map[string]*bintree{
+		"dashboard": &bintree{nil, map[string]*bintree{
+			"app.js":     &bintree{assetDashboardAppJs, map[string]*bintree{}},
+			"index.html": &bintree{assetDashboardIndexHtml, map[string]*bintree{}},
+			"style.css":  &bintree{assetDashboardStyleCss, map[string]*bintree{}},
+		}},
+	}},
+}}
+
+// RestoreAsset restores an asset under the given directory
+func RestoreAsset(dir, name string) error {
+	data, err := Asset(name)
+	if err != nil {
+		return err
+	}
+	info, err := AssetInfo(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(_filePath(dir, filepath.Dir(name)), os.FileMode(0755))
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(_filePath(dir, name), data, info.Mode())
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RestoreAssets restores an asset under the given directory recursively
+func RestoreAssets(dir, name string) error {
+	children, err := AssetDir(name)
+	// File
+	if err != nil {
+		return RestoreAsset(dir, name)
+	}
+	// Dir
+	for _, child := range children {
+		err = RestoreAssets(dir, filepath.Join(name, child))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func _filePath(dir, name string) string {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
+}
@@ -0,0 +1,223 @@
+package integrator
+
+// asset以下を変更したらbindata.goを作り直す
+//go:generate go-bindata -pkg integrator asset/...
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"github.com/AutomaticCoinTrader/ACT/robot"
+	"mime"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	dashboardPath            = "/dashboard"
+	dashboardAssetDir        = "asset/dashboard"
+	dashboardBoardDepth      = 10
+	dashboardPushInterval    = time.Second
+	dashboardAccountInterval = 10 * time.Second
+	dashboardWriteTimeout    = 10 * time.Second
+)
+
+type dashboardPair struct {
+	CurrencyPair string      `json:"currencyPair"`
+	LastPrice    float64     `json:"lastPrice"`
+	Asks         [][]float64 `json:"asks"`
+	Bids         [][]float64 `json:"bids"`
+}
+
+type dashboardAccount struct {
+	Funds  map[string]float64 `json:"funds"`
+	Orders []*apiOrder        `json:"orders"`
+	Errors []string           `json:"errors"`
+	Time   time.Time          `json:"time"`
+}
+
+type dashboardExchange struct {
+	Name    string            `json:"name"`
+	Pairs   []*dashboardPair  `json:"pairs"`
+	Account *dashboardAccount `json:"account"`
+}
+
+type dashboardSnapshot struct {
+	Time          time.Time                        `json:"time"`
+	Exchanges     []*dashboardExchange             `json:"exchanges"`
+	Algorithms    []*robot.AlgorithmStats          `json:"algorithms"`
+	Positions     map[string][]*algorithm.Position `json:"positions"`
+	KillSwitch    *robot.KillSwitchStatus          `json:"killSwitch"`
+	Notifications []*notifier.Notification         `json:"notifications"`
+}
+
+// dashboard keeps balances and active orders for a while not to call private api of exchanges every push
+type dashboard struct {
+	upgrader   *websocket.Upgrader
+	accounts   map[string]*dashboardAccount
+	mutex      *sync.Mutex
+	finishChan chan bool
+	finishOnce *sync.Once
+}
+
+func (d *dashboard) finish() {
+	d.finishOnce.Do(func() {
+		close(d.finishChan)
+	})
+}
+
+func newDashboard() (*dashboard) {
+	return &dashboard{
+		upgrader:   new(websocket.Upgrader),
+		accounts:   make(map[string]*dashboardAccount),
+		mutex:      new(sync.Mutex),
+		finishChan: make(chan bool),
+		finishOnce: new(sync.Once),
+	}
+}
+
+func (i *Integrator) setupDashboardRouting(engine *gin.Engine) {
+	engine.GET(dashboardPath, i.requireRole(roleViewer), i.dashboardIndex)
+	engine.GET(dashboardPath+"/asset/*name", i.requireRole(roleViewer), i.dashboardAsset)
+	engine.GET(dashboardPath+"/state", i.requireRole(roleViewer), i.dashboardState)
+	engine.GET(dashboardPath+"/ws", i.requireRole(roleViewer), i.dashboardWebsocket)
+}
+
+func (i *Integrator) dashboardIndex(context *gin.Context) {
+	data, err := Asset(path.Join(dashboardAssetDir, "index.html"))
+	if err != nil {
+		context.String(http.StatusNotFound, "not found")
+		return
+	}
+	context.Data(http.StatusOK, "text/html; charset=utf-8", data)
+}
+
+func (i *Integrator) dashboardAsset(context *gin.Context) {
+	name := path.Join(dashboardAssetDir, path.Clean("/"+context.Param("name")))
+	data, err := Asset(name)
+	if err != nil {
+		context.String(http.StatusNotFound, "not found")
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	context.Data(http.StatusOK, contentType, data)
+}
+
+func (i *Integrator) getDashboardAccount(name string) (*dashboardAccount) {
+	i.dashboard.mutex.Lock()
+	defer i.dashboard.mutex.Unlock()
+	account, ok := i.dashboard.accounts[name]
+	if ok && time.Since(account.Time) < dashboardAccountInterval {
+		return account
+	}
+	ex, ok := i.getExchange(name)
+	if !ok {
+		return nil
+	}
+	account = &dashboardAccount{
+		Orders: make([]*apiOrder, 0),
+		Errors: make([]string, 0),
+		Time:   time.Now(),
+	}
+	funds, err := ex.GetFunds()
+	if err != nil {
+		account.Errors = append(account.Errors, err.Error())
+	} else {
+		account.Funds = funds
+		metrics.SetBalances(ex.GetName(), funds)
+	}
+	orderCursor, err := ex.GetActiveOrderCursor()
+	if err != nil {
+		account.Errors = append(account.Errors, err.Error())
+	} else {
+		account.Orders = newAPIOrders(ex, orderCursor)
+	}
+	i.dashboard.accounts[name] = account
+	return account
+}
+
+func (i *Integrator) getDashboardSnapshot() (*dashboardSnapshot) {
+	snapshot := &dashboardSnapshot{
+		Time:          time.Now(),
+		Exchanges:     make([]*dashboardExchange, 0),
+		Algorithms:    i.robot.GetAlgorithmStats(),
+		Positions:     i.robot.GetAllPositions(),
+		KillSwitch:    i.robot.GetKillSwitchStatus(),
+		Notifications: i.notifier.GetRecentNotifications(),
+	}
+	for _, ex := range i.getExchanges() {
+		dashboardExchange := &dashboardExchange{
+			Name:    ex.GetName(),
+			Pairs:   make([]*dashboardPair, 0),
+			Account: i.getDashboardAccount(ex.GetName()),
+		}
+		for _, currencyPair := range newAPIExchange(ex).CurrencyPairs {
+			pair := &dashboardPair{
+				CurrencyPair: currencyPair,
+				Asks:         make([][]float64, 0),
+				Bids:         make([][]float64, 0),
+			}
+			lastPrice, err := ex.GetLastPrice(currencyPair)
+			if err == nil {
+				pair.LastPrice = lastPrice
+			}
+			sellBoardCursor, buyBoardCursor, err := ex.GetSellBuyBoardCursor(currencyPair)
+			if err == nil {
+				pair.Asks = limitBoard(sellBoardCursor.All(), dashboardBoardDepth)
+				pair.Bids = limitBoard(buyBoardCursor.All(), dashboardBoardDepth)
+			}
+			dashboardExchange.Pairs = append(dashboardExchange.Pairs, pair)
+		}
+		snapshot.Exchanges = append(snapshot.Exchanges, dashboardExchange)
+	}
+	return snapshot
+}
+
+func (i *Integrator) dashboardState(context *gin.Context) {
+	apiOK(context, i.getDashboardSnapshot())
+}
+
+func (i *Integrator) dashboardWebsocket(context *gin.Context) {
+	ws, err := i.dashboard.upgrader.Upgrade(context.Writer, context.Request, nil)
+	if err != nil {
+		i.logger.Warnf("can not upgrade to websocket (reason = %v)", err)
+		return
+	}
+	defer ws.Close()
+	closeChan := make(chan bool)
+	go func() {
+		// クライアントからは何も来ないが、切断を検出するために読み続ける
+		defer close(closeChan)
+		for {
+			_, _, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+	ticker := time.NewTicker(dashboardPushInterval)
+	defer ticker.Stop()
+	for {
+		ws.SetWriteDeadline(time.Now().Add(dashboardWriteTimeout))
+		err := ws.WriteJSON(gin.H{"type": "snapshot", "data": i.getDashboardSnapshot()})
+		if err != nil {
+			i.logger.Debugf("can not write to dashboard (peer = %v, reason = %v)", ws.RemoteAddr(), err)
+			return
+		}
+		select {
+		case <-closeChan:
+			return
+		case <-i.dashboard.finishChan:
+			ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		case <-ticker.C:
+		}
+	}
+}
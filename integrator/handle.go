@@ -1,7 +1,153 @@
 package integrator
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/AutomaticCoinTrader/ACT/journal"
+	"net/http"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func (i *Integrator) index(context *gin.Context) {
+	context.Redirect(http.StatusFound, dashboardPath)
+}
+
+func (i *Integrator) reloadAlgorithms(context *gin.Context) {
+	name := context.Query("name")
+	if name == "" {
+		context.JSON(http.StatusOK, i.ReloadAlgorithms())
+		return
+	}
+	result, err := i.robot.ReloadAlgorithm(name)
+	if err != nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, result)
+}
+
+func (i *Integrator) getPositions(context *gin.Context) {
+	name := context.Param("name")
+	if name == "" {
+		context.JSON(http.StatusOK, i.robot.GetAllPositions())
+		return
+	}
+	context.JSON(http.StatusOK, i.robot.GetPositions(name))
+}
+
+func (i *Integrator) getKillSwitch(context *gin.Context) {
+	context.JSON(http.StatusOK, i.robot.GetKillSwitchStatus())
+}
+
+func (i *Integrator) kill(context *gin.Context) {
+	reason := context.Query("reason")
+	if reason == "" {
+		reason = fmt.Sprintf("http request (user = %v)", context.GetString(userContextKey))
+	}
+	context.JSON(http.StatusOK, i.Kill(reason))
+}
+
+func (i *Integrator) resetKillSwitch(context *gin.Context) {
+	err := i.robot.ResetKillSwitch()
+	if err != nil {
+		context.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, i.robot.GetKillSwitchStatus())
+}
+
+func (i *Integrator) getReconcileResults(context *gin.Context) {
+	context.JSON(http.StatusOK, i.robot.GetReconcileResults())
+}
+
+func parseTimeQuery(context *gin.Context, key string) (time.Time, error) {
+	value := context.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid %v", key)
+	}
+	return t, nil
+}
+
+func parseJournalQuery(context *gin.Context) (*journal.Query, error) {
+	from, err := parseTimeQuery(context, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTimeQuery(context, "to")
+	if err != nil {
+		return nil, err
+	}
+	query := &journal.Query{
+		From:         from,
+		To:           to,
+		Name:         context.Query("name"),
+		Exchange:     context.Query("exchange"),
+		CurrencyPair: context.Query("currencyPair"),
+	}
+	if types := context.Query("type"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			query.Types = append(query.Types, journal.EntryType(t))
+		}
+	}
+	if orderID := context.Query("orderId"); orderID != "" {
+		query.OrderID, err = strconv.ParseInt(orderID, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid orderId")
+		}
+	}
+	if limit := context.Query("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid limit")
+		}
+	}
+	return query, nil
+}
+
+func (i *Integrator) getJournal(context *gin.Context) {
+	query, err := parseJournalQuery(context)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := context.Query("format")
+	switch format {
+	case "":
+		entries, err := i.robot.GetJournal().Query(query)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		context.JSON(http.StatusOK, entries)
+	case journal.FormatJSONLines, journal.FormatCSV:
+		contentType := "application/x-ndjson"
+		if format == journal.FormatCSV {
+			contentType = "text/csv"
+		}
+		context.Header("Content-Type", contentType)
+		context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=journal.%v", format))
+		err := i.robot.GetJournal().Export(context.Writer, format, query)
+		if err != nil {
+			// 途中まで書いてしまっているのでステータスは変えられない
+			context.Error(err)
+		}
+	default:
+		context.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported format (format = %v)", format)})
+	}
+}
 
+func (i *Integrator) verifyJournal(context *gin.Context) {
+	count, err := i.robot.GetJournal().Verify()
+	if err != nil {
+		context.JSON(http.StatusConflict, gin.H{"entries": count, "error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"entries": count})
 }
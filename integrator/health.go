@@ -0,0 +1,311 @@
+package integrator
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/AutomaticCoinTrader/ACT/robot"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStaleTimeout       = 60
+	defaultReconnectWindow    = 600
+	defaultReconnectThreshold = 5
+	// ストリーミングの状態を確認する間隔
+	healthCheckInterval = time.Second
+)
+
+type healthConfig struct {
+	// 秒。この間ストリーミングの更新がなければ古いとみなす
+	StaleTimeout       int `json:"staleTimeout"       yaml:"staleTimeout"       toml:"staleTimeout"`
+	// 秒。この間にreconnectThreshold回以上再接続したら通知する
+	ReconnectWindow    int `json:"reconnectWindow"    yaml:"reconnectWindow"    toml:"reconnectWindow"`
+	ReconnectThreshold int `json:"reconnectThreshold" yaml:"reconnectThreshold" toml:"reconnectThreshold"`
+}
+
+// feedState is state of streaming of one currency pair
+type feedState struct {
+	Exchange       string      `json:"exchange"`
+	CurrencyPair   string      `json:"currencyPair"`
+	LastUpdate     time.Time   `json:"lastUpdate"`
+	Reconnects     int         `json:"reconnects"`
+	Stale          bool        `json:"stale"`
+	reconnectTimes []time.Time
+	// 再接続が多いことを通知済み
+	reconnectNotified bool
+}
+
+type healthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+type readiness struct {
+	Ready  bool           `json:"ready"`
+	Time   time.Time      `json:"time"`
+	Checks []*healthCheck `json:"checks"`
+	Feeds  []*feedState   `json:"feeds"`
+}
+
+type healthEvent struct {
+	severity notifier.Severity
+	subject  string
+	body     string
+}
+
+// healthMonitor is watch initialization of exchanges and freshness of streamings
+type healthMonitor struct {
+	staleTimeout       time.Duration
+	reconnectWindow    time.Duration
+	reconnectThreshold int
+	initialized        bool
+	started            bool
+	startTime          time.Time
+	feeds              map[string]*feedState
+	mutex              *sync.Mutex
+}
+
+func feedKey(exchangeName string, currencyPair string) (string) {
+	return exchangeName + "/" + strings.ToLower(currencyPair)
+}
+
+func (h *healthMonitor) setInitialized() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.initialized = true
+}
+
+// getFeed is must be called with mutex locked
+func (h *healthMonitor) getFeed(exchangeName string, currencyPair string) (*feedState) {
+	key := feedKey(exchangeName, currencyPair)
+	feed, ok := h.feeds[key]
+	if !ok {
+		feed = &feedState{
+			Exchange:       exchangeName,
+			CurrencyPair:   currencyPair,
+			reconnectTimes: make([]time.Time, 0),
+		}
+		h.feeds[key] = feed
+	}
+	return feed
+}
+
+// addFeed is add streaming which should deliver data
+func (h *healthMonitor) addFeed(exchangeName string, currencyPair string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.getFeed(exchangeName, currencyPair)
+}
+
+func (h *healthMonitor) start(now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.started = true
+	h.startTime = now
+}
+
+func (h *healthMonitor) stop() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.started = false
+}
+
+func (h *healthMonitor) update(exchangeName string, currencyPair string, now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.getFeed(exchangeName, currencyPair).LastUpdate = now
+}
+
+func (h *healthMonitor) reconnect(exchangeName string, currencyPair string, now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	feed := h.getFeed(exchangeName, currencyPair)
+	feed.reconnectTimes = append(feed.reconnectTimes, now)
+}
+
+// isStale is must be called with mutex locked
+func (h *healthMonitor) isStale(feed *feedState, now time.Time) (bool) {
+	if !h.started {
+		return false
+	}
+	// まだ一度も届いていなければ開始した時刻から数える
+	last := feed.LastUpdate
+	if last.Before(h.startTime) {
+		last = h.startTime
+	}
+	return now.Sub(last) > h.staleTimeout
+}
+
+// check is update state of feeds and return events which should be notified
+func (h *healthMonitor) check(now time.Time) ([]*healthEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	events := make([]*healthEvent, 0)
+	for _, feed := range h.sortedFeeds() {
+		stale := h.isStale(feed, now)
+		if stale && !feed.Stale {
+			events = append(events, &healthEvent{
+				severity: notifier.SeverityWarning,
+				subject:  fmt.Sprintf("[ACT] streaming of %v %v is stale", feed.Exchange, feed.CurrencyPair),
+				body: fmt.Sprintf("no data is delivered by streaming.\nexchange = %v\ncurrency pair = %v\nlast update = %v\nstale timeout = %v\n",
+					feed.Exchange, feed.CurrencyPair, feed.LastUpdate, h.staleTimeout),
+			})
+		} else if !stale && feed.Stale && h.started {
+			events = append(events, &healthEvent{
+				severity: notifier.SeverityInfo,
+				subject:  fmt.Sprintf("[ACT] streaming of %v %v is recovered", feed.Exchange, feed.CurrencyPair),
+				body: fmt.Sprintf("streaming delivers data again.\nexchange = %v\ncurrency pair = %v\nlast update = %v\n",
+					feed.Exchange, feed.CurrencyPair, feed.LastUpdate),
+			})
+		}
+		feed.Stale = stale
+		// 窓から外れた再接続を捨てる
+		idx := 0
+		for idx < len(feed.reconnectTimes) && now.Sub(feed.reconnectTimes[idx]) > h.reconnectWindow {
+			idx++
+		}
+		feed.reconnectTimes = feed.reconnectTimes[idx:]
+		feed.Reconnects = len(feed.reconnectTimes)
+		if feed.Reconnects < h.reconnectThreshold {
+			feed.reconnectNotified = false
+		} else if !feed.reconnectNotified {
+			feed.reconnectNotified = true
+			events = append(events, &healthEvent{
+				severity: notifier.SeverityWarning,
+				subject:  fmt.Sprintf("[ACT] streaming of %v %v reconnects repeatedly", feed.Exchange, feed.CurrencyPair),
+				body: fmt.Sprintf("streaming reconnected %v times in %v.\nexchange = %v\ncurrency pair = %v\n",
+					feed.Reconnects, h.reconnectWindow, feed.Exchange, feed.CurrencyPair),
+			})
+		}
+	}
+	return events
+}
+
+// sortedFeeds is must be called with mutex locked
+func (h *healthMonitor) sortedFeeds() ([]*feedState) {
+	feeds := make([]*feedState, 0, len(h.feeds))
+	for _, feed := range h.feeds {
+		feeds = append(feeds, feed)
+	}
+	sort.Slice(feeds, func(i, j int) (bool) {
+		return feedKey(feeds[i].Exchange, feeds[i].CurrencyPair) < feedKey(feeds[j].Exchange, feeds[j].CurrencyPair)
+	})
+	return feeds
+}
+
+func (h *healthMonitor) getReadiness(now time.Time, algorithmStats []*robot.AlgorithmStats) (*readiness) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	result := &readiness{
+		Ready:  true,
+		Time:   now,
+		Checks: make([]*healthCheck, 0),
+		Feeds:  make([]*feedState, 0, len(h.feeds)),
+	}
+	addCheck := func(name string, problems []string) {
+		check := &healthCheck{
+			Name:    name,
+			OK:      len(problems) == 0,
+			Message: strings.Join(problems, ", "),
+		}
+		if !check.OK {
+			result.Ready = false
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	problems := make([]string, 0)
+	if !h.initialized {
+		problems = append(problems, "exchanges are not initialized")
+	}
+	addCheck("exchanges", problems)
+	problems = make([]string, 0)
+	if !h.started {
+		problems = append(problems, "streamings are not started")
+	}
+	for _, feed := range h.sortedFeeds() {
+		copied := *feed
+		copied.Stale = h.isStale(feed, now)
+		result.Feeds = append(result.Feeds, &copied)
+		if copied.Stale {
+			problems = append(problems, fmt.Sprintf("%v %v is stale", feed.Exchange, feed.CurrencyPair))
+		} else if h.started && feed.LastUpdate.Before(h.startTime) {
+			problems = append(problems, fmt.Sprintf("%v %v has no data", feed.Exchange, feed.CurrencyPair))
+		}
+	}
+	addCheck("feeds", problems)
+	problems = make([]string, 0)
+	for _, stats := range algorithmStats {
+		// 一時停止は操作した結果なので準備できていないとはみなさない
+		if stats.Disabled {
+			problems = append(problems, fmt.Sprintf("%v is disabled", stats.Name))
+		}
+	}
+	addCheck("algorithms", problems)
+	return result
+}
+
+func newHealthMonitor(config *healthConfig) (*healthMonitor) {
+	staleTimeout := defaultStaleTimeout
+	reconnectWindow := defaultReconnectWindow
+	reconnectThreshold := defaultReconnectThreshold
+	if config != nil {
+		if config.StaleTimeout > 0 {
+			staleTimeout = config.StaleTimeout
+		}
+		if config.ReconnectWindow > 0 {
+			reconnectWindow = config.ReconnectWindow
+		}
+		if config.ReconnectThreshold > 0 {
+			reconnectThreshold = config.ReconnectThreshold
+		}
+	}
+	return &healthMonitor{
+		staleTimeout:       time.Duration(staleTimeout) * time.Second,
+		reconnectWindow:    time.Duration(reconnectWindow) * time.Second,
+		reconnectThreshold: reconnectThreshold,
+		feeds:              make(map[string]*feedState),
+		mutex:              new(sync.Mutex),
+	}
+}
+
+func (i *Integrator) checkHealth() {
+	for _, event := range i.health.check(i.clock.Now()) {
+		i.logger.Warnf("%v", event.subject)
+		err := i.notifier.Notify(&notifier.Event{
+			Severity: event.severity,
+			Subject:  event.subject,
+			Body:     event.body,
+		})
+		if err != nil {
+			i.logger.Errorf("can not send notification (reason = %v)", err)
+		}
+	}
+}
+
+func (i *Integrator) healthz(context *gin.Context) {
+	// httpサーバーが応答できれば生きている
+	context.JSON(http.StatusOK, gin.H{"status": "ok", "time": i.clock.Now()})
+}
+
+func (i *Integrator) readyz(context *gin.Context) {
+	result := i.health.getReadiness(i.clock.Now(), i.robot.GetAlgorithmStats())
+	if !result.Ready {
+		context.JSON(http.StatusServiceUnavailable, result)
+		return
+	}
+	context.JSON(http.StatusOK, result)
+}
+
+func (i *Integrator) setupHealthRouting(engine *gin.Engine) {
+	// supervisorから呼ぶので認証しない
+	engine.GET("/healthz", i.healthz)
+	engine.HEAD("/healthz", i.healthz)
+	engine.GET("/readyz", i.readyz)
+	engine.HEAD("/readyz", i.readyz)
+}
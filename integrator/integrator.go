@@ -6,13 +6,18 @@ import (
 	"github.com/braintree/manners"
 	"github.com/AutomaticCoinTrader/ACT/exchange"
 	"github.com/AutomaticCoinTrader/ACT/robot"
-	"log"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/configurator"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
 	"time"
 	"fmt"
+	"path"
 	"reflect"
+	"sort"
+	"sync"
 	"net/http"
 	"github.com/AutomaticCoinTrader/ACT/notifier"
-	"log/syslog"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 )
 
 type StartStreamingCallback func(ex exchange.Exchange, userCallbackData interface{}) (error)
@@ -22,6 +27,12 @@ type StartArbitrageCallback func(exs map[string]exchange.Exchange, userCallbackD
 type UpdateArbitrageCallback func(exs map[string]exchange.Exchange, userCallbackData interface{}) (error)
 type StopArbitrageCallback func(exs map[string]exchange.Exchange, userCallbackData interface{}) (error)
 
+// 残高のメトリクスを更新する間隔
+const balanceMetricsInterval = time.Minute
+
+// ConfigPathPrefix is path prefix of integrator config file in config directory
+const ConfigPathPrefix = "act"
+
 type gracefulServer struct {
 	server    *manners.GracefulServer
 	startChan chan error
@@ -29,29 +40,94 @@ type gracefulServer struct {
 
 type Integrator struct {
 	config                  *Config
+	configDir               string
 	gracefulServer          *gracefulServer
 	exchanges               map[string]exchange.Exchange
+	exchangesMutex          *sync.Mutex
 	arbitrageLoopFinishChan chan bool
+	arbitrageLoopFinishOnce *sync.Once
 	notifier                *notifier.Notifier
 	robot                   *robot.Robot
+	clock                   clock.Clock
+	dashboard               *dashboard
+	authenticator           *authenticator
+	auditLogger             *auditLogger
+	pushHub                 *pushHub
+	health                  *healthMonitor
+	logger                  *logger.Logger
 }
 
 func (i *Integrator) setupRouting(engine *gin.Engine) {
-	engine.HEAD("/", i.index)
-	engine.GET("/", i.index)
+	engine.HEAD("/", i.requireRole(roleViewer), i.index)
+	engine.GET("/", i.requireRole(roleViewer), i.index)
+	engine.POST("/algorithms/reload", i.requireRole(roleAdmin), i.audit, i.reloadAlgorithms)
+	engine.GET("/positions", i.requireRole(roleViewer), i.getPositions)
+	engine.GET("/positions/:name", i.requireRole(roleViewer), i.getPositions)
+	engine.GET("/reconcile", i.requireRole(roleViewer), i.getReconcileResults)
+	engine.GET("/journal", i.requireRole(roleViewer), i.getJournal)
+	engine.GET("/journal/verify", i.requireRole(roleViewer), i.verifyJournal)
+	engine.GET("/killswitch", i.requireRole(roleViewer), i.getKillSwitch)
+	// 止めるのはトレードできる人なら誰でもできるが、解除は管理者だけ
+	engine.POST("/killswitch", i.requireRole(roleTrader), i.audit, i.kill)
+	engine.DELETE("/killswitch", i.requireRole(roleAdmin), i.audit, i.resetKillSwitch)
+	engine.GET("/metrics", i.requireRole(roleViewer), gin.WrapH(metrics.Handler()))
+	i.setupAPIRouting(engine)
+	i.setupDashboardRouting(engine)
+	i.setupPushRouting(engine)
+	i.setupHealthRouting(engine)
+}
+
+// Handler is http handler which has same routes as http server. it can be used without server.addrPort
+func (i *Integrator) Handler() (http.Handler) {
+	engine := gin.New()
+	i.setupRouting(engine)
+	return engine
+}
+
+// getExchange is get exchange by name. http handlers may be called while exchanges are created
+func (i *Integrator) getExchange(name string) (exchange.Exchange, bool) {
+	i.exchangesMutex.Lock()
+	defer i.exchangesMutex.Unlock()
+	ex, ok := i.exchanges[name]
+	return ex, ok
+}
+
+// getExchanges is get exchanges sorted by name
+func (i *Integrator) getExchanges() ([]exchange.Exchange) {
+	i.exchangesMutex.Lock()
+	defer i.exchangesMutex.Unlock()
+	exchanges := make([]exchange.Exchange, 0, len(i.exchanges))
+	for _, ex := range i.exchanges {
+		exchanges = append(exchanges, ex)
+	}
+	sort.Slice(exchanges, func(a, b int) (bool) {
+		return exchanges[a].GetName() < exchanges[b].GetName()
+	})
+	return exchanges
 }
 
 func (i *Integrator) runHttpServer() {
-	err := i.gracefulServer.server.ListenAndServe()
+	var err error
+	if i.config.Server.TLSCertFile != "" {
+		err = i.gracefulServer.server.ListenAndServeTLS(i.config.Server.TLSCertFile, i.config.Server.TLSKeyFile)
+	} else {
+		err = i.gracefulServer.server.ListenAndServe()
+	}
 	if err != nil {
 		i.gracefulServer.startChan <- err
 	}
 }
 
 func (i *Integrator) initHttpServer() (error) {
-	if i.config.Server.AddrPort == "" {
+	if i.config.Server == nil || i.config.Server.AddrPort == "" {
 		return nil
 	}
+	if (i.config.Server.TLSCertFile == "") != (i.config.Server.TLSKeyFile == "") {
+		return errors.New("both of tlsCertFile and tlsKeyFile are required")
+	}
+	if i.config.Server.TLSCertFile == "" && i.authenticator.configured() {
+		i.logger.Warnf("credentials are sent without tls (addr port = %v)", i.config.Server.AddrPort)
+	}
 	if !i.config.Server.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -77,22 +153,43 @@ func (i *Integrator) initHttpServer() (error) {
 	return nil
 }
 
-func (i *Integrator) streamingCallback(currencyPair string, ex exchange.Exchange) (error) {
-	// トレード処理を期待
-	err := i.robot.UpdateInternalTradeAlgorithms(currencyPair, ex)
-	if err != nil {
-		log.Printf("can not run algorithm (reason = %v)", err)
+func (i *Integrator) newStreamingCallback(exchangeName string) (exchange.StreamingCallback) {
+	return func(currencyPair string, ex exchange.Exchange) (error) {
+		if ex.GetName() != exchangeName {
+			i.logger.Errorf("unexpected exchange in streaming callback (expected = %v, actual = %v)", exchangeName, ex.GetName())
+			return nil
+		}
+		i.health.update(exchangeName, currencyPair, i.clock.Now())
+		// websocketの購読者に板の更新を知らせる
+		i.pushHub.publishBoard(exchangeName, currencyPair)
+		// トレード処理を期待
+		err := i.robot.UpdateInternalTradeAlgorithms(currencyPair, ex)
+		if err != nil {
+			i.logger.With(logger.Fields{
+				logger.FieldExchange:     exchangeName,
+				logger.FieldCurrencyPair: currencyPair,
+			}).Errorf("can not run algorithm (reason = %v)", err)
+		}
+		// 板のトリガーを持つ取引所間取引アルゴリズムを起こす
+		err = i.robot.UpdateExternalTradeAlgorithmsByBoard(currencyPair, ex)
+		if err != nil {
+			i.logger.With(logger.Fields{
+				logger.FieldExchange:     exchangeName,
+				logger.FieldCurrencyPair: currencyPair,
+			}).Errorf("can not run external algorithm (reason = %v)", err)
+		}
+		return nil
 	}
-	return nil
 }
 
 func (i *Integrator) Initialize() (error) {
 	err := i.initHttpServer()
 	if err != nil {
-		errors.Errorf("can not initalize of http server (reason = %v)", err)
+		// 認証やtlsの設定が間違っているまま起動しない
+		return errors.Wrap(err, "can not initalize of http server")
 	}
 	for name, exchangeNewFunc := range exchange.GetRegisterdExchanges() {
-		log.Printf("listing exchange: %s\n", name)
+		i.logger.Debugf("listing exchange: %s", name)
 		t := reflect.TypeOf(i.config.Exchanges).Elem()
 		for idx := 0; idx < t.NumField(); idx++ {
 			f := t.Field(idx)
@@ -112,22 +209,63 @@ func (i *Integrator) Initialize() (error) {
 			if exchangeNewFunc == nil {
 				continue
 			}
-			log.Printf("%v exchange create", name)
+			i.logger.WithField(logger.FieldExchange, name).Infof("create exchange")
 			ex, err := exchangeNewFunc(conf, )
 			if err != nil {
 				i.Finalize()
 				return errors.Wrap(err, fmt.Sprintf("can not create exchange of %v", name))
 			}
-			ex.Initialize(i.streamingCallback)
+			if clockSetter, ok := ex.(exchange.ClockSetter); ok {
+				clockSetter.SetClock(i.clock)
+			}
+			ex.Initialize(i.newStreamingCallback(ex.GetName()))
+			if reconnectNotifier, ok := ex.(exchange.ReconnectNotifier); ok {
+				reconnectNotifier.SetReconnectCallback(i.onReconnect)
+			}
 			// 作った取引所を保存しておく
+			i.exchangesMutex.Lock()
 			i.exchanges[name] = ex
+			i.exchangesMutex.Unlock()
 		}
 	}
+	// アルゴリズムが取引を始める前に取引所の状態と記録を突き合わせる
+	for _, ex := range i.exchanges {
+		i.robot.Reconcile(ex)
+	}
+	i.health.setInitialized()
 	return nil
 }
 
+func (i *Integrator) onReconnect(currencyPair string, ex exchange.Exchange) {
+	i.health.reconnect(ex.GetName(), currencyPair, i.clock.Now())
+}
+
 func (i *Integrator) Finalize() (error) {
-	i.gracefulServer.server.BlockingClose()
+	err := i.robot.Finalize()
+	if err != nil {
+		i.logger.Errorf("can not finalize robot (reason = %v)", err)
+	}
+	if i.gracefulServer != nil {
+		// ダッシュボードとwebsocketの接続が残っていると止まらないので先に閉じる
+		i.dashboard.finish()
+		i.pushHub.finish()
+		// addrPortが空の場合はhttpサーバーを起動していない
+		i.gracefulServer.server.BlockingClose()
+	}
+	err = i.auditLogger.close()
+	if err != nil {
+		i.logger.Errorf("can not close audit log (reason = %v)", err)
+	}
+	// 停止までの通知を送り切る
+	err = i.notifier.Close()
+	if err != nil {
+		i.logger.Errorf("can not close notifier (reason = %v)", err)
+	}
+	// ファイルやsyslogを閉じて以降はstderrに書く
+	err = logger.Close()
+	if err != nil {
+		return errors.Wrap(err, "can not close logger")
+	}
 	return nil
 }
 
@@ -146,6 +284,9 @@ func (i *Integrator) startInternalTrade() (error) {
 			i.stopInternalTrade()
 			return errors.Wrap(err, fmt.Sprintf("can not start streaming (name = %v)", ex.GetName()))
 		}
+		for _, currencyPair := range ex.GetCurrencyPairs() {
+			i.health.addFeed(ex.GetName(), currencyPair)
+		}
 	}
 
 	return nil
@@ -158,26 +299,55 @@ func (i *Integrator) stopInternalTrade() (error) {
 		// streamingを停止
 		err := ex.StopStreamings()
 		if err != nil {
-			log.Printf("can not stop streaming (name = %v)", ex.GetName())
+			i.logger.WithField(logger.FieldExchange, ex.GetName()).Errorf("can not stop streaming (reason = %v)", err)
 		}
 		// straming止めた後の終了処理を期待
 		err = i.robot.DestroyInternalTradeAlgorithms(ex)
 		if err != nil {
-			log.Printf("can not destroy algorithm (name = %v, reason = %v)", ex.GetName(), err)
+			i.logger.WithField(logger.FieldExchange, ex.GetName()).Errorf("can not destroy algorithm (reason = %v)", err)
 		}
 	}
 	return nil
 }
 
-func (i *Integrator) externalTradeEventLoop() {
+// updateBalanceMetrics is fetch funds of exchanges and update balance metrics
+func (i *Integrator) updateBalanceMetrics() {
+	for _, ex := range i.exchanges {
+		funds, err := ex.GetFunds()
+		if err != nil {
+			i.logger.WithField(logger.FieldExchange, ex.GetName()).Warnf("can not get funds for metrics (reason = %v)", err)
+			continue
+		}
+		metrics.SetBalances(ex.GetName(), funds)
+	}
+}
+
+// fillPollLoop is detect fills of orders placed by algorithms and watch flag file of kill switch
+func (i *Integrator) fillPollLoop() {
+	ticker := i.clock.NewTicker(i.robot.GetFillPollInterval())
+	defer ticker.Stop()
+	killSwitchTicker := i.clock.NewTicker(time.Second)
+	defer killSwitchTicker.Stop()
+	balanceTicker := i.clock.NewTicker(balanceMetricsInterval)
+	defer balanceTicker.Stop()
+	healthTicker := i.clock.NewTicker(healthCheckInterval)
+	defer healthTicker.Stop()
 	for {
 		select {
 		case <-i.arbitrageLoopFinishChan:
 			return
-		case <-time.After(500 * time.Millisecond):
-			err := i.robot.UpdateExternalTradeAlgorithms(i.exchanges)
-			if err != nil {
-				log.Printf("can not update external trade algorithm (reason = %v)", err)
+		case <-killSwitchTicker.C():
+			i.robot.CheckKillSwitchFile()
+		case <-balanceTicker.C():
+			i.updateBalanceMetrics()
+		case <-healthTicker.C():
+			i.checkHealth()
+		case <-ticker.C():
+			for _, ex := range i.exchanges {
+				err := i.robot.PollFills(ex)
+				if err != nil {
+					i.logger.WithField(logger.FieldExchange, ex.GetName()).Warnf("can not poll fills (reason = %v)", err)
+				}
 			}
 		}
 	}
@@ -188,15 +358,18 @@ func (i *Integrator) startExternalTrade() (error) {
 	if err != nil {
 		return errors.Wrap(err, "can not create external trade algorithm")
 	}
-	go i.externalTradeEventLoop()
+	go i.fillPollLoop()
 	return nil
 }
 
 func (i *Integrator) stopExternalTrade() (error) {
-	close(i.arbitrageLoopFinishChan)
+	// ShutdownとStopの両方から呼ばれることがある
+	i.arbitrageLoopFinishOnce.Do(func() {
+		close(i.arbitrageLoopFinishChan)
+	})
 	err := i.robot.DestroyExternalTradeAlgorithms(i.exchanges)
 	if err != nil {
-		log.Printf("can not destroy external trade algorithm (reason = %v)", err)
+		i.logger.Errorf("can not destroy external trade algorithm (reason = %v)", err)
 	}
 	return nil
 }
@@ -206,6 +379,8 @@ func (i *Integrator) Start() (error) {
 	if err != nil {
 		return errors.Wrap(err, "can not start streaming")
 	}
+	// ここから更新が届かないストリーミングを古いとみなす
+	i.health.start(i.clock.Now())
 	err = i.startExternalTrade()
 	if err != nil {
 		return errors.Wrap(err, "can not start external trade")
@@ -213,57 +388,148 @@ func (i *Integrator) Start() (error) {
 	return nil
 }
 
+// SetClock is set clock used by integrator, exchanges and algorithms. it should be called before Start
+func (i *Integrator) SetClock(clock clock.Clock) {
+	i.clock = clock
+	i.robot.SetClock(clock)
+	i.notifier.SetClock(clock)
+	i.exchangesMutex.Lock()
+	defer i.exchangesMutex.Unlock()
+	for _, ex := range i.exchanges {
+		if clockSetter, ok := ex.(exchange.ClockSetter); ok {
+			clockSetter.SetClock(clock)
+		}
+	}
+}
+
+func (i *Integrator) loadRobotConfig() (*robot.Config, error) {
+	cf, err := configurator.NewConfigurator(path.Join(i.configDir, ConfigPathPrefix))
+	if err != nil {
+		// 設定ファイルから作られていなければ今の設定のまま
+		return nil, nil
+	}
+	newConfig := new(Config)
+	err = cf.Load(newConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not load config (path = %v)", cf.GetConfigPath())
+	}
+	if newConfig.Robot == nil {
+		return new(robot.Config), nil
+	}
+	return newConfig.Robot, nil
+}
+
+// ReloadAlgorithms is reload algorithm plugins, algorithm instances of robot config and algorithm configs without stopping streaming
+func (i *Integrator) ReloadAlgorithms() (*robot.ReloadResult) {
+	robotConfig, err := i.loadRobotConfig()
+	if err != nil {
+		i.logger.Errorf("can not reload robot config (reason = %v)", err)
+		result := i.robot.ReloadAlgorithms()
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	return i.robot.ReloadAlgorithmsWithConfig(robotConfig)
+}
+
+// Kill is stop algorithms and cancel all orders
+func (i *Integrator) Kill(reason string) (*robot.KillResult) {
+	return i.robot.Kill(reason)
+}
+
 func (i *Integrator) Stop() (error) {
+	i.health.stop()
 	err := i.stopExternalTrade()
 	if err != nil {
-		log.Printf("can not stop extenal trade (reason = %v)", err)
+		i.logger.Errorf("can not stop extenal trade (reason = %v)", err)
 	}
 	err = i.stopInternalTrade()
 	if err != nil {
-		log.Printf("can not stop internal trade (reason = %v)", err)
+		i.logger.Errorf("can not stop internal trade (reason = %v)", err)
 	}
 	return nil
 }
 
 type serverConfig struct {
-	Debug    bool   `json:"debug"     yaml:"debug"     toml:"debug"`
-	AddrPort string `json:"addrPort"  yaml:"addrPort"  toml:"addrPort"`
+	Debug        bool          `json:"debug"        yaml:"debug"        toml:"debug"`
+	AddrPort     string        `json:"addrPort"     yaml:"addrPort"     toml:"addrPort"`
+	// APIのトークン
+	Tokens       []*apiToken   `json:"tokens"       yaml:"tokens"       toml:"tokens"`
+	// 以前の設定との互換のため。ロールを省略するとtrader
+	TradeTokens  []*apiToken   `json:"tradeTokens"  yaml:"tradeTokens"  toml:"tradeTokens"`
+	// basic認証のユーザー
+	Users        []*userConfig `json:"users"        yaml:"users"        toml:"users"`
+	// 両方設定するとhttpsで起動する
+	TLSCertFile  string        `json:"tlsCertFile"  yaml:"tlsCertFile"  toml:"tlsCertFile"`
+	TLSKeyFile   string        `json:"tlsKeyFile"   yaml:"tlsKeyFile"   toml:"tlsKeyFile"`
+	// 誰が何をしたかの記録。省略時はログにだけ出す
+	AuditLogFile string        `json:"auditLogFile" yaml:"auditLogFile" toml:"auditLogFile"`
 }
 
-type loggerConfig struct {
-	Output   string   `json:"output"    yaml:"output"    toml:"output"`
+type shutdownConfig struct {
+	// 秒
+	Timeout      int  `json:"timeout"      yaml:"timeout"      toml:"timeout"`
+	// 停止時にアクティブな注文を取り消す
+	CancelOrders bool `json:"cancelOrders" yaml:"cancelOrders" toml:"cancelOrders"`
 }
 
+// 出力先、形式、コンポーネントごとのレベル、ローテーション
+type loggerConfig logger.Config
+
 type Config struct {
 	Server    *serverConfig    `json:"server"    yaml:"server"    toml:"server"`
 	Exchanges *ExchangesConfig `json:"exchanges" yaml:"exchanges" toml:"exchanges"`
 	Robot     *robot.Config    `json:"robot"     yaml:"robot"     toml:"robot"`
 	Notifier  *notifier.Config `json:"notifier"  yaml:"notifier"  toml:"notifier"`
 	Logger    *loggerConfig    `json:"logger"    yaml:"logger"    toml:"logger"`
+	Shutdown  *shutdownConfig  `json:"shutdown"  yaml:"shutdown"  toml:"shutdown"`
+	Health    *healthConfig    `json:"health"    yaml:"health"    toml:"health"`
 }
 
 func NewIntegrator(config *Config, configDir string) (*Integrator, error) {
-	log.SetFlags(log.Ldate|log.Ltime)
-	if config.Logger != nil && config.Logger.Output == "syslog" {
-		logger, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_USER, "ACT")
-		if err != nil {
-			return nil, errors.Wrapf(err, "can not open syslog", err)
-		}
-		log.SetOutput(logger)
+	err := logger.Configure((*logger.Config)(config.Logger), configDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not configure logger")
 	}
-	ntf, err := notifier.NewMailNotifier(config.Notifier)
+	ntf, err := notifier.NewNotifier(config.Notifier)
 	if err != nil {
 		return nil, errors.Wrapf(err, "can not create notifier (config dir = %v, reason = %v)", configDir, err)
 	}
+	authenticator, err := newAuthenticator(config.Server)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid server config")
+	}
+	auditLogFile := ""
+	if config.Server != nil {
+		auditLogFile = config.Server.AuditLogFile
+	}
+	auditLogger, err := newAuditLogger(auditLogFile)
+	if err != nil {
+		return nil, err
+	}
 	rbt, err := robot.NewRobot(config.Robot, configDir, ntf)
 	if err != nil {
+		auditLogger.close()
 		return nil, errors.Wrapf(err, "can not create robot (config dir = %v, reason = %v)", configDir, err)
 	}
+	hub := newPushHub()
+	// 注文、約定、通知は起きたときに購読者に溜める
+	rbt.GetJournal().AddListener(hub.publishJournalEntry)
+	ntf.AddListener(hub.publishNotification)
 	return &Integrator{
 		config:                  config,
+		configDir:               configDir,
 		exchanges:               make(map[string]exchange.Exchange),
+		exchangesMutex:          new(sync.Mutex),
 		arbitrageLoopFinishChan: make(chan bool),
+		arbitrageLoopFinishOnce: new(sync.Once),
 		notifier:                ntf,
 		robot:                   rbt,
+		clock:                   clock.Default(),
+		dashboard:               newDashboard(),
+		authenticator:           authenticator,
+		auditLogger:             auditLogger,
+		pushHub:                 hub,
+		health:                  newHealthMonitor(config.Health),
+		logger:                  logger.Get("integrator"),
 	}, nil
 }
@@ -0,0 +1,74 @@
+package integrator
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"net/http"
+	"fmt"
+	"strconv"
+)
+
+const apiErrorRiskRejected = "riskRejected"
+
+type manualOrderRequest struct {
+	CurrencyPair string               `json:"currencyPair"`
+	Action       exchange.OrderAction `json:"action"`
+	Price        float64              `json:"price"`
+	Amount       float64              `json:"amount"`
+}
+
+func apiAbortOrderError(context *gin.Context, err error) {
+	riskError, ok := risk.GetRiskError(err)
+	if !ok {
+		apiAbort(context, http.StatusBadGateway, apiErrorExchangeError, err.Error())
+		return
+	}
+	if riskError.Rule == risk.RuleInvalidOrder {
+		apiAbort(context, http.StatusBadRequest, apiErrorBadRequest, riskError.Error())
+		return
+	}
+	apiAbort(context, http.StatusForbidden, apiErrorRiskRejected, riskError.Error())
+}
+
+func (i *Integrator) apiPlaceOrder(context *gin.Context) {
+	ex, ok := i.apiExchangeParam(context)
+	if !ok {
+		return
+	}
+	request := new(manualOrderRequest)
+	err := context.ShouldBindJSON(request)
+	if err != nil {
+		apiAbort(context, http.StatusBadRequest, apiErrorBadRequest, fmt.Sprintf("invalid order request (reason = %v)", err))
+		return
+	}
+	order, err := i.robot.PlaceManualOrder(context.GetString(userContextKey), ex, request.CurrencyPair, request.Action, request.Price, request.Amount)
+	if err != nil {
+		apiAbortOrderError(context, err)
+		return
+	}
+	apiOK(context, order)
+}
+
+func (i *Integrator) apiCancelOrder(context *gin.Context) {
+	ex, ok := i.apiExchangeParam(context)
+	if !ok {
+		return
+	}
+	orderID, err := strconv.ParseInt(context.Param("orderId"), 10, 64)
+	if err != nil || orderID <= 0 {
+		apiAbort(context, http.StatusBadRequest, apiErrorBadRequest, fmt.Sprintf("invalid order id (order id = %v)", context.Param("orderId")))
+		return
+	}
+	currencyPair := context.Query("currencyPair")
+	if currencyPair == "" {
+		apiAbort(context, http.StatusBadRequest, apiErrorBadRequest, "no currencyPair")
+		return
+	}
+	err = i.robot.CancelManualOrder(context.GetString(userContextKey), ex, orderID, currencyPair)
+	if err != nil {
+		apiAbortOrderError(context, err)
+		return
+	}
+	apiOK(context, gin.H{"exchange": ex.GetName(), "currencyPair": currencyPair, "orderId": orderID})
+}
@@ -0,0 +1,447 @@
+package integrator
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/AutomaticCoinTrader/ACT/journal"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"github.com/AutomaticCoinTrader/ACT/robot"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	pushChannelBoard         = "board"
+	pushChannelTicker        = "ticker"
+	pushChannelOrders        = "orders"
+	pushChannelFills         = "fills"
+	pushChannelAlgorithms    = "algorithms"
+	pushChannelNotifications = "notifications"
+)
+
+const (
+	pushOpSubscribe   = "subscribe"
+	pushOpUnsubscribe = "unsubscribe"
+)
+
+const (
+	pushTypeSubscribed   = "subscribed"
+	pushTypeUnsubscribed = "unsubscribed"
+	pushTypeUpdate       = "update"
+	pushTypeError        = "error"
+)
+
+const (
+	// 送信を確認する間隔。これより短いintervalは指定できない
+	pushTick                      = 100 * time.Millisecond
+	pushWriteTimeout              = 10 * time.Second
+	pushMaxSubscriptions          = 100
+	// 送る前に溜めておくイベントの上限。超えたら古いものから捨てる
+	pushMaxPendingEvents          = 1000
+	defaultPushInterval           = time.Second
+	defaultPushAlgorithmsInterval = 5 * time.Second
+)
+
+// pushRequest is message from client
+type pushRequest struct {
+	Op           string `json:"op"`
+	Channel      string `json:"channel"`
+	Exchange     string `json:"exchange"`
+	CurrencyPair string `json:"currencyPair"`
+	// アルゴリズムのインスタンス名 (orders, fills, algorithms)
+	Name         string `json:"name"`
+	// 送信する最短の間隔(ミリ秒)
+	Interval     int64  `json:"interval"`
+	// 板の件数 (board)
+	Depth        int64  `json:"depth"`
+}
+
+// pushMessage is message to client
+type pushMessage struct {
+	Type         string      `json:"type"`
+	Channel      string      `json:"channel,omitempty"`
+	Exchange     string      `json:"exchange,omitempty"`
+	CurrencyPair string      `json:"currencyPair,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	Time         time.Time   `json:"time"`
+	Data         interface{} `json:"data,omitempty"`
+	// 溜めきれずに捨てたイベントの数
+	Dropped      int         `json:"dropped,omitempty"`
+	Error        *apiError   `json:"error,omitempty"`
+}
+
+type pushSubscription struct {
+	channel      string
+	exchange     string
+	currencyPair string
+	name         string
+	interval     time.Duration
+	depth        int64
+	// 板、最終価格、アルゴリズムの状態は送るときに最新のものを取る
+	dirty        bool
+	events       []interface{}
+	dropped      int
+	lastSent     time.Time
+}
+
+func (s *pushSubscription) key() (string) {
+	return fmt.Sprintf("%v/%v/%v/%v", s.channel, s.exchange, s.currencyPair, s.name)
+}
+
+func (s *pushSubscription) matchEntry(entry *journal.Entry) (bool) {
+	if (s.exchange != "" && s.exchange != entry.Exchange) ||
+		(s.currencyPair != "" && s.currencyPair != entry.CurrencyPair) ||
+		(s.name != "" && s.name != entry.Name) {
+		return false
+	}
+	if entry.Type == journal.EntryTypeFill {
+		return s.channel == pushChannelFills
+	}
+	return s.channel == pushChannelOrders
+}
+
+func (s *pushSubscription) addEvent(event interface{}) {
+	if len(s.events) >= pushMaxPendingEvents {
+		s.events = s.events[1:]
+		s.dropped++
+	}
+	s.events = append(s.events, event)
+}
+
+type pushClient struct {
+	subscriptions map[string]*pushSubscription
+	replies       []*pushMessage
+	mutex         *sync.Mutex
+}
+
+// pushHub is deliver updates to subscriptions of websocket clients
+type pushHub struct {
+	upgrader   *websocket.Upgrader
+	clients    map[*pushClient]bool
+	mutex      *sync.Mutex
+	finishChan chan bool
+	finishOnce *sync.Once
+}
+
+func (h *pushHub) finish() {
+	h.finishOnce.Do(func() {
+		close(h.finishChan)
+	})
+}
+
+func (h *pushHub) addClient(client *pushClient) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.clients[client] = true
+}
+
+func (h *pushHub) removeClient(client *pushClient) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.clients, client)
+}
+
+func (h *pushHub) publish(callback func(subscription *pushSubscription)) {
+	h.mutex.Lock()
+	clients := make([]*pushClient, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mutex.Unlock()
+	for _, client := range clients {
+		client.mutex.Lock()
+		for _, subscription := range client.subscriptions {
+			callback(subscription)
+		}
+		client.mutex.Unlock()
+	}
+}
+
+// publishBoard is called when board of currency pair is updated by streaming
+func (h *pushHub) publishBoard(exchangeName string, currencyPair string) {
+	h.publish(func(subscription *pushSubscription) {
+		if (subscription.channel == pushChannelBoard || subscription.channel == pushChannelTicker) &&
+			subscription.exchange == exchangeName && subscription.currencyPair == currencyPair {
+			subscription.dirty = true
+		}
+	})
+}
+
+func (h *pushHub) publishJournalEntry(entry *journal.Entry) {
+	h.publish(func(subscription *pushSubscription) {
+		if subscription.matchEntry(entry) {
+			subscription.addEvent(entry)
+		}
+	})
+}
+
+func (h *pushHub) publishNotification(notification *notifier.Notification) {
+	h.publish(func(subscription *pushSubscription) {
+		if subscription.channel == pushChannelNotifications {
+			subscription.addEvent(notification)
+		}
+	})
+}
+
+func newPushHub() (*pushHub) {
+	return &pushHub{
+		upgrader:   new(websocket.Upgrader),
+		clients:    make(map[*pushClient]bool),
+		mutex:      new(sync.Mutex),
+		finishChan: make(chan bool),
+		finishOnce: new(sync.Once),
+	}
+}
+
+func (i *Integrator) setupPushRouting(engine *gin.Engine) {
+	engine.GET(apiVersionPath+"/ws", i.requireRole(roleViewer), i.pushWebsocket)
+}
+
+func pushErrorMessage(code string, message string) (*pushMessage) {
+	return &pushMessage{
+		Type:  pushTypeError,
+		Time:  time.Now(),
+		Error: &apiError{Code: code, Message: message},
+	}
+}
+
+// normalizePushRequest is clear filters which channel does not use. subscriptions are identified by channel and filters
+func normalizePushRequest(request *pushRequest) {
+	switch request.Channel {
+	case pushChannelBoard, pushChannelTicker:
+		request.Name = ""
+	case pushChannelAlgorithms:
+		request.Exchange = ""
+		request.CurrencyPair = ""
+	case pushChannelNotifications:
+		request.Exchange = ""
+		request.CurrencyPair = ""
+		request.Name = ""
+	}
+}
+
+// newPushSubscription is validate request and create subscription
+func (i *Integrator) newPushSubscription(request *pushRequest) (*pushSubscription, *pushMessage) {
+	subscription := &pushSubscription{
+		channel:      request.Channel,
+		exchange:     request.Exchange,
+		currencyPair: request.CurrencyPair,
+		name:         request.Name,
+		interval:     time.Duration(request.Interval) * time.Millisecond,
+		depth:        request.Depth,
+		events:       make([]interface{}, 0),
+	}
+	switch request.Channel {
+	case pushChannelBoard, pushChannelTicker:
+		ex, ok := i.getExchange(request.Exchange)
+		if !ok {
+			return nil, pushErrorMessage(apiErrorNotFound, fmt.Sprintf("not found exchange (exchange = %v)", request.Exchange))
+		}
+		found := false
+		for _, p := range ex.GetCurrencyPairs() {
+			if p == request.CurrencyPair {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, pushErrorMessage(apiErrorNotFound, fmt.Sprintf("not found currency pair (exchange = %v, currency pair = %v)", request.Exchange, request.CurrencyPair))
+		}
+		// 最初の1回はすぐに送る
+		subscription.dirty = true
+		if subscription.interval == 0 {
+			subscription.interval = defaultPushInterval
+		}
+	case pushChannelOrders, pushChannelFills:
+	case pushChannelAlgorithms:
+		subscription.dirty = true
+		if subscription.interval == 0 {
+			subscription.interval = defaultPushAlgorithmsInterval
+		}
+	case pushChannelNotifications:
+	default:
+		return nil, pushErrorMessage(apiErrorBadRequest, fmt.Sprintf("unexpected channel (channel = %v)", request.Channel))
+	}
+	if request.Interval < 0 || (subscription.interval != 0 && subscription.interval < pushTick) {
+		return nil, pushErrorMessage(apiErrorBadRequest, fmt.Sprintf("interval is too short (interval = %v, min = %v)", request.Interval, int64(pushTick/time.Millisecond)))
+	}
+	return subscription, nil
+}
+
+func (i *Integrator) handlePushRequest(client *pushClient, request *pushRequest) (*pushMessage) {
+	normalizePushRequest(request)
+	switch request.Op {
+	case pushOpSubscribe:
+		subscription, errorMessage := i.newPushSubscription(request)
+		if errorMessage != nil {
+			return errorMessage
+		}
+		client.mutex.Lock()
+		defer client.mutex.Unlock()
+		if _, ok := client.subscriptions[subscription.key()]; !ok && len(client.subscriptions) >= pushMaxSubscriptions {
+			return pushErrorMessage(apiErrorBadRequest, fmt.Sprintf("too many subscriptions (max = %v)", pushMaxSubscriptions))
+		}
+		// 同じ購読は設定を置き換える
+		client.subscriptions[subscription.key()] = subscription
+		return &pushMessage{
+			Type:         pushTypeSubscribed,
+			Channel:      subscription.channel,
+			Exchange:     subscription.exchange,
+			CurrencyPair: subscription.currencyPair,
+			Name:         subscription.name,
+			Time:         time.Now(),
+		}
+	case pushOpUnsubscribe:
+		key := (&pushSubscription{
+			channel:      request.Channel,
+			exchange:     request.Exchange,
+			currencyPair: request.CurrencyPair,
+			name:         request.Name,
+		}).key()
+		client.mutex.Lock()
+		defer client.mutex.Unlock()
+		if _, ok := client.subscriptions[key]; !ok {
+			return pushErrorMessage(apiErrorNotFound, fmt.Sprintf("not found subscription (channel = %v)", request.Channel))
+		}
+		delete(client.subscriptions, key)
+		return &pushMessage{
+			Type:         pushTypeUnsubscribed,
+			Channel:      request.Channel,
+			Exchange:     request.Exchange,
+			CurrencyPair: request.CurrencyPair,
+			Name:         request.Name,
+			Time:         time.Now(),
+		}
+	default:
+		return pushErrorMessage(apiErrorBadRequest, fmt.Sprintf("unexpected op (op = %v)", request.Op))
+	}
+}
+
+// takeUpdate is take pending update of subscription if interval is passed. client mutex must be locked
+func (i *Integrator) takeUpdate(subscription *pushSubscription, now time.Time) (*pushMessage, bool) {
+	if now.Sub(subscription.lastSent) < subscription.interval {
+		return nil, false
+	}
+	message := &pushMessage{
+		Type:         pushTypeUpdate,
+		Channel:      subscription.channel,
+		Exchange:     subscription.exchange,
+		CurrencyPair: subscription.currencyPair,
+		Name:         subscription.name,
+		Time:         now,
+	}
+	switch subscription.channel {
+	case pushChannelBoard, pushChannelTicker:
+		if !subscription.dirty {
+			return nil, false
+		}
+		ex, ok := i.getExchange(subscription.exchange)
+		if !ok {
+			return nil, false
+		}
+		var err error
+		if subscription.channel == pushChannelBoard {
+			message.Data, err = newAPIBoard(ex, subscription.currencyPair, subscription.depth)
+		} else {
+			message.Data, err = newAPITicker(ex, subscription.currencyPair)
+		}
+		if err != nil {
+			// 次の更新で送る
+			return nil, false
+		}
+	case pushChannelAlgorithms:
+		stats := make([]*robot.AlgorithmStats, 0)
+		for _, s := range i.robot.GetAlgorithmStats() {
+			if subscription.name == "" || s.Name == subscription.name {
+				stats = append(stats, s)
+			}
+		}
+		message.Data = stats
+	default:
+		if len(subscription.events) == 0 {
+			return nil, false
+		}
+		message.Data = subscription.events
+		message.Dropped = subscription.dropped
+		subscription.events = make([]interface{}, 0)
+		subscription.dropped = 0
+	}
+	subscription.dirty = false
+	subscription.lastSent = now
+	return message, true
+}
+
+func (i *Integrator) takeMessages(client *pushClient) ([]*pushMessage) {
+	now := time.Now()
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	messages := client.replies
+	client.replies = make([]*pushMessage, 0)
+	for _, subscription := range client.subscriptions {
+		message, ok := i.takeUpdate(subscription, now)
+		if ok {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func (i *Integrator) pushWebsocket(context *gin.Context) {
+	ws, err := i.pushHub.upgrader.Upgrade(context.Writer, context.Request, nil)
+	if err != nil {
+		i.logger.Warnf("can not upgrade to websocket (reason = %v)", err)
+		return
+	}
+	defer ws.Close()
+	client := &pushClient{
+		subscriptions: make(map[string]*pushSubscription),
+		replies:       make([]*pushMessage, 0),
+		mutex:         new(sync.Mutex),
+	}
+	i.pushHub.addClient(client)
+	defer i.pushHub.removeClient(client)
+	closeChan := make(chan bool)
+	go func() {
+		defer close(closeChan)
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var reply *pushMessage
+			request := new(pushRequest)
+			err = json.Unmarshal(data, request)
+			if err != nil {
+				// jsonとして読めないメッセージはエラーを返して続ける
+				reply = pushErrorMessage(apiErrorBadRequest, fmt.Sprintf("invalid request (reason = %v)", err))
+			} else {
+				reply = i.handlePushRequest(client, request)
+			}
+			client.mutex.Lock()
+			client.replies = append(client.replies, reply)
+			client.mutex.Unlock()
+		}
+	}()
+	ticker := time.NewTicker(pushTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closeChan:
+			return
+		case <-i.pushHub.finishChan:
+			ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		case <-ticker.C:
+		}
+		for _, message := range i.takeMessages(client) {
+			ws.SetWriteDeadline(time.Now().Add(pushWriteTimeout))
+			err := ws.WriteJSON(message)
+			if err != nil {
+				i.logger.Debugf("can not write to websocket (peer = %v, reason = %v)", ws.RemoteAddr(), err)
+				return
+			}
+		}
+	}
+}
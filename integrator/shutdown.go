@@ -0,0 +1,172 @@
+package integrator
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/robot"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"time"
+)
+
+const (
+	defaultShutdownTimeout = 30
+	// タイムアウトした後に残った注文を確認して状態を閉じるまでの時間
+	shutdownReportTimeout  = 10 * time.Second
+)
+
+// ShutdownSummary is what was done and what was left on exchanges by shutdown
+type ShutdownSummary struct {
+	TimedOut            bool                             `json:"timedOut"`
+	UndrainedAlgorithms []string                         `json:"undrainedAlgorithms"`
+	CanceledOrders      []*robot.ExchangeOrder           `json:"canceledOrders"`
+	OpenOrders          []*robot.ExchangeOrder           `json:"openOrders"`
+	Positions           map[string][]*algorithm.Position `json:"positions"`
+	Errors              []string                         `json:"errors"`
+}
+
+func (i *Integrator) getShutdownConfig() (*shutdownConfig) {
+	if i.config.Shutdown == nil {
+		return new(shutdownConfig)
+	}
+	return i.config.Shutdown
+}
+
+func (i *Integrator) shutdown(summary *ShutdownSummary, exchanges map[string]exchange.Exchange) {
+	// 新しい更新を受け付けずに残っている更新を処理する
+	i.robot.DrainAlgorithms()
+	// 取引所を跨いだ取引のループとストリーミングを止めてアルゴリズムをFinalizeする
+	err := i.Stop()
+	if err != nil {
+		summary.Errors = append(summary.Errors, err.Error())
+	}
+	if i.getShutdownConfig().CancelOrders {
+		canceledOrders, errs := i.robot.CancelOrdersOf(exchanges)
+		summary.CanceledOrders = canceledOrders
+		summary.Errors = append(summary.Errors, errs...)
+	}
+	openOrders, errs := i.robot.GetOpenOrdersOf(exchanges)
+	summary.OpenOrders = openOrders
+	summary.Errors = append(summary.Errors, errs...)
+	summary.Positions = i.robot.GetAllPositionsOf(exchanges)
+	// アルゴリズムの状態を保存する
+	err = i.Finalize()
+	if err != nil {
+		summary.Errors = append(summary.Errors, err.Error())
+	}
+}
+
+// Shutdown is stop algorithms in order, cancel orders if configured and persist state within timeout
+func (i *Integrator) Shutdown() (*ShutdownSummary) {
+	timeout := i.getShutdownConfig().Timeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	i.logger.Infof("shutdown (timeout = %vs, cancel orders = %v)", timeout, i.getShutdownConfig().CancelOrders)
+	summary := &ShutdownSummary{
+		CanceledOrders: make([]*robot.ExchangeOrder, 0),
+		OpenOrders:     make([]*robot.ExchangeOrder, 0),
+		Positions:      make(map[string][]*algorithm.Position),
+		Errors:         make([]string, 0),
+	}
+	// Stopでrobotから取引所が外れるので先に控えておく
+	exchanges := make(map[string]exchange.Exchange)
+	for _, ex := range i.getExchanges() {
+		exchanges[ex.GetName()] = ex
+	}
+	finishChan := make(chan bool)
+	go func() {
+		defer close(finishChan)
+		i.shutdown(summary, exchanges)
+	}()
+	select {
+	case <-finishChan:
+		i.logShutdownSummary(summary)
+		return summary
+	case <-time.After(time.Duration(timeout) * time.Second):
+	}
+	timedOutSummary := i.shutdownAfterTimeout(exchanges)
+	i.logShutdownSummary(timedOutSummary)
+	return timedOutSummary
+}
+
+// shutdownAfterTimeout is report orders left on exchanges and close stores while shutdown is still running
+func (i *Integrator) shutdownAfterTimeout(exchanges map[string]exchange.Exchange) (*ShutdownSummary) {
+	// 途中の結果は触らずに分かっていることだけ返す
+	summary := &ShutdownSummary{
+		TimedOut:            true,
+		UndrainedAlgorithms: i.robot.GetUndrainedAlgorithms(),
+		CanceledOrders:      make([]*robot.ExchangeOrder, 0),
+		OpenOrders:          make([]*robot.ExchangeOrder, 0),
+		Positions:           i.robot.GetAllPositionsOf(exchanges),
+		Errors:              []string{"shutdown timeout"},
+	}
+	timeoutChan := time.After(shutdownReportTimeout)
+	var openOrders []*robot.ExchangeOrder
+	var errs []string
+	openOrdersChan := make(chan bool)
+	go func() {
+		defer close(openOrdersChan)
+		openOrders, errs = i.robot.GetOpenOrdersOf(exchanges)
+	}()
+	select {
+	case <-openOrdersChan:
+		summary.OpenOrders = openOrders
+		summary.Errors = append(summary.Errors, errs...)
+	case <-timeoutChan:
+		summary.Errors = append(summary.Errors, "can not get open orders in time")
+		return summary
+	}
+	// 止まっていない処理が後で書き込んでもエラーになるだけで、ここまでの状態は残る
+	var err error
+	closeChan := make(chan bool)
+	go func() {
+		defer close(closeChan)
+		err = i.robot.CloseStores()
+	}()
+	select {
+	case <-closeChan:
+		if err != nil {
+			summary.Errors = append(summary.Errors, err.Error())
+		}
+	case <-timeoutChan:
+		summary.Errors = append(summary.Errors, "can not close state store in time")
+	}
+	return summary
+}
+
+func (i *Integrator) logShutdownSummary(summary *ShutdownSummary) {
+	if summary.TimedOut {
+		i.logger.Errorf("shutdown timed out, state of undrained algorithms may not be saved (undrained algorithms = %v)", summary.UndrainedAlgorithms)
+	}
+	for _, order := range summary.CanceledOrders {
+		i.logger.With(logger.Fields{
+			logger.FieldExchange:     order.Exchange,
+			logger.FieldCurrencyPair: order.CurrencyPair,
+			logger.FieldOrderID:      order.OrderID,
+		}).Infof("canceled order (action = %v, price = %v, amount = %v)", order.Action, order.Price, order.Amount)
+	}
+	for _, order := range summary.OpenOrders {
+		i.logger.With(logger.Fields{
+			logger.FieldExchange:     order.Exchange,
+			logger.FieldCurrencyPair: order.CurrencyPair,
+			logger.FieldOrderID:      order.OrderID,
+		}).Warnf("order left on exchange (action = %v, price = %v, amount = %v)", order.Action, order.Price, order.Amount)
+	}
+	for name, positions := range summary.Positions {
+		for _, p := range positions {
+			if p.Amount == 0 {
+				continue
+			}
+			i.logger.With(logger.Fields{
+				logger.FieldAlgorithm:    name,
+				logger.FieldExchange:     p.Exchange,
+				logger.FieldCurrencyPair: p.CurrencyPair,
+			}).Warnf("position left (amount = %v, average price = %v, unrealized pnl = %v)", p.Amount, p.AveragePrice, p.UnrealizedPnL)
+		}
+	}
+	for _, e := range summary.Errors {
+		i.logger.Errorf("shutdown error (%v)", e)
+	}
+	i.logger.Infof("shutdown summary (timed out = %v, canceled orders = %v, open orders = %v, errors = %v)",
+		summary.TimedOut, len(summary.CanceledOrders), len(summary.OpenOrders), len(summary.Errors))
+}
@@ -0,0 +1,211 @@
+package integratortest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/configurator"
+	"github.com/AutomaticCoinTrader/ACT/integrator"
+	"github.com/pkg/errors"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+type apiResponse struct {
+	Data  json.RawMessage `json:"data"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func apiRequest(t *testing.T, i *integrator.Integrator, method string, path string) (int, *apiResponse) {
+	return apiRequestWithBody(t, i, method, path, "", "")
+}
+
+func apiRequestWithBody(t *testing.T, i *integrator.Integrator, method string, path string, token string, body string) (int, *apiResponse) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	i.Handler().ServeHTTP(recorder, request)
+	response := new(apiResponse)
+	err := json.Unmarshal(recorder.Body.Bytes(), response)
+	if err != nil {
+		t.Fatalf("can not unmarshal response (path = %v, body = %v, reason = %v)", path, recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func expectAPIError(t *testing.T, i *integrator.Integrator, path string, status int, code string) {
+	s, response := apiRequest(t, i, http.MethodGet, path)
+	if s != status {
+		t.Fatalf("unexpected status (path = %v, status = %v)", path, s)
+	}
+	if response.Error == nil || response.Error.Code != code || response.Error.Message == "" {
+		t.Fatalf("unexpected error envelope (path = %v, error = %v)", path, response.Error)
+	}
+	if response.Data != nil {
+		t.Fatalf("error response has data (path = %v, data = %s)", path, response.Data)
+	}
+}
+
+func TestAPIEnvelope(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	ex := newFakeExchange()
+	i := startIntegratorWithExchange(t, dir, ex, nil)
+	defer i.Finalize()
+
+	// 成功時はdataに入る
+	status, response := apiRequest(t, i, http.MethodGet, "/api/v1/exchanges/"+fakeExchangeName)
+	if status != http.StatusOK || response.Error != nil {
+		t.Fatalf("can not get exchange (status = %v, error = %v)", status, response.Error)
+	}
+	detail := new(struct {
+		Name          string   `json:"name"`
+		CurrencyPairs []string `json:"currencyPairs"`
+	})
+	err = json.Unmarshal(response.Data, detail)
+	if err != nil {
+		t.Fatalf("can not unmarshal data (data = %s, reason = %v)", response.Data, err)
+	}
+	if detail.Name != fakeExchangeName || len(detail.CurrencyPairs) != 1 || detail.CurrencyPairs[0] != "btc_jpy" {
+		t.Fatalf("unexpected exchange (exchange = %v)", detail)
+	}
+	status, response = apiRequest(t, i, http.MethodGet, "/api/v1/exchanges/"+fakeExchangeName+"/pairs/btc_jpy/ticker")
+	if status != http.StatusOK {
+		t.Fatalf("can not get ticker (status = %v, error = %v)", status, response.Error)
+	}
+	ticker := new(struct {
+		LastPrice float64 `json:"lastPrice"`
+		BestAsk   float64 `json:"bestAsk"`
+		BestBid   float64 `json:"bestBid"`
+	})
+	err = json.Unmarshal(response.Data, ticker)
+	if err != nil {
+		t.Fatalf("can not unmarshal data (data = %s, reason = %v)", response.Data, err)
+	}
+	if ticker.LastPrice != 1000000 || ticker.BestAsk != 1000001 || ticker.BestBid != 999999 {
+		t.Fatalf("unexpected ticker (ticker = %v)", ticker)
+	}
+
+	expectAPIError(t, i, "/api/v1/exchanges/unknown", http.StatusNotFound, "notFound")
+	expectAPIError(t, i, "/api/v1/exchanges/unknown/funds", http.StatusNotFound, "notFound")
+	expectAPIError(t, i, "/api/v1/exchanges/"+fakeExchangeName+"/pairs/xxx_jpy/ticker", http.StatusNotFound, "notFound")
+	expectAPIError(t, i, "/api/v1/exchanges/"+fakeExchangeName+"/orders/history?count=abc", http.StatusBadRequest, "badRequest")
+
+	// 取引所のエラーは502で返す
+	ex.setError(errors.New("maintenance"))
+	expectAPIError(t, i, "/api/v1/exchanges/"+fakeExchangeName+"/funds", http.StatusBadGateway, "exchangeError")
+	expectAPIError(t, i, "/api/v1/exchanges/"+fakeExchangeName+"/pairs/btc_jpy/ticker", http.StatusBadGateway, "exchangeError")
+	ex.setError(nil)
+	status, _ = apiRequest(t, i, http.MethodGet, "/api/v1/exchanges/"+fakeExchangeName+"/funds")
+	if status != http.StatusOK {
+		t.Fatalf("can not get funds after recovery (status = %v)", status)
+	}
+}
+
+// configuredAlgorithm can not be initialized with empty message in its config file
+type configuredAlgorithm struct {
+	configDir string
+}
+
+func (c *configuredAlgorithm) GetName() (string) {
+	return "configured"
+}
+
+func (c *configuredAlgorithm) Initialize(ctx *algorithm.Context) (error) {
+	cf, err := configurator.NewConfigurator(path.Join(c.configDir, "integratortest-configured"))
+	if err != nil {
+		// 登録したアルゴリズムは他のテストでも動くので設定ファイルがなければ何もしない
+		return nil
+	}
+	config := struct {
+		Message string `yaml:"message"`
+	}{}
+	err = cf.Load(&config)
+	if err != nil {
+		return err
+	}
+	if config.Message == "" {
+		return errors.New("message is empty")
+	}
+	return nil
+}
+
+func (c *configuredAlgorithm) Update(ctx *algorithm.Context, currencyPair string) (error) {
+	return nil
+}
+
+func (c *configuredAlgorithm) Finalize(ctx *algorithm.Context) (error) {
+	return nil
+}
+
+func TestAPIReconfigureAlgorithm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	algorithm.RegisterAlgorithmV2("integratortest-configured", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return &configuredAlgorithm{configDir: configDir}, nil
+	}, nil)
+	configDir := path.Join(dir, "configured")
+	err = os.MkdirAll(configDir, 0755)
+	if err != nil {
+		t.Fatalf("can not create config dir (reason = %v)", err)
+	}
+	err = ioutil.WriteFile(path.Join(configDir, "integratortest-configured.yaml"), []byte("message: hello\n"), 0644)
+	if err != nil {
+		t.Fatalf("can not write config (reason = %v)", err)
+	}
+	i := startIntegratorWithExchange(t, dir, newFakeExchange(), map[string]interface{}{
+		"robot": map[string]interface{}{
+			"algorithmPluginDir": dir,
+			"algorithms":         []map[string]interface{}{{"name": "configured", "algorithm": "integratortest-configured", "configDir": configDir}},
+		},
+		"server": map[string]interface{}{
+			"tokens": []map[string]string{{"user": "admin", "token": "admin-token", "role": "admin"}},
+		},
+	})
+	defer i.Finalize()
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("can not start integrator (reason = %v)", err)
+	}
+	defer i.Stop()
+
+	status, response := apiRequestWithBody(t, i, http.MethodPut, "/api/v1/algorithms/configured/config", "admin-token", `{"message": "world"}`)
+	if status != http.StatusOK {
+		t.Fatalf("can not reconfigure algorithm (status = %v, error = %v)", status, response.Error)
+	}
+	// 初期化できない設定は400で返して設定ファイルを戻す
+	status, response = apiRequestWithBody(t, i, http.MethodPut, "/api/v1/algorithms/configured/config", "admin-token", `{"message": ""}`)
+	if status != http.StatusBadRequest || response.Error == nil || response.Error.Code != "badRequest" {
+		t.Fatalf("unexpected response (status = %v, error = %v)", status, response.Error)
+	}
+	data, err := ioutil.ReadFile(path.Join(configDir, "integratortest-configured.yaml"))
+	if err != nil || !strings.Contains(string(data), "world") {
+		t.Fatalf("config file is not restored (data = %s, reason = %v)", data, err)
+	}
+	status, response = apiRequestWithBody(t, i, http.MethodGet, "/api/v1/algorithms/configured", "admin-token", "")
+	if status != http.StatusOK {
+		t.Fatalf("algorithm is not running (status = %v, error = %v)", status, response.Error)
+	}
+	status, response = apiRequestWithBody(t, i, http.MethodPut, "/api/v1/algorithms/unknown/config", "admin-token", `{"message": "hello"}`)
+	if status != http.StatusNotFound || response.Error == nil || response.Error.Code != "notFound" {
+		t.Fatalf("unexpected response (status = %v, error = %v)", status, response.Error)
+	}
+}
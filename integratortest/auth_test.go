@@ -0,0 +1,160 @@
+package integratortest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/integrator"
+	"golang.org/x/crypto/bcrypt"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func freeAddrPort(t *testing.T) (string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not listen (reason = %v)", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func startIntegrator(t *testing.T, dir string, server map[string]interface{}) (*integrator.Integrator) {
+	data, err := json.Marshal(map[string]interface{}{
+		"server": server,
+		"robot":  map[string]interface{}{"algorithmPluginDir": dir},
+	})
+	if err != nil {
+		t.Fatalf("can not marshal config (reason = %v)", err)
+	}
+	config := new(integrator.Config)
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		t.Fatalf("can not unmarshal config (reason = %v)", err)
+	}
+	i, err := integrator.NewIntegrator(config, dir)
+	if err != nil {
+		t.Fatalf("can not create integrator (reason = %v)", err)
+	}
+	err = i.Initialize()
+	if err != nil {
+		t.Fatalf("can not initialize integrator (reason = %v)", err)
+	}
+	return i
+}
+
+func request(t *testing.T, method string, url string, setAuth func(request *http.Request)) (int) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("can not create request (reason = %v)", err)
+	}
+	if setAuth != nil {
+		setAuth(req)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("can not request (url = %v, reason = %v)", url, err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func bearer(token string) (func(request *http.Request)) {
+	return func(request *http.Request) {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func basic(user string, password string) (func(request *http.Request)) {
+	return func(request *http.Request) {
+		request.SetBasicAuth(user, password)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("can not hash password (reason = %v)", err)
+	}
+	addrPort := freeAddrPort(t)
+	auditLogFile := path.Join(dir, "audit.jsonl")
+	i := startIntegrator(t, dir, map[string]interface{}{
+		"addrPort": addrPort,
+		"tokens": []map[string]string{
+			{"user": "viewer", "token": "viewer-token"},
+			{"user": "trader", "token": "trader-token", "role": "trader"},
+		},
+		"users": []map[string]string{
+			{"user": "alice", "passwordHash": string(hash), "role": "admin"},
+		},
+		"auditLogFile": auditLogFile,
+	})
+	base := fmt.Sprintf("http://%v", addrPort)
+	cases := []struct {
+		method  string
+		path    string
+		setAuth func(request *http.Request)
+		status  int
+	}{
+		{"GET", "/api/v1/exchanges", nil, http.StatusUnauthorized},
+		{"GET", "/api/v1/exchanges", bearer("wrong"), http.StatusUnauthorized},
+		{"GET", "/api/v1/exchanges", bearer("viewer-token"), http.StatusOK},
+		{"GET", "/dashboard", basic("alice", "wrong"), http.StatusUnauthorized},
+		{"GET", "/dashboard", basic("alice", "secret"), http.StatusOK},
+		{"POST", "/api/v1/algorithms/none/pause", bearer("viewer-token"), http.StatusForbidden},
+		// 認可を通ればハンドラーのエラーになる
+		{"POST", "/api/v1/algorithms/none/pause", bearer("trader-token"), http.StatusNotFound},
+		{"POST", "/algorithms/reload", bearer("trader-token"), http.StatusForbidden},
+		{"POST", "/algorithms/reload", basic("alice", "secret"), http.StatusOK},
+	}
+	for _, c := range cases {
+		status := request(t, c.method, base+c.path, c.setAuth)
+		if status != c.status {
+			t.Errorf("unexpected status (method = %v, path = %v, expected = %v, actual = %v)", c.method, c.path, c.status, status)
+		}
+	}
+	i.Finalize()
+
+	data, err := ioutil.ReadFile(auditLogFile)
+	if err != nil {
+		t.Fatalf("can not read audit log (reason = %v)", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// 認証の失敗、権限の不足、状態を変えるリクエストを記録する
+	if len(lines) != 6 {
+		t.Fatalf("unexpected audit log (log = %v)", string(data))
+	}
+	if !strings.Contains(lines[5], `"user":"alice","role":"admin"`) || !strings.Contains(lines[5], `"path":"/algorithms/reload"`) {
+		t.Fatalf("unexpected audit entry (entry = %v)", lines[5])
+	}
+}
+
+func TestAuthNotConfigured(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	addrPort := freeAddrPort(t)
+	i := startIntegrator(t, dir, map[string]interface{}{"addrPort": addrPort})
+	defer i.Finalize()
+	base := fmt.Sprintf("http://%v", addrPort)
+	// 認証情報がなければ参照だけできる
+	status := request(t, "GET", base+"/api/v1/algorithms", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status of viewer route (status = %v)", status)
+	}
+	status = request(t, "POST", base+"/killswitch", nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("unexpected status of trader route (status = %v)", status)
+	}
+}
@@ -0,0 +1,111 @@
+package integratortest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDashboardAsset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	i := startIntegratorWithExchange(t, dir, newFakeExchange(), nil)
+	defer i.Finalize()
+	for _, name := range []string{"index.html", "app.js", "style.css"} {
+		path := "/dashboard/asset/" + name
+		if name == "index.html" {
+			path = "/dashboard"
+		}
+		recorder := httptest.NewRecorder()
+		i.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("can not get asset (path = %v, status = %v)", path, recorder.Code)
+		}
+		// bindata.goを作り直し忘れていないか
+		data, err := ioutil.ReadFile(filepath.Join("..", "integrator", "asset", "dashboard", name))
+		if err != nil {
+			t.Fatalf("can not read asset (name = %v, reason = %v)", name, err)
+		}
+		if !bytes.Equal(recorder.Body.Bytes(), data) {
+			t.Fatalf("bindata.go is out of date, run make bindata (name = %v)", name)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	i.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/dashboard/asset/app.js", nil))
+	if !strings.Contains(recorder.Header().Get("Content-Type"), "javascript") {
+		t.Fatalf("unexpected content type (content type = %v)", recorder.Header().Get("Content-Type"))
+	}
+	// asset以外のファイルは返さない
+	for _, path := range []string{"/dashboard/asset/unknown.js", "/dashboard/asset/../bindata.go"} {
+		recorder := httptest.NewRecorder()
+		i.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusNotFound {
+			t.Fatalf("unexpected status (path = %v, status = %v)", path, recorder.Code)
+		}
+	}
+}
+
+func TestDashboardState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	i := startIntegratorWithExchange(t, dir, newFakeExchange(), nil)
+	defer i.Finalize()
+	recorder := httptest.NewRecorder()
+	i.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/dashboard/state", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("can not get state (status = %v)", recorder.Code)
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("unexpected content type (content type = %v)", recorder.Header().Get("Content-Type"))
+	}
+	response := new(struct {
+		Data *struct {
+			Exchanges []*struct {
+				Name  string `json:"name"`
+				Pairs []*struct {
+					CurrencyPair string      `json:"currencyPair"`
+					LastPrice    float64     `json:"lastPrice"`
+					Asks         [][]float64 `json:"asks"`
+					Bids         [][]float64 `json:"bids"`
+				} `json:"pairs"`
+				Account *struct {
+					Funds map[string]float64 `json:"funds"`
+				} `json:"account"`
+			} `json:"exchanges"`
+			KillSwitch    map[string]interface{} `json:"killSwitch"`
+			Notifications []interface{}          `json:"notifications"`
+		} `json:"data"`
+	})
+	err = json.Unmarshal(recorder.Body.Bytes(), response)
+	if err != nil {
+		t.Fatalf("can not unmarshal state (body = %v, reason = %v)", recorder.Body.String(), err)
+	}
+	if response.Data == nil || len(response.Data.Exchanges) != 1 || response.Data.Exchanges[0].Name != fakeExchangeName {
+		t.Fatalf("unexpected state (body = %v)", recorder.Body.String())
+	}
+	exchange := response.Data.Exchanges[0]
+	if len(exchange.Pairs) != 1 || exchange.Pairs[0].CurrencyPair != "btc_jpy" || exchange.Pairs[0].LastPrice != 1000000 {
+		t.Fatalf("unexpected pairs (body = %v)", recorder.Body.String())
+	}
+	if len(exchange.Pairs[0].Asks) != 1 || len(exchange.Pairs[0].Bids) != 1 {
+		t.Fatalf("unexpected board (body = %v)", recorder.Body.String())
+	}
+	if exchange.Account == nil || exchange.Account.Funds["jpy"] != 100000 {
+		t.Fatalf("unexpected account (body = %v)", recorder.Body.String())
+	}
+	if response.Data.KillSwitch == nil {
+		t.Fatalf("kill switch is not included (body = %v)", recorder.Body.String())
+	}
+}
@@ -0,0 +1,362 @@
+package integratortest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/integrator"
+	"github.com/pkg/errors"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 設定から作れる取引所はzaifだけなので、その名前でfakeExchangeを登録する
+const fakeExchangeName = "zaif"
+
+type fakeOrder struct {
+	orderID      int64
+	currencyPair string
+	action       exchange.OrderAction
+	price        float64
+	amount       float64
+}
+
+type fakeOrderCursor struct {
+	index  int
+	orders []*fakeOrder
+}
+
+func (f *fakeOrderCursor) Next() (int64, string, exchange.OrderAction, float64, float64, int64, bool) {
+	if f.index >= len(f.orders) {
+		return 0, "", exchange.OrderActUnkown, 0, 0, 0, false
+	}
+	order := f.orders[f.index]
+	f.index++
+	return order.orderID, order.currencyPair, order.action, order.price, order.amount, 0, true
+}
+
+func (f *fakeOrderCursor) Reset() {
+	f.index = 0
+}
+
+func (f *fakeOrderCursor) Len() (int) {
+	return len(f.orders)
+}
+
+type fakeBoardCursor struct {
+	index  int
+	values [][]float64
+}
+
+func (f *fakeBoardCursor) Next() (float64, float64, bool) {
+	if f.index >= len(f.values) {
+		return 0, 0, false
+	}
+	value := f.values[f.index]
+	f.index++
+	return value[0], value[1], true
+}
+
+func (f *fakeBoardCursor) Reset() {
+	f.index = 0
+}
+
+func (f *fakeBoardCursor) Len() (int) {
+	return len(f.values)
+}
+
+func (f *fakeBoardCursor) All() ([][]float64) {
+	return f.values
+}
+
+type fakeTradesCursor struct {
+}
+
+func (f *fakeTradesCursor) Next() (int64, float64, float64, string, bool) {
+	return 0, 0, 0, "", false
+}
+
+func (f *fakeTradesCursor) Reset() {
+}
+
+func (f *fakeTradesCursor) Len() (int) {
+	return 0
+}
+
+// fakeExchange is exchange which keeps orders in memory
+type fakeExchange struct {
+	currencyPairs []string
+	lastPrice     float64
+	funds         map[string]float64
+	orders        map[int64]*fakeOrder
+	nextOrderID   int64
+	// 設定するとAPIがこのエラーを返す
+	err           error
+	clock         clock.Clock
+	mutex         *sync.Mutex
+}
+
+func (f *fakeExchange) SetClock(clock clock.Clock) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.clock = clock
+}
+
+func (f *fakeExchange) getClock() (clock.Clock) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.clock
+}
+
+func (f *fakeExchange) setError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.err = err
+}
+
+func (f *fakeExchange) getError() (error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.err
+}
+
+func (f *fakeExchange) hasCurrencyPair(currencyPair string) (bool) {
+	for _, c := range f.currencyPairs {
+		if c == currencyPair {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeExchange) order(currencyPair string, action exchange.OrderAction, price float64, amount float64) (int64, float64, float64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return -1, price, amount, f.err
+	}
+	if !f.hasCurrencyPair(currencyPair) {
+		return -1, price, amount, errors.Errorf("unknown currency pair (currency pair = %v)", currencyPair)
+	}
+	f.nextOrderID++
+	f.orders[f.nextOrderID] = &fakeOrder{
+		orderID:      f.nextOrderID,
+		currencyPair: currencyPair,
+		action:       action,
+		price:        price,
+		amount:       amount,
+	}
+	return f.nextOrderID, price, amount, nil
+}
+
+func (f *fakeExchange) getOpenOrderIDs() ([]int64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	orderIDs := make([]int64, 0, len(f.orders))
+	for orderID := range f.orders {
+		orderIDs = append(orderIDs, orderID)
+	}
+	sort.Slice(orderIDs, func(i, j int) (bool) {
+		return orderIDs[i] < orderIDs[j]
+	})
+	return orderIDs
+}
+
+func (f *fakeExchange) GetName() (string) {
+	return fakeExchangeName
+}
+
+func (f *fakeExchange) GetCurrencyPairs() ([]string) {
+	return f.currencyPairs
+}
+
+func (f *fakeExchange) Buy(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	return f.order(currencyPair, exchange.OrderActBuy, price, amount)
+}
+
+func (f *fakeExchange) Sell(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	return f.order(currencyPair, exchange.OrderActSell, price, amount)
+}
+
+func (f *fakeExchange) Cancel(orderID int64, currencyPair string) (error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	_, ok := f.orders[orderID]
+	if !ok {
+		return errors.Errorf("order not found (order id = %v)", orderID)
+	}
+	delete(f.orders, orderID)
+	return nil
+}
+
+func (f *fakeExchange) GetFunds() (map[string]float64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	funds := make(map[string]float64)
+	for currency, amount := range f.funds {
+		funds[currency] = amount
+	}
+	return funds, nil
+}
+
+func (f *fakeExchange) GetLastPrice(currencyPair string) (float64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.lastPrice, nil
+}
+
+func (f *fakeExchange) GetSellBoardCursor(currencyPair string) (exchange.BoardCursor, error) {
+	sellBoardCursor, _, err := f.GetSellBuyBoardCursor(currencyPair)
+	return sellBoardCursor, err
+}
+
+func (f *fakeExchange) GetBuyBoardCursor(currencyPair string) (exchange.BoardCursor, error) {
+	_, buyBoardCursor, err := f.GetSellBuyBoardCursor(currencyPair)
+	return buyBoardCursor, err
+}
+
+func (f *fakeExchange) GetSellBuyBoardCursor(currencyPair string) (exchange.BoardCursor, exchange.BoardCursor, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return &fakeBoardCursor{values: [][]float64{{f.lastPrice + 1, 1}}}, &fakeBoardCursor{values: [][]float64{{f.lastPrice - 1, 1}}}, nil
+}
+
+func (f *fakeExchange) GetTradesCursor(currencyPair string) (exchange.TradesCursor, error) {
+	return &fakeTradesCursor{}, nil
+}
+
+func (f *fakeExchange) GetOrderHistoryCursor(count int64) (exchange.OrderCursor, error) {
+	return &fakeOrderCursor{orders: make([]*fakeOrder, 0)}, nil
+}
+
+func (f *fakeExchange) GetActiveOrderCursor() (exchange.OrderCursor, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	orders := make([]*fakeOrder, 0, len(f.orders))
+	for _, order := range f.orders {
+		copied := *order
+		orders = append(orders, &copied)
+	}
+	sort.Slice(orders, func(i, j int) (bool) {
+		return orders[i].orderID < orders[j].orderID
+	})
+	return &fakeOrderCursor{orders: orders}, nil
+}
+
+func (f *fakeExchange) GetMinPriceUnit(currencyPair string) (float64) {
+	return 1
+}
+
+func (f *fakeExchange) GetMinAmountUnit(currencyPair string) (float64) {
+	return 0.0001
+}
+
+func (f *fakeExchange) GetTradeFeeRate(currencyPair string) (float64) {
+	return 0
+}
+
+func (f *fakeExchange) FixPrice(currencyPair string, price float64) (float64) {
+	return price
+}
+
+func (f *fakeExchange) FixAmount(currencyPair string, amount float64) (float64) {
+	return amount
+}
+
+func (f *fakeExchange) Initialize(streamingCallback exchange.StreamingCallback) (error) {
+	return nil
+}
+
+func (f *fakeExchange) Finalize() (error) {
+	return nil
+}
+
+func (f *fakeExchange) StartStreamings() (error) {
+	return nil
+}
+
+func (f *fakeExchange) StopStreamings() (error) {
+	return nil
+}
+
+func newFakeExchange() (*fakeExchange) {
+	return &fakeExchange{
+		currencyPairs: []string{"btc_jpy"},
+		lastPrice:     1000000,
+		funds:         map[string]float64{"jpy": 100000, "btc": 1},
+		orders:        make(map[int64]*fakeOrder),
+		nextOrderID:   10,
+		mutex:         new(sync.Mutex),
+	}
+}
+
+// startIntegratorWithExchange is start integrator which uses ex as exchange
+func startIntegratorWithExchange(t *testing.T, dir string, ex *fakeExchange, config map[string]interface{}) (*integrator.Integrator) {
+	exchange.RegisterExchange(fakeExchangeName, func(config interface{}) (exchange.Exchange, error) {
+		return ex, nil
+	})
+	values := map[string]interface{}{
+		"robot":     map[string]interface{}{"algorithmPluginDir": dir},
+		"exchanges": map[string]interface{}{fakeExchangeName: map[string]interface{}{}},
+	}
+	for k, v := range config {
+		values[k] = v
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		t.Fatalf("can not marshal config (reason = %v)", err)
+	}
+	newConfig := new(integrator.Config)
+	err = json.Unmarshal(data, newConfig)
+	if err != nil {
+		t.Fatalf("can not unmarshal config (reason = %v)", err)
+	}
+	i, err := integrator.NewIntegrator(newConfig, dir)
+	if err != nil {
+		t.Fatalf("can not create integrator (reason = %v)", err)
+	}
+	err = i.Initialize()
+	if err != nil {
+		t.Fatalf("can not initialize integrator (reason = %v)", err)
+	}
+	return i
+}
+
+func TestExchangeClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	ex := newFakeExchange()
+	i := startIntegratorWithExchange(t, dir, ex, nil)
+	defer i.Finalize()
+	// 作った取引所には統合側の時計を渡す
+	if ex.getClock() != clock.Default() {
+		t.Fatalf("clock is not passed to exchange (clock = %v)", ex.getClock())
+	}
+	c := clock.NewSimulatedClock(time.Unix(0, 0))
+	i.SetClock(c)
+	if ex.getClock() != c {
+		t.Fatalf("clock is not propagated to exchange (clock = %v)", ex.getClock())
+	}
+}
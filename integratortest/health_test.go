@@ -0,0 +1,47 @@
+package integratortest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	addrPort := freeAddrPort(t)
+	// 認証を設定していてもsupervisorから呼べる
+	i := startIntegrator(t, dir, map[string]interface{}{
+		"addrPort": addrPort,
+		"tokens":   []map[string]string{{"user": "viewer", "token": "viewer-token"}},
+	})
+	defer i.Finalize()
+	base := fmt.Sprintf("http://%v", addrPort)
+	status := request(t, "GET", base+"/healthz", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status of healthz (status = %v)", status)
+	}
+	// ストリーミングを始めるまでは準備できていない
+	status = request(t, "GET", base+"/readyz", nil)
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status of readyz before start (status = %v)", status)
+	}
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("can not start integrator (reason = %v)", err)
+	}
+	status = request(t, "GET", base+"/readyz", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status of readyz after start (status = %v)", status)
+	}
+	i.Stop()
+	status = request(t, "GET", base+"/readyz", nil)
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status of readyz after stop (status = %v)", status)
+	}
+}
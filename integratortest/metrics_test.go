@@ -0,0 +1,53 @@
+package integratortest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	addrPort := freeAddrPort(t)
+	i := startIntegrator(t, dir, map[string]interface{}{
+		"addrPort": addrPort,
+		"tokens":   []map[string]string{{"user": "prometheus", "token": "scrape-token"}},
+	})
+	defer i.Finalize()
+	url := fmt.Sprintf("http://%v/metrics", addrPort)
+	status := request(t, "GET", url, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("unexpected status without token (status = %v)", status)
+	}
+
+	metrics.IncOrder("testExchange", "testName", metrics.OrderOutcomeRejected)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("can not create request (reason = %v)", err)
+	}
+	bearer("scrape-token")(req)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("can not request (url = %v, reason = %v)", url, err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("can not read body (reason = %v)", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status (status = %v, body = %v)", res.StatusCode, string(body))
+	}
+	expected := `act_orders_total{exchange="testExchange",name="testName",outcome="rejected"} 1`
+	if !strings.Contains(string(body), expected) {
+		t.Fatalf("metric is not found (expected = %v, body = %v)", expected, string(body))
+	}
+}
@@ -0,0 +1,75 @@
+package integratortest
+
+import (
+	"github.com/gorilla/websocket"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+type pushMessage struct {
+	Type    string            `json:"type"`
+	Channel string            `json:"channel"`
+	Data    []json.RawMessage `json:"data"`
+	Error   *struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+func readPushMessage(t *testing.T, ws *websocket.Conn) (*pushMessage) {
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message := new(pushMessage)
+	err := ws.ReadJSON(message)
+	if err != nil {
+		t.Fatalf("can not read message (reason = %v)", err)
+	}
+	return message
+}
+
+func TestPush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	addrPort := freeAddrPort(t)
+	i := startIntegrator(t, dir, map[string]interface{}{"addrPort": addrPort})
+	defer i.Finalize()
+	ws, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%v/api/v1/ws", addrPort), nil)
+	if err != nil {
+		t.Fatalf("can not connect (reason = %v)", err)
+	}
+	defer ws.Close()
+
+	ws.WriteJSON(map[string]interface{}{"op": "subscribe", "channel": "board", "exchange": "none", "currencyPair": "btc_jpy"})
+	message := readPushMessage(t, ws)
+	if message.Type != "error" || message.Error == nil || message.Error.Code != "notFound" {
+		t.Fatalf("unexpected reply of unknown exchange (message = %+v)", message)
+	}
+	ws.WriteJSON(map[string]interface{}{"op": "subscribe", "channel": "notifications", "interval": 50})
+	message = readPushMessage(t, ws)
+	if message.Type != "error" || message.Error.Code != "badRequest" {
+		t.Fatalf("too short interval is accepted (message = %+v)", message)
+	}
+	ws.WriteJSON(map[string]interface{}{"op": "subscribe", "channel": "notifications"})
+	message = readPushMessage(t, ws)
+	if message.Type != "subscribed" || message.Channel != "notifications" {
+		t.Fatalf("unexpected reply of subscribe (message = %+v)", message)
+	}
+
+	// キルスイッチの通知が届く
+	i.Kill("test")
+	message = readPushMessage(t, ws)
+	if message.Type != "update" || message.Channel != "notifications" || len(message.Data) != 1 {
+		t.Fatalf("unexpected update (message = %+v)", message)
+	}
+
+	ws.WriteJSON(map[string]interface{}{"op": "unsubscribe", "channel": "notifications"})
+	message = readPushMessage(t, ws)
+	if message.Type != "unsubscribed" {
+		t.Fatalf("unexpected reply of unsubscribe (message = %+v)", message)
+	}
+}
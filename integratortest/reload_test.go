@@ -0,0 +1,84 @@
+package integratortest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/integrator"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// idleAlgorithm does nothing
+type idleAlgorithm struct {
+}
+
+func (i *idleAlgorithm) GetName() (string) {
+	return "idle"
+}
+
+func (i *idleAlgorithm) Initialize(ctx *algorithm.Context) (error) {
+	return nil
+}
+
+func (i *idleAlgorithm) Update(ctx *algorithm.Context, currencyPair string) (error) {
+	return nil
+}
+
+func (i *idleAlgorithm) Finalize(ctx *algorithm.Context) (error) {
+	return nil
+}
+
+func TestReloadRobotConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	algorithm.RegisterAlgorithmV2("integratortest-idle", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return &idleAlgorithm{}, nil
+	}, nil)
+	i := startIntegratorWithExchange(t, dir, newFakeExchange(), map[string]interface{}{
+		"robot": map[string]interface{}{
+			"algorithmPluginDir": dir,
+			"algorithms":         []map[string]interface{}{{"name": "idle", "algorithm": "integratortest-idle"}},
+		},
+	})
+	defer i.Finalize()
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("can not start integrator (reason = %v)", err)
+	}
+	defer i.Stop()
+
+	// 設定ファイルのインスタンスに入れ替える
+	config := "robot:\n" +
+		"  algorithmPluginDir: " + dir + "\n" +
+		"  algorithms:\n" +
+		"    - name: idle2\n" +
+		"      algorithm: integratortest-idle\n"
+	err = ioutil.WriteFile(path.Join(dir, integrator.ConfigPathPrefix+".yaml"), []byte(config), 0644)
+	if err != nil {
+		t.Fatalf("can not write config (reason = %v)", err)
+	}
+	result := i.ReloadAlgorithms()
+	if len(result.Errors) != 0 {
+		t.Fatalf("can not reload algorithms (errors = %v)", result.Errors)
+	}
+	if len(result.Started) != 1 || result.Started[0] != "idle2@"+fakeExchangeName {
+		t.Fatalf("new instance is not started (result = %+v)", result)
+	}
+	if len(result.Stopped) != 1 || result.Stopped[0] != "idle@"+fakeExchangeName {
+		t.Fatalf("removed instance is not stopped (result = %+v)", result)
+	}
+
+	// 読めない設定ファイルなら今のインスタンスのまま
+	err = ioutil.WriteFile(path.Join(dir, integrator.ConfigPathPrefix+".yaml"), []byte("robot: [\n"), 0644)
+	if err != nil {
+		t.Fatalf("can not write config (reason = %v)", err)
+	}
+	result = i.ReloadAlgorithms()
+	if len(result.Errors) != 1 || len(result.Stopped) != 0 {
+		t.Fatalf("unexpected result of broken config (result = %+v)", result)
+	}
+}
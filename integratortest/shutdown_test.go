@@ -0,0 +1,143 @@
+package integratortest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/state"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestShutdownCancelOrders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	ex := newFakeExchange()
+	i := startIntegratorWithExchange(t, dir, ex, map[string]interface{}{
+		"shutdown": map[string]interface{}{"timeout": 10, "cancelOrders": true},
+	})
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("can not start integrator (reason = %v)", err)
+	}
+	orderID, _, _, err := ex.order("btc_jpy", exchange.OrderActBuy, 900000, 0.1)
+	if err != nil {
+		t.Fatalf("can not order (reason = %v)", err)
+	}
+	summary := i.Shutdown()
+	if summary.TimedOut || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected summary (summary = %v)", summary)
+	}
+	if len(summary.CanceledOrders) != 1 || summary.CanceledOrders[0].OrderID != orderID {
+		t.Fatalf("order is not canceled (canceled orders = %v)", summary.CanceledOrders)
+	}
+	if len(summary.OpenOrders) != 0 || len(ex.getOpenOrderIDs()) != 0 {
+		t.Fatalf("order is left (open orders = %v, exchange = %v)", summary.OpenOrders, ex.getOpenOrderIDs())
+	}
+	// 停止後にもう一度Stopしてもpanicしない
+	err = i.Stop()
+	if err != nil {
+		t.Fatalf("can not stop integrator again (reason = %v)", err)
+	}
+}
+
+func TestShutdownLeaveOrders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	ex := newFakeExchange()
+	i := startIntegratorWithExchange(t, dir, ex, map[string]interface{}{
+		"shutdown": map[string]interface{}{"timeout": 10},
+	})
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("can not start integrator (reason = %v)", err)
+	}
+	orderID, _, _, err := ex.order("btc_jpy", exchange.OrderActSell, 1100000, 0.1)
+	if err != nil {
+		t.Fatalf("can not order (reason = %v)", err)
+	}
+	summary := i.Shutdown()
+	if summary.TimedOut || len(summary.Errors) != 0 || len(summary.CanceledOrders) != 0 {
+		t.Fatalf("unexpected summary (summary = %v)", summary)
+	}
+	// 取り消さない設定でも残った注文は報告する
+	if len(summary.OpenOrders) != 1 || summary.OpenOrders[0].OrderID != orderID || summary.OpenOrders[0].Exchange != fakeExchangeName {
+		t.Fatalf("open order is not reported (open orders = %v)", summary.OpenOrders)
+	}
+	if len(ex.getOpenOrderIDs()) != 1 {
+		t.Fatalf("order is canceled (exchange = %v)", ex.getOpenOrderIDs())
+	}
+}
+
+// stuckAlgorithm does not return from Finalize until released
+type stuckAlgorithm struct {
+	release chan bool
+}
+
+func (s *stuckAlgorithm) GetName() (string) {
+	return "stuck"
+}
+
+func (s *stuckAlgorithm) Initialize(ctx *algorithm.Context) (error) {
+	return nil
+}
+
+func (s *stuckAlgorithm) Update(ctx *algorithm.Context, currencyPair string) (error) {
+	return nil
+}
+
+func (s *stuckAlgorithm) Finalize(ctx *algorithm.Context) (error) {
+	<-s.release
+	return nil
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrator")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	alg := &stuckAlgorithm{release: make(chan bool)}
+	defer close(alg.release)
+	algorithm.RegisterAlgorithmV2("integratortest-stuck", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	ex := newFakeExchange()
+	i := startIntegratorWithExchange(t, dir, ex, map[string]interface{}{
+		"robot": map[string]interface{}{
+			"algorithmPluginDir": dir,
+			"stateFile":          "state.db",
+			"algorithms":         []map[string]interface{}{{"name": "stuck", "algorithm": "integratortest-stuck"}},
+		},
+		"shutdown": map[string]interface{}{"timeout": 1},
+	})
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("can not start integrator (reason = %v)", err)
+	}
+	orderID, _, _, err := ex.order("btc_jpy", exchange.OrderActBuy, 900000, 0.1)
+	if err != nil {
+		t.Fatalf("can not order (reason = %v)", err)
+	}
+	summary := i.Shutdown()
+	if !summary.TimedOut {
+		t.Fatalf("shutdown does not time out (summary = %v)", summary)
+	}
+	// タイムアウトしても残った注文は報告する
+	if len(summary.OpenOrders) != 1 || summary.OpenOrders[0].OrderID != orderID {
+		t.Fatalf("open order is not reported (open orders = %v, errors = %v)", summary.OpenOrders, summary.Errors)
+	}
+	// 状態のファイルは閉じているので開ける
+	store, err := state.NewStore(path.Join(dir, "state.db"))
+	if err != nil {
+		t.Fatalf("state store is not closed (reason = %v)", err)
+	}
+	store.Close()
+}
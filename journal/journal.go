@@ -0,0 +1,421 @@
+package journal
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EntryType is type of journal entry
+type EntryType string
+
+const (
+	// EntryTypeRequest is order request sent to exchange
+	EntryTypeRequest EntryType = "request"
+	// EntryTypeResponse is result of order request
+	EntryTypeResponse EntryType = "response"
+	// EntryTypeRetry is retry of order request
+	EntryTypeRetry EntryType = "retry"
+	// EntryTypeReject is order rejected before sending to exchange
+	EntryTypeReject EntryType = "reject"
+	// EntryTypeCancel is cancel of order
+	EntryTypeCancel EntryType = "cancel"
+	// EntryTypeFill is detected fill of order
+	EntryTypeFill EntryType = "fill"
+)
+
+const (
+	FormatJSONLines = "jsonl"
+	FormatCSV       = "csv"
+)
+
+// ファイルがない場合にメモリに残すエントリの数
+const maxMemoryEntries = 10000
+
+// Entry is one record of journal
+type Entry struct {
+	Seq          uint64    `json:"seq"`
+	Time         time.Time `json:"time"`
+	Type         EntryType `json:"type"`
+	// アルゴリズムのインスタンス名。ロボット自身の注文はkillswitchなど
+	Name         string    `json:"name"`
+	Exchange     string    `json:"exchange"`
+	CurrencyPair string    `json:"currencyPair"`
+	Action       string    `json:"action"`
+	Price        float64   `json:"price"`
+	Amount       float64   `json:"amount"`
+	Fee          float64   `json:"fee"`
+	OrderID      int64     `json:"orderId"`
+	// responseの場合は注文を出した時刻
+	RequestTime  time.Time `json:"requestTime"`
+	Error        string    `json:"error"`
+	// 前のエントリのチェックサムとこのエントリから計算する
+	Checksum     string    `json:"checksum"`
+}
+
+func (e *Entry) computeChecksum(prevChecksum string) (string, error) {
+	copied := *e
+	copied.Checksum = ""
+	data, err := json.Marshal(&copied)
+	if err != nil {
+		return "", errors.Wrap(err, "can not marshal entry")
+	}
+	hash := sha256.New()
+	hash.Write([]byte(prevChecksum))
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Query is condition of entries. zero value matches all
+type Query struct {
+	From         time.Time
+	To           time.Time
+	Name         string
+	Exchange     string
+	CurrencyPair string
+	Types        []EntryType
+	OrderID      int64
+	Limit        int
+}
+
+func (q *Query) match(entry *Entry) (bool) {
+	if !q.From.IsZero() && entry.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !entry.Time.Before(q.To) {
+		return false
+	}
+	if q.Name != "" && entry.Name != q.Name {
+		return false
+	}
+	if q.Exchange != "" && entry.Exchange != q.Exchange {
+		return false
+	}
+	if q.CurrencyPair != "" && entry.CurrencyPair != q.CurrencyPair {
+		return false
+	}
+	if q.OrderID != 0 && entry.OrderID != q.OrderID {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if entry.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Listener is called with copy of entry after it is written
+type Listener func(entry *Entry)
+
+// Journal is append-only log of orders and fills. each entry is one json line chained by checksum
+type Journal struct {
+	path         string
+	file         *os.File
+	// 書き込み済みのファイルのサイズ。読み込みは書き込み途中の行を含まないようにここまでにする
+	size         int64
+	memory       []*Entry
+	seq          uint64
+	lastChecksum string
+	listeners    []Listener
+	mutex        *sync.Mutex
+	logger       *logger.Logger
+}
+
+// AddListener is add listener of appended entries. listener should return quickly
+func (j *Journal) AddListener(listener Listener) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.listeners = append(j.listeners, listener)
+}
+
+// Append is write entry to journal. seq and checksum are set
+func (j *Journal) Append(entry *Entry) (error) {
+	listeners, err := j.append(entry)
+	if err != nil {
+		return err
+	}
+	// 書き込んだ後にロックの外で呼ぶ
+	for _, listener := range listeners {
+		copied := *entry
+		listener(&copied)
+	}
+	return nil
+}
+
+func (j *Journal) append(entry *Entry) ([]Listener, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Seq = j.seq + 1
+	checksum, err := entry.computeChecksum(j.lastChecksum)
+	if err != nil {
+		return nil, err
+	}
+	entry.Checksum = checksum
+	if j.path == "" {
+		copied := *entry
+		j.memory = append(j.memory, &copied)
+		if len(j.memory) > maxMemoryEntries {
+			j.memory = j.memory[len(j.memory)-maxMemoryEntries:]
+		}
+	} else {
+		if j.file == nil {
+			return nil, errors.Errorf("journal already closed (path = %v)", j.path)
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, errors.Wrap(err, "can not marshal entry")
+		}
+		// 1行を1回で書いて途中で止まっても前の行は壊れないようにする
+		_, err = j.file.Write(append(data, '\n'))
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not write journal (path = %v)", j.path)
+		}
+		err = j.file.Sync()
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not sync journal (path = %v)", j.path)
+		}
+		j.size += int64(len(data) + 1)
+	}
+	j.seq = entry.Seq
+	j.lastChecksum = checksum
+	return append([]Listener(nil), j.listeners...), nil
+}
+
+// scan is read entries appended before it is called and verify checksums.
+// only snapshot is taken under mutex so that Append is not blocked while reading file
+func (j *Journal) scan(callback func(entry *Entry) (bool)) (error) {
+	j.mutex.Lock()
+	memory := j.memory
+	size := j.size
+	j.mutex.Unlock()
+	if j.path == "" {
+		// 追加されたエントリは書き換えないのでロックの外で読める
+		for _, entry := range memory {
+			copied := *entry
+			if !callback(&copied) {
+				break
+			}
+		}
+		return nil
+	}
+	file, err := os.Open(j.path)
+	if err != nil {
+		return errors.Wrapf(err, "can not open journal (path = %v)", j.path)
+	}
+	defer file.Close()
+	return scanEntries(io.LimitReader(file, size), callback)
+}
+
+func scanEntries(reader io.Reader, callback func(entry *Entry) (bool)) (error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	prevChecksum := ""
+	var seq uint64
+	for scanner.Scan() {
+		entry := new(Entry)
+		err := json.Unmarshal(scanner.Bytes(), entry)
+		if err != nil {
+			return errors.Wrapf(err, "broken journal entry (seq = %v)", seq+1)
+		}
+		if entry.Seq != seq+1 {
+			return errors.Errorf("journal entry is missing (expected seq = %v, actual seq = %v)", seq+1, entry.Seq)
+		}
+		checksum, err := entry.computeChecksum(prevChecksum)
+		if err != nil {
+			return err
+		}
+		if checksum != entry.Checksum {
+			return errors.Errorf("checksum mismatch of journal entry (seq = %v)", entry.Seq)
+		}
+		prevChecksum = checksum
+		seq = entry.Seq
+		if !callback(entry) {
+			return nil
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return errors.Wrap(err, "can not read journal")
+	}
+	return nil
+}
+
+// Verify is check checksums of all entries and returns number of entries
+func (j *Journal) Verify() (uint64, error) {
+	var count uint64
+	err := j.scan(func(entry *Entry) (bool) {
+		count++
+		return true
+	})
+	return count, err
+}
+
+// Query is get entries matching query in order of seq
+func (j *Journal) Query(query *Query) ([]*Entry, error) {
+	if query == nil {
+		query = new(Query)
+	}
+	entries := make([]*Entry, 0)
+	err := j.scan(func(entry *Entry) (bool) {
+		if !query.match(entry) {
+			return true
+		}
+		entries = append(entries, entry)
+		return query.Limit <= 0 || len(entries) < query.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+var csvHeader = []string{
+	"seq", "time", "type", "name", "exchange", "currencyPair", "action",
+	"price", "amount", "fee", "orderId", "requestTime", "error", "checksum",
+}
+
+func formatTime(t time.Time) (string) {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func formatFloat(f float64) (string) {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Export is write entries matching query to writer. format is jsonl (json lines) or csv
+func (j *Journal) Export(writer io.Writer, format string, query *Query) (error) {
+	entries, err := j.Query(query)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatJSONLines, "":
+		encoder := json.NewEncoder(writer)
+		for _, entry := range entries {
+			err := encoder.Encode(entry)
+			if err != nil {
+				return errors.Wrap(err, "can not write entry")
+			}
+		}
+		return nil
+	case FormatCSV:
+		csvWriter := csv.NewWriter(writer)
+		err := csvWriter.Write(csvHeader)
+		if err != nil {
+			return errors.Wrap(err, "can not write header")
+		}
+		for _, entry := range entries {
+			err := csvWriter.Write([]string{
+				strconv.FormatUint(entry.Seq, 10),
+				formatTime(entry.Time),
+				string(entry.Type),
+				entry.Name,
+				entry.Exchange,
+				entry.CurrencyPair,
+				entry.Action,
+				formatFloat(entry.Price),
+				formatFloat(entry.Amount),
+				formatFloat(entry.Fee),
+				strconv.FormatInt(entry.OrderID, 10),
+				formatTime(entry.RequestTime),
+				entry.Error,
+				entry.Checksum,
+			})
+			if err != nil {
+				return errors.Wrap(err, "can not write entry")
+			}
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		return errors.Errorf("unsupported export format (format = %v)", format)
+	}
+}
+
+// Close is close journal file
+func (j *Journal) Close() (error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// recover is read existing journal, drop incomplete last line and restore seq and checksum
+func (j *Journal) recover() (error) {
+	data, err := ioutil.ReadFile(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "can not read journal (path = %v)", j.path)
+	}
+	complete := len(data)
+	if complete > 0 && data[complete-1] != '\n' {
+		// 書き込み途中で止まった行は記録されていない
+		complete = bytes.LastIndexByte(data, '\n') + 1
+		j.logger.Warnf("drop incomplete journal entry (path = %v, size = %v)", j.path, len(data)-complete)
+		err = os.Truncate(j.path, int64(complete))
+		if err != nil {
+			return errors.Wrapf(err, "can not truncate journal (path = %v)", j.path)
+		}
+	}
+	j.size = int64(complete)
+	return scanEntries(bytes.NewReader(data[:complete]), func(entry *Entry) (bool) {
+		j.seq = entry.Seq
+		j.lastChecksum = entry.Checksum
+		return true
+	})
+}
+
+// NewJournal is open journal file. latest entries are kept in memory if path is empty
+func NewJournal(path string) (*Journal, error) {
+	j := &Journal{
+		path:   path,
+		memory: make([]*Entry, 0),
+		mutex:  new(sync.Mutex),
+		logger: logger.Get("journal"),
+	}
+	if path == "" {
+		return j, nil
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not create journal directory (path = %v)", path)
+	}
+	err = j.recover()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not open journal (path = %v)", path)
+	}
+	j.file = file
+	return j, nil
+}
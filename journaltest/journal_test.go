@@ -0,0 +1,201 @@
+package journaltest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/journal"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendEntries(t *testing.T, j *journal.Journal, base time.Time) {
+	entries := []*journal.Entry{
+		{Time: base, Type: journal.EntryTypeRequest, Name: "a", Exchange: "zaif", CurrencyPair: "btc_jpy", Action: "buy", Price: 100, Amount: 1},
+		{Time: base.Add(time.Second), Type: journal.EntryTypeResponse, Name: "a", Exchange: "zaif", CurrencyPair: "btc_jpy", Action: "buy", Price: 100, Amount: 1, OrderID: 10, RequestTime: base},
+		{Time: base.Add(2 * time.Second), Type: journal.EntryTypeFill, Name: "a", Exchange: "zaif", CurrencyPair: "btc_jpy", Action: "buy", Price: 100, Amount: 1, Fee: 0.1, OrderID: 10},
+		{Time: base.Add(3 * time.Second), Type: journal.EntryTypeCancel, Name: "b", Exchange: "zaif", CurrencyPair: "eth_jpy", OrderID: 11, Error: "not found"},
+	}
+	for _, entry := range entries {
+		err := j.Append(entry)
+		if err != nil {
+			t.Fatalf("can not append entry (reason = %v)", err)
+		}
+	}
+}
+
+func TestJournalQuery(t *testing.T) {
+	j, err := journal.NewJournal("")
+	if err != nil {
+		t.Fatalf("can not create journal (reason = %v)", err)
+	}
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	appendEntries(t, j, base)
+	entries, err := j.Query(nil)
+	if err != nil || len(entries) != 4 || entries[3].Seq != 4 {
+		t.Fatalf("unexpected entries (entries = %v, reason = %v)", entries, err)
+	}
+	entries, _ = j.Query(&journal.Query{OrderID: 10, Types: []journal.EntryType{journal.EntryTypeFill}})
+	if len(entries) != 1 || entries[0].Fee != 0.1 {
+		t.Fatalf("unexpected fill entries (entries = %v)", entries)
+	}
+	entries, _ = j.Query(&journal.Query{From: base.Add(time.Second), To: base.Add(3 * time.Second)})
+	if len(entries) != 2 || entries[0].Type != journal.EntryTypeResponse {
+		t.Fatalf("unexpected entries in range (entries = %v)", entries)
+	}
+	entries, _ = j.Query(&journal.Query{Name: "a", Limit: 2})
+	if len(entries) != 2 {
+		t.Fatalf("limit is not applied (entries = %v)", entries)
+	}
+	buffer := new(bytes.Buffer)
+	err = j.Export(buffer, journal.FormatCSV, &journal.Query{Types: []journal.EntryType{journal.EntryTypeFill}})
+	if err != nil {
+		t.Fatalf("can not export (reason = %v)", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "3,2018-01-01T00:00:02Z,fill,a,zaif,btc_jpy,buy,100,1,0.1,10,,,") {
+		t.Fatalf("unexpected csv (csv = %v)", buffer.String())
+	}
+	err = j.Export(buffer, "xml", nil)
+	if err == nil {
+		t.Fatalf("unsupported format is accepted")
+	}
+}
+
+func TestJournalListener(t *testing.T) {
+	j, err := journal.NewJournal("")
+	if err != nil {
+		t.Fatalf("can not create journal (reason = %v)", err)
+	}
+	entries := make([]*journal.Entry, 0)
+	j.AddListener(func(entry *journal.Entry) {
+		entries = append(entries, entry)
+	})
+	appendEntries(t, j, time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(entries) != 4 || entries[2].Type != journal.EntryTypeFill || entries[2].Seq != 3 || entries[2].Checksum == "" {
+		t.Fatalf("unexpected entries of listener (entries = %v)", entries)
+	}
+}
+
+func TestJournalFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	journalPath := path.Join(dir, "journal.jsonl")
+	j, err := journal.NewJournal(journalPath)
+	if err != nil {
+		t.Fatalf("can not create journal (reason = %v)", err)
+	}
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	appendEntries(t, j, base)
+	j.Close()
+
+	// 書き込み途中で止まった行は捨てて続きから追記する
+	file, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("can not open journal file (reason = %v)", err)
+	}
+	file.WriteString(`{"seq":5,"time":`)
+	file.Close()
+	j, err = journal.NewJournal(journalPath)
+	if err != nil {
+		t.Fatalf("can not reopen journal (reason = %v)", err)
+	}
+	err = j.Append(&journal.Entry{Time: base.Add(4 * time.Second), Type: journal.EntryTypeRequest, Name: "a"})
+	if err != nil {
+		t.Fatalf("can not append entry after reopen (reason = %v)", err)
+	}
+	count, err := j.Verify()
+	if err != nil || count != 5 {
+		t.Fatalf("unexpected verify result (count = %v, reason = %v)", count, err)
+	}
+	j.Close()
+
+	// 書き換えられた記録は検出する
+	data, err := ioutil.ReadFile(journalPath)
+	if err != nil {
+		t.Fatalf("can not read journal file (reason = %v)", err)
+	}
+	tampered := strings.Replace(string(data), `"price":100,"amount":1,"fee":0.1`, `"price":90,"amount":1,"fee":0.1`, 1)
+	if tampered == string(data) {
+		t.Fatalf("can not tamper journal")
+	}
+	err = ioutil.WriteFile(journalPath, []byte(tampered), 0600)
+	if err != nil {
+		t.Fatalf("can not write journal file (reason = %v)", err)
+	}
+	_, err = journal.NewJournal(journalPath)
+	if err == nil {
+		t.Fatalf("tampered journal is opened")
+	}
+}
+
+func TestJournalMemoryLimit(t *testing.T) {
+	j, err := journal.NewJournal("")
+	if err != nil {
+		t.Fatalf("can not create journal (reason = %v)", err)
+	}
+	// メモリに残るのは最新の10000件
+	for i := 0; i < 10010; i++ {
+		err := j.Append(&journal.Entry{Type: journal.EntryTypeRequest, Name: "a"})
+		if err != nil {
+			t.Fatalf("can not append entry (reason = %v)", err)
+		}
+	}
+	entries, err := j.Query(nil)
+	if err != nil || len(entries) != 10000 || entries[0].Seq != 11 || entries[9999].Seq != 10010 {
+		t.Fatalf("unexpected entries (count = %v, reason = %v)", len(entries), err)
+	}
+}
+
+func TestJournalQueryWhileAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	j, err := journal.NewJournal(path.Join(dir, "journal.jsonl"))
+	if err != nil {
+		t.Fatalf("can not create journal (reason = %v)", err)
+	}
+	defer j.Close()
+	finished := make(chan error)
+	go func() {
+		for i := 0; i < 200; i++ {
+			err := j.Append(&journal.Entry{Type: journal.EntryTypeRequest, Name: "a", Error: strings.Repeat("x", i)})
+			if err != nil {
+				finished <- err
+				return
+			}
+		}
+		close(finished)
+	}()
+	for {
+		// 書き込み中の行は読まずに、書き込み済みのエントリだけを検証して返す
+		entries, err := j.Query(nil)
+		if err != nil {
+			t.Fatalf("can not query while appending (reason = %v)", err)
+		}
+		for i, entry := range entries {
+			if entry.Seq != uint64(i+1) {
+				t.Fatalf("unexpected seq (index = %v, seq = %v)", i, entry.Seq)
+			}
+		}
+		select {
+		case err, ok := <-finished:
+			if ok {
+				t.Fatalf("can not append entry (reason = %v)", err)
+			}
+			entries, err = j.Query(nil)
+			if err != nil || len(entries) != 200 {
+				t.Fatalf("unexpected entries (count = %v, reason = %v)", len(entries), err)
+			}
+			return
+		default:
+		}
+	}
+}
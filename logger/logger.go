@@ -0,0 +1,362 @@
+package logger
+
+import (
+	"github.com/pkg/errors"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/syslog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is severity of log
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// keys of common fields
+const (
+	FieldExchange     = "exchange"
+	FieldCurrencyPair = "currencyPair"
+	FieldAlgorithm    = "algorithm"
+	FieldOrderID      = "orderId"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputSyslog = "syslog"
+	OutputFile   = "file"
+)
+
+// 標準のlogパッケージに書かれたもののコンポーネント名
+const stdComponent = "std"
+
+func (l Level) String() (string) {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLevel is parse name of level. empty is info
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, errors.Errorf("unknown log level (level = %v)", name)
+	}
+}
+
+// Fields is additional values of log
+type Fields map[string]interface{}
+
+// Config is config of output of loggers
+type Config struct {
+	// stdout、stderr、syslog、file。省略時はstderr
+	Output     string            `json:"output"     yaml:"output"     toml:"output"`
+	// textまたはjson
+	Format     string            `json:"format"     yaml:"format"     toml:"format"`
+	Level      string            `json:"level"      yaml:"level"      toml:"level"`
+	// コンポーネントごとのレベル
+	Levels     map[string]string `json:"levels"     yaml:"levels"     toml:"levels"`
+	// outputがfileの場合のファイル
+	File       string            `json:"file"       yaml:"file"       toml:"file"`
+	// MB。超えたらローテートする。0ならローテートしない
+	MaxSize    int               `json:"maxSize"    yaml:"maxSize"    toml:"maxSize"`
+	// 残す古いファイルの数
+	MaxBackups int               `json:"maxBackups" yaml:"maxBackups" toml:"maxBackups"`
+}
+
+type output struct {
+	writer io.Writer
+	closer io.Closer
+	syslog *syslog.Writer
+	format string
+	level  Level
+	levels map[string]Level
+}
+
+var current *output
+var mutex *sync.Mutex
+
+// Logger is logger of component with fields
+type Logger struct {
+	component string
+	fields    Fields
+}
+
+// Get is get logger of component
+func Get(component string) (*Logger) {
+	return &Logger{
+		component: component,
+		fields:    make(Fields),
+	}
+}
+
+// With is get logger which has fields in addition to fields of l
+func (l *Logger) With(fields Fields) (*Logger) {
+	newFields := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		newFields[k] = v
+	}
+	for k, v := range fields {
+		newFields[k] = v
+	}
+	return &Logger{
+		component: l.component,
+		fields:    newFields,
+	}
+}
+
+// WithField is get logger which has field in addition to fields of l
+func (l *Logger) WithField(key string, value interface{}) (*Logger) {
+	return l.With(Fields{key: value})
+}
+
+// Enabled is whether log of level is written
+func (l *Logger) Enabled(level Level) (bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return current.enabled(l.component, level)
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.write(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.write(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.write(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.write(LevelError, fmt.Sprintf(format, args...))
+}
+
+// StdLogger is get logger of log package which writes with level info
+func (l *Logger) StdLogger(prefix string) (*log.Logger) {
+	return log.New(&logWriter{logger: l, level: LevelInfo}, prefix, 0)
+}
+
+func (l *Logger) write(level Level, message string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !current.enabled(l.component, level) {
+		return
+	}
+	current.write(time.Now(), level, l.component, message, l.fields)
+}
+
+func (o *output) enabled(component string, level Level) (bool) {
+	componentLevel, ok := o.levels[component]
+	if !ok {
+		componentLevel = o.level
+	}
+	return level >= componentLevel
+}
+
+func (o *output) encode(t time.Time, level Level, component string, message string, fields Fields, withTime bool) ([]byte) {
+	if o.format == FormatJSON {
+		values := make(map[string]interface{}, len(fields)+4)
+		for k, v := range fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			values[k] = v
+		}
+		values["time"] = t.Format(time.RFC3339Nano)
+		values["level"] = level.String()
+		values["component"] = component
+		values["message"] = message
+		data, err := json.Marshal(values)
+		if err == nil {
+			return data
+		}
+		// 出力できない値があればテキストにする
+		message = fmt.Sprintf("%v (marshal error = %v)", message, err)
+	}
+	buf := make([]byte, 0, len(message)+64)
+	if withTime {
+		buf = append(buf, t.Format("2006/01/02 15:04:05 ")...)
+	}
+	buf = append(buf, strings.ToUpper(level.String())...)
+	buf = append(buf, ' ')
+	buf = append(buf, component...)
+	buf = append(buf, ": "...)
+	buf = append(buf, message...)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf = append(buf, fmt.Sprintf(" %v=%v", k, fields[k])...)
+	}
+	return buf
+}
+
+func (o *output) write(t time.Time, level Level, component string, message string, fields Fields) {
+	message = strings.TrimSuffix(message, "\n")
+	if o.syslog != nil {
+		// 時刻はsyslogが付ける
+		line := string(o.encode(t, level, component, message, fields, false))
+		var err error
+		switch level {
+		case LevelDebug:
+			err = o.syslog.Debug(line)
+		case LevelInfo:
+			err = o.syslog.Info(line)
+		case LevelWarn:
+			err = o.syslog.Warning(line)
+		default:
+			err = o.syslog.Err(line)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "can not write to syslog (reason = %v)\n", err)
+		}
+		return
+	}
+	line := append(o.encode(t, level, component, message, fields, true), '\n')
+	_, err := o.writer.Write(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can not write log (reason = %v)\n", err)
+	}
+}
+
+// logWriter is writer for log package
+type logWriter struct {
+	logger *Logger
+	level  Level
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.logger.write(w.level, string(p))
+	return len(p), nil
+}
+
+func newOutput(config *Config, configDir string) (*output, error) {
+	o := &output{
+		writer: os.Stderr,
+		format: FormatText,
+		level:  LevelInfo,
+		levels: make(map[string]Level),
+	}
+	if config == nil {
+		return o, nil
+	}
+	switch config.Format {
+	case "", FormatText:
+	case FormatJSON:
+		o.format = FormatJSON
+	default:
+		return nil, errors.Errorf("unknown log format (format = %v)", config.Format)
+	}
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	o.level = level
+	for component, name := range config.Levels {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid level of component (component = %v)", component)
+		}
+		o.levels[component] = level
+	}
+	switch config.Output {
+	case "", OutputStderr:
+	case OutputStdout:
+		o.writer = os.Stdout
+	case OutputSyslog:
+		writer, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_USER, "ACT")
+		if err != nil {
+			return nil, errors.Wrap(err, "can not open syslog")
+		}
+		o.syslog = writer
+		o.closer = writer
+	case OutputFile:
+		if config.File == "" {
+			return nil, errors.New("file is required if output is file")
+		}
+		filePath := config.File
+		if !filepath.IsAbs(filePath) {
+			filePath = path.Join(configDir, filePath)
+		}
+		file, err := newRotatingFile(filePath, int64(config.MaxSize)*1024*1024, config.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		o.writer = file
+		o.closer = file
+	default:
+		return nil, errors.Errorf("unknown log output (output = %v)", config.Output)
+	}
+	return o, nil
+}
+
+// Configure is set output of all loggers. output of log package is also written as component std
+func Configure(config *Config, configDir string) (error) {
+	newOutput, err := newOutput(config, configDir)
+	if err != nil {
+		return err
+	}
+	mutex.Lock()
+	old := current
+	current = newOutput
+	mutex.Unlock()
+	log.SetFlags(0)
+	log.SetOutput(&logWriter{logger: Get(stdComponent), level: LevelInfo})
+	if old.closer != nil {
+		err = old.closer.Close()
+		if err != nil {
+			return errors.Wrap(err, "can not close old log output")
+		}
+	}
+	return nil
+}
+
+// Close is close output and write to stderr after that
+func Close() (error) {
+	return Configure(nil, "")
+}
+
+func init() {
+	mutex = new(sync.Mutex)
+	current, _ = newOutput(nil, "")
+}
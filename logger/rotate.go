@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"github.com/pkg/errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile is file which is renamed to path.1, path.2 ... when size exceeds max size
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	mutex      *sync.Mutex
+}
+
+func (r *rotatingFile) open() (error) {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "can not open log file (path = %v)", r.path)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "can not stat log file (path = %v)", r.path)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) backupPath(n int) (string) {
+	return fmt.Sprintf("%v.%v", r.path, n)
+}
+
+func (r *rotatingFile) rotate() (error) {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return errors.Wrapf(err, "can not close log file (path = %v)", r.path)
+	}
+	if r.maxBackups <= 0 {
+		err = os.Remove(r.path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "can not remove log file (path = %v)", r.path)
+		}
+		return r.open()
+	}
+	// 一番古いものを消して順にずらす
+	err = os.Remove(r.backupPath(r.maxBackups))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "can not remove old log file (path = %v)", r.backupPath(r.maxBackups))
+	}
+	for n := r.maxBackups - 1; n >= 1; n-- {
+		err = os.Rename(r.backupPath(n), r.backupPath(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "can not rename old log file (path = %v)", r.backupPath(n))
+		}
+	}
+	err = os.Rename(r.path, r.backupPath(1))
+	if err != nil {
+		return errors.Wrapf(err, "can not rename log file (path = %v)", r.path)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		// ローテートに失敗していれば開き直す
+		err := r.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Close() (error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func newRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		mutex:      new(sync.Mutex),
+	}
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
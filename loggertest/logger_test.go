@@ -0,0 +1,177 @@
+package loggertest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func readLines(t *testing.T, filePath string) ([]string) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("can not read log file (path = %v, reason = %v)", filePath, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]logger.Level{"": logger.LevelInfo, "debug": logger.LevelDebug, "WARN": logger.LevelWarn, "error": logger.LevelError} {
+		level, err := logger.ParseLevel(name)
+		if err != nil || level != expected {
+			t.Fatalf("unexpected level (name = %v, level = %v, reason = %v)", name, level, err)
+		}
+	}
+	_, err := logger.ParseLevel("verbose")
+	if err == nil {
+		t.Fatalf("unknown level is accepted")
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	err = logger.Configure(&logger.Config{
+		Output: logger.OutputFile,
+		File:   "act.log",
+		Level:  "info",
+		Levels: map[string]string{"noisy": "error", "verbose": "debug"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("can not configure logger (reason = %v)", err)
+	}
+	l := logger.Get("robot").With(logger.Fields{logger.FieldExchange: "zaif", logger.FieldCurrencyPair: "btc_jpy"})
+	l.Debugf("hidden")
+	l.Infof("order (id = %v)", 10)
+	logger.Get("noisy").Warnf("hidden")
+	logger.Get("noisy").Errorf("shown")
+	logger.Get("verbose").Debugf("shown")
+	log.Printf("from log package")
+	err = logger.Close()
+	if err != nil {
+		t.Fatalf("can not close logger (reason = %v)", err)
+	}
+	lines := readLines(t, path.Join(dir, "act.log"))
+	expected := []string{
+		"INFO robot: order (id = 10) currencyPair=btc_jpy exchange=zaif",
+		"ERROR noisy: shown",
+		"DEBUG verbose: shown",
+		"INFO std: from log package",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected lines (lines = %v)", lines)
+	}
+	for idx, line := range lines {
+		if !strings.HasSuffix(line, " "+expected[idx]) {
+			t.Fatalf("unexpected line (line = %v, expected = %v)", line, expected[idx])
+		}
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "act.json")
+	err = logger.Configure(&logger.Config{
+		Output: logger.OutputFile,
+		Format: logger.FormatJSON,
+		File:   filePath,
+	}, "")
+	if err != nil {
+		t.Fatalf("can not configure logger (reason = %v)", err)
+	}
+	algorithmLogger := logger.Get("algorithm").WithField(logger.FieldAlgorithm, "example")
+	algorithmLogger.WithField(logger.FieldOrderID, 10).Warnf("canceled")
+	algorithmLogger.StdLogger("[example] ").Printf("from context")
+	err = logger.Close()
+	if err != nil {
+		t.Fatalf("can not close logger (reason = %v)", err)
+	}
+	lines := readLines(t, filePath)
+	if len(lines) != 2 {
+		t.Fatalf("unexpected lines (lines = %v)", lines)
+	}
+	values := make(map[string]interface{})
+	err = json.Unmarshal([]byte(lines[0]), &values)
+	if err != nil {
+		t.Fatalf("can not unmarshal line (line = %v, reason = %v)", lines[0], err)
+	}
+	if values["level"] != "warn" || values["component"] != "algorithm" || values["message"] != "canceled" ||
+		values[logger.FieldAlgorithm] != "example" || values[logger.FieldOrderID] != float64(10) || values["time"] == nil {
+		t.Fatalf("unexpected values (values = %v)", values)
+	}
+	values = make(map[string]interface{})
+	err = json.Unmarshal([]byte(lines[1]), &values)
+	if err != nil {
+		t.Fatalf("can not unmarshal line (line = %v, reason = %v)", lines[1], err)
+	}
+	if values["level"] != "info" || values["message"] != "[example] from context" || values[logger.FieldOrderID] != nil {
+		t.Fatalf("unexpected values (values = %v)", values)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "act.log")
+	err = logger.Configure(&logger.Config{
+		Output:     logger.OutputFile,
+		File:       filePath,
+		MaxSize:    1,
+		MaxBackups: 2,
+	}, "")
+	if err != nil {
+		t.Fatalf("can not configure logger (reason = %v)", err)
+	}
+	l := logger.Get("rotate")
+	message := strings.Repeat("x", 1000)
+	// 1MBのファイルが3つ以上になるだけ書く
+	for n := 0; n < 3500; n++ {
+		l.Infof("%v", message)
+	}
+	err = logger.Close()
+	if err != nil {
+		t.Fatalf("can not close logger (reason = %v)", err)
+	}
+	for _, p := range []string{filePath, filePath + ".1", filePath + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("log file is not found (path = %v, reason = %v)", p, err)
+		}
+		if info.Size() > 1024*1024 {
+			t.Fatalf("log file is too large (path = %v, size = %v)", p, info.Size())
+		}
+	}
+	_, err = os.Stat(filePath + ".3")
+	if !os.IsNotExist(err) {
+		t.Fatalf("too many backups are kept (reason = %v)", err)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, config := range []*logger.Config{
+		{Output: "printer"},
+		{Format: "xml"},
+		{Level: "verbose"},
+		{Levels: map[string]string{"robot": "verbose"}},
+		{Output: logger.OutputFile},
+	} {
+		err := logger.Configure(config, "")
+		if err == nil {
+			t.Fatalf("invalid config is accepted (config = %v)", config)
+		}
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/AutomaticCoinTrader/ACT/integrator"
 	"github.com/AutomaticCoinTrader/ACT/configurator"
-	"log"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"runtime"
 	"flag"
 	"os"
@@ -14,20 +14,28 @@ import (
 	"path/filepath"
 )
 
-const (
-	actConfigPrefix = "act"
-)
+var mainLogger = logger.Get("main")
 
-func signalWait() {
+func signalWait(integrator *integrator.Integrator) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan,
 		syscall.SIGINT,
 		syscall.SIGQUIT,
-		syscall.SIGTERM)
+		syscall.SIGTERM,
+		syscall.SIGHUP,
+		syscall.SIGUSR1)
 Loop:
 	for {
 		sig := <-sigChan
 		switch sig {
+		case syscall.SIGHUP:
+			// アルゴリズムのpluginと設定を読み直す
+			result := integrator.ReloadAlgorithms()
+			mainLogger.Infof("reload algorithms (loaded = %v, reloaded = %v, started = %v, stopped = %v, errors = %v)", result.LoadedAlgorithms, result.Reloaded, result.Started, result.Stopped, result.Errors)
+		case syscall.SIGUSR1:
+			// キルスイッチ
+			result := integrator.Kill("signal")
+			mainLogger.Warnf("kill switch (canceled = %v, flatten = %v, errors = %v)", len(result.CanceledOrders), len(result.FlattenOrders), result.Errors)
 		case syscall.SIGINT:
 			fallthrough
 		case syscall.SIGQUIT:
@@ -35,7 +43,7 @@ Loop:
 		case syscall.SIGTERM:
 			break Loop
 		default:
-			log.Printf("unexpected signal (sig = %v)", sig)
+			mainLogger.Warnf("unexpected signal (sig = %v)", sig)
 		}
 	}
 }
@@ -53,13 +61,10 @@ func actStart(integrator *integrator.Integrator) (error) {
 }
 
 func actStop(integrator *integrator.Integrator) (error) {
-	err := integrator.Stop()
-	if err != nil {
-		log.Printf("can not stop integrator (reason = %v)", err)
-	}
-	err = integrator.Finalize()
-	if err != nil {
-		log.Printf("finalize error of integrator(reazon = %v)", err)
+	// 結果はShutdownの中でログに出る
+	summary := integrator.Shutdown()
+	if summary.TimedOut {
+		return errors.New("shutdown timeout")
 	}
 	return nil
 }
@@ -70,38 +75,38 @@ func main() {
 	if err == nil {
 		abswd, err := filepath.Abs(wd)
 		if err == nil {
-			log.Printf("workdir: %v", abswd)
+			mainLogger.Infof("workdir: %v", abswd)
 		} else {
-			log.Printf("workdir: %v", wd)
+			mainLogger.Infof("workdir: %v", wd)
 		}
 	}
 	configDir := flag.String("confdir", "", "config directory")
 	flag.Parse()
-	cf, err := configurator.NewConfigurator(path.Join(*configDir, actConfigPrefix))
+	cf, err := configurator.NewConfigurator(path.Join(*configDir, integrator.ConfigPathPrefix))
 	if err != nil {
-		log.Printf("can not create configurator (config dir = %v, reason = %v)", *configDir, err)
+		mainLogger.Errorf("can not create configurator (config dir = %v, reason = %v)", *configDir, err)
 		return
 	}
 	newConfig := new(integrator.Config)
 	err = cf.Load(newConfig)
 	if err != nil {
-		log.Printf("can not load config (config dir = %v, reason = %v)", *configDir, err)
+		mainLogger.Errorf("can not load config (config dir = %v, reason = %v)", *configDir, err)
 		return
 
 	}
 	it, err := integrator.NewIntegrator(newConfig, *configDir)
 	if err != nil {
-		log.Printf("can not create exchangers (config dir = %v, reason = %v)", *configDir, err)
+		mainLogger.Errorf("can not create exchangers (config dir = %v, reason = %v)", *configDir, err)
 		return
 	}
 	err = actStart(it)
 	if err != nil {
-		log.Printf("can not start act (reason = %v)", err)
+		mainLogger.Errorf("can not start act (reason = %v)", err)
 		return
 	}
-	signalWait()
+	signalWait(it)
 	err = actStop(it)
 	if err != nil {
-		log.Printf("can not stop act (reason = %v)", err)
+		mainLogger.Errorf("can not stop act (reason = %v)", err)
 	}
 }
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "act"
+
+// outcomes of orders
+const (
+	OrderOutcomePlaced       = "placed"
+	OrderOutcomeFailed       = "failed"
+	OrderOutcomeRetried      = "retried"
+	OrderOutcomeRejected     = "rejected"
+	OrderOutcomeCanceled     = "canceled"
+	OrderOutcomeCancelFailed = "cancel_failed"
+	OrderOutcomeFilled       = "filled"
+)
+
+var (
+	exchangeHTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests to exchange api. each retry is observed.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"exchange", "endpoint"})
+	exchangeHTTPRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "http_request_errors_total",
+		Help:      "Number of failed http requests to exchange api. status is 0 if no response.",
+	}, []string{"exchange", "endpoint", "status"})
+	exchangeRateLimitWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "rate_limit_wait_seconds",
+		Help:      "Time waited by rate limiter before requests to exchange api.",
+		Buckets:   []float64{0, 0.01, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"exchange", "api"})
+	websocketReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "websocket_reconnects_total",
+		Help:      "Number of reconnections of streaming websocket.",
+	}, []string{"exchange", "currency_pair"})
+	websocketMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "websocket_messages_total",
+		Help:      "Number of messages received by streaming websocket.",
+	}, []string{"exchange", "currency_pair"})
+	algorithmUpdateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "algorithm",
+		Name:      "update_duration_seconds",
+		Help:      "Latency of updates of algorithm instances.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	}, []string{"name", "exchange"})
+	algorithmUpdateErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "algorithm",
+		Name:      "update_errors_total",
+		Help:      "Number of updates of algorithm instances which returned error or panicked.",
+	}, []string{"name", "exchange"})
+	algorithmUpdatesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "algorithm",
+		Name:      "updates_dropped_total",
+		Help:      "Number of updates dropped because mailbox of algorithm instance was full.",
+	}, []string{"name", "exchange"})
+	orders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_total",
+		Help:      "Number of orders by outcome.",
+	}, []string{"exchange", "name", "outcome"})
+	balance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "balance",
+		Help:      "Balance per currency last fetched from exchange.",
+	}, []string{"exchange", "currency"})
+)
+
+// ObserveHTTPRequest is record latency and result of http request to exchange api
+func ObserveHTTPRequest(exchange string, endpoint string, duration time.Duration, status int, err error) {
+	exchangeHTTPRequestDuration.WithLabelValues(exchange, endpoint).Observe(duration.Seconds())
+	if err != nil {
+		exchangeHTTPRequestErrors.WithLabelValues(exchange, endpoint, strconv.Itoa(status)).Inc()
+	}
+}
+
+// ObserveRateLimitWait is record time waited by rate limiter. api is kind of api such as public or trade
+func ObserveRateLimitWait(exchange string, api string, wait time.Duration) {
+	exchangeRateLimitWait.WithLabelValues(exchange, api).Observe(wait.Seconds())
+}
+
+// IncWebsocketReconnect is count reconnection of streaming
+func IncWebsocketReconnect(exchange string, currencyPair string) {
+	websocketReconnects.WithLabelValues(exchange, currencyPair).Inc()
+}
+
+// IncWebsocketMessage is count message of streaming
+func IncWebsocketMessage(exchange string, currencyPair string) {
+	websocketMessages.WithLabelValues(exchange, currencyPair).Inc()
+}
+
+// ObserveAlgorithmUpdate is record latency and result of update of algorithm instance
+func ObserveAlgorithmUpdate(name string, exchange string, duration time.Duration, err error) {
+	algorithmUpdateDuration.WithLabelValues(name, exchange).Observe(duration.Seconds())
+	if err != nil {
+		algorithmUpdateErrors.WithLabelValues(name, exchange).Inc()
+	}
+}
+
+// IncAlgorithmUpdateDropped is count dropped update of algorithm instance
+func IncAlgorithmUpdateDropped(name string, exchange string) {
+	algorithmUpdatesDropped.WithLabelValues(name, exchange).Inc()
+}
+
+// IncOrder is count order by outcome
+func IncOrder(exchange string, name string, outcome string) {
+	orders.WithLabelValues(exchange, name, outcome).Inc()
+}
+
+// SetBalances is set balances of exchange
+func SetBalances(exchange string, funds map[string]float64) {
+	for currency, amount := range funds {
+		balance.WithLabelValues(exchange, currency).Set(amount)
+	}
+}
+
+// Handler is http handler of prometheus text format
+func Handler() (http.Handler) {
+	return promhttp.Handler()
+}
+
+func init() {
+	prometheus.MustRegister(
+		exchangeHTTPRequestDuration,
+		exchangeHTTPRequestErrors,
+		exchangeRateLimitWait,
+		websocketReconnects,
+		websocketMessages,
+		algorithmUpdateDuration,
+		algorithmUpdateErrors,
+		algorithmUpdatesDropped,
+		orders,
+		balance,
+	)
+}
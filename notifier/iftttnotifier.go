@@ -1,33 +1,56 @@
 package notifier
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 
 	"github.com/AutomaticCoinTrader/ACT/utility"
+	"github.com/pkg/errors"
 )
 
 const (
 	EventName = "act_notify"
+
+	iftttChannelName = "ifttt"
 )
 
 type IFTTTNotifierConfig struct {
-	Key string `json:"key" yaml:"key" toml:"key"`
+	Key         string `json:"key"         yaml:"key"         toml:"key"`
+	// 省略時はact_notify
+	Event       string `json:"event"       yaml:"event"       toml:"event"`
+	MinSeverity string `json:"minSeverity" yaml:"minSeverity" toml:"minSeverity"`
 }
 
 type IFTTTNotifier struct {
 	Key        string
+	Event      string
 	httpClient *utility.HTTPClient
 }
 
-// Notify : Send an IFTTT notification via Web Request
-func (n *IFTTTNotifier) Notify(msg string) error {
+func (n *IFTTTNotifier) GetName() string {
+	return iftttChannelName
+}
+
+// Send : Send an IFTTT notification via Web Request. value1 is subject, value2 is body and value3 is severity
+func (n *IFTTTNotifier) Send(notification *Notification) error {
+	body, err := json.Marshal(map[string]string{
+		"value1": notification.Subject,
+		"value2": notification.Body,
+		"value3": string(notification.Severity),
+	})
+	if err != nil {
+		return errors.Wrap(err, "can not marshal values of IFTTT")
+	}
 
+	// Endpoint is set not to expose key in metrics
 	req := &utility.HTTPRequest{
-		URL: fmt.Sprintf("https://maker.ifttt.com/trigger/%s/with/key/%s", EventName, n.Key),
+		URL:      fmt.Sprintf("https://maker.ifttt.com/trigger/%s/with/key/%s", n.Event, n.Key),
+		Headers:  map[string]string{"Content-Type": "application/json"},
+		Body:     string(body),
+		Endpoint: "trigger",
 	}
 
-	resp, _, err := n.httpClient.DoRequest(utility.HTTPMethodGET, req, false)
+	resp, _, err := n.httpClient.DoRequest(utility.HTTPMethdoPOST, req, false)
 	if err != nil {
 		return err
 	}
@@ -39,8 +62,13 @@ func (n *IFTTTNotifier) Notify(msg string) error {
 }
 
 func NewIFTTTNotifier(config *IFTTTNotifierConfig) (*IFTTTNotifier, error) {
+	event := config.Event
+	if event == "" {
+		event = EventName
+	}
 	return &IFTTTNotifier{
 		Key:        config.Key,
+		Event:      event,
 		httpClient: utility.NewHTTPClient(10, 1000, 60, nil),
 	}, nil
 }
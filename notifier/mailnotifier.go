@@ -1,17 +1,11 @@
 package notifier
 
-import "github.com/AutomaticCoinTrader/ACT/utility"
+import (
+	"github.com/AutomaticCoinTrader/ACT/utility"
+	"fmt"
+)
 
-type Notifier struct {
-	smtpClient *utility.SMTPClient
-}
-
-func (n *Notifier) SendMail(subject string, body string) error {
-	if n.smtpClient == nil {
-		return nil
-	}
-	return n.smtpClient.SendMail(subject, body)
-}
+const mailChannelName = "mail"
 
 type MailNotifierConfig struct {
 	HostPort    string `json:"hostPort"    yaml:"hostPort"    toml:"hostPort"`
@@ -22,21 +16,31 @@ type MailNotifierConfig struct {
 	UseStartTLS bool   `json:"useStartTls" yaml:"useStartTls" toml:"useStartTls"`
 	From        string `json:"from"        yaml:"from"        toml:"from"`
 	To          string `json:"to"          yaml:"to"          toml:"to"`
+	// この重要度以上の通知だけ送る。省略時はinfo
+	MinSeverity string `json:"minSeverity" yaml:"minSeverity" toml:"minSeverity"`
 }
 
-type Config struct {
-	Mail  *MailNotifierConfig  `json:"mail" yaml:"mail" toml:"mail"`
-	IFTTT *IFTTTNotifierConfig `json:"ifttt" yaml:"ifttt" toml:"ifttt"`
+// MailNotifier is channel which sends notifications by mail
+type MailNotifier struct {
+	smtpClient *utility.SMTPClient
+}
+
+func (m *MailNotifier) GetName() (string) {
+	return mailChannelName
 }
 
-func NewMailNotifier(config *Config) (*Notifier, error) {
-	var smtpClient *utility.SMTPClient
-	if config != nil {
-		smtpClient = utility.NewSMTPClient(config.Mail.HostPort, config.Mail.Username,
-			config.Mail.Password, utility.GetSMTPAuthType(config.Mail.AuthType),
-			config.Mail.UseTLS, config.Mail.UseStartTLS, config.Mail.From, config.Mail.To)
+func (m *MailNotifier) Send(notification *Notification) (error) {
+	subject := notification.Subject
+	if notification.Severity != SeverityInfo {
+		subject = fmt.Sprintf("[%v] %v", notification.Severity, subject)
 	}
-	return &Notifier{
-		smtpClient: smtpClient,
+	return m.smtpClient.SendMail(subject, notification.Body)
+}
+
+func NewMailNotifier(config *MailNotifierConfig) (*MailNotifier, error) {
+	return &MailNotifier{
+		smtpClient: utility.NewSMTPClient(config.HostPort, config.Username,
+			config.Password, utility.GetSMTPAuthType(config.AuthType),
+			config.UseTLS, config.UseStartTLS, config.From, config.To),
 	}, nil
 }
@@ -0,0 +1,393 @@
+package notifier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxRecentNotifications = 50
+	// 送り先に送っていない通知をこれ以上溜めない
+	notificationQueueSize  = 100
+	// criticalは通常の枠が埋まっていても予約した枠に溜める
+	criticalQueueSize      = 10
+	// 予約した枠も埋まっている場合にcriticalを待つ時間
+	criticalEnqueueTimeout = time.Second
+	closeTimeout           = 10 * time.Second
+)
+
+// Severity is severity of notification
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+func (s Severity) rank() (int) {
+	switch s {
+	case SeverityWarning:
+		return 1
+	case SeverityCritical:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity is parse name of severity. empty is info
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "", "info":
+		return SeverityInfo, nil
+	case "warn", "warning":
+		return SeverityWarning, nil
+	case "critical":
+		return SeverityCritical, nil
+	default:
+		return SeverityInfo, errors.Errorf("unknown severity (severity = %v)", name)
+	}
+}
+
+// Event is event to notify
+type Event struct {
+	// 省略時はinfo
+	Severity Severity
+	Subject  string
+	Body     string
+	// 送信元。アルゴリズムから送る場合はインスタンス名など
+	Source   string
+}
+
+// Notification is notification sent by notifier
+type Notification struct {
+	Time     time.Time `json:"time"`
+	Severity Severity  `json:"severity"`
+	Source   string    `json:"source,omitempty"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+}
+
+// Listener is called with sent notification
+type Listener func(notification *Notification)
+
+// Channel is destination of notifications such as mail
+type Channel interface {
+	GetName() (string)
+	Send(notification *Notification) (error)
+}
+
+type channelEntry struct {
+	channel     Channel
+	minSeverity Severity
+}
+
+type delivery struct {
+	notification *Notification
+	channels     []*channelEntry
+	// Flushの目印。notificationはnil
+	done         chan bool
+}
+
+// Notifier is dispatcher which sends notifications to all channels in background
+type Notifier struct {
+	channels      []*channelEntry
+	recent        []*Notification
+	listeners     []Listener
+	queue         chan *delivery
+	criticalQueue chan *delivery
+	closed        bool
+	finishChan    chan bool
+	clock         clock.Clock
+	mutex         *sync.Mutex
+	logger        *logger.Logger
+}
+
+// SetClock is set clock used for time of notifications
+func (n *Notifier) SetClock(clock clock.Clock) {
+	if n == nil {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.clock = clock
+}
+
+func (n *Notifier) now() (time.Time) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.clock.Now()
+}
+
+// AddChannel is add channel which receives notifications of minSeverity or higher
+func (n *Notifier) AddChannel(channel Channel, minSeverity Severity) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.channels = append(n.channels, &channelEntry{
+		channel:     channel,
+		minSeverity: minSeverity,
+	})
+}
+
+// AddListener is add listener of notifications. listener should return quickly
+func (n *Notifier) AddListener(listener Listener) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.listeners = append(n.listeners, listener)
+}
+
+func (n *Notifier) record(notification *Notification) ([]*channelEntry) {
+	n.mutex.Lock()
+	n.recent = append(n.recent, notification)
+	if len(n.recent) > maxRecentNotifications {
+		n.recent = n.recent[len(n.recent)-maxRecentNotifications:]
+	}
+	listeners := append([]Listener(nil), n.listeners...)
+	channels := append([]*channelEntry(nil), n.channels...)
+	n.mutex.Unlock()
+	for _, listener := range listeners {
+		listener(notification)
+	}
+	return channels
+}
+
+func (n *Notifier) enqueue(d *delivery) (error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		return errors.Errorf("notifier is closed (subject = %v)", d.notification.Subject)
+	}
+	// 注文やpanicの処理を止めないように待たない
+	select {
+	case n.queue <- d:
+		return nil
+	default:
+	}
+	if d.notification.Severity != SeverityCritical {
+		return errors.Errorf("notification queue is full (subject = %v)", d.notification.Subject)
+	}
+	// キルスイッチなどのcriticalは落とさないように予約した枠に入れ、それも埋まっていれば少しだけ待つ
+	select {
+	case n.criticalQueue <- d:
+		return nil
+	default:
+	}
+	select {
+	case n.criticalQueue <- d:
+		return nil
+	case n.queue <- d:
+		return nil
+	case <-time.After(criticalEnqueueTimeout):
+		return errors.Errorf("critical notification queue is full (subject = %v)", d.notification.Subject)
+	}
+}
+
+func (n *Notifier) deliver(d *delivery) {
+	// 1つの送り先が失敗しても残りには送る
+	for _, entry := range d.channels {
+		if d.notification.Severity.rank() < entry.minSeverity.rank() {
+			continue
+		}
+		err := entry.channel.Send(d.notification)
+		if err != nil {
+			n.logger.Errorf("can not send notification (channel = %v, subject = %v, reason = %v)", entry.channel.GetName(), d.notification.Subject, err)
+		}
+	}
+}
+
+func (n *Notifier) drainCritical() {
+	for {
+		select {
+		case d := <-n.criticalQueue:
+			n.deliver(d)
+		default:
+			return
+		}
+	}
+}
+
+func (n *Notifier) run() {
+	for {
+		// criticalを先に送る
+		n.drainCritical()
+		select {
+		case d := <-n.criticalQueue:
+			n.deliver(d)
+		case d := <-n.queue:
+			if d.done != nil {
+				// Flushより前に予約した枠に入ったものも送ってから知らせる
+				n.drainCritical()
+				close(d.done)
+				continue
+			}
+			n.deliver(d)
+		case <-n.finishChan:
+			return
+		}
+	}
+}
+
+// Notify is record event and queue it to channels whose severity filter accepts it. it does not wait for channels
+func (n *Notifier) Notify(event *Event) (error) {
+	if n == nil {
+		return nil
+	}
+	severity := event.Severity
+	if severity == "" {
+		severity = SeverityInfo
+	}
+	notification := &Notification{
+		Time:     n.now(),
+		Severity: severity,
+		Source:   event.Source,
+		Subject:  event.Subject,
+		Body:     event.Body,
+	}
+	// 送り先を設定していなくても画面で見られるように残す
+	channels := n.record(notification)
+	if len(channels) == 0 {
+		return nil
+	}
+	return n.enqueue(&delivery{
+		notification: notification,
+		channels:     channels,
+	})
+}
+
+// Flush is wait until notifications queued before are sent to channels
+func (n *Notifier) Flush(timeout time.Duration) (error) {
+	if n == nil {
+		return nil
+	}
+	timeoutChan := time.After(timeout)
+	done := make(chan bool)
+	select {
+	case n.queue <- &delivery{done: done}:
+	case <-n.finishChan:
+		return nil
+	case <-timeoutChan:
+		return errors.Errorf("can not flush notifications (timeout = %v)", timeout)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-n.finishChan:
+		return nil
+	case <-timeoutChan:
+		return errors.Errorf("can not flush notifications (timeout = %v)", timeout)
+	}
+}
+
+// Close is send queued notifications and stop sending. notifications after close are only recorded
+func (n *Notifier) Close() (error) {
+	if n == nil {
+		return nil
+	}
+	n.mutex.Lock()
+	if n.closed {
+		n.mutex.Unlock()
+		return nil
+	}
+	n.closed = true
+	n.mutex.Unlock()
+	err := n.Flush(closeTimeout)
+	close(n.finishChan)
+	return err
+}
+
+// SendMail is notify with severity info. use Notify to specify severity
+func (n *Notifier) SendMail(subject string, body string) (error) {
+	return n.Notify(&Event{
+		Severity: SeverityInfo,
+		Subject:  subject,
+		Body:     body,
+	})
+}
+
+// GetRecentNotifications is get recent notifications in order of time
+func (n *Notifier) GetRecentNotifications() ([]*Notification) {
+	if n == nil {
+		return make([]*Notification, 0)
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return append(make([]*Notification, 0, len(n.recent)), n.recent...)
+}
+
+type Config struct {
+	Mail     *MailNotifierConfig      `json:"mail"     yaml:"mail"     toml:"mail"`
+	IFTTT    *IFTTTNotifierConfig     `json:"ifttt"    yaml:"ifttt"    toml:"ifttt"`
+	Webhooks []*WebhookNotifierConfig `json:"webhooks" yaml:"webhooks" toml:"webhooks"`
+}
+
+func (n *Notifier) addConfiguredChannel(name string, minSeverity string, newChannel func() (Channel, error)) (error) {
+	severity, err := ParseSeverity(minSeverity)
+	if err != nil {
+		return errors.Wrapf(err, "invalid min severity (channel = %v)", name)
+	}
+	channel, err := newChannel()
+	if err != nil {
+		return errors.Wrapf(err, "can not create channel (channel = %v)", name)
+	}
+	n.AddChannel(channel, severity)
+	return nil
+}
+
+// NewNotifier is create notifier with channels in config. notifications are only recorded if config is nil
+func NewNotifier(config *Config) (*Notifier, error) {
+	n := &Notifier{
+		channels:      make([]*channelEntry, 0),
+		recent:        make([]*Notification, 0),
+		listeners:     make([]Listener, 0),
+		queue:         make(chan *delivery, notificationQueueSize),
+		criticalQueue: make(chan *delivery, criticalQueueSize),
+		finishChan:    make(chan bool),
+		clock:         clock.Default(),
+		mutex:         new(sync.Mutex),
+		logger:        logger.Get("notifier"),
+	}
+	if config == nil {
+		go n.run()
+		return n, nil
+	}
+	// hostPortやkeyが空なら送らない
+	if config.Mail != nil && config.Mail.HostPort != "" {
+		err := n.addConfiguredChannel(mailChannelName, config.Mail.MinSeverity, func() (Channel, error) {
+			return NewMailNotifier(config.Mail)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.IFTTT != nil && config.IFTTT.Key != "" {
+		err := n.addConfiguredChannel(iftttChannelName, config.IFTTT.MinSeverity, func() (Channel, error) {
+			return NewIFTTTNotifier(config.IFTTT)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for idx, webhookConfig := range config.Webhooks {
+		if webhookConfig == nil {
+			continue
+		}
+		name := webhookConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("%v%v", webhookChannelName, idx)
+		}
+		err := n.addConfiguredChannel(name, webhookConfig.MinSeverity, func() (Channel, error) {
+			return NewWebhookNotifier(name, webhookConfig)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	go n.run()
+	return n, nil
+}
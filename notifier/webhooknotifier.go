@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/utility"
+	"encoding/json"
+	"fmt"
+)
+
+const webhookChannelName = "webhook"
+
+type WebhookNotifierConfig struct {
+	// ログとエラーに出す名前。省略時はwebhookと番号
+	Name        string `json:"name"        yaml:"name"        toml:"name"`
+	URL         string `json:"url"         yaml:"url"         toml:"url"`
+	MinSeverity string `json:"minSeverity" yaml:"minSeverity" toml:"minSeverity"`
+}
+
+// webhookMessage is body of webhook. text is for slack compatible services
+type webhookMessage struct {
+	*Notification
+	Text string `json:"text"`
+}
+
+// WebhookNotifier is channel which posts notifications as json
+type WebhookNotifier struct {
+	name       string
+	url        string
+	httpClient *utility.HTTPClient
+}
+
+func (w *WebhookNotifier) GetName() (string) {
+	return w.name
+}
+
+func (w *WebhookNotifier) Send(notification *Notification) (error) {
+	body, err := json.Marshal(&webhookMessage{
+		Notification: notification,
+		Text:         fmt.Sprintf("[%v] %v\n%v", notification.Severity, notification.Subject, notification.Body),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "can not marshal notification (name = %v)", w.name)
+	}
+	// urlにトークンが含まれることがあるのでメトリクスには名前を使う
+	request := &utility.HTTPRequest{
+		URL:      w.url,
+		Headers:  map[string]string{"Content-Type": "application/json"},
+		Body:     string(body),
+		Endpoint: w.name,
+	}
+	_, _, err = w.httpClient.DoRequest(utility.HTTPMethdoPOST, request, false)
+	if err != nil {
+		return errors.Wrapf(err, "can not post notification (name = %v)", w.name)
+	}
+	return nil
+}
+
+func NewWebhookNotifier(name string, config *WebhookNotifierConfig) (*WebhookNotifier, error) {
+	if config.URL == "" {
+		return nil, errors.Errorf("url is required (name = %v)", name)
+	}
+	return &WebhookNotifier{
+		name:       name,
+		url:        config.URL,
+		httpClient: utility.NewHTTPClient(3, 1000, 10, nil),
+	}, nil
+}
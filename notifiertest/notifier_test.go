@@ -0,0 +1,296 @@
+package notifiertest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"github.com/pkg/errors"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingChannel struct {
+	name          string
+	fail          bool
+	notifications []*notifier.Notification
+}
+
+func (r *recordingChannel) GetName() (string) {
+	return r.name
+}
+
+func (r *recordingChannel) Send(notification *notifier.Notification) (error) {
+	r.notifications = append(r.notifications, notification)
+	if r.fail {
+		return errors.New("channel is down")
+	}
+	return nil
+}
+
+func TestNewNotifierWithoutChannels(t *testing.T) {
+	for _, config := range []*notifier.Config{
+		nil,
+		{},
+		{IFTTT: &notifier.IFTTTNotifierConfig{}},
+		{Mail: &notifier.MailNotifierConfig{}},
+	} {
+		n, err := notifier.NewNotifier(config)
+		if err != nil {
+			t.Fatalf("can not create notifier (config = %v, reason = %v)", config, err)
+		}
+		err = n.SendMail("subject", "body")
+		if err != nil {
+			t.Fatalf("can not send mail (config = %v, reason = %v)", config, err)
+		}
+		recent := n.GetRecentNotifications()
+		if len(recent) != 1 || recent[0].Severity != notifier.SeverityInfo || recent[0].Subject != "subject" {
+			t.Fatalf("unexpected recent notifications (config = %v, recent = %v)", config, recent)
+		}
+	}
+}
+
+func TestNotifySeverityFilter(t *testing.T) {
+	n, err := notifier.NewNotifier(nil)
+	if err != nil {
+		t.Fatalf("can not create notifier (reason = %v)", err)
+	}
+	all := &recordingChannel{name: "all"}
+	critical := &recordingChannel{name: "critical"}
+	down := &recordingChannel{name: "down", fail: true}
+	n.AddChannel(down, notifier.SeverityWarning)
+	n.AddChannel(all, notifier.SeverityInfo)
+	n.AddChannel(critical, notifier.SeverityCritical)
+	listened := make([]*notifier.Notification, 0)
+	n.AddListener(func(notification *notifier.Notification) {
+		listened = append(listened, notification)
+	})
+
+	err = n.Notify(&notifier.Event{Subject: "info"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	// 送り先の失敗はログに出すだけで、Notifyは送り終わるのを待たない
+	err = n.Notify(&notifier.Event{Severity: notifier.SeverityWarning, Subject: "warning", Source: "example"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	err = n.Notify(&notifier.Event{Severity: notifier.SeverityCritical, Subject: "critical"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	err = n.Flush(time.Second)
+	if err != nil {
+		t.Fatalf("can not flush (reason = %v)", err)
+	}
+
+	if len(all.notifications) != 3 || all.notifications[0].Severity != notifier.SeverityInfo || all.notifications[1].Source != "example" {
+		t.Fatalf("unexpected notifications of all (notifications = %v)", all.notifications)
+	}
+	if len(critical.notifications) != 1 || critical.notifications[0].Subject != "critical" {
+		t.Fatalf("unexpected notifications of critical (notifications = %v)", critical.notifications)
+	}
+	// 失敗した送り先にも毎回送ろうとする
+	if len(down.notifications) != 2 {
+		t.Fatalf("unexpected notifications of down (notifications = %v)", down.notifications)
+	}
+	if len(listened) != 3 || len(n.GetRecentNotifications()) != 3 {
+		t.Fatalf("unexpected recorded notifications (listened = %v, recent = %v)", listened, n.GetRecentNotifications())
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	mutex := new(sync.Mutex)
+	bodies := make([]map[string]interface{}, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		data, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body := make(map[string]interface{})
+		err = json.Unmarshal(data, &body)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mutex.Lock()
+		bodies = append(bodies, body)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+	n, err := notifier.NewNotifier(&notifier.Config{
+		Webhooks: []*notifier.WebhookNotifierConfig{
+			{Name: "slack", URL: server.URL + "/hooks/token", MinSeverity: "warning"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("can not create notifier (reason = %v)", err)
+	}
+	err = n.Notify(&notifier.Event{Severity: notifier.SeverityInfo, Subject: "ignored"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	err = n.Notify(&notifier.Event{Severity: notifier.SeverityCritical, Subject: "kill switch", Body: "stopped"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	err = n.Close()
+	if err != nil {
+		t.Fatalf("can not close (reason = %v)", err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(bodies) != 1 {
+		t.Fatalf("unexpected webhook requests (bodies = %v)", bodies)
+	}
+	if bodies[0]["severity"] != "critical" || bodies[0]["subject"] != "kill switch" || bodies[0]["text"] != "[critical] kill switch\nstopped" {
+		t.Fatalf("unexpected webhook body (body = %v)", bodies[0])
+	}
+}
+
+// blockingChannel is channel which does not return until released
+type blockingChannel struct {
+	started  chan bool
+	release  chan bool
+	subjects []string
+}
+
+func (b *blockingChannel) GetName() (string) {
+	return "blocking"
+}
+
+func (b *blockingChannel) Send(notification *notifier.Notification) (error) {
+	b.started <- true
+	<-b.release
+	b.subjects = append(b.subjects, notification.Subject)
+	return nil
+}
+
+func TestNotifyDoesNotWaitChannel(t *testing.T) {
+	n, err := notifier.NewNotifier(nil)
+	if err != nil {
+		t.Fatalf("can not create notifier (reason = %v)", err)
+	}
+	channel := &blockingChannel{
+		started: make(chan bool, 1000),
+		release: make(chan bool),
+	}
+	n.AddChannel(channel, notifier.SeverityInfo)
+	err = n.Notify(&notifier.Event{Subject: "first"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	select {
+	case <-channel.started:
+	case <-time.After(time.Second):
+		t.Fatalf("notification is not sent")
+	}
+	// 送り先が止まっていても溜められるだけ溜める
+	notified := make(chan error)
+	go func() {
+		for idx := 0; idx < 100; idx++ {
+			err := n.Notify(&notifier.Event{Subject: fmt.Sprintf("queued%v", idx)})
+			if err != nil {
+				notified <- err
+				return
+			}
+		}
+		notified <- n.Notify(&notifier.Event{Subject: "overflow"})
+	}()
+	select {
+	case err := <-notified:
+		if err == nil || !strings.Contains(err.Error(), "full") {
+			t.Fatalf("overflow is not returned (reason = %v)", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("notify waits channel")
+	}
+	// 送れなかった通知も画面では見られる
+	recent := n.GetRecentNotifications()
+	if recent[len(recent)-1].Subject != "overflow" {
+		t.Fatalf("overflow is not recorded (recent = %v)", recent[len(recent)-1])
+	}
+	// criticalは予約した枠に入る
+	for idx := 0; idx < 10; idx++ {
+		err := n.Notify(&notifier.Event{Severity: notifier.SeverityCritical, Subject: fmt.Sprintf("critical%v", idx)})
+		if err != nil {
+			t.Fatalf("critical notification is dropped (reason = %v)", err)
+		}
+	}
+	// 予約した枠も埋まっていれば少し待ってから諦める
+	go func() {
+		notified <- n.Notify(&notifier.Event{Severity: notifier.SeverityCritical, Subject: "criticalOverflow"})
+	}()
+	select {
+	case err := <-notified:
+		if err == nil || !strings.Contains(err.Error(), "full") {
+			t.Fatalf("critical overflow is not returned (reason = %v)", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("notify waits channel")
+	}
+	if n.Flush(100*time.Millisecond) == nil {
+		t.Fatalf("flush does not time out")
+	}
+	close(channel.release)
+	err = n.Close()
+	if err != nil {
+		t.Fatalf("can not close (reason = %v)", err)
+	}
+	// criticalを先に送る
+	if len(channel.subjects) != 111 || channel.subjects[0] != "first" || channel.subjects[1] != "critical0" ||
+		channel.subjects[10] != "critical9" || channel.subjects[110] != "queued99" {
+		t.Fatalf("unexpected sent notifications (count = %v)", len(channel.subjects))
+	}
+	// 閉じた後は記録だけする
+	err = n.Notify(&notifier.Event{Subject: "closed"})
+	if err == nil {
+		t.Fatalf("notification after close is accepted")
+	}
+	if len(channel.subjects) != 111 {
+		t.Fatalf("notification after close is sent (count = %v)", len(channel.subjects))
+	}
+}
+
+func TestNotifyClock(t *testing.T) {
+	n, err := notifier.NewNotifier(nil)
+	if err != nil {
+		t.Fatalf("can not create notifier (reason = %v)", err)
+	}
+	defer n.Close()
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := clock.NewSimulatedClock(start)
+	n.SetClock(c)
+	err = n.Notify(&notifier.Event{Subject: "first"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	c.Advance(time.Minute)
+	err = n.Notify(&notifier.Event{Subject: "second"})
+	if err != nil {
+		t.Fatalf("can not notify (reason = %v)", err)
+	}
+	recent := n.GetRecentNotifications()
+	if !recent[0].Time.Equal(start) || !recent[1].Time.Equal(start.Add(time.Minute)) {
+		t.Fatalf("time of notification is not clock time (recent = %v, %v)", recent[0].Time, recent[1].Time)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, config := range []*notifier.Config{
+		{Mail: &notifier.MailNotifierConfig{HostPort: "127.0.0.1:25", MinSeverity: "fatal"}},
+		{IFTTT: &notifier.IFTTTNotifierConfig{Key: "key", MinSeverity: "fatal"}},
+		{Webhooks: []*notifier.WebhookNotifierConfig{{Name: "nourl"}}},
+	} {
+		_, err := notifier.NewNotifier(config)
+		if err == nil {
+			t.Fatalf("invalid config is accepted (config = %v)", config)
+		}
+	}
+}
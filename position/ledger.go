@@ -0,0 +1,170 @@
+package position
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// これより小さい数量は丸め誤差とみなす
+	amountEpsilon = 0.00000001
+)
+
+// Ledger records fills of algorithm instances and keeps their positions
+type Ledger struct {
+	store     algorithm.StateStore
+	positions map[string]map[string]*algorithm.Position
+	mutex     *sync.Mutex
+}
+
+func positionKey(exchangeName string, currencyPair string) (string) {
+	return exchangeName + "/" + currencyPair
+}
+
+func copyPosition(position *algorithm.Position) (*algorithm.Position) {
+	p := *position
+	return &p
+}
+
+// applyFill is update position by fill and returns realized profit of the fill
+func applyFill(position *algorithm.Position, amount float64, price float64, fee float64) (float64) {
+	position.Fees += fee
+	position.Fills++
+	if position.Amount == 0 || (position.Amount > 0) == (amount > 0) {
+		// 建玉を増やす
+		total := position.Amount + amount
+		position.AveragePrice = (position.AveragePrice*math.Abs(position.Amount) + price*math.Abs(amount)) / math.Abs(total)
+		position.Amount = total
+		return 0
+	}
+	// 建玉を減らす
+	closed := math.Min(math.Abs(amount), math.Abs(position.Amount))
+	var realizedPnL float64
+	if position.Amount > 0 {
+		realizedPnL = (price - position.AveragePrice) * closed
+	} else {
+		realizedPnL = (position.AveragePrice - price) * closed
+	}
+	position.RealizedPnL += realizedPnL
+	position.Amount += amount
+	if math.Abs(position.Amount) < amountEpsilon {
+		position.Amount = 0
+		position.AveragePrice = 0
+	} else if (position.Amount > 0) == (amount > 0) {
+		// ドテンした分は約定価格で建てたことになる
+		position.AveragePrice = price
+	}
+	return realizedPnL
+}
+
+// Mark is set last price and unrealized profit of position
+func Mark(position *algorithm.Position, lastPrice float64) {
+	position.LastPrice = lastPrice
+	if position.Amount == 0 {
+		position.UnrealizedPnL = 0
+		return
+	}
+	position.UnrealizedPnL = (lastPrice - position.AveragePrice) * position.Amount
+}
+
+// AddFill is record fill of algorithm instance. it returns updated position and profit of the fill (realized profit - fee)
+func (l *Ledger) AddFill(name string, exchangeName string, currencyPair string, action exchange.OrderAction, price float64, amount float64, fee float64) (*algorithm.Position, float64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	positions, ok := l.positions[name]
+	if !ok {
+		positions = make(map[string]*algorithm.Position)
+		l.positions[name] = positions
+	}
+	key := positionKey(exchangeName, currencyPair)
+	position, ok := positions[key]
+	if !ok {
+		position = &algorithm.Position{
+			Exchange:     exchangeName,
+			CurrencyPair: currencyPair,
+		}
+		positions[key] = position
+	}
+	var realizedPnL float64
+	switch action {
+	case exchange.OrderActBuy:
+		realizedPnL = applyFill(position, amount, price, fee)
+	case exchange.OrderActSell:
+		realizedPnL = applyFill(position, -amount, price, fee)
+	default:
+		return nil, 0, errors.Errorf("unexpected order action (name = %v, action = %v)", name, action)
+	}
+	err := l.store.PutJSON(name, positions)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "can not save positions (name = %v)", name)
+	}
+	return copyPosition(position), realizedPnL - fee, nil
+}
+
+// GetPositions is get positions of algorithm instance
+func (l *Ledger) GetPositions(name string) ([]*algorithm.Position) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	keys := make([]string, 0, len(l.positions[name]))
+	for key := range l.positions[name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	positions := make([]*algorithm.Position, 0, len(keys))
+	for _, key := range keys {
+		positions = append(positions, copyPosition(l.positions[name][key]))
+	}
+	return positions
+}
+
+// GetPosition is get position of algorithm instance. it returns empty position if there is no fill
+func (l *Ledger) GetPosition(name string, exchangeName string, currencyPair string) (*algorithm.Position) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	position, ok := l.positions[name][positionKey(exchangeName, currencyPair)]
+	if !ok {
+		return &algorithm.Position{
+			Exchange:     exchangeName,
+			CurrencyPair: currencyPair,
+		}
+	}
+	return copyPosition(position)
+}
+
+// GetNames is get names of algorithm instances that have positions
+func (l *Ledger) GetNames() ([]string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	names := make([]string, 0, len(l.positions))
+	for name := range l.positions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLedger is create ledger and load positions from store
+func NewLedger(store algorithm.StateStore) (*Ledger, error) {
+	l := &Ledger{
+		store:     store,
+		positions: make(map[string]map[string]*algorithm.Position),
+		mutex:     new(sync.Mutex),
+	}
+	names, err := store.Keys()
+	if err != nil {
+		return nil, errors.Wrap(err, "can not load positions")
+	}
+	for _, name := range names {
+		positions := make(map[string]*algorithm.Position)
+		_, err := store.GetJSON(name, &positions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not load positions (name = %v)", name)
+		}
+		l.positions[name] = positions
+	}
+	return l, nil
+}
@@ -0,0 +1,98 @@
+package positiontest
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/position"
+	"github.com/AutomaticCoinTrader/ACT/state"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"testing"
+)
+
+func almostEqual(a float64, b float64) (bool) {
+	return math.Abs(a-b) < 0.0000001
+}
+
+func TestLedger(t *testing.T) {
+	store, err := state.NewStore("")
+	if err != nil {
+		t.Fatalf("can not create store (reason = %v)", err)
+	}
+	ledger, err := position.NewLedger(store.Namespace("positions"))
+	if err != nil {
+		t.Fatalf("can not create ledger (reason = %v)", err)
+	}
+	ledger.AddFill("a", "zaif", "btc_jpy", exchange.OrderActBuy, 100, 1, 0.1)
+	ledger.AddFill("a", "zaif", "btc_jpy", exchange.OrderActBuy, 130, 2, 0.2)
+	p := ledger.GetPosition("a", "zaif", "btc_jpy")
+	if !almostEqual(p.Amount, 3) || !almostEqual(p.AveragePrice, 120) || !almostEqual(p.Fees, 0.3) || p.Fills != 2 {
+		t.Fatalf("unexpected position after buy (%+v)", p)
+	}
+	// 一部決済
+	_, pnl, _ := ledger.AddFill("a", "zaif", "btc_jpy", exchange.OrderActSell, 150, 1, 0.5)
+	if !almostEqual(pnl, 29.5) {
+		t.Fatalf("unexpected pnl of fill (%v)", pnl)
+	}
+	p = ledger.GetPosition("a", "zaif", "btc_jpy")
+	if !almostEqual(p.Amount, 2) || !almostEqual(p.AveragePrice, 120) || !almostEqual(p.RealizedPnL, 30) || !almostEqual(p.Fees, 0.8) {
+		t.Fatalf("unexpected position after partial close (%+v)", p)
+	}
+	position.Mark(p, 110)
+	if !almostEqual(p.UnrealizedPnL, -20) {
+		t.Fatalf("unexpected unrealized pnl (%+v)", p)
+	}
+	// ドテン
+	ledger.AddFill("a", "zaif", "btc_jpy", exchange.OrderActSell, 100, 3, 0)
+	p = ledger.GetPosition("a", "zaif", "btc_jpy")
+	if !almostEqual(p.Amount, -1) || !almostEqual(p.AveragePrice, 100) || !almostEqual(p.RealizedPnL, -10) {
+		t.Fatalf("unexpected position after reverse (%+v)", p)
+	}
+	position.Mark(p, 90)
+	if !almostEqual(p.UnrealizedPnL, 10) {
+		t.Fatalf("unexpected unrealized pnl of short position (%+v)", p)
+	}
+	ledger.AddFill("a", "zaif", "btc_jpy", exchange.OrderActBuy, 90, 1, 0)
+	p = ledger.GetPosition("a", "zaif", "btc_jpy")
+	if p.Amount != 0 || p.AveragePrice != 0 || !almostEqual(p.RealizedPnL, 0) {
+		t.Fatalf("unexpected position after close (%+v)", p)
+	}
+	if len(ledger.GetPositions("b")) != 0 {
+		t.Fatalf("position of other instance exists")
+	}
+}
+
+func TestLedgerPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "positiontest")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := state.NewStore(path.Join(dir, "state.db"))
+	if err != nil {
+		t.Fatalf("can not create store (reason = %v)", err)
+	}
+	ledger, err := position.NewLedger(store.Namespace("positions"))
+	if err != nil {
+		t.Fatalf("can not create ledger (reason = %v)", err)
+	}
+	_, _, err = ledger.AddFill("a", "zaif", "btc_jpy", exchange.OrderActBuy, 100, 2, 0)
+	if err != nil {
+		t.Fatalf("can not add fill (reason = %v)", err)
+	}
+	store.Close()
+	store, err = state.NewStore(path.Join(dir, "state.db"))
+	if err != nil {
+		t.Fatalf("can not open store again (reason = %v)", err)
+	}
+	defer store.Close()
+	ledger, err = position.NewLedger(store.Namespace("positions"))
+	if err != nil {
+		t.Fatalf("can not create ledger again (reason = %v)", err)
+	}
+	positions := ledger.GetPositions("a")
+	if len(positions) != 1 || positions[0].Amount != 2 || positions[0].AveragePrice != 100 {
+		t.Fatalf("positions are not restored (%v)", positions)
+	}
+}
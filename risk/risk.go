@@ -0,0 +1,317 @@
+package risk
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RuleMaxOrderNotional   = "maxOrderNotional"
+	RuleMaxPosition        = "maxPosition"
+	RuleMaxOpenOrders      = "maxOpenOrders"
+	RulePriceBand          = "priceBand"
+	RuleMaxOrdersPerMinute = "maxOrdersPerMinute"
+	RuleDailyLossLimit     = "dailyLossLimit"
+	RuleInvalidOrder       = "invalidOrder"
+	RuleKillSwitch         = "killSwitch"
+)
+
+const (
+	// 同じルールの違反を通知する間隔
+	notifyInterval = time.Minute
+	dailyPnLKey    = "dailyPnL"
+)
+
+// Config is limits of orders. zero means no limit
+type Config struct {
+	// 1注文の金額 (価格 x 数量) の上限
+	MaxOrderNotional   float64            `json:"maxOrderNotional"   yaml:"maxOrderNotional"   toml:"maxOrderNotional"`
+	// 通貨ごとの建玉の上限 (btc: 0.5など)
+	MaxPosition        map[string]float64 `json:"maxPosition"        yaml:"maxPosition"        toml:"maxPosition"`
+	// 取引所ごとの未約定の注文数の上限
+	MaxOpenOrders      int                `json:"maxOpenOrders"      yaml:"maxOpenOrders"      toml:"maxOpenOrders"`
+	// 最終価格からの乖離の上限 (%)
+	PriceBand          float64            `json:"priceBand"          yaml:"priceBand"          toml:"priceBand"`
+	MaxOrdersPerMinute int                `json:"maxOrdersPerMinute" yaml:"maxOrdersPerMinute" toml:"maxOrdersPerMinute"`
+	// 1日 (UTC) の損失 (確定損益 - 手数料) の上限。超えたらその日は新規の注文を出さない
+	DailyLossLimit     float64            `json:"dailyLossLimit"     yaml:"dailyLossLimit"     toml:"dailyLossLimit"`
+}
+
+// Validate is check config
+func (c *Config) Validate() (error) {
+	if c.MaxOrderNotional < 0 || c.MaxOpenOrders < 0 || c.PriceBand < 0 || c.MaxOrdersPerMinute < 0 || c.DailyLossLimit < 0 {
+		return errors.New("negative limit in risk config")
+	}
+	for currency, maxPosition := range c.MaxPosition {
+		if maxPosition < 0 {
+			return errors.Errorf("negative max position in risk config (currency = %v)", currency)
+		}
+	}
+	return nil
+}
+
+// Error is error of order rejected by risk manager
+type Error struct {
+	Rule    string
+	Message string
+	Order   *Order
+}
+
+func (e *Error) Error() (string) {
+	return fmt.Sprintf("order is rejected by risk manager (rule = %v, name = %v, exchange = %v, currency pair = %v, action = %v, price = %v, amount = %v, reason = %v)",
+		e.Rule, e.Order.Name, e.Order.Exchange, e.Order.CurrencyPair, e.Order.Action, e.Order.Price, e.Order.Amount, e.Message)
+}
+
+// GetRiskError is get risk error from error that may be wrapped
+func GetRiskError(err error) (*Error, bool) {
+	riskError, ok := errors.Cause(err).(*Error)
+	return riskError, ok
+}
+
+// Order is order checked by risk manager
+type Order struct {
+	Name         string
+	Exchange     string
+	CurrencyPair string
+	Action       exchange.OrderAction
+	Price        float64
+	Amount       float64
+}
+
+// Exposure is current state of exchange used for checks
+type Exposure struct {
+	LastPrice  float64
+	OpenOrders int
+	// 注文の通貨ペアの基軸通貨の建玉
+	Position   float64
+	// 注文の通貨ペアの基軸通貨の未約定の注文の数量
+	OpenBuy    float64
+	OpenSell   float64
+}
+
+// Store is persistent store of daily pnl
+type Store interface {
+	GetJSON(key string, value interface{}) (bool, error)
+	PutJSON(key string, value interface{}) (error)
+}
+
+type dailyPnL struct {
+	Day string  `json:"day"`
+	PnL float64 `json:"pnl"`
+}
+
+// orderTime is time of order passed Check
+type orderTime struct {
+	time  time.Time
+	order *Order
+}
+
+// Manager checks orders before they are sent to exchange
+type Manager struct {
+	config       *Config
+	clock        clock.Clock
+	orderTimes   []*orderTime
+	day          string
+	dailyPnL     float64
+	store        Store
+	lastNotified map[string]time.Time
+	mutex        *sync.Mutex
+}
+
+// GetConfig is get limits
+func (m *Manager) GetConfig() (*Config) {
+	return m.config
+}
+
+// SetClock is set clock used for orders per minute and daily loss
+func (m *Manager) SetClock(clock clock.Clock) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.clock = clock
+}
+
+// SetStore is restore daily pnl from store and persist it on every change so that restart does not reset daily loss
+func (m *Manager) SetStore(store Store) (error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	saved := new(dailyPnL)
+	ok, err := store.GetJSON(dailyPnLKey, saved)
+	if err != nil {
+		return errors.Wrap(err, "can not load daily pnl")
+	}
+	if ok {
+		m.day = saved.Day
+		m.dailyPnL = saved.PnL
+	}
+	m.store = store
+	return nil
+}
+
+// NeedLastPrice is check that exposure needs last price
+func (m *Manager) NeedLastPrice() (bool) {
+	return m.config.PriceBand > 0
+}
+
+// NewError is create risk error
+func NewError(rule string, order *Order, format string, args ...interface{}) (error) {
+	return &Error{
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+		Order:   order,
+	}
+}
+
+// resetDay is reset daily pnl on date change. mutex must be locked
+func (m *Manager) resetDay(now time.Time) {
+	day := now.UTC().Format("2006-01-02")
+	if m.day != day {
+		m.day = day
+		m.dailyPnL = 0
+	}
+}
+
+// Check is check order and returns *Error if order violates limits
+func (m *Manager) Check(order *Order, exposure *Exposure) (error) {
+	if order.Price <= 0 || order.Amount <= 0 {
+		return NewError(RuleInvalidOrder, order, "price and amount must be positive")
+	}
+	if m.config.MaxOrderNotional > 0 && order.Price*order.Amount > m.config.MaxOrderNotional {
+		return NewError(RuleMaxOrderNotional, order, "notional %v exceeds %v", order.Price*order.Amount, m.config.MaxOrderNotional)
+	}
+	currency := BaseCurrency(order.CurrencyPair)
+	maxPosition, ok := m.config.MaxPosition[currency]
+	if ok && maxPosition > 0 {
+		// 同じ向きの未約定の注文も全部約定したとみなす
+		current := exposure.Position
+		if order.Action == exchange.OrderActBuy {
+			current += exposure.OpenBuy
+		} else {
+			current -= exposure.OpenSell
+		}
+		position := current
+		if order.Action == exchange.OrderActBuy {
+			position += order.Amount
+		} else {
+			position -= order.Amount
+		}
+		// 建玉を減らす注文は通す
+		if math.Abs(position) > maxPosition && math.Abs(position) > math.Abs(current) {
+			return NewError(RuleMaxPosition, order, "position of %v with open orders becomes %v, limit is %v", currency, position, maxPosition)
+		}
+	}
+	if m.config.MaxOpenOrders > 0 && exposure.OpenOrders >= m.config.MaxOpenOrders {
+		return NewError(RuleMaxOpenOrders, order, "%v open orders, limit is %v", exposure.OpenOrders, m.config.MaxOpenOrders)
+	}
+	if m.config.PriceBand > 0 {
+		if exposure.LastPrice <= 0 {
+			// 価格が分からなければ確認できないので通さない
+			return NewError(RulePriceBand, order, "no last price to check price band")
+		}
+		deviation := math.Abs(order.Price-exposure.LastPrice) / exposure.LastPrice * 100
+		if deviation > m.config.PriceBand {
+			return NewError(RulePriceBand, order, "price deviates %v%% from last price %v, limit is %v%%", deviation, exposure.LastPrice, m.config.PriceBand)
+		}
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := m.clock.Now()
+	m.resetDay(now)
+	if m.config.DailyLossLimit > 0 && -m.dailyPnL >= m.config.DailyLossLimit {
+		return NewError(RuleDailyLossLimit, order, "daily loss %v reaches %v", -m.dailyPnL, m.config.DailyLossLimit)
+	}
+	if m.config.MaxOrdersPerMinute > 0 {
+		orderTimes := make([]*orderTime, 0, len(m.orderTimes)+1)
+		for _, orderTime := range m.orderTimes {
+			if now.Sub(orderTime.time) < time.Minute {
+				orderTimes = append(orderTimes, orderTime)
+			}
+		}
+		m.orderTimes = orderTimes
+		if len(m.orderTimes) >= m.config.MaxOrdersPerMinute {
+			return NewError(RuleMaxOrdersPerMinute, order, "%v orders in last minute, limit is %v", len(m.orderTimes), m.config.MaxOrdersPerMinute)
+		}
+		// 発注に失敗したらReleaseで戻す
+		m.orderTimes = append(m.orderTimes, &orderTime{time: now, order: order})
+	}
+	return nil
+}
+
+// Release is release order checked by Check when it is not placed. it is not counted in orders per minute
+func (m *Manager) Release(order *Order) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for idx, orderTime := range m.orderTimes {
+		if orderTime.order == order {
+			m.orderTimes = append(m.orderTimes[:idx], m.orderTimes[idx+1:]...)
+			return
+		}
+	}
+}
+
+// AddPnL is add realized profit (negative is loss) for daily loss limit
+func (m *Manager) AddPnL(pnl float64) (error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.resetDay(m.clock.Now())
+	m.dailyPnL += pnl
+	if m.store == nil {
+		return nil
+	}
+	err := m.store.PutJSON(dailyPnLKey, &dailyPnL{Day: m.day, PnL: m.dailyPnL})
+	if err != nil {
+		return errors.Wrap(err, "can not save daily pnl")
+	}
+	return nil
+}
+
+// GetDailyPnL is get realized profit of today
+func (m *Manager) GetDailyPnL() (float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.resetDay(m.clock.Now())
+	return m.dailyPnL
+}
+
+// ShouldNotify is check that violation of rule should be notified. same rule is notified once in notifyInterval
+func (m *Manager) ShouldNotify(rule string) (bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := m.clock.Now()
+	lastNotified, ok := m.lastNotified[rule]
+	if ok && now.Sub(lastNotified) < notifyInterval {
+		return false
+	}
+	m.lastNotified[rule] = now
+	return true
+}
+
+// BaseCurrency is get base currency of currency pair (btc of btc_jpy)
+func BaseCurrency(currencyPair string) (string) {
+	return strings.ToLower(strings.Split(currencyPair, "_")[0])
+}
+
+// NewManager is create risk manager
+func NewManager(config *Config, clock clock.Clock) (*Manager) {
+	if config == nil {
+		config = new(Config)
+	}
+	maxPosition := make(map[string]float64)
+	for currency, amount := range config.MaxPosition {
+		maxPosition[strings.ToLower(currency)] = amount
+	}
+	c := *config
+	c.MaxPosition = maxPosition
+	return &Manager{
+		config:       &c,
+		clock:        clock,
+		orderTimes:   make([]*orderTime, 0),
+		lastNotified: make(map[string]time.Time),
+		mutex:        new(sync.Mutex),
+	}
+}
@@ -0,0 +1,177 @@
+package risktest
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	values map[string][]byte
+}
+
+func (m *memoryStore) GetJSON(key string, value interface{}) (bool, error) {
+	data, ok := m.values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, value)
+}
+
+func (m *memoryStore) PutJSON(key string, value interface{}) (error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.values[key] = data
+	return nil
+}
+
+func newOrder(action exchange.OrderAction, price float64, amount float64) (*risk.Order) {
+	return &risk.Order{
+		Name:         "test",
+		Exchange:     "zaif",
+		CurrencyPair: "btc_jpy",
+		Action:       action,
+		Price:        price,
+		Amount:       amount,
+	}
+}
+
+func expectRule(t *testing.T, err error, rule string) {
+	if rule == "" {
+		if err != nil {
+			t.Fatalf("order is rejected (reason = %v)", err)
+		}
+		return
+	}
+	riskError, ok := risk.GetRiskError(errors.Wrap(err, "wrapped"))
+	if !ok {
+		t.Fatalf("risk error is not returned (expected rule = %v, reason = %v)", rule, err)
+	}
+	if riskError.Rule != rule {
+		t.Fatalf("unexpected rule (expected = %v, actual = %v)", rule, riskError.Rule)
+	}
+}
+
+func TestRiskLimits(t *testing.T) {
+	simulatedClock := clock.NewSimulatedClock(time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC))
+	manager := risk.NewManager(&risk.Config{
+		MaxOrderNotional: 10000,
+		MaxPosition:      map[string]float64{"BTC": 2},
+		MaxOpenOrders:    3,
+		PriceBand:        5,
+	}, simulatedClock)
+	exposure := &risk.Exposure{LastPrice: 1000, OpenOrders: 0, Position: 0}
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 0), exposure), risk.RuleInvalidOrder)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 11), exposure), risk.RuleMaxOrderNotional)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1100, 1), exposure), risk.RulePriceBand)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActSell, 900, 1), exposure), risk.RulePriceBand)
+	exposure.Position = 1.5
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), risk.RuleMaxPosition)
+	// 建玉を減らす注文は通す
+	expectRule(t, manager.Check(newOrder(exchange.OrderActSell, 1000, 1), exposure), "")
+	// 未約定の買い注文も建玉に含める
+	exposure.Position = 0.5
+	exposure.OpenBuy = 1
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), risk.RuleMaxPosition)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 0.5), exposure), "")
+	exposure.Position = -1.5
+	exposure.OpenBuy = 0
+	exposure.OpenSell = 0.4
+	expectRule(t, manager.Check(newOrder(exchange.OrderActSell, 1000, 0.2), exposure), risk.RuleMaxPosition)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+	exposure.Position = 0
+	exposure.OpenSell = 0
+	exposure.OpenOrders = 3
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), risk.RuleMaxOpenOrders)
+	// 最終価格が取れなければ価格の乖離を確認できないので拒否する
+	exposure.OpenOrders = 0
+	exposure.LastPrice = 0
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), risk.RulePriceBand)
+}
+
+func TestRiskOrdersPerMinute(t *testing.T) {
+	simulatedClock := clock.NewSimulatedClock(time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC))
+	manager := risk.NewManager(&risk.Config{MaxOrdersPerMinute: 2}, simulatedClock)
+	exposure := &risk.Exposure{}
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), risk.RuleMaxOrdersPerMinute)
+	simulatedClock.Advance(time.Minute)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+	// 出せなかった注文は数えない
+	failed := newOrder(exchange.OrderActBuy, 1000, 1)
+	expectRule(t, manager.Check(failed, exposure), "")
+	manager.Release(failed)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), risk.RuleMaxOrdersPerMinute)
+}
+
+func TestRiskDailyLossLimit(t *testing.T) {
+	simulatedClock := clock.NewSimulatedClock(time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC))
+	manager := risk.NewManager(&risk.Config{DailyLossLimit: 100}, simulatedClock)
+	exposure := &risk.Exposure{}
+	manager.AddPnL(50)
+	manager.AddPnL(-149)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+	manager.AddPnL(-1)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), risk.RuleDailyLossLimit)
+	if !manager.ShouldNotify(risk.RuleDailyLossLimit) || manager.ShouldNotify(risk.RuleDailyLossLimit) {
+		t.Fatalf("same violation should be notified once")
+	}
+	// 日付が変わったらリセット
+	simulatedClock.Advance(12 * time.Hour)
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), exposure), "")
+}
+
+func TestRiskDailyPnLStore(t *testing.T) {
+	simulatedClock := clock.NewSimulatedClock(time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := &memoryStore{values: make(map[string][]byte)}
+	manager := risk.NewManager(&risk.Config{DailyLossLimit: 100}, simulatedClock)
+	err := manager.SetStore(store)
+	if err != nil {
+		t.Fatalf("can not set store (reason = %v)", err)
+	}
+	err = manager.AddPnL(-100)
+	if err != nil {
+		t.Fatalf("can not add pnl (reason = %v)", err)
+	}
+	// 再起動しても損失は残る
+	manager = risk.NewManager(&risk.Config{DailyLossLimit: 100}, simulatedClock)
+	err = manager.SetStore(store)
+	if err != nil {
+		t.Fatalf("can not set store again (reason = %v)", err)
+	}
+	if manager.GetDailyPnL() != -100 {
+		t.Fatalf("daily pnl is not restored (pnl = %v)", manager.GetDailyPnL())
+	}
+	expectRule(t, manager.Check(newOrder(exchange.OrderActBuy, 1000, 1), &risk.Exposure{}), risk.RuleDailyLossLimit)
+	// 翌日の再起動ではリセット
+	simulatedClock.Advance(12 * time.Hour)
+	manager = risk.NewManager(&risk.Config{DailyLossLimit: 100}, simulatedClock)
+	err = manager.SetStore(store)
+	if err != nil {
+		t.Fatalf("can not set store again (reason = %v)", err)
+	}
+	if manager.GetDailyPnL() != 0 {
+		t.Fatalf("daily pnl of yesterday is restored (pnl = %v)", manager.GetDailyPnL())
+	}
+}
+
+func TestInvalidRiskConfig(t *testing.T) {
+	configs := []*risk.Config{
+		{MaxOrderNotional: -1},
+		{MaxPosition: map[string]float64{"btc": -1}},
+	}
+	for _, config := range configs {
+		if config.Validate() == nil {
+			t.Fatalf("invalid config is accepted (config = %+v)", config)
+		}
+	}
+}
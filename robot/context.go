@@ -0,0 +1,66 @@
+package robot
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/configurator"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"fmt"
+	"path"
+)
+
+// algorithmConfigAccessor is implementation of algorithm.ConfigAccessor
+type algorithmConfigAccessor struct {
+	configDir     string
+	algorithmName string
+}
+
+func (a *algorithmConfigAccessor) GetConfigDir() (string) {
+	return a.configDir
+}
+
+func (a *algorithmConfigAccessor) Load(data interface{}) (error) {
+	cf, err := configurator.NewConfigurator(path.Join(a.configDir, a.algorithmName))
+	if err != nil {
+		return err
+	}
+	return cf.Load(data)
+}
+
+func (r *Robot) newAlgorithmContext(info *algorithmInstanceInfo, label string, exchanges map[string]exchange.Exchange, store algorithm.StateStore) (*algorithm.Context) {
+	// 約定をインスタンスに紐付けるため注文を記録する取引所を渡す
+	trackingExchanges := make(map[string]exchange.Exchange)
+	for name, ex := range exchanges {
+		trackingExchanges[name] = r.newTrackingExchange(info.name, ex)
+	}
+	return &algorithm.Context{
+		Version:       algorithm.ContextVersion,
+		Name:          info.name,
+		AlgorithmName: info.algorithmName,
+		Exchanges:     trackingExchanges,
+		Notifier:      r.notifier,
+		Logger:        logger.Get("algorithm").WithField(logger.FieldAlgorithm, info.name).StdLogger(fmt.Sprintf("[%v] ", label)),
+		Clock:         r.clock,
+		Config: &algorithmConfigAccessor{
+			configDir:     info.configDir,
+			algorithmName: info.algorithmName,
+		},
+		State: store,
+		Positions: &instancePositionLedger{
+			robot:     r,
+			name:      info.name,
+			exchanges: exchanges,
+		},
+	}
+}
+
+func (r *Robot) newInternalAlgorithmContext(info *algorithmInstanceInfo, ex exchange.Exchange) (*algorithm.Context) {
+	ctx := r.newAlgorithmContext(info, fmt.Sprintf("%v@%v", info.name, ex.GetName()),
+		map[string]exchange.Exchange{ex.GetName(): ex}, r.internalStateNamespace(info.name, ex.GetName()))
+	ctx.Exchange = ctx.Exchanges[ex.GetName()]
+	return ctx
+}
+
+func (r *Robot) newExternalAlgorithmContext(info *algorithmInstanceInfo, exchanges map[string]exchange.Exchange) (*algorithm.Context) {
+	return r.newAlgorithmContext(info, info.name, r.scopedExchanges(info.scope, exchanges), r.externalStateNamespace(info.name))
+}
@@ -0,0 +1,219 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/configurator"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+func (r *Robot) isAlgorithmPaused(name string) (bool) {
+	r.pauseMutex.Lock()
+	defer r.pauseMutex.Unlock()
+	return r.pausedAlgorithms[name]
+}
+
+func (r *Robot) getRunners(name string) ([]*algorithmRunner) {
+	runners := make([]*algorithmRunner, 0)
+	for _, runner := range r.getAllRunners() {
+		if runner.name == name {
+			runners = append(runners, runner)
+		}
+	}
+	return runners
+}
+
+func (r *Robot) setAlgorithmPaused(name string, paused bool) (error) {
+	runners := r.getRunners(name)
+	if len(runners) == 0 {
+		return errors.Errorf("not found algorithm instance (name = %v)", name)
+	}
+	r.pauseMutex.Lock()
+	if paused {
+		r.pausedAlgorithms[name] = true
+	} else {
+		delete(r.pausedAlgorithms, name)
+	}
+	r.pauseMutex.Unlock()
+	for _, runner := range runners {
+		runner.setPaused(paused)
+	}
+	return nil
+}
+
+// PauseAlgorithm is stop passing updates to algorithm instance. streaming and other algorithms continue
+func (r *Robot) PauseAlgorithm(name string) (error) {
+	err := r.setAlgorithmPaused(name, true)
+	if err != nil {
+		return err
+	}
+	r.logger.WithField(logger.FieldAlgorithm, name).Infof("algorithm is paused")
+	return nil
+}
+
+// ResumeAlgorithm is resume updates of paused algorithm instance
+func (r *Robot) ResumeAlgorithm(name string) (error) {
+	err := r.setAlgorithmPaused(name, false)
+	if err != nil {
+		return err
+	}
+	r.logger.WithField(logger.FieldAlgorithm, name).Infof("algorithm is resumed")
+	return nil
+}
+
+// GetAlgorithmStatsByName is get statistics of algorithm instance. internal algorithm has one per exchange
+func (r *Robot) GetAlgorithmStatsByName(name string) ([]*AlgorithmStats, error) {
+	stats := make([]*AlgorithmStats, 0)
+	for _, s := range r.GetAlgorithmStats() {
+		if s.Name == name {
+			stats = append(stats, s)
+		}
+	}
+	if len(stats) == 0 {
+		return nil, errors.Errorf("not found algorithm instance (name = %v)", name)
+	}
+	return stats, nil
+}
+
+func (r *Robot) getAlgorithmInstanceInfo(name string) (*algorithmInstanceInfo, bool) {
+	for _, info := range r.getAlgorithmInstanceInfos() {
+		if info.name == name {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+const (
+	// ReconfigureErrorNotFound is kind of reconfigure error that algorithm instance is not found
+	ReconfigureErrorNotFound = "notFound"
+	// ReconfigureErrorSave is kind of reconfigure error that config file can not be saved
+	ReconfigureErrorSave = "save"
+	// ReconfigureErrorInitialize is kind of reconfigure error that algorithm can not be initialized with new config
+	ReconfigureErrorInitialize = "initialize"
+)
+
+// ReconfigureError is error of reconfigure algorithm
+type ReconfigureError struct {
+	Kind    string
+	Name    string
+	Message string
+}
+
+func (e *ReconfigureError) Error() (string) {
+	return fmt.Sprintf("can not reconfigure algorithm (kind = %v, name = %v, reason = %v)", e.Kind, e.Name, e.Message)
+}
+
+// GetReconfigureError is get reconfigure error from error that may be wrapped
+func GetReconfigureError(err error) (*ReconfigureError, bool) {
+	reconfigureError, ok := errors.Cause(err).(*ReconfigureError)
+	return reconfigureError, ok
+}
+
+type configBackup struct {
+	path    string
+	data    []byte
+	mode    os.FileMode
+	created bool
+}
+
+func (b *configBackup) restore() (error) {
+	if b.created {
+		return os.Remove(b.path)
+	}
+	return ioutil.WriteFile(b.path, b.data, b.mode)
+}
+
+func (r *Robot) saveAlgorithmConfig(info *algorithmInstanceInfo, config map[string]interface{}) (*configBackup, error) {
+	configPathPrefix := path.Join(info.configDir, info.algorithmName)
+	created := false
+	cf, err := configurator.NewConfigurator(configPathPrefix)
+	if err != nil {
+		// 設定ファイルがなければyamlで作る
+		err = os.MkdirAll(info.configDir, 0755)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not create config directory (name = %v)", info.name)
+		}
+		err = ioutil.WriteFile(configPathPrefix+".yaml", []byte("{}\n"), 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not create config file (name = %v)", info.name)
+		}
+		created = true
+		cf, err = configurator.NewConfigurator(configPathPrefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	backup := &configBackup{
+		path:    cf.GetConfigPath(),
+		created: created,
+	}
+	fileInfo, err := os.Stat(backup.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not stat config file (name = %v)", info.name)
+	}
+	backup.mode = fileInfo.Mode()
+	backup.data, err = ioutil.ReadFile(backup.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not read config file (name = %v)", info.name)
+	}
+	err = cf.Lock()
+	if err != nil {
+		return backup, errors.Wrapf(err, "can not lock config file (name = %v)", info.name)
+	}
+	err = cf.Save(config)
+	cf.Unlock()
+	if err != nil {
+		return backup, errors.Wrapf(err, "can not save config (name = %v)", info.name)
+	}
+	return backup, nil
+}
+
+// ReconfigureAlgorithm is save new config of algorithm instance and finalize and initialize it again.
+// if it can not be initialized with new config, old config file is restored and instance is started with it
+func (r *Robot) ReconfigureAlgorithm(name string, config map[string]interface{}) (*ReloadResult, error) {
+	r.reloadMutex.Lock()
+	defer r.reloadMutex.Unlock()
+	info, ok := r.getAlgorithmInstanceInfo(name)
+	if !ok {
+		return nil, &ReconfigureError{Kind: ReconfigureErrorNotFound, Name: name, Message: "not found algorithm instance"}
+	}
+	algorithmLogger := r.logger.WithField(logger.FieldAlgorithm, name)
+	backup, err := r.saveAlgorithmConfig(info, config)
+	if err != nil {
+		if backup != nil {
+			restoreErr := backup.restore()
+			if restoreErr != nil {
+				algorithmLogger.Errorf("can not restore config file (path = %v, reason = %v)", backup.path, restoreErr)
+			}
+		}
+		return nil, &ReconfigureError{Kind: ReconfigureErrorSave, Name: name, Message: err.Error()}
+	}
+	algorithmLogger.Infof("algorithm config is updated (path = %v)", backup.path)
+	result, err := r.reloadAlgorithm(name)
+	if err != nil {
+		return nil, &ReconfigureError{Kind: ReconfigureErrorNotFound, Name: name, Message: err.Error()}
+	}
+	if !result.failedInstances[name] {
+		return result, nil
+	}
+	// 新しい設定で動かせなければ設定ファイルを戻して古い設定で動かし直す
+	message := strings.Join(result.Errors, ", ")
+	err = backup.restore()
+	if err != nil {
+		algorithmLogger.Errorf("can not restore config file (path = %v, reason = %v)", backup.path, err)
+		return nil, &ReconfigureError{Kind: ReconfigureErrorInitialize, Name: name, Message: message}
+	}
+	algorithmLogger.Warnf("algorithm config is restored (path = %v)", backup.path)
+	restoreResult, err := r.reloadAlgorithm(name)
+	if err != nil {
+		algorithmLogger.Errorf("can not restart algorithm with old config (reason = %v)", err)
+	} else if restoreResult.failedInstances[name] {
+		algorithmLogger.Errorf("can not restart algorithm with old config (reason = %v)", strings.Join(restoreResult.Errors, ", "))
+	}
+	return nil, &ReconfigureError{Kind: ReconfigureErrorInitialize, Name: name, Message: message}
+}
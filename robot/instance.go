@@ -0,0 +1,175 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/configurator"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+type algorithmScope struct {
+	exchanges     map[string]bool
+	currencyPairs map[string]bool
+}
+
+func (s *algorithmScope) inExchange(exchangeName string) (bool) {
+	if len(s.exchanges) == 0 {
+		return true
+	}
+	return s.exchanges[strings.ToLower(exchangeName)]
+}
+
+func (s *algorithmScope) inCurrencyPair(currencyPair string) (bool) {
+	if len(s.currencyPairs) == 0 {
+		return true
+	}
+	return s.currencyPairs[strings.ToLower(currencyPair)]
+}
+
+func newAlgorithmScope(exchanges []string, currencyPairs []string) (*algorithmScope) {
+	s := &algorithmScope{
+		exchanges:     make(map[string]bool),
+		currencyPairs: make(map[string]bool),
+	}
+	for _, exchangeName := range exchanges {
+		s.exchanges[strings.ToLower(exchangeName)] = true
+	}
+	for _, currencyPair := range currencyPairs {
+		s.currencyPairs[strings.ToLower(currencyPair)] = true
+	}
+	return s
+}
+
+type algorithmInstanceInfo struct {
+	name          string
+	algorithmName string
+	configDir     string
+	scope         *algorithmScope
+	mailboxSize   int
+	mailboxPolicy MailboxPolicy
+	triggers      *algorithm.Triggers
+}
+
+type internalTradeAlgorithmInstance struct {
+	*algorithmInstanceInfo
+	algorithm     algorithm.InternalTradeAlgorithmV2
+	context       *algorithm.Context
+	runner        *algorithmRunner
+	configModTime time.Time
+}
+
+type externalTradeAlgorithmInstance struct {
+	*algorithmInstanceInfo
+	algorithm       algorithm.ExternalTradeAlgorithmV2
+	context         *algorithm.Context
+	runner          *algorithmRunner
+	triggers        *algorithm.Triggers
+	intervalTrigger *intervalTrigger
+	configModTime   time.Time
+}
+
+// equal is check that instance is started with same config
+func (a *algorithmInstanceInfo) equal(b *algorithmInstanceInfo) (bool) {
+	return a.name == b.name &&
+		a.algorithmName == b.algorithmName &&
+		a.configDir == b.configDir &&
+		reflect.DeepEqual(a.scope, b.scope) &&
+		a.mailboxSize == b.mailboxSize &&
+		a.mailboxPolicy == b.mailboxPolicy &&
+		reflect.DeepEqual(a.triggers, b.triggers)
+}
+
+// getConfigModTime is get modification time of algorithm config file
+func (a *algorithmInstanceInfo) getConfigModTime() (time.Time) {
+	cf, err := configurator.NewConfigurator(path.Join(a.configDir, a.algorithmName))
+	if err != nil {
+		// 設定ファイルを持たないアルゴリズム
+		return time.Time{}
+	}
+	fi, err := os.Stat(cf.GetConfigPath())
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// callAlgorithm is call function of algorithm with panic recovery
+func (r *Robot) callAlgorithm(name string, f func() (error)) (err error) {
+	defer func() {
+		if reason := recover(); reason != nil {
+			r.logger.WithField(logger.FieldAlgorithm, name).Errorf("panic in algorithm (reason = %v)\n%s", reason, debug.Stack())
+			err = errors.Errorf("panic in algorithm (name = %v, reason = %v)", name, reason)
+		}
+	}()
+	return f()
+}
+
+func (r *Robot) algorithmConfigDir(configDir string) (string) {
+	baseDir := path.Join(r.configDir, algorithm.AlgorithmConfigDir)
+	if configDir == "" {
+		return baseDir
+	}
+	if filepath.IsAbs(configDir) {
+		return configDir
+	}
+	return path.Join(baseDir, configDir)
+}
+
+func (r *Robot) getAlgorithmConfigs() ([]*AlgorithmConfig) {
+	r.algorithmConfigsMutex.Lock()
+	defer r.algorithmConfigsMutex.Unlock()
+	return r.algorithmConfigs
+}
+
+func (r *Robot) setAlgorithmConfigs(algorithmConfigs []*AlgorithmConfig) {
+	r.algorithmConfigsMutex.Lock()
+	defer r.algorithmConfigsMutex.Unlock()
+	r.algorithmConfigs = algorithmConfigs
+}
+
+// getAlgorithmInstanceInfos is enumerate algorithm instances from config
+func (r *Robot) getAlgorithmInstanceInfos() ([]*algorithmInstanceInfo) {
+	infos := make([]*algorithmInstanceInfo, 0)
+	algorithmConfigs := r.getAlgorithmConfigs()
+	if len(algorithmConfigs) == 0 {
+		// 設定がなければ登録済みのアルゴリズムを全ての取引所、全ての通貨ペアで動かす
+		for name := range algorithm.GetRegisterdAlgoriths() {
+			infos = append(infos, &algorithmInstanceInfo{
+				name:          name,
+				algorithmName: name,
+				configDir:     r.algorithmConfigDir(""),
+				scope:         newAlgorithmScope(nil, nil),
+				mailboxPolicy: defaultMailboxPolicy,
+			})
+		}
+		return infos
+	}
+	for _, algorithmConfig := range algorithmConfigs {
+		if algorithmConfig.Disable {
+			continue
+		}
+		name := algorithmConfig.Name
+		if name == "" {
+			name = algorithmConfig.Algorithm
+		}
+		// validateで確認済みなのでエラーにはならない
+		mailboxPolicy, _ := getMailboxPolicy(algorithmConfig.MailboxPolicy)
+		infos = append(infos, &algorithmInstanceInfo{
+			name:          name,
+			algorithmName: algorithmConfig.Algorithm,
+			configDir:     r.algorithmConfigDir(algorithmConfig.ConfigDir),
+			scope:         newAlgorithmScope(algorithmConfig.Exchanges, algorithmConfig.CurrencyPairs),
+			mailboxSize:   algorithmConfig.MailboxSize,
+			mailboxPolicy: mailboxPolicy,
+			triggers:      algorithmConfig.Triggers,
+		})
+	}
+	return infos
+}
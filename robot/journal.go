@@ -0,0 +1,175 @@
+package robot
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/journal"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// ロボット自身が出した注文のインスタンス名
+	killSwitchJournalName = "killswitch"
+	reconcileJournalName  = "reconcile"
+)
+
+func (r *Robot) journalFilePath() (string) {
+	if r.config == nil || r.config.JournalFile == "" {
+		// 設定がなければメモリ上にだけ保持する
+		return ""
+	}
+	if filepath.IsAbs(r.config.JournalFile) {
+		return r.config.JournalFile
+	}
+	return path.Join(r.configDir, r.config.JournalFile)
+}
+
+func (r *Robot) writeJournal(entry *journal.Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = r.clock.Now()
+	}
+	err := r.journal.Append(entry)
+	if err != nil {
+		r.logger.WithField(logger.FieldAlgorithm, entry.Name).Errorf("can not write journal (type = %v, reason = %v)", entry.Type, err)
+	}
+	if outcome := orderOutcome(entry); outcome != "" {
+		metrics.IncOrder(entry.Exchange, entry.Name, outcome)
+	}
+}
+
+func orderOutcome(entry *journal.Entry) (string) {
+	switch entry.Type {
+	case journal.EntryTypeResponse:
+		if entry.Error != "" {
+			return metrics.OrderOutcomeFailed
+		}
+		return metrics.OrderOutcomePlaced
+	case journal.EntryTypeRetry:
+		return metrics.OrderOutcomeRetried
+	case journal.EntryTypeReject:
+		return metrics.OrderOutcomeRejected
+	case journal.EntryTypeCancel:
+		if entry.Error != "" {
+			return metrics.OrderOutcomeCancelFailed
+		}
+		return metrics.OrderOutcomeCanceled
+	case journal.EntryTypeFill:
+		return metrics.OrderOutcomeFilled
+	default:
+		// リクエストは結果で数える
+		return ""
+	}
+}
+
+func errorString(err error) (string) {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// placeOrder is send order to exchange and record request, retries and response to journal
+func (r *Robot) placeOrder(name string, ex exchange.Exchange, currencyPair string, action exchange.OrderAction, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	requestTime := r.clock.Now()
+	r.writeJournal(&journal.Entry{
+		Time:         requestTime,
+		Type:         journal.EntryTypeRequest,
+		Name:         name,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Action:       string(action),
+		Price:        price,
+		Amount:       amount,
+	})
+	journalRetryCallback := func(price *float64, amount *float64, errMsg string, retryCallbackData interface{}) (bool) {
+		retry := false
+		if retryCallback != nil {
+			retry = retryCallback(price, amount, errMsg, retryCallbackData)
+		}
+		if retry {
+			r.writeJournal(&journal.Entry{
+				Type:         journal.EntryTypeRetry,
+				Name:         name,
+				Exchange:     ex.GetName(),
+				CurrencyPair: currencyPair,
+				Action:       string(action),
+				Price:        *price,
+				Amount:       *amount,
+				RequestTime:  requestTime,
+				Error:        errMsg,
+			})
+		}
+		return retry
+	}
+	var orderID int64
+	var err error
+	if action == exchange.OrderActBuy {
+		orderID, price, amount, err = ex.Buy(currencyPair, price, amount, journalRetryCallback, retryCallbackData)
+	} else {
+		orderID, price, amount, err = ex.Sell(currencyPair, price, amount, journalRetryCallback, retryCallbackData)
+	}
+	r.writeJournal(&journal.Entry{
+		Type:         journal.EntryTypeResponse,
+		Name:         name,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Action:       string(action),
+		Price:        price,
+		Amount:       amount,
+		OrderID:      orderID,
+		RequestTime:  requestTime,
+		Error:        errorString(err),
+	})
+	return orderID, price, amount, err
+}
+
+// cancelOrder is cancel order and record it to journal
+func (r *Robot) cancelOrder(name string, ex exchange.Exchange, orderID int64, currencyPair string) (error) {
+	requestTime := r.clock.Now()
+	err := ex.Cancel(orderID, currencyPair)
+	r.writeJournal(&journal.Entry{
+		Type:         journal.EntryTypeCancel,
+		Name:         name,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		OrderID:      orderID,
+		RequestTime:  requestTime,
+		Error:        errorString(err),
+	})
+	return err
+}
+
+func (r *Robot) journalReject(name string, ex exchange.Exchange, currencyPair string, action exchange.OrderAction, price float64, amount float64, err error) {
+	r.writeJournal(&journal.Entry{
+		Type:         journal.EntryTypeReject,
+		Name:         name,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Action:       string(action),
+		Price:        price,
+		Amount:       amount,
+		Error:        errorString(err),
+	})
+}
+
+func (r *Robot) journalFill(fill *Fill) {
+	r.writeJournal(&journal.Entry{
+		Time:         fill.Time,
+		Type:         journal.EntryTypeFill,
+		Name:         fill.Name,
+		Exchange:     fill.Exchange,
+		CurrencyPair: fill.CurrencyPair,
+		Action:       string(fill.Action),
+		Price:        fill.Price,
+		Amount:       fill.Amount,
+		Fee:          fill.Fee,
+		OrderID:      fill.OrderID,
+	})
+}
+
+// GetJournal is get journal of orders and fills
+func (r *Robot) GetJournal() (*journal.Journal) {
+	return r.journal
+}
@@ -0,0 +1,436 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFlattenBaseCurrency = "jpy"
+)
+
+// KillSwitchConfig is config of kill switch
+type KillSwitchConfig struct {
+	// 停止時にアルゴリズムが買い越している分を売って基軸通貨に戻す
+	Flatten      bool     `json:"flatten"      yaml:"flatten"      toml:"flatten"`
+	BaseCurrency string   `json:"baseCurrency" yaml:"baseCurrency" toml:"baseCurrency"`
+	// このファイルが作られたら停止する
+	FlagFile     string   `json:"flagFile"     yaml:"flagFile"     toml:"flagFile"`
+	// 違反したら停止するリスク管理のルール (dailyLossLimitなど)
+	RiskRules    []string `json:"riskRules"    yaml:"riskRules"    toml:"riskRules"`
+}
+
+// ExchangeOrder is order on exchange canceled, placed or left by robot
+type ExchangeOrder struct {
+	Exchange     string               `json:"exchange"`
+	CurrencyPair string               `json:"currencyPair"`
+	OrderID      int64                `json:"orderId"`
+	Action       exchange.OrderAction `json:"action"`
+	Price        float64              `json:"price"`
+	Amount       float64              `json:"amount"`
+}
+
+// KillResult is result of kill switch
+type KillResult struct {
+	Reason         string       `json:"reason"`
+	Time           time.Time    `json:"time"`
+	CanceledOrders []*ExchangeOrder `json:"canceledOrders"`
+	FlattenOrders  []*ExchangeOrder `json:"flattenOrders"`
+	Errors         []string     `json:"errors"`
+}
+
+// KillSwitchStatus is status of kill switch
+type KillSwitchStatus struct {
+	Killed     bool        `json:"killed"`
+	LastResult *KillResult `json:"lastResult"`
+}
+
+type killSwitch struct {
+	killed     bool
+	lastResult *KillResult
+	mutex      *sync.Mutex
+	killMutex  *sync.Mutex
+	// 注文の確認から発注までは読み込みでロックし、キルスイッチを入れるときは書き込みでロックする
+	placeMutex *sync.RWMutex
+}
+
+func newKillSwitch() (*killSwitch) {
+	return &killSwitch{
+		mutex:      new(sync.Mutex),
+		killMutex:  new(sync.Mutex),
+		placeMutex: new(sync.RWMutex),
+	}
+}
+
+func (r *Robot) getKillSwitchConfig() (*KillSwitchConfig) {
+	if r.config == nil || r.config.KillSwitch == nil {
+		return new(KillSwitchConfig)
+	}
+	return r.config.KillSwitch
+}
+
+func (r *Robot) killFlagFilePath() (string) {
+	flagFile := r.getKillSwitchConfig().FlagFile
+	if flagFile == "" || filepath.IsAbs(flagFile) {
+		return flagFile
+	}
+	return path.Join(r.configDir, flagFile)
+}
+
+// IsKilled is check that kill switch is on
+func (r *Robot) IsKilled() (bool) {
+	r.killSwitch.mutex.Lock()
+	defer r.killSwitch.mutex.Unlock()
+	return r.killSwitch.killed
+}
+
+// GetKillSwitchStatus is get status of kill switch
+func (r *Robot) GetKillSwitchStatus() (*KillSwitchStatus) {
+	r.killSwitch.mutex.Lock()
+	defer r.killSwitch.mutex.Unlock()
+	return &KillSwitchStatus{
+		Killed:     r.killSwitch.killed,
+		LastResult: r.killSwitch.lastResult,
+	}
+}
+
+func (r *Robot) getAllExchanges() ([]exchange.Exchange) {
+	exchanges := make(map[string]exchange.Exchange)
+	r.internalTradeAlgorithmsMutex.Lock()
+	for name, ex := range r.internalExchanges {
+		exchanges[name] = ex
+	}
+	r.internalTradeAlgorithmsMutex.Unlock()
+	r.externalTradeAlgorithmsMutex.Lock()
+	for name, ex := range r.externalExchanges {
+		exchanges[name] = ex
+	}
+	r.externalTradeAlgorithmsMutex.Unlock()
+	return sortExchanges(exchanges)
+}
+
+func sortExchanges(exchanges map[string]exchange.Exchange) ([]exchange.Exchange) {
+	names := make([]string, 0, len(exchanges))
+	for name := range exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sortedExchanges := make([]exchange.Exchange, 0, len(names))
+	for _, name := range names {
+		sortedExchanges = append(sortedExchanges, exchanges[name])
+	}
+	return sortedExchanges
+}
+
+func getActiveOrders(ex exchange.Exchange) ([]*ExchangeOrder, error) {
+	orderCursor, err := ex.GetActiveOrderCursor()
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not get active orders (exchange = %v)", ex.GetName())
+	}
+	orders := make([]*ExchangeOrder, 0, orderCursor.Len())
+	for {
+		orderID, currencyPair, action, price, amount, _, ok := orderCursor.Next()
+		if !ok {
+			break
+		}
+		orders = append(orders, &ExchangeOrder{
+			Exchange:     ex.GetName(),
+			CurrencyPair: currencyPair,
+			OrderID:      orderID,
+			Action:       action,
+			Price:        price,
+			Amount:       amount,
+		})
+	}
+	return orders, nil
+}
+
+// cancelAllOrders is cancel all active orders of exchange and returns canceled orders and errors
+func (r *Robot) cancelAllOrders(ex exchange.Exchange) ([]*ExchangeOrder, []string) {
+	canceledOrders := make([]*ExchangeOrder, 0)
+	errs := make([]string, 0)
+	orders, err := getActiveOrders(ex)
+	if err != nil {
+		return canceledOrders, append(errs, err.Error())
+	}
+	for _, order := range orders {
+		err := r.cancelOrder(killSwitchJournalName, ex, order.OrderID, order.CurrencyPair)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("can not cancel order (exchange = %v, order id = %v, reason = %v)", ex.GetName(), order.OrderID, err))
+			continue
+		}
+		r.orderTracker.remove(ex.GetName(), order.OrderID)
+		canceledOrders = append(canceledOrders, order)
+	}
+	return canceledOrders, errs
+}
+
+// CancelAllOrders is cancel all active orders of all exchanges
+func (r *Robot) CancelAllOrders() ([]*ExchangeOrder, []string) {
+	return r.cancelOrdersOf(r.getAllExchanges())
+}
+
+// CancelOrdersOf is cancel all active orders of exchanges. it can be used after algorithms are destroyed
+func (r *Robot) CancelOrdersOf(exchanges map[string]exchange.Exchange) ([]*ExchangeOrder, []string) {
+	return r.cancelOrdersOf(sortExchanges(exchanges))
+}
+
+func (r *Robot) cancelOrdersOf(exchanges []exchange.Exchange) ([]*ExchangeOrder, []string) {
+	canceledOrders := make([]*ExchangeOrder, 0)
+	errs := make([]string, 0)
+	for _, ex := range exchanges {
+		orders, e := r.cancelAllOrders(ex)
+		canceledOrders = append(canceledOrders, orders...)
+		errs = append(errs, e...)
+	}
+	return canceledOrders, errs
+}
+
+// GetOpenOrders is get active orders of all exchanges
+func (r *Robot) GetOpenOrders() ([]*ExchangeOrder, []string) {
+	return getOpenOrdersOf(r.getAllExchanges())
+}
+
+// GetOpenOrdersOf is get active orders of exchanges. it can be used after algorithms are destroyed
+func (r *Robot) GetOpenOrdersOf(exchanges map[string]exchange.Exchange) ([]*ExchangeOrder, []string) {
+	return getOpenOrdersOf(sortExchanges(exchanges))
+}
+
+func getOpenOrdersOf(exchanges []exchange.Exchange) ([]*ExchangeOrder, []string) {
+	openOrders := make([]*ExchangeOrder, 0)
+	errs := make([]string, 0)
+	for _, ex := range exchanges {
+		orders, err := getActiveOrders(ex)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		openOrders = append(openOrders, orders...)
+	}
+	return openOrders, errs
+}
+
+func noRetry(price *float64, amount *float64, errMsg string, retryCallbackData interface{}) (bool) {
+	return false
+}
+
+// flattenTarget is long position of algorithm instance which kill switch sells
+type flattenTarget struct {
+	name         string
+	currencyPair string
+	amount       float64
+}
+
+// getFlattenTargets is get long positions of currency pairs quoted in base currency in order of currency pair and name.
+// amounts are limited to net long amount over all algorithm instances
+func (r *Robot) getFlattenTargets(ex exchange.Exchange, baseCurrency string) ([]*flattenTarget) {
+	currencyPairs := make(map[string]bool)
+	for _, currencyPair := range ex.GetCurrencyPairs() {
+		currencyPairs[currencyPair] = true
+	}
+	longs := make([]*flattenTarget, 0)
+	netAmounts := make(map[string]float64)
+	for _, name := range r.positionLedger.GetNames() {
+		for _, p := range r.positionLedger.GetPositions(name) {
+			if p.Exchange != ex.GetName() || !currencyPairs[p.CurrencyPair] || !strings.HasSuffix(p.CurrencyPair, "_"+baseCurrency) {
+				continue
+			}
+			netAmounts[p.CurrencyPair] += p.Amount
+			if p.Amount > 0 {
+				longs = append(longs, &flattenTarget{
+					name:         name,
+					currencyPair: p.CurrencyPair,
+					amount:       p.Amount,
+				})
+			}
+		}
+	}
+	sort.SliceStable(longs, func(i, j int) (bool) {
+		return longs[i].currencyPair < longs[j].currencyPair
+	})
+	// 他のインスタンスが売り越している分は売らない
+	targets := make([]*flattenTarget, 0, len(longs))
+	for _, target := range longs {
+		amount := math.Min(target.amount, netAmounts[target.currencyPair])
+		if amount <= 0 {
+			continue
+		}
+		netAmounts[target.currencyPair] -= amount
+		target.amount = amount
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// flatten is sell long positions of algorithm instances. holdings which robot did not buy are left
+func (r *Robot) flatten(ex exchange.Exchange, baseCurrency string, result *KillResult) {
+	targets := r.getFlattenTargets(ex, baseCurrency)
+	if len(targets) == 0 {
+		return
+	}
+	funds, err := ex.GetFunds()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("can not get funds (exchange = %v, reason = %v)", ex.GetName(), err))
+		return
+	}
+	for _, target := range targets {
+		currencyPair := target.currencyPair
+		currency := risk.BaseCurrency(currencyPair)
+		// 手動で売った場合などは残高の分だけ売る
+		amount := ex.FixAmount(currencyPair, math.Min(target.amount, funds[currency]))
+		if amount <= 0 || amount < ex.GetMinAmountUnit(currencyPair) {
+			continue
+		}
+		// 一番高い買い注文に売る
+		buyBoardCursor, err := ex.GetBuyBoardCursor(currencyPair)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("can not get board (exchange = %v, currency pair = %v, reason = %v)", ex.GetName(), currencyPair, err))
+			continue
+		}
+		price, _, ok := buyBoardCursor.Next()
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("no bid (exchange = %v, currency pair = %v)", ex.GetName(), currencyPair))
+			continue
+		}
+		price = ex.FixPrice(currencyPair, price)
+		orderID, price, amount, err := r.placeOrder(killSwitchJournalName, ex, currencyPair, exchange.OrderActSell, price, amount, noRetry, nil)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("can not sell (exchange = %v, currency pair = %v, name = %v, reason = %v)", ex.GetName(), currencyPair, target.name, err))
+			continue
+		}
+		funds[currency] -= amount
+		// 約定は建玉を持っていたインスタンスに付ける
+		r.trackOrder(target.name, ex, currencyPair, orderID, exchange.OrderActSell, price, amount)
+		result.FlattenOrders = append(result.FlattenOrders, &ExchangeOrder{
+			Exchange:     ex.GetName(),
+			CurrencyPair: currencyPair,
+			OrderID:      orderID,
+			Action:       exchange.OrderActSell,
+			Price:        price,
+			Amount:       amount,
+		})
+	}
+}
+
+// Kill is stop all algorithm updates and orders, cancel all active orders and flatten funds if configured
+func (r *Robot) Kill(reason string) (*KillResult) {
+	// 同時に呼ばれても注文の取り消しは1回ずつ
+	r.killSwitch.killMutex.Lock()
+	defer r.killSwitch.killMutex.Unlock()
+	// 確認を通って発注中の注文が終わるのを待つので、取り消しの後に注文が残らない
+	r.killSwitch.placeMutex.Lock()
+	r.killSwitch.mutex.Lock()
+	r.killSwitch.killed = true
+	r.killSwitch.mutex.Unlock()
+	r.killSwitch.placeMutex.Unlock()
+	r.logger.Errorf("kill switch is on (reason = %v)", reason)
+	config := r.getKillSwitchConfig()
+	baseCurrency := strings.ToLower(config.BaseCurrency)
+	if baseCurrency == "" {
+		baseCurrency = defaultFlattenBaseCurrency
+	}
+	result := &KillResult{
+		Reason:         reason,
+		Time:           r.clock.Now(),
+		CanceledOrders: make([]*ExchangeOrder, 0),
+		FlattenOrders:  make([]*ExchangeOrder, 0),
+		Errors:         make([]string, 0),
+	}
+	for _, ex := range r.getAllExchanges() {
+		canceledOrders, errs := r.cancelAllOrders(ex)
+		result.CanceledOrders = append(result.CanceledOrders, canceledOrders...)
+		result.Errors = append(result.Errors, errs...)
+		if config.Flatten {
+			r.flatten(ex, baseCurrency, result)
+		}
+	}
+	for _, e := range result.Errors {
+		r.logger.Errorf("kill switch error (%v)", e)
+	}
+	r.killSwitch.mutex.Lock()
+	r.killSwitch.lastResult = result
+	r.killSwitch.mutex.Unlock()
+	r.notifyKill(result)
+	return result
+}
+
+func (r *Robot) notifyKill(result *KillResult) {
+	if r.notifier == nil {
+		return
+	}
+	body := fmt.Sprintf("kill switch is on.\nreason = %v\ntime = %v\n\ncanceled orders:\n", result.Reason, result.Time)
+	for _, order := range result.CanceledOrders {
+		body += fmt.Sprintf("  exchange = %v, currency pair = %v, order id = %v, action = %v, price = %v, amount = %v\n",
+			order.Exchange, order.CurrencyPair, order.OrderID, order.Action, order.Price, order.Amount)
+	}
+	body += "\nflatten orders:\n"
+	for _, order := range result.FlattenOrders {
+		body += fmt.Sprintf("  exchange = %v, currency pair = %v, order id = %v, price = %v, amount = %v\n",
+			order.Exchange, order.CurrencyPair, order.OrderID, order.Price, order.Amount)
+	}
+	body += "\nerrors:\n"
+	for _, e := range result.Errors {
+		body += fmt.Sprintf("  %v\n", e)
+	}
+	err := r.notifier.Notify(&notifier.Event{
+		Severity: notifier.SeverityCritical,
+		Subject:  "[ACT] kill switch is on",
+		Body:     body,
+	})
+	if err != nil {
+		r.logger.Errorf("can not send notification (reason = %v)", err)
+	}
+}
+
+// ResetKillSwitch is resume algorithm updates and orders
+func (r *Robot) ResetKillSwitch() (error) {
+	flagFile := r.killFlagFilePath()
+	if flagFile != "" {
+		_, err := os.Stat(flagFile)
+		if err == nil {
+			return errors.Errorf("remove flag file before reset kill switch (flag file = %v)", flagFile)
+		}
+	}
+	r.killSwitch.mutex.Lock()
+	defer r.killSwitch.mutex.Unlock()
+	r.killSwitch.killed = false
+	r.logger.Warnf("kill switch is reset")
+	return nil
+}
+
+// CheckKillSwitchFile is kill if flag file exists
+func (r *Robot) CheckKillSwitchFile() {
+	flagFile := r.killFlagFilePath()
+	if flagFile == "" || r.IsKilled() {
+		return
+	}
+	_, err := os.Stat(flagFile)
+	if err != nil {
+		return
+	}
+	r.Kill(fmt.Sprintf("flag file %v exists", flagFile))
+}
+
+func (r *Robot) killOnRiskViolation(riskError *risk.Error) {
+	if r.IsKilled() {
+		return
+	}
+	for _, rule := range r.getKillSwitchConfig().RiskRules {
+		if rule == riskError.Rule {
+			// アルゴリズムの注文の中から呼ばれるので待たない
+			go r.Kill(fmt.Sprintf("risk rule %v is violated by %v", riskError.Rule, riskError.Order.Name))
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package robot
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"fmt"
+)
+
+// ManualOrderName is instance name of orders placed by operator
+const ManualOrderName = "manual"
+
+// ManualOrder is order placed by operator through web api
+type ManualOrder struct {
+	// 注文した人
+	User         string               `json:"user"`
+	Exchange     string               `json:"exchange"`
+	CurrencyPair string               `json:"currencyPair"`
+	OrderID      int64                `json:"orderId"`
+	Action       exchange.OrderAction `json:"action"`
+	Price        float64              `json:"price"`
+	Amount       float64              `json:"amount"`
+}
+
+func (r *Robot) notifyManualOrder(subject string, body string) {
+	if r.notifier == nil {
+		return
+	}
+	err := r.notifier.Notify(&notifier.Event{
+		Severity: notifier.SeverityInfo,
+		Subject:  subject,
+		Body:     body,
+		Source:   "manual",
+	})
+	if err != nil {
+		r.logger.Errorf("can not send notification (reason = %v)", err)
+	}
+}
+
+// PlaceManualOrder is place limit order with same normalization and risk checks as algorithms
+func (r *Robot) PlaceManualOrder(user string, ex exchange.Exchange, currencyPair string, action exchange.OrderAction, price float64, amount float64) (*ManualOrder, error) {
+	riskOrder := &risk.Order{
+		Name:         ManualOrderName,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Action:       action,
+		Price:        price,
+		Amount:       amount,
+	}
+	found := false
+	for _, p := range ex.GetCurrencyPairs() {
+		if p == currencyPair {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, risk.NewError(risk.RuleInvalidOrder, riskOrder, "unsupported currency pair")
+	}
+	if action != exchange.OrderActBuy && action != exchange.OrderActSell {
+		return nil, risk.NewError(risk.RuleInvalidOrder, riskOrder, "unexpected action")
+	}
+	// アルゴリズムと同じく取引所の単位に丸める
+	price = ex.FixPrice(currencyPair, price)
+	amount = ex.FixAmount(currencyPair, amount)
+	if price <= 0 {
+		return nil, risk.NewError(risk.RuleInvalidOrder, riskOrder, "price is too small (fixed price = %v)", price)
+	}
+	if amount <= 0 || amount < ex.GetMinAmountUnit(currencyPair) {
+		return nil, risk.NewError(risk.RuleInvalidOrder, riskOrder, "amount is too small (fixed amount = %v, min amount = %v)", amount, ex.GetMinAmountUnit(currencyPair))
+	}
+	order := &ManualOrder{
+		User:         user,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Action:       action,
+		Price:        price,
+		Amount:       amount,
+	}
+	manualLogger := r.logger.With(logger.Fields{
+		logger.FieldAlgorithm:    ManualOrderName,
+		logger.FieldExchange:     ex.GetName(),
+		logger.FieldCurrencyPair: currencyPair,
+	})
+	manualLogger.Infof("[manual] place order (user = %v, action = %v, price = %v, amount = %v)", user, action, price, amount)
+	// 約定や建玉はmanualのインスタンスとして記録する
+	trackingExchange := r.newTrackingExchange(ManualOrderName, ex)
+	var err error
+	if action == exchange.OrderActBuy {
+		order.OrderID, order.Price, order.Amount, err = trackingExchange.Buy(currencyPair, price, amount, noRetry, nil)
+	} else {
+		order.OrderID, order.Price, order.Amount, err = trackingExchange.Sell(currencyPair, price, amount, noRetry, nil)
+	}
+	if err != nil {
+		manualLogger.Errorf("[manual] can not place order (user = %v, reason = %v)", user, err)
+		return nil, err
+	}
+	manualLogger.WithField(logger.FieldOrderID, order.OrderID).Infof("[manual] order placed (user = %v)", user)
+	r.notifyManualOrder(fmt.Sprintf("[ACT] manual %v order (exchange = %v, currency pair = %v)", action, ex.GetName(), currencyPair),
+		fmt.Sprintf("manual order is placed.\nuser = %v\nexchange = %v\ncurrency pair = %v\norder id = %v\naction = %v\nprice = %v\namount = %v\n",
+			user, ex.GetName(), currencyPair, order.OrderID, action, order.Price, order.Amount))
+	return order, nil
+}
+
+// CancelManualOrder is cancel order by operator. any active order can be canceled
+func (r *Robot) CancelManualOrder(user string, ex exchange.Exchange, orderID int64, currencyPair string) (error) {
+	manualLogger := r.logger.With(logger.Fields{
+		logger.FieldAlgorithm:    ManualOrderName,
+		logger.FieldExchange:     ex.GetName(),
+		logger.FieldCurrencyPair: currencyPair,
+		logger.FieldOrderID:      orderID,
+	})
+	manualLogger.Infof("[manual] cancel order (user = %v)", user)
+	err := r.cancelOrder(ManualOrderName, ex, orderID, currencyPair)
+	if err != nil {
+		manualLogger.Errorf("[manual] can not cancel order (user = %v, reason = %v)", user, err)
+		return err
+	}
+	// アルゴリズムの注文を取り消した場合も追跡をやめる
+	r.orderTracker.remove(ex.GetName(), orderID)
+	r.notifyManualOrder(fmt.Sprintf("[ACT] manual cancel (exchange = %v, order id = %v)", ex.GetName(), orderID),
+		fmt.Sprintf("order is canceled manually.\nuser = %v\nexchange = %v\ncurrency pair = %v\norder id = %v\n", user, ex.GetName(), currencyPair, orderID))
+	return nil
+}
@@ -0,0 +1,419 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFillPollInterval = 5
+	orderNamespace          = "orders"
+)
+
+// Fill is fill of order placed by algorithm instance
+type Fill struct {
+	Name         string               `json:"name"`
+	Exchange     string               `json:"exchange"`
+	CurrencyPair string               `json:"currencyPair"`
+	OrderID      int64                `json:"orderId"`
+	Action       exchange.OrderAction `json:"action"`
+	Price        float64              `json:"price"`
+	Amount       float64              `json:"amount"`
+	Fee          float64              `json:"fee"`
+	Time         time.Time            `json:"time"`
+}
+
+// trackedOrder is order placed by algorithm. it is persisted to reconcile on startup
+type trackedOrder struct {
+	Name         string               `json:"name"`
+	Exchange     string               `json:"exchange"`
+	CurrencyPair string               `json:"currencyPair"`
+	OrderID      int64                `json:"orderId"`
+	Action       exchange.OrderAction `json:"action"`
+	Price        float64              `json:"price"`
+	Remaining    float64              `json:"remaining"`
+	TrackedAt    time.Time            `json:"trackedAt"`
+}
+
+func trackedOrderKey(exchangeName string, orderID int64) (string) {
+	return fmt.Sprintf("%v/%v", exchangeName, orderID)
+}
+
+// orderTracker keeps orders placed by algorithms until they are filled or canceled
+type orderTracker struct {
+	store  algorithm.StateStore
+	orders map[string]map[int64]*trackedOrder
+	mutex  *sync.Mutex
+	logger *logger.Logger
+}
+
+// save is persist order. mutex must be locked
+func (o *orderTracker) save(order *trackedOrder) {
+	err := o.store.PutJSON(trackedOrderKey(order.Exchange, order.OrderID), order)
+	if err != nil {
+		o.logger.With(logger.Fields{
+			logger.FieldExchange: order.Exchange,
+			logger.FieldOrderID:  order.OrderID,
+		}).Errorf("can not save order (reason = %v)", err)
+	}
+}
+
+// delete is delete order. mutex must be locked
+func (o *orderTracker) delete(exchangeName string, orderID int64) {
+	delete(o.orders[exchangeName], orderID)
+	err := o.store.Delete(trackedOrderKey(exchangeName, orderID))
+	if err != nil {
+		o.logger.With(logger.Fields{
+			logger.FieldExchange: exchangeName,
+			logger.FieldOrderID:  orderID,
+		}).Errorf("can not delete order (reason = %v)", err)
+	}
+}
+
+func (o *orderTracker) add(order *trackedOrder) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	orders, ok := o.orders[order.Exchange]
+	if !ok {
+		orders = make(map[int64]*trackedOrder)
+		o.orders[order.Exchange] = orders
+	}
+	orders[order.OrderID] = order
+	o.save(order)
+}
+
+// remove is stop tracking order and returns false if it is not tracked
+func (o *orderTracker) remove(exchangeName string, orderID int64) (bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.orders[exchangeName][orderID]; !ok {
+		return false
+	}
+	o.delete(exchangeName, orderID)
+	return true
+}
+
+func (o *orderTracker) get(exchangeName string, orderID int64) (*trackedOrder, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	order, ok := o.orders[exchangeName][orderID]
+	if !ok {
+		return nil, false
+	}
+	copied := *order
+	return &copied, true
+}
+
+func (o *orderTracker) getAll(exchangeName string) ([]*trackedOrder) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	orders := make([]*trackedOrder, 0, len(o.orders[exchangeName]))
+	for _, order := range o.orders[exchangeName] {
+		copied := *order
+		orders = append(orders, &copied)
+	}
+	sort.Slice(orders, func(i, j int) (bool) {
+		return orders[i].OrderID < orders[j].OrderID
+	})
+	return orders
+}
+
+func (o *orderTracker) count(exchangeName string) (int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.orders[exchangeName])
+}
+
+// openAmounts is get remaining amounts of buy and sell orders of base currency
+func (o *orderTracker) openAmounts(exchangeName string, currency string) (float64, float64) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var buy float64
+	var sell float64
+	for _, order := range o.orders[exchangeName] {
+		if risk.BaseCurrency(order.CurrencyPair) != currency {
+			continue
+		}
+		switch order.Action {
+		case exchange.OrderActBuy:
+			buy += order.Remaining
+		case exchange.OrderActSell:
+			sell += order.Remaining
+		}
+	}
+	return buy, sell
+}
+
+// update is compare tracked orders with active orders taken at snapshotTime.
+// it returns fills of partially filled orders and orders which disappeared from active orders
+func (o *orderTracker) update(exchangeName string, activeOrders map[int64]float64, snapshotTime time.Time, now time.Time) ([]*Fill, []*trackedOrder) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	fills := make([]*Fill, 0)
+	closedOrders := make([]*trackedOrder, 0)
+	for orderID, order := range o.orders[exchangeName] {
+		if order.TrackedAt.After(snapshotTime) {
+			// 一覧を取得している間に出された注文は一覧にないので次回に回す
+			continue
+		}
+		remaining, ok := activeOrders[orderID]
+		if !ok {
+			// 約定か取り消しかは履歴を見ないと分からない
+			copied := *order
+			closedOrders = append(closedOrders, &copied)
+			continue
+		}
+		if remaining >= order.Remaining {
+			continue
+		}
+		// 残量が減ったものは一部約定とみなす
+		amount := order.Remaining - remaining
+		order.Remaining = remaining
+		o.save(order)
+		fills = append(fills, &Fill{
+			Name:         order.Name,
+			Exchange:     exchangeName,
+			CurrencyPair: order.CurrencyPair,
+			OrderID:      orderID,
+			Action:       order.Action,
+			Price:        order.Price,
+			Amount:       amount,
+			Time:         now,
+		})
+	}
+	sort.Slice(closedOrders, func(i, j int) (bool) {
+		return closedOrders[i].OrderID < closedOrders[j].OrderID
+	})
+	return fills, closedOrders
+}
+
+// newOrderTracker is create order tracker and load persisted orders
+func newOrderTracker(store algorithm.StateStore) (*orderTracker, error) {
+	o := &orderTracker{
+		store:  store,
+		orders: make(map[string]map[int64]*trackedOrder),
+		mutex:  new(sync.Mutex),
+		logger: logger.Get("robot"),
+	}
+	keys, err := store.Keys()
+	if err != nil {
+		return nil, errors.Wrap(err, "can not load orders")
+	}
+	for _, key := range keys {
+		order := new(trackedOrder)
+		_, err := store.GetJSON(key, order)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can not load order (key = %v)", key)
+		}
+		orders, ok := o.orders[order.Exchange]
+		if !ok {
+			orders = make(map[int64]*trackedOrder)
+			o.orders[order.Exchange] = orders
+		}
+		orders[order.OrderID] = order
+	}
+	return o, nil
+}
+
+// trackingExchange is exchange passed to algorithm. it checks orders by risk manager and records orders to attribute fills to algorithm instance
+type trackingExchange struct {
+	exchange.Exchange
+	robot *Robot
+	name  string
+}
+
+func (t *trackingExchange) order(currencyPair string, action exchange.OrderAction, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	// キルスイッチの確認と発注の間にキルスイッチが入らないようにする
+	t.robot.killSwitch.placeMutex.RLock()
+	defer t.robot.killSwitch.placeMutex.RUnlock()
+	// 同じ取引所の注文は確認から追跡までを1つずつ行い、同じ建玉や注文数で確認を通らないようにする
+	orderMutex := t.robot.getOrderMutex(t.Exchange.GetName())
+	orderMutex.Lock()
+	defer orderMutex.Unlock()
+	riskOrder, err := t.robot.checkOrder(t.name, t.Exchange, currencyPair, action, price, amount)
+	if err != nil {
+		t.robot.journalReject(t.name, t.Exchange, currencyPair, action, price, amount, err)
+		return 0, 0, 0, err
+	}
+	orderID, price, amount, err := t.robot.placeOrder(t.name, t.Exchange, currencyPair, action, price, amount, retryCallback, retryCallbackData)
+	if err != nil {
+		// 出せなかった注文は1分あたりの注文数に数えない
+		t.robot.riskManager.Release(riskOrder)
+		return orderID, price, amount, err
+	}
+	t.robot.trackOrder(t.name, t.Exchange, currencyPair, orderID, action, price, amount)
+	return orderID, price, amount, err
+}
+
+func (t *trackingExchange) Buy(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	return t.order(currencyPair, exchange.OrderActBuy, price, amount, retryCallback, retryCallbackData)
+}
+
+func (t *trackingExchange) Sell(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	return t.order(currencyPair, exchange.OrderActSell, price, amount, retryCallback, retryCallbackData)
+}
+
+func (t *trackingExchange) Cancel(orderID int64, currencyPair string) (error) {
+	err := t.robot.cancelOrder(t.name, t.Exchange, orderID, currencyPair)
+	if err == nil {
+		t.robot.orderTracker.remove(t.Exchange.GetName(), orderID)
+	}
+	return err
+}
+
+func (r *Robot) newTrackingExchange(name string, ex exchange.Exchange) (exchange.Exchange) {
+	return &trackingExchange{
+		Exchange: ex,
+		robot:    r,
+		name:     name,
+	}
+}
+
+// tradeFee is fee of fill. fee rate of exchange is percent
+func tradeFee(ex exchange.Exchange, currencyPair string, price float64, amount float64) (float64) {
+	return price * amount * ex.GetTradeFeeRate(currencyPair) / 100
+}
+
+func (r *Robot) trackOrder(name string, ex exchange.Exchange, currencyPair string, orderID int64, action exchange.OrderAction, price float64, amount float64) {
+	if orderID <= 0 {
+		// 注文IDがないものはすぐに約定した
+		r.onFill(&Fill{
+			Name:         name,
+			Exchange:     ex.GetName(),
+			CurrencyPair: currencyPair,
+			OrderID:      orderID,
+			Action:       action,
+			Price:        price,
+			Amount:       amount,
+			Fee:          tradeFee(ex, currencyPair, price, amount),
+			Time:         r.clock.Now(),
+		})
+		return
+	}
+	r.orderTracker.add(&trackedOrder{
+		Name:         name,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		OrderID:      orderID,
+		Action:       action,
+		Price:        price,
+		Remaining:    amount,
+		TrackedAt:    r.clock.Now(),
+	})
+}
+
+func (r *Robot) onFill(fill *Fill) {
+	fillLogger := r.logger.With(logger.Fields{
+		logger.FieldAlgorithm:    fill.Name,
+		logger.FieldExchange:     fill.Exchange,
+		logger.FieldCurrencyPair: fill.CurrencyPair,
+		logger.FieldOrderID:      fill.OrderID,
+	})
+	fillLogger.Infof("order filled (action = %v, price = %v, amount = %v)", fill.Action, fill.Price, fill.Amount)
+	r.journalFill(fill)
+	_, pnl, err := r.positionLedger.AddFill(fill.Name, fill.Exchange, fill.CurrencyPair, fill.Action, fill.Price, fill.Amount, fill.Fee)
+	if err != nil {
+		fillLogger.Errorf("can not record fill (reason = %v)", err)
+	} else {
+		err = r.riskManager.AddPnL(pnl)
+		if err != nil {
+			fillLogger.Errorf("can not record daily pnl (reason = %v)", err)
+		}
+	}
+	r.updateExternalTradeAlgorithmsByFill(fill)
+}
+
+// PollFills is check active orders of exchange and detect fills of orders placed by algorithms
+func (r *Robot) PollFills(ex exchange.Exchange) (error) {
+	if r.orderTracker.count(ex.GetName()) == 0 {
+		// 注文がなければAPIを呼ばない
+		return nil
+	}
+	snapshotTime := r.clock.Now()
+	orderCursor, err := ex.GetActiveOrderCursor()
+	if err != nil {
+		return err
+	}
+	activeOrders := make(map[int64]float64)
+	for {
+		orderID, _, _, _, amount, _, ok := orderCursor.Next()
+		if !ok {
+			break
+		}
+		activeOrders[orderID] = amount
+	}
+	now := r.clock.Now()
+	fills, closedOrders := r.orderTracker.update(ex.GetName(), activeOrders, snapshotTime, now)
+	for _, fill := range fills {
+		fill.Fee = tradeFee(ex, fill.CurrencyPair, fill.Price, fill.Amount)
+		r.onFill(fill)
+	}
+	if len(closedOrders) == 0 {
+		return nil
+	}
+	fills, canceledOrders, err := r.closeOrders(ex, closedOrders, now)
+	if err != nil {
+		// 履歴が取れなければ注文は追跡したままにして次回確認する
+		return err
+	}
+	for _, order := range canceledOrders {
+		r.logger.With(logger.Fields{
+			logger.FieldAlgorithm: order.Name,
+			logger.FieldExchange:  order.Exchange,
+			logger.FieldOrderID:   order.OrderID,
+		}).Warnf("order is closed without fill in history, assume canceled")
+	}
+	for _, fill := range fills {
+		r.onFill(fill)
+	}
+	return nil
+}
+
+// closeOrders is stop tracking orders which disappeared from active orders and returns their fills found in order history.
+// orders without fill in history are returned as canceled
+func (r *Robot) closeOrders(ex exchange.Exchange, orders []*trackedOrder, now time.Time) ([]*Fill, []*trackedOrder, error) {
+	history, err := getOrderHistory(ex, r.getHistoryCount())
+	if err != nil {
+		return nil, nil, err
+	}
+	fills := make([]*Fill, 0, len(orders))
+	canceledOrders := make([]*trackedOrder, 0)
+	for _, order := range orders {
+		if !r.orderTracker.remove(ex.GetName(), order.OrderID) {
+			// 履歴を取得している間に取り消されている
+			continue
+		}
+		filled, price := matchHistory(order, history)
+		if filled <= 0 {
+			canceledOrders = append(canceledOrders, order)
+			continue
+		}
+		fills = append(fills, &Fill{
+			Name:         order.Name,
+			Exchange:     ex.GetName(),
+			CurrencyPair: order.CurrencyPair,
+			OrderID:      order.OrderID,
+			Action:       order.Action,
+			Price:        price,
+			Amount:       filled,
+			Fee:          tradeFee(ex, order.CurrencyPair, price, filled),
+			Time:         now,
+		})
+	}
+	return fills, canceledOrders, nil
+}
+
+// GetFillPollInterval is get interval of PollFills
+func (r *Robot) GetFillPollInterval() (time.Duration) {
+	if r.config == nil || r.config.FillPollInterval <= 0 {
+		return defaultFillPollInterval * time.Second
+	}
+	return time.Duration(r.config.FillPollInterval) * time.Second
+}
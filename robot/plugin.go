@@ -3,20 +3,28 @@ package robot
 import (
 	"github.com/pkg/errors"
 	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/rpcplugin"
 	"plugin"
 	"io/ioutil"
 	"path/filepath"
-	"log"
 	"fmt"
 	"regexp"
 	"os/user"
 )
 
 type GetRegistrationInfoType func() (string, algorithm.InternalTradeAlgorithmNewFunc, algorithm.ExternalTradeAlgorithmNewFunc)
+type GetRegistrationInfoV2Type func() (string, algorithm.InternalTradeAlgorithmV2NewFunc, algorithm.ExternalTradeAlgorithmV2NewFunc)
 
-func (r *Robot) registerAlgorithm(getRegistrationInfo GetRegistrationInfoType) {
+func (r *Robot) registerAlgorithm(getRegistrationInfo GetRegistrationInfoType) (string) {
 	name, tradeAlgorithmNewFunc, arbitrageTradeAlgorithmNewFunc := getRegistrationInfo()
 	algorithm.RegisterAlgorithm(name, tradeAlgorithmNewFunc, arbitrageTradeAlgorithmNewFunc)
+	return name
+}
+
+func (r *Robot) registerAlgorithmV2(getRegistrationInfo GetRegistrationInfoV2Type) (string) {
+	name, tradeAlgorithmNewFunc, arbitrageTradeAlgorithmNewFunc := getRegistrationInfo()
+	algorithm.RegisterAlgorithmV2(name, tradeAlgorithmNewFunc, arbitrageTradeAlgorithmNewFunc)
+	return name
 }
 
 func (r *Robot) checkPluginSymbole(p *plugin.Plugin) (GetRegistrationInfoType, error) {
@@ -27,39 +35,49 @@ func (r *Robot) checkPluginSymbole(p *plugin.Plugin) (GetRegistrationInfoType, e
 	return s.(func() (string, algorithm.InternalTradeAlgorithmNewFunc, algorithm.ExternalTradeAlgorithmNewFunc)), nil
 }
 
-func (r *Robot) loadPluginFile(pluginFile string) (error) {
+func (r *Robot) loadPluginFile(pluginFile string) (string, error) {
 	p, err := plugin.Open(pluginFile)
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("can not open plugin file (plugin file = %v)", pluginFile))
+		return "", errors.Wrap(err, fmt.Sprintf("can not open plugin file (plugin file = %v)", pluginFile))
+	}
+	// Contextを受け取る新しいアルゴリズム
+	s, err := p.Lookup("GetRegistrationInfoV2")
+	if err == nil {
+		f, ok := s.(func() (string, algorithm.InternalTradeAlgorithmV2NewFunc, algorithm.ExternalTradeAlgorithmV2NewFunc))
+		if !ok {
+			return "", errors.Errorf("unexpected type of GetRegistrationInfoV2 symbole (plugin file = %v)", pluginFile)
+		}
+		return r.registerAlgorithmV2(f), nil
 	}
 	f, err := r.checkPluginSymbole(p)
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("not plugin file (plugin file = %v)", pluginFile))
+		return "", errors.Wrap(err, fmt.Sprintf("not plugin file (plugin file = %v)", pluginFile))
 	}
-	r.registerAlgorithm(f)
-	return nil
+	return r.registerAlgorithm(f), nil
 }
 
 func (r *Robot) fixupAlgorithmPluginDir(algorithmPluginDir string) (string) {
 	// shell表現 "~/" をなんとかする
 	u, err := user.Current()
 	if err != nil {
-		log.Printf("can not get user info (reason = %v)", err)
+		r.logger.Warnf("can not get user info (reason = %v)", err)
 		return algorithmPluginDir
 	}
 	re := regexp.MustCompile("^~/")
 	return re.ReplaceAllString(r.config.AlgorithmPluginDir, u.HomeDir+"/")
 }
 
-func (r *Robot) loadPluginFiles() {
+// loadPluginFiles is load plugin files not loaded yet and returns names of registered algorithms
+func (r *Robot) loadPluginFiles() ([]string) {
+	names := make([]string, 0)
 	if r.config == nil || r.config.AlgorithmPluginDir == "" {
-		return
+		return names
 	}
 	algorithmPluginDir := r.fixupAlgorithmPluginDir(r.config.AlgorithmPluginDir)
 	filist, err := ioutil.ReadDir(algorithmPluginDir)
 	if err != nil {
-		log.Printf("can not readdir (dir = %v)", algorithmPluginDir)
-		return
+		r.logger.Errorf("can not readdir (dir = %v, reason = %v)", algorithmPluginDir, err)
+		return names
 	}
 	for _, fi := range filist {
 		if fi.IsDir() {
@@ -70,10 +88,45 @@ func (r *Robot) loadPluginFiles() {
 			continue
 		}
 		pluginPath := filepath.Join(algorithmPluginDir, fi.Name())
-		err := r.loadPluginFile(pluginPath)
+		modTime, ok := r.loadedPluginFiles[pluginPath]
+		if ok {
+			if !modTime.Equal(fi.ModTime()) {
+				// goのpluginは同じパスのものを読み直すことができない
+				r.logger.Warnf("plugin file is changed, but can not reload same path. use new file name or restart (plugin file = %v)", pluginPath)
+			}
+			continue
+		}
+		name, err := r.loadPluginFile(pluginPath)
 		if err != nil {
-			log.Printf("can not load plugin file (plugin file = %v, reason = %v)", pluginPath, err)
+			r.logger.Errorf("can not load plugin file (plugin file = %v, reason = %v)", pluginPath, err)
 			continue
 		}
+		r.loadedPluginFiles[pluginPath] = fi.ModTime()
+		names = append(names, name)
+	}
+	return names
+}
+
+func (r *Robot) startProcessPlugins() (error) {
+	if r.config == nil {
+		return nil
+	}
+	for _, processPluginConfig := range r.config.ProcessPlugins {
+		host, err := rpcplugin.NewHost(processPluginConfig)
+		if err != nil {
+			r.stopProcessPlugins()
+			return errors.Wrapf(err, "can not create process plugin host (name = %v)", processPluginConfig.Name)
+		}
+		// プロセスは最初にアルゴリズムが初期化されるときに起動する
+		rpcplugin.RegisterAlgorithm(host)
+		r.processPluginHosts = append(r.processPluginHosts, host)
+	}
+	return nil
+}
+
+func (r *Robot) stopProcessPlugins() {
+	for _, host := range r.processPluginHosts {
+		host.Stop()
 	}
+	r.processPluginHosts = make([]*rpcplugin.Host, 0)
 }
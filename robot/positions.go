@@ -0,0 +1,107 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/position"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+)
+
+const (
+	positionNamespace = "positions"
+)
+
+// instancePositionLedger is implementation of algorithm.PositionLedger for one algorithm instance
+type instancePositionLedger struct {
+	robot     *Robot
+	name      string
+	exchanges map[string]exchange.Exchange
+}
+
+func (i *instancePositionLedger) GetPositions() ([]*algorithm.Position, error) {
+	positions := i.robot.positionLedger.GetPositions(i.name)
+	for _, p := range positions {
+		err := markPosition(p, i.exchanges[p.Exchange])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return positions, nil
+}
+
+func (i *instancePositionLedger) GetPosition(exchangeName string, currencyPair string) (*algorithm.Position, error) {
+	p := i.robot.positionLedger.GetPosition(i.name, exchangeName, currencyPair)
+	err := markPosition(p, i.exchanges[exchangeName])
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func markPosition(p *algorithm.Position, ex exchange.Exchange) (error) {
+	if ex == nil || p.Amount == 0 {
+		// 評価できない取引所のものは含み損益なし
+		position.Mark(p, p.LastPrice)
+		return nil
+	}
+	lastPrice, err := ex.GetLastPrice(p.CurrencyPair)
+	if err != nil {
+		return errors.Wrapf(err, "can not get last price (exchange = %v, currency pair = %v)", p.Exchange, p.CurrencyPair)
+	}
+	position.Mark(p, lastPrice)
+	return nil
+}
+
+func (r *Robot) getExchange(exchangeName string) (exchange.Exchange) {
+	r.internalTradeAlgorithmsMutex.Lock()
+	ex, ok := r.internalExchanges[exchangeName]
+	r.internalTradeAlgorithmsMutex.Unlock()
+	if ok {
+		return ex
+	}
+	r.externalTradeAlgorithmsMutex.Lock()
+	defer r.externalTradeAlgorithmsMutex.Unlock()
+	return r.externalExchanges[exchangeName]
+}
+
+// GetPositions is get positions of algorithm instance marked at last price
+func (r *Robot) GetPositions(name string) ([]*algorithm.Position) {
+	return r.getPositions(name, r.getExchange)
+}
+
+func (r *Robot) getPositions(name string, getExchange func(exchangeName string) (exchange.Exchange)) ([]*algorithm.Position) {
+	positions := r.positionLedger.GetPositions(name)
+	for _, p := range positions {
+		err := markPosition(p, getExchange(p.Exchange))
+		if err != nil {
+			r.logger.With(logger.Fields{
+				logger.FieldAlgorithm:    name,
+				logger.FieldExchange:     p.Exchange,
+				logger.FieldCurrencyPair: p.CurrencyPair,
+			}).Warnf("can not mark position (reason = %v)", err)
+		}
+	}
+	return positions
+}
+
+// GetAllPositions is get positions of all algorithm instances marked at last price
+func (r *Robot) GetAllPositions() (map[string][]*algorithm.Position) {
+	allPositions := make(map[string][]*algorithm.Position)
+	for _, name := range r.positionLedger.GetNames() {
+		allPositions[name] = r.GetPositions(name)
+	}
+	return allPositions
+}
+
+// GetAllPositionsOf is get positions of all algorithm instances marked at last price of exchanges.
+// it can be used after algorithms are destroyed
+func (r *Robot) GetAllPositionsOf(exchanges map[string]exchange.Exchange) (map[string][]*algorithm.Position) {
+	allPositions := make(map[string][]*algorithm.Position)
+	for _, name := range r.positionLedger.GetNames() {
+		allPositions[name] = r.getPositions(name, func(exchangeName string) (exchange.Exchange) {
+			return exchanges[exchangeName]
+		})
+	}
+	return allPositions
+}
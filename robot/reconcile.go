@@ -0,0 +1,334 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// 誰のものでもない注文は通知だけする
+	OrphanPolicyAlert  = "alert"
+	// 誰のものでもない注文をadoptAsのインスタンスの注文として扱う
+	OrphanPolicyAdopt  = "adopt"
+	// 誰のものでもない注文を取り消す
+	OrphanPolicyCancel = "cancel"
+)
+
+const (
+	defaultReconcileHistoryCount = 100
+	defaultAdoptName             = "adopted"
+	// 残高と建玉の比較で無視する差
+	fundsTolerance               = 0.00000001
+)
+
+// ReconcileConfig is config of reconciliation on startup
+type ReconcileConfig struct {
+	// alert, adopt, cancel (省略時はalert)
+	OrphanPolicy string `json:"orphanPolicy" yaml:"orphanPolicy" toml:"orphanPolicy"`
+	AdoptAs      string `json:"adoptAs"      yaml:"adoptAs"      toml:"adoptAs"`
+	// 停止中や稼働中に取引所から消えた注文の約定を探す注文履歴の数
+	HistoryCount int64  `json:"historyCount" yaml:"historyCount" toml:"historyCount"`
+}
+
+func (c *ReconcileConfig) validate() (error) {
+	switch c.OrphanPolicy {
+	case "", OrphanPolicyAlert, OrphanPolicyAdopt, OrphanPolicyCancel:
+		return nil
+	default:
+		return errors.Errorf("unexpected orphan policy (%v)", c.OrphanPolicy)
+	}
+}
+
+// ReconcileResult is result of reconciliation of one exchange
+type ReconcileResult struct {
+	Exchange       string             `json:"exchange"`
+	Time           time.Time          `json:"time"`
+	Funds          map[string]float64 `json:"funds"`
+	// 記録にあって取引所にも残っている注文
+	OwnedOrders    []*ExchangeOrder   `json:"ownedOrders"`
+	// 取引所にあるが記録にない注文
+	OrphanedOrders []*ExchangeOrder   `json:"orphanedOrders"`
+	AdoptedOrders  []*ExchangeOrder   `json:"adoptedOrders"`
+	CanceledOrders []*ExchangeOrder   `json:"canceledOrders"`
+	// 記録にあるが取引所に残っていない注文
+	ClosedOrders   []*ExchangeOrder   `json:"closedOrders"`
+	// 停止中に約定したとみなしたもの
+	Fills          []*Fill            `json:"fills"`
+	Warnings       []string           `json:"warnings"`
+	Errors         []string           `json:"errors"`
+}
+
+func (r *Robot) getReconcileConfig() (*ReconcileConfig) {
+	if r.config == nil || r.config.Reconcile == nil {
+		return new(ReconcileConfig)
+	}
+	return r.config.Reconcile
+}
+
+// getHistoryCount is get count of order history to confirm fills of closed orders
+func (r *Robot) getHistoryCount() (int64) {
+	historyCount := r.getReconcileConfig().HistoryCount
+	if historyCount <= 0 {
+		return defaultReconcileHistoryCount
+	}
+	return historyCount
+}
+
+type historyOrder struct {
+	currencyPair string
+	action       exchange.OrderAction
+	price        float64
+	amount       float64
+	timestamp    int64
+}
+
+func getOrderHistory(ex exchange.Exchange, count int64) ([]*historyOrder, error) {
+	orderCursor, err := ex.GetOrderHistoryCursor(count)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not get order history (exchange = %v)", ex.GetName())
+	}
+	history := make([]*historyOrder, 0, orderCursor.Len())
+	for {
+		_, currencyPair, action, price, amount, timestamp, ok := orderCursor.Next()
+		if !ok {
+			break
+		}
+		history = append(history, &historyOrder{
+			currencyPair: currencyPair,
+			action:       action,
+			price:        price,
+			amount:       amount,
+			timestamp:    timestamp,
+		})
+	}
+	return history, nil
+}
+
+// matchHistory is consume history that matches closed order and returns filled amount and average executed price.
+// 履歴のIDは注文IDではないので通貨ペア、売買、指値より不利でない約定価格で突き合わせる
+func matchHistory(order *trackedOrder, history []*historyOrder) (float64, float64) {
+	var filled float64
+	var notional float64
+	for _, h := range history {
+		if filled >= order.Remaining {
+			break
+		}
+		if h.amount <= 0 || !strings.EqualFold(h.currencyPair, order.CurrencyPair) || h.action != order.Action {
+			continue
+		}
+		if (order.Action == exchange.OrderActBuy && h.price > order.Price) || (order.Action == exchange.OrderActSell && h.price < order.Price) {
+			continue
+		}
+		if h.timestamp > 0 && !order.TrackedAt.IsZero() && h.timestamp < order.TrackedAt.Unix() {
+			// 注文より前の約定は他の注文のもの
+			continue
+		}
+		amount := math.Min(h.amount, order.Remaining-filled)
+		h.amount -= amount
+		filled += amount
+		notional += h.price * amount
+	}
+	if filled <= 0 {
+		return 0, 0
+	}
+	return filled, notional / filled
+}
+
+func toExchangeOrder(order *trackedOrder) (*ExchangeOrder) {
+	return &ExchangeOrder{
+		Exchange:     order.Exchange,
+		CurrencyPair: order.CurrencyPair,
+		OrderID:      order.OrderID,
+		Action:       order.Action,
+		Price:        order.Price,
+		Amount:       order.Remaining,
+	}
+}
+
+// Reconcile is compare funds, active orders and order history of exchange with persisted orders and positions.
+// it should be called before algorithms start trading
+func (r *Robot) Reconcile(ex exchange.Exchange) (*ReconcileResult) {
+	config := r.getReconcileConfig()
+	result := &ReconcileResult{
+		Exchange:       ex.GetName(),
+		Time:           r.clock.Now(),
+		Funds:          make(map[string]float64),
+		OwnedOrders:    make([]*ExchangeOrder, 0),
+		OrphanedOrders: make([]*ExchangeOrder, 0),
+		AdoptedOrders:  make([]*ExchangeOrder, 0),
+		CanceledOrders: make([]*ExchangeOrder, 0),
+		ClosedOrders:   make([]*ExchangeOrder, 0),
+		Fills:          make([]*Fill, 0),
+		Warnings:       make([]string, 0),
+		Errors:         make([]string, 0),
+	}
+	defer r.finishReconcile(result)
+	funds, err := ex.GetFunds()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("can not get funds (exchange = %v, reason = %v)", ex.GetName(), err))
+	} else {
+		result.Funds = funds
+		metrics.SetBalances(ex.GetName(), funds)
+	}
+	activeOrders, err := getActiveOrders(ex)
+	if err != nil {
+		// 注文が分からなければ突き合わせできない
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	activeAmounts := make(map[int64]float64)
+	for _, order := range activeOrders {
+		activeAmounts[order.OrderID] = order.Amount
+		_, ok := r.orderTracker.get(ex.GetName(), order.OrderID)
+		if ok {
+			result.OwnedOrders = append(result.OwnedOrders, order)
+			continue
+		}
+		result.OrphanedOrders = append(result.OrphanedOrders, order)
+	}
+
+	// 停止中に取引所から消えた注文は履歴と突き合わせる
+	fills, closedOrders := r.orderTracker.update(ex.GetName(), activeAmounts, result.Time, result.Time)
+	for _, order := range closedOrders {
+		result.ClosedOrders = append(result.ClosedOrders, toExchangeOrder(order))
+	}
+	if len(closedOrders) > 0 {
+		closedFills, canceledOrders, err := r.closeOrders(ex, closedOrders, result.Time)
+		if err != nil {
+			// 注文は追跡したままにしてPollFillsで確認する
+			result.Errors = append(result.Errors, err.Error())
+		}
+		for _, order := range canceledOrders {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("order is closed without fill in history, assume canceled (name = %v, order id = %v)", order.Name, order.OrderID))
+		}
+		result.Fills = append(result.Fills, closedFills...)
+	}
+	// 残っている注文の一部約定
+	for _, fill := range fills {
+		fill.Fee = tradeFee(ex, fill.CurrencyPair, fill.Price, fill.Amount)
+		result.Fills = append(result.Fills, fill)
+	}
+	for _, fill := range result.Fills {
+		r.onFill(fill)
+	}
+
+	r.handleOrphanedOrders(ex, config, result)
+	r.checkPositionsWithFunds(ex, result)
+	return result
+}
+
+func (r *Robot) handleOrphanedOrders(ex exchange.Exchange, config *ReconcileConfig, result *ReconcileResult) {
+	for _, order := range result.OrphanedOrders {
+		switch config.OrphanPolicy {
+		case OrphanPolicyAdopt:
+			name := config.AdoptAs
+			if name == "" {
+				name = defaultAdoptName
+			}
+			r.orderTracker.add(&trackedOrder{
+				Name:         name,
+				Exchange:     order.Exchange,
+				CurrencyPair: order.CurrencyPair,
+				OrderID:      order.OrderID,
+				Action:       order.Action,
+				Price:        order.Price,
+				Remaining:    order.Amount,
+				TrackedAt:    result.Time,
+			})
+			result.AdoptedOrders = append(result.AdoptedOrders, order)
+		case OrphanPolicyCancel:
+			err := r.cancelOrder(reconcileJournalName, ex, order.OrderID, order.CurrencyPair)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("can not cancel orphaned order (exchange = %v, order id = %v, reason = %v)", ex.GetName(), order.OrderID, err))
+				continue
+			}
+			result.CanceledOrders = append(result.CanceledOrders, order)
+		}
+	}
+}
+
+func (r *Robot) checkPositionsWithFunds(ex exchange.Exchange, result *ReconcileResult) {
+	if len(result.Funds) == 0 {
+		return
+	}
+	positions := make(map[string]float64)
+	for _, name := range r.positionLedger.GetNames() {
+		for _, p := range r.positionLedger.GetPositions(name) {
+			if p.Exchange == ex.GetName() {
+				positions[risk.BaseCurrency(p.CurrencyPair)] += p.Amount
+			}
+		}
+	}
+	currencies := make([]string, 0, len(positions))
+	for currency := range positions {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		// 買い建玉の合計が残高より多ければ記録がずれている
+		if positions[currency] > result.Funds[currency]+fundsTolerance {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("position of algorithms exceeds funds (exchange = %v, currency = %v, position = %v, funds = %v)",
+				ex.GetName(), currency, positions[currency], result.Funds[currency]))
+		}
+	}
+}
+
+func (r *Robot) finishReconcile(result *ReconcileResult) {
+	r.reconcileMutex.Lock()
+	r.reconcileResults[result.Exchange] = result
+	r.reconcileMutex.Unlock()
+	r.logger.WithField(logger.FieldExchange, result.Exchange).Infof("reconcile (owned = %v, orphaned = %v, adopted = %v, canceled = %v, closed = %v, fills = %v, warnings = %v, errors = %v)",
+		len(result.OwnedOrders), len(result.OrphanedOrders), len(result.AdoptedOrders), len(result.CanceledOrders),
+		len(result.ClosedOrders), len(result.Fills), result.Warnings, result.Errors)
+	if len(result.OrphanedOrders) == 0 && len(result.Warnings) == 0 && len(result.Errors) == 0 {
+		return
+	}
+	if r.notifier == nil {
+		return
+	}
+	body := fmt.Sprintf("reconcile of %v needs attention.\n\norphaned orders (policy = %v):\n", result.Exchange, r.getReconcileConfig().OrphanPolicy)
+	for _, order := range result.OrphanedOrders {
+		body += fmt.Sprintf("  currency pair = %v, order id = %v, action = %v, price = %v, amount = %v\n",
+			order.CurrencyPair, order.OrderID, order.Action, order.Price, order.Amount)
+	}
+	body += "\nwarnings:\n"
+	for _, w := range result.Warnings {
+		body += fmt.Sprintf("  %v\n", w)
+	}
+	body += "\nerrors:\n"
+	for _, e := range result.Errors {
+		body += fmt.Sprintf("  %v\n", e)
+	}
+	severity := notifier.SeverityWarning
+	if len(result.Errors) > 0 {
+		severity = notifier.SeverityCritical
+	}
+	err := r.notifier.Notify(&notifier.Event{
+		Severity: severity,
+		Subject:  fmt.Sprintf("[ACT] reconcile of %v", result.Exchange),
+		Body:     body,
+	})
+	if err != nil {
+		r.logger.Errorf("can not send notification (reason = %v)", err)
+	}
+}
+
+// GetReconcileResults is get results of last reconciliation
+func (r *Robot) GetReconcileResults() (map[string]*ReconcileResult) {
+	r.reconcileMutex.Lock()
+	defer r.reconcileMutex.Unlock()
+	results := make(map[string]*ReconcileResult)
+	for name, result := range r.reconcileResults {
+		results[name] = result
+	}
+	return results
+}
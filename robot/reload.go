@@ -0,0 +1,274 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"fmt"
+	"time"
+)
+
+// ReloadResult is result of reload
+type ReloadResult struct {
+	LoadedAlgorithms []string `json:"loadedAlgorithms"`
+	Reloaded         []string `json:"reloaded"`
+	Started          []string `json:"started"`
+	// 設定から消えたので終了したもの
+	Stopped          []string `json:"stopped"`
+	Errors           []string `json:"errors"`
+	// 作成か初期化に失敗したインスタンス名
+	failedInstances  map[string]bool
+}
+
+func (r *Robot) addReloadError(result *ReloadResult, err error) {
+	r.logger.Errorf("reload error (reason = %v)", err)
+	result.Errors = append(result.Errors, err.Error())
+}
+
+func (r *Robot) addReloadFailure(result *ReloadResult, name string, err error) {
+	r.addReloadError(result, err)
+	result.failedInstances[name] = true
+}
+
+func newReloadResult() (*ReloadResult) {
+	return &ReloadResult{
+		LoadedAlgorithms: make([]string, 0),
+		Reloaded:         make([]string, 0),
+		Started:          make([]string, 0),
+		Stopped:          make([]string, 0),
+		Errors:           make([]string, 0),
+		failedInstances:  make(map[string]bool),
+	}
+}
+
+type reloadTarget struct {
+	// 設定ファイルの変更がなくても読み直すアルゴリズム名とインスタンス名
+	forceAlgorithms map[string]bool
+	forceInstances  map[string]bool
+}
+
+func (t *reloadTarget) needReload(info *algorithmInstanceInfo, configChanged bool) (bool) {
+	return configChanged || t.forceAlgorithms[info.algorithmName] || t.forceInstances[info.name]
+}
+
+// isConfigChanged is check that config file or robot config of instance is changed
+func isConfigChanged(oldInfo *algorithmInstanceInfo, configModTime time.Time, info *algorithmInstanceInfo) (bool) {
+	return !configModTime.Equal(info.getConfigModTime()) || !oldInfo.equal(info)
+}
+
+func (r *Robot) reloadInternalTradeAlgorithms(ex exchange.Exchange, instances []*internalTradeAlgorithmInstance, infos []*algorithmInstanceInfo, target *reloadTarget, result *ReloadResult) ([]*internalTradeAlgorithmInstance) {
+	registeredAlgorithms := algorithm.GetRegisterdAlgoriths()
+	oldInstances := make(map[string]*internalTradeAlgorithmInstance)
+	for _, instance := range instances {
+		oldInstances[instance.name] = instance
+	}
+	newInstances := make([]*internalTradeAlgorithmInstance, 0, len(instances))
+	for _, info := range infos {
+		registeredAlgorithm, ok := registeredAlgorithms[info.algorithmName]
+		if !ok || !registeredAlgorithm.HasInternalTradeAlgorithm() || !info.scope.inExchange(ex.GetName()) {
+			continue
+		}
+		label := fmt.Sprintf("%v@%v", info.name, ex.GetName())
+		oldInstance, ok := oldInstances[info.name]
+		if ok {
+			delete(oldInstances, info.name)
+			if !target.needReload(info, isConfigChanged(oldInstance.algorithmInstanceInfo, oldInstance.configModTime, info)) {
+				newInstances = append(newInstances, oldInstance)
+				continue
+			}
+		}
+		newInternalTradeAlgoritm, err := registeredAlgorithm.NewInternalTradeAlgorithm(info.configDir)
+		if err != nil {
+			// 新しい設定で作れない場合は古いものを動かし続ける
+			r.addReloadFailure(result, info.name, errors.Wrapf(err, "can not create internal algorithm (name = %v)", label))
+			if oldInstance != nil {
+				newInstances = append(newInstances, oldInstance)
+			}
+			continue
+		}
+		if oldInstance != nil {
+			r.logger.With(logger.Fields{
+				logger.FieldAlgorithm: info.name,
+				logger.FieldExchange:  ex.GetName(),
+			}).Infof("reload internal algorithm")
+			r.stopInternalTradeAlgorithm(oldInstance, ex)
+		} else {
+			r.logger.With(logger.Fields{
+				logger.FieldAlgorithm: info.name,
+				logger.FieldExchange:  ex.GetName(),
+			}).Infof("start internal algorithm")
+		}
+		newInstance, err := r.startInternalTradeAlgorithm(info, newInternalTradeAlgoritm, ex)
+		if err != nil {
+			r.addReloadFailure(result, info.name, err)
+			continue
+		}
+		newInstances = append(newInstances, newInstance)
+		if oldInstance != nil {
+			result.Reloaded = append(result.Reloaded, label)
+		} else {
+			result.Started = append(result.Started, label)
+		}
+	}
+	for _, instance := range instances {
+		_, ok := oldInstances[instance.name]
+		if ok {
+			// 設定から消えたもの、取引所が対象外になったものは終了する
+			r.logger.With(logger.Fields{
+				logger.FieldAlgorithm: instance.name,
+				logger.FieldExchange:  ex.GetName(),
+			}).Infof("stop internal algorithm removed from config")
+			r.stopInternalTradeAlgorithm(instance, ex)
+			result.Stopped = append(result.Stopped, fmt.Sprintf("%v@%v", instance.name, ex.GetName()))
+		}
+	}
+	return newInstances
+}
+
+func (r *Robot) reloadExternalTradeAlgorithms(exchanges map[string]exchange.Exchange, instances []*externalTradeAlgorithmInstance, infos []*algorithmInstanceInfo, target *reloadTarget, result *ReloadResult) ([]*externalTradeAlgorithmInstance) {
+	registeredAlgorithms := algorithm.GetRegisterdAlgoriths()
+	oldInstances := make(map[string]*externalTradeAlgorithmInstance)
+	for _, instance := range instances {
+		oldInstances[instance.name] = instance
+	}
+	newInstances := make([]*externalTradeAlgorithmInstance, 0, len(instances))
+	for _, info := range infos {
+		registeredAlgorithm, ok := registeredAlgorithms[info.algorithmName]
+		if !ok || !registeredAlgorithm.HasExternalTradeAlgorithm() {
+			continue
+		}
+		oldInstance, ok := oldInstances[info.name]
+		if ok {
+			delete(oldInstances, info.name)
+			if !target.needReload(info, isConfigChanged(oldInstance.algorithmInstanceInfo, oldInstance.configModTime, info)) {
+				newInstances = append(newInstances, oldInstance)
+				continue
+			}
+		}
+		newExternalTradeAlgoritm, err := registeredAlgorithm.NewExternalTradeAlgorithm(info.configDir)
+		if err != nil {
+			r.addReloadFailure(result, info.name, errors.Wrapf(err, "can not create external algorithm (name = %v)", info.name))
+			if oldInstance != nil {
+				newInstances = append(newInstances, oldInstance)
+			}
+			continue
+		}
+		if oldInstance != nil {
+			r.logger.WithField(logger.FieldAlgorithm, info.name).Infof("reload external algorithm")
+			r.stopExternalTradeAlgorithm(oldInstance, exchanges)
+		} else {
+			r.logger.WithField(logger.FieldAlgorithm, info.name).Infof("start external algorithm")
+		}
+		newInstance, err := r.startExternalTradeAlgorithm(info, newExternalTradeAlgoritm, exchanges)
+		if err != nil {
+			r.addReloadFailure(result, info.name, err)
+			continue
+		}
+		newInstances = append(newInstances, newInstance)
+		if oldInstance != nil {
+			result.Reloaded = append(result.Reloaded, info.name)
+		} else {
+			result.Started = append(result.Started, info.name)
+		}
+	}
+	for _, instance := range instances {
+		_, ok := oldInstances[instance.name]
+		if ok {
+			r.logger.WithField(logger.FieldAlgorithm, instance.name).Infof("stop external algorithm removed from config")
+			r.stopExternalTradeAlgorithm(instance, exchanges)
+			result.Stopped = append(result.Stopped, instance.name)
+		}
+	}
+	return newInstances
+}
+
+func (r *Robot) reload(target *reloadTarget, result *ReloadResult) {
+	infos := r.getAlgorithmInstanceInfos()
+
+	r.internalTradeAlgorithmsMutex.Lock()
+	internalExchanges := make(map[string]exchange.Exchange)
+	for name, ex := range r.internalExchanges {
+		internalExchanges[name] = ex
+	}
+	r.internalTradeAlgorithmsMutex.Unlock()
+	for name, ex := range internalExchanges {
+		newInstances := r.reloadInternalTradeAlgorithms(ex, r.getInternalTradeAlgorithms(name), infos, target, result)
+		r.internalTradeAlgorithmsMutex.Lock()
+		r.internalTradeAlgorithms[name] = newInstances
+		r.internalTradeAlgorithmsMutex.Unlock()
+	}
+
+	r.externalTradeAlgorithmsMutex.Lock()
+	externalExchanges := r.externalExchanges
+	r.externalTradeAlgorithmsMutex.Unlock()
+	if externalExchanges == nil {
+		// 外部取引アルゴリズムはまだ始まっていない
+		return
+	}
+	newInstances := r.reloadExternalTradeAlgorithms(externalExchanges, r.getExternalTradeAlgorithms(), infos, target, result)
+	r.externalTradeAlgorithmsMutex.Lock()
+	r.externalTradeAlgorithms = newInstances
+	r.externalTradeAlgorithmsMutex.Unlock()
+}
+
+// ReloadAlgorithms is rescan plugin directory, reload algorithms that config file changed and start new algorithms
+func (r *Robot) ReloadAlgorithms() (*ReloadResult) {
+	return r.ReloadAlgorithmsWithConfig(nil)
+}
+
+// ReloadAlgorithmsWithConfig is ReloadAlgorithms with algorithm instances of new robot config.
+// instances removed from config are finalized. other items of robot config are not applied until restart
+func (r *Robot) ReloadAlgorithmsWithConfig(config *Config) (*ReloadResult) {
+	r.reloadMutex.Lock()
+	defer r.reloadMutex.Unlock()
+	result := newReloadResult()
+	if config != nil {
+		err := config.validate()
+		if err != nil {
+			// 不正な設定なら今のインスタンスの設定のまま読み直す
+			r.addReloadError(result, errors.Wrap(err, "invalid robot config"))
+		} else {
+			r.setAlgorithmConfigs(config.Algorithms)
+		}
+	}
+	target := &reloadTarget{
+		forceAlgorithms: make(map[string]bool),
+		forceInstances:  make(map[string]bool),
+	}
+	for _, name := range r.loadPluginFiles() {
+		r.logger.Infof("load %v algorithm from plugin", name)
+		result.LoadedAlgorithms = append(result.LoadedAlgorithms, name)
+		// pluginから登録し直されたものは作り直す
+		target.forceAlgorithms[name] = true
+	}
+	r.reload(target, result)
+	return result
+}
+
+// ReloadAlgorithm is reload algorithm instance regardless of config file change
+func (r *Robot) ReloadAlgorithm(name string) (*ReloadResult, error) {
+	r.reloadMutex.Lock()
+	defer r.reloadMutex.Unlock()
+	return r.reloadAlgorithm(name)
+}
+
+func (r *Robot) reloadAlgorithm(name string) (*ReloadResult, error) {
+	found := false
+	for _, info := range r.getAlgorithmInstanceInfos() {
+		if info.name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("not found algorithm instance (name = %v)", name)
+	}
+	result := newReloadResult()
+	target := &reloadTarget{
+		forceAlgorithms: make(map[string]bool),
+		forceInstances:  map[string]bool{name: true},
+	}
+	r.reload(target, result)
+	return result, nil
+}
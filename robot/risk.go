@@ -0,0 +1,99 @@
+package robot
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"fmt"
+	"sync"
+)
+
+const (
+	riskNamespace = "risk"
+)
+
+// getCurrencyPosition is get total position of currency in exchange over all algorithm instances
+func (r *Robot) getCurrencyPosition(exchangeName string, currency string) (float64) {
+	var amount float64
+	for _, name := range r.positionLedger.GetNames() {
+		for _, p := range r.positionLedger.GetPositions(name) {
+			if p.Exchange == exchangeName && risk.BaseCurrency(p.CurrencyPair) == currency {
+				amount += p.Amount
+			}
+		}
+	}
+	return amount
+}
+
+// getOrderMutex is get mutex of exchange which is locked from risk check to placement of order
+func (r *Robot) getOrderMutex(exchangeName string) (*sync.Mutex) {
+	r.orderMutexesMutex.Lock()
+	defer r.orderMutexesMutex.Unlock()
+	orderMutex, ok := r.orderMutexes[exchangeName]
+	if !ok {
+		orderMutex = new(sync.Mutex)
+		r.orderMutexes[exchangeName] = orderMutex
+	}
+	return orderMutex
+}
+
+// checkOrder is check order by risk manager. order must be released by risk manager if it is not placed
+func (r *Robot) checkOrder(name string, ex exchange.Exchange, currencyPair string, action exchange.OrderAction, price float64, amount float64) (*risk.Order, error) {
+	order := &risk.Order{
+		Name:         name,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Action:       action,
+		Price:        price,
+		Amount:       amount,
+	}
+	if r.IsKilled() {
+		return order, risk.NewError(risk.RuleKillSwitch, order, "kill switch is on")
+	}
+	currency := risk.BaseCurrency(currencyPair)
+	openBuy, openSell := r.orderTracker.openAmounts(ex.GetName(), currency)
+	exposure := &risk.Exposure{
+		OpenOrders: r.orderTracker.count(ex.GetName()),
+		Position:   r.getCurrencyPosition(ex.GetName(), currency),
+		OpenBuy:    openBuy,
+		OpenSell:   openSell,
+	}
+	if r.riskManager.NeedLastPrice() {
+		lastPrice, err := ex.GetLastPrice(currencyPair)
+		if err != nil {
+			r.logger.With(logger.Fields{
+				logger.FieldExchange:     ex.GetName(),
+				logger.FieldCurrencyPair: currencyPair,
+			}).Warnf("can not get last price for risk check (reason = %v)", err)
+		}
+		exposure.LastPrice = lastPrice
+	}
+	err := r.riskManager.Check(order, exposure)
+	if err != nil {
+		r.onRiskViolation(err)
+	}
+	return order, err
+}
+
+func (r *Robot) onRiskViolation(err error) {
+	r.logger.Warnf("%v", err)
+	riskError, ok := risk.GetRiskError(err)
+	if !ok {
+		return
+	}
+	r.killOnRiskViolation(riskError)
+	if r.notifier == nil || !r.riskManager.ShouldNotify(riskError.Rule) {
+		return
+	}
+	subject := fmt.Sprintf("[ACT] order of %v is rejected (rule = %v)", riskError.Order.Name, riskError.Rule)
+	err = r.notifier.Notify(&notifier.Event{
+		Severity: notifier.SeverityWarning,
+		Subject:  subject,
+		Body:     err.Error(),
+		Source:   riskError.Order.Name,
+	})
+	if err != nil {
+		r.logger.Errorf("can not send notification (reason = %v)", err)
+	}
+}
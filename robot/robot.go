@@ -4,116 +4,480 @@ import (
 	"github.com/pkg/errors"
 	"github.com/AutomaticCoinTrader/ACT/exchange"
 	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/journal"
 	"github.com/AutomaticCoinTrader/ACT/notifier"
-	"log"
+	"github.com/AutomaticCoinTrader/ACT/position"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"github.com/AutomaticCoinTrader/ACT/rpcplugin"
+	"github.com/AutomaticCoinTrader/ACT/state"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"fmt"
-	"path"
+	"sort"
+	"sync"
+	"time"
 )
 
 type Robot struct {
-	config                  *Config
-	configDir               string
-	notifier                *notifier.Notifier
-	internalTradeAlgorithms []algorithm.InternalTradeAlgorithm
-	externalTradeAlgorithms []algorithm.ExternalTradeAlgorithm
+	config                       *Config
+	configDir                    string
+	algorithmConfigs             []*AlgorithmConfig
+	algorithmConfigsMutex        *sync.Mutex
+	notifier                     *notifier.Notifier
+	internalTradeAlgorithms      map[string][]*internalTradeAlgorithmInstance
+	internalExchanges            map[string]exchange.Exchange
+	internalTradeAlgorithmsMutex *sync.Mutex
+	externalTradeAlgorithms      []*externalTradeAlgorithmInstance
+	externalExchanges            map[string]exchange.Exchange
+	externalTradeAlgorithmsMutex *sync.Mutex
+	loadedPluginFiles            map[string]time.Time
+	processPluginHosts           []*rpcplugin.Host
+	stateStore                   *state.Store
+	orderTracker                 *orderTracker
+	positionLedger               *position.Ledger
+	riskManager                  *risk.Manager
+	journal                      *journal.Journal
+	killSwitch                   *killSwitch
+	reconcileResults             map[string]*ReconcileResult
+	reconcileMutex               *sync.Mutex
+	pausedAlgorithms             map[string]bool
+	pauseMutex                   *sync.Mutex
+	clock                        clock.Clock
+	reloadMutex                  *sync.Mutex
+	orderMutexes                 map[string]*sync.Mutex
+	orderMutexesMutex            *sync.Mutex
+	closeStoresOnce              *sync.Once
+	closeStoresErr               error
+	logger                       *logger.Logger
 }
 
-func (r *Robot) CreateInternalTradeAlgorithms(ex exchange.Exchange) (error) {
+func (r *Robot) getInternalTradeAlgorithms(exchangeName string) ([]*internalTradeAlgorithmInstance) {
+	r.internalTradeAlgorithmsMutex.Lock()
+	defer r.internalTradeAlgorithmsMutex.Unlock()
+	return r.internalTradeAlgorithms[exchangeName]
+}
+
+func (r *Robot) getExternalTradeAlgorithms() ([]*externalTradeAlgorithmInstance) {
+	r.externalTradeAlgorithmsMutex.Lock()
+	defer r.externalTradeAlgorithmsMutex.Unlock()
+	return r.externalTradeAlgorithms
+}
+
+func (r *Robot) startInternalTradeAlgorithm(info *algorithmInstanceInfo, newInternalTradeAlgoritm algorithm.InternalTradeAlgorithmV2, ex exchange.Exchange) (*internalTradeAlgorithmInstance, error) {
+	configModTime := info.getConfigModTime()
+	ctx := r.newInternalAlgorithmContext(info, ex)
+	err := r.restoreAlgorithmState(info.name, newInternalTradeAlgoritm, ctx.State)
+	if err != nil {
+		return nil, err
+	}
+	err = r.callAlgorithm(info.name, func() (error) {
+		return newInternalTradeAlgoritm.Initialize(ctx)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("internal algorithm initialize error of %v (exchange = %v)", info.name, ex.GetName()))
+	}
+	instance := &internalTradeAlgorithmInstance{
+		algorithmInstanceInfo: info,
+		algorithm:             newInternalTradeAlgoritm,
+		context:               ctx,
+		configModTime:         configModTime,
+	}
+	instance.runner = newAlgorithmRunner(info.name, info.algorithmName, ex.GetName(), info.mailboxSize, info.mailboxPolicy,
+		func(currencyPair string) (error) {
+			if r.IsKilled() {
+				// キルスイッチが入っている間はアルゴリズムを動かさない
+				return nil
+			}
+			return instance.algorithm.Update(instance.context, currencyPair)
+		}, r.onAlgorithmPanic, r.clock)
+	// 作り直しても一時停止したまま
+	instance.runner.setPaused(r.isAlgorithmPaused(info.name))
+	instance.runner.start()
+	return instance, nil
+}
+
+func (r *Robot) stopInternalTradeAlgorithm(instance *internalTradeAlgorithmInstance, ex exchange.Exchange) {
+	instance.runner.stop()
+	err := r.callAlgorithm(instance.name, func() (error) {
+		return instance.algorithm.Finalize(instance.context)
+	})
+	if err != nil {
+		r.logger.With(logger.Fields{
+			logger.FieldAlgorithm: instance.name,
+			logger.FieldExchange:  ex.GetName(),
+		}).Errorf("internal algorithm finalize error (reason = %v)", err)
+	}
+	r.snapshotAlgorithmState(instance.name, instance.algorithm, instance.context.State)
+}
 
-	for name, registeredAlgorithm := range algorithm.GetRegisterdAlgoriths() {
-		if registeredAlgorithm.InternalTradeAlgorithmNewFunc == nil {
+func (r *Robot) CreateInternalTradeAlgorithms(ex exchange.Exchange) (error) {
+	registeredAlgorithms := algorithm.GetRegisterdAlgoriths()
+	instances := make([]*internalTradeAlgorithmInstance, 0)
+	for _, info := range r.getAlgorithmInstanceInfos() {
+		registeredAlgorithm, ok := registeredAlgorithms[info.algorithmName]
+		if !ok {
+			r.logger.WithField(logger.FieldAlgorithm, info.name).Warnf("not found algorithm (algorithm = %v)", info.algorithmName)
+			continue
+		}
+		if !registeredAlgorithm.HasInternalTradeAlgorithm() {
+			continue
+		}
+		if !info.scope.inExchange(ex.GetName()) {
 			continue
 		}
-		log.Printf("create %v internal algorithm", name)
-		newInternalTradeAlgoritm, err := registeredAlgorithm.InternalTradeAlgorithmNewFunc(path.Join(r.configDir, algorithm.AlgorithmConfigDir))
+		r.logger.With(logger.Fields{
+			logger.FieldAlgorithm: info.name,
+			logger.FieldExchange:  ex.GetName(),
+		}).Infof("create internal algorithm (algorithm = %v)", info.algorithmName)
+		newInternalTradeAlgoritm, err := registeredAlgorithm.NewInternalTradeAlgorithm(info.configDir)
 		if err != nil {
-			log.Printf("can not create internal algorithm of %v (reason = %v)", name, err)
+			r.logger.WithField(logger.FieldAlgorithm, info.name).Errorf("can not create internal algorithm (reason = %v)", err)
 			continue
 		}
-		err = newInternalTradeAlgoritm.Initialize(ex, r.notifier)
+		instance, err := r.startInternalTradeAlgorithm(info, newInternalTradeAlgoritm, ex)
 		if err != nil {
-			r.DestroyInternalTradeAlgorithms(ex)
-			return errors.Wrap(err, fmt.Sprintf("internal algorithm initialize error of %v", name))
+			// この取引所で初期化済みのものだけ終了させる
+			for _, instance := range instances {
+				r.stopInternalTradeAlgorithm(instance, ex)
+			}
+			return err
 		}
-		r.internalTradeAlgorithms = append(r.internalTradeAlgorithms, newInternalTradeAlgoritm)
+		instances = append(instances, instance)
 	}
-
+	r.internalTradeAlgorithmsMutex.Lock()
+	defer r.internalTradeAlgorithmsMutex.Unlock()
+	r.internalTradeAlgorithms[ex.GetName()] = instances
+	r.internalExchanges[ex.GetName()] = ex
 	return nil
 }
 
 func (r *Robot) UpdateInternalTradeAlgorithms(currencyPair string, ex exchange.Exchange) (error) {
-	for _, tradeAlgorithm := range r.internalTradeAlgorithms {
-		err := tradeAlgorithm.Update(currencyPair, ex, r.notifier)
-		if err != nil {
-			log.Printf("internal algorithm update error (name = %v, reason = %v)", tradeAlgorithm.GetName(), err)
+	for _, instance := range r.getInternalTradeAlgorithms(ex.GetName()) {
+		if !instance.scope.inCurrencyPair(currencyPair) {
+			continue
 		}
+		// 実際の更新はアルゴリズムごとのgoroutineで行う
+		instance.runner.post(currencyPair)
 	}
 	return nil
 }
 
 func (r *Robot) DestroyInternalTradeAlgorithms(ex exchange.Exchange) (error) {
-	for _, tradeAlgorithm := range r.internalTradeAlgorithms {
-		err := tradeAlgorithm.Finalize(ex, r.notifier)
-		if err != nil {
-			log.Printf("internal algorithm finalize error (name = %v, reason = %v)", tradeAlgorithm.GetName(), err)
-		}
+	r.reloadMutex.Lock()
+	defer r.reloadMutex.Unlock()
+	r.internalTradeAlgorithmsMutex.Lock()
+	instances := r.internalTradeAlgorithms[ex.GetName()]
+	delete(r.internalTradeAlgorithms, ex.GetName())
+	delete(r.internalExchanges, ex.GetName())
+	r.internalTradeAlgorithmsMutex.Unlock()
+	for _, instance := range instances {
+		r.stopInternalTradeAlgorithm(instance, ex)
 	}
 	return nil
 }
 
+func (r *Robot) scopedExchanges(scope *algorithmScope, exchanges map[string]exchange.Exchange) (map[string]exchange.Exchange) {
+	scopedExchanges := make(map[string]exchange.Exchange)
+	for name, ex := range exchanges {
+		if !scope.inExchange(name) {
+			continue
+		}
+		scopedExchanges[name] = ex
+	}
+	return scopedExchanges
+}
+
+func (r *Robot) startExternalTradeAlgorithm(info *algorithmInstanceInfo, newExternalTradeAlgoritm algorithm.ExternalTradeAlgorithmV2, exchanges map[string]exchange.Exchange) (*externalTradeAlgorithmInstance, error) {
+	configModTime := info.getConfigModTime()
+	ctx := r.newExternalAlgorithmContext(info, exchanges)
+	err := r.restoreAlgorithmState(info.name, newExternalTradeAlgoritm, ctx.State)
+	if err != nil {
+		return nil, err
+	}
+	err = r.callAlgorithm(info.name, func() (error) {
+		return newExternalTradeAlgoritm.Initialize(ctx)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("external algorithm initialize error of %v", info.name))
+	}
+	instance := &externalTradeAlgorithmInstance{
+		algorithmInstanceInfo: info,
+		algorithm:             newExternalTradeAlgoritm,
+		context:               ctx,
+		triggers:              r.getTriggers(info, newExternalTradeAlgoritm),
+		configModTime:         configModTime,
+	}
+	instance.runner = newAlgorithmRunner(info.name, info.algorithmName, "", info.mailboxSize, info.mailboxPolicy,
+		func(_ string) (error) {
+			if r.IsKilled() {
+				return nil
+			}
+			return instance.algorithm.Update(instance.context)
+		}, r.onAlgorithmPanic, r.clock)
+	// 作り直しても一時停止したまま
+	instance.runner.setPaused(r.isAlgorithmPaused(info.name))
+	instance.runner.start()
+	if instance.triggers.Interval > 0 {
+		instance.intervalTrigger = r.startIntervalTrigger(time.Duration(instance.triggers.Interval)*time.Millisecond, instance.runner)
+	}
+	return instance, nil
+}
+
+func (r *Robot) stopExternalTradeAlgorithm(instance *externalTradeAlgorithmInstance, exchanges map[string]exchange.Exchange) {
+	if instance.intervalTrigger != nil {
+		instance.intervalTrigger.stop()
+	}
+	instance.runner.stop()
+	err := r.callAlgorithm(instance.name, func() (error) {
+		return instance.algorithm.Finalize(instance.context)
+	})
+	if err != nil {
+		r.logger.WithField(logger.FieldAlgorithm, instance.name).Errorf("external algorithm finalize error (reason = %v)", err)
+	}
+	r.snapshotAlgorithmState(instance.name, instance.algorithm, instance.context.State)
+}
+
 func (r *Robot) CreateExternalTradeAlgorithms(exchanges map[string]exchange.Exchange) (error) {
-	for name, registeredAlgorithm := range algorithm.GetRegisterdAlgoriths() {
-		if registeredAlgorithm.ExternalTradeAlgorithmNewFunc == nil {
+	registeredAlgorithms := algorithm.GetRegisterdAlgoriths()
+	instances := make([]*externalTradeAlgorithmInstance, 0)
+	for _, info := range r.getAlgorithmInstanceInfos() {
+		registeredAlgorithm, ok := registeredAlgorithms[info.algorithmName]
+		if !ok {
+			r.logger.WithField(logger.FieldAlgorithm, info.name).Warnf("not found algorithm (algorithm = %v)", info.algorithmName)
+			continue
+		}
+		if !registeredAlgorithm.HasExternalTradeAlgorithm() {
 			continue
 		}
-		log.Printf("create %v external algorithm", name)
-		newExternalTradeAlgoritm, err := registeredAlgorithm.ExternalTradeAlgorithmNewFunc(path.Join(r.configDir, algorithm.AlgorithmConfigDir))
+		r.logger.WithField(logger.FieldAlgorithm, info.name).Infof("create external algorithm (algorithm = %v)", info.algorithmName)
+		newExternalTradeAlgoritm, err := registeredAlgorithm.NewExternalTradeAlgorithm(info.configDir)
 		if err != nil {
-			log.Printf("can not create external algorithm of %v (reason = %v)", name, err)
+			r.logger.WithField(logger.FieldAlgorithm, info.name).Errorf("can not create external algorithm (reason = %v)", err)
 			continue
 		}
-		err = newExternalTradeAlgoritm.Initialize(exchanges, r.notifier)
+		instance, err := r.startExternalTradeAlgorithm(info, newExternalTradeAlgoritm, exchanges)
 		if err != nil {
-			r.DestroyExternalTradeAlgorithms(exchanges)
-			return errors.Wrap(err, fmt.Sprintf("external algorithm initialize error of %v", name))
+			for _, instance := range instances {
+				r.stopExternalTradeAlgorithm(instance, exchanges)
+			}
+			return err
 		}
-		r.externalTradeAlgorithms = append(r.externalTradeAlgorithms, newExternalTradeAlgoritm)
+		instances = append(instances, instance)
 	}
+	r.externalTradeAlgorithmsMutex.Lock()
+	defer r.externalTradeAlgorithmsMutex.Unlock()
+	r.externalTradeAlgorithms = instances
+	r.externalExchanges = exchanges
 	return nil
 }
 
-func (r *Robot) UpdateExternalTradeAlgorithms(exchanges map[string]exchange.Exchange) (error) {
-	for _, externalTradeAlgoritm := range r.externalTradeAlgorithms {
-		err := externalTradeAlgoritm.Update(exchanges, r.notifier)
-		if err != nil {
-			log.Printf("external algorithm update error (name = %v, reason = %v)", externalTradeAlgoritm.GetName(), err)
-		}
+func (r *Robot) DestroyExternalTradeAlgorithms(exchanges map[string]exchange.Exchange) (error) {
+	r.reloadMutex.Lock()
+	defer r.reloadMutex.Unlock()
+	r.externalTradeAlgorithmsMutex.Lock()
+	instances := r.externalTradeAlgorithms
+	r.externalTradeAlgorithms = make([]*externalTradeAlgorithmInstance, 0)
+	r.externalExchanges = nil
+	r.externalTradeAlgorithmsMutex.Unlock()
+	for _, instance := range instances {
+		r.stopExternalTradeAlgorithm(instance, exchanges)
 	}
 	return nil
 }
 
-func (r *Robot) DestroyExternalTradeAlgorithms(exchanges map[string]exchange.Exchange) (error) {
-	for _, externalTradeAlgoritm := range r.externalTradeAlgorithms {
-		err := externalTradeAlgoritm.Finalize(exchanges, r.notifier)
-		if err != nil {
-			log.Printf("external algorithm finalize error (name = %v, reason = %v)", externalTradeAlgoritm.GetName(), err)
+// GetAlgorithmStats is get statistics of all algorithm instances
+func (r *Robot) GetAlgorithmStats() ([]*AlgorithmStats) {
+	stats := make([]*AlgorithmStats, 0)
+	r.internalTradeAlgorithmsMutex.Lock()
+	for _, instances := range r.internalTradeAlgorithms {
+		for _, instance := range instances {
+			stats = append(stats, instance.runner.getStats())
 		}
 	}
-	return nil
+	r.internalTradeAlgorithmsMutex.Unlock()
+	for _, instance := range r.getExternalTradeAlgorithms() {
+		stats = append(stats, instance.runner.getStats())
+	}
+	sort.Slice(stats, func(i, j int) (bool) {
+		if stats[i].Name != stats[j].Name {
+			return stats[i].Name < stats[j].Name
+		}
+		return stats[i].Exchange < stats[j].Exchange
+	})
+	return stats
+}
+
+type AlgorithmConfig struct {
+	Name          string              `json:"name"          yaml:"name"          toml:"name"`
+	Algorithm     string              `json:"algorithm"     yaml:"algorithm"     toml:"algorithm"`
+	ConfigDir     string              `json:"configDir"     yaml:"configDir"     toml:"configDir"`
+	Exchanges     []string            `json:"exchanges"     yaml:"exchanges"     toml:"exchanges"`
+	CurrencyPairs []string            `json:"currencyPairs" yaml:"currencyPairs" toml:"currencyPairs"`
+	Disable       bool                `json:"disable"       yaml:"disable"       toml:"disable"`
+	MailboxSize   int                 `json:"mailboxSize"   yaml:"mailboxSize"   toml:"mailboxSize"`
+	MailboxPolicy string              `json:"mailboxPolicy" yaml:"mailboxPolicy" toml:"mailboxPolicy"`
+	// 外部アルゴリズムを起こす契機。アルゴリズムが宣言したものより優先される
+	Triggers      *algorithm.Triggers `json:"triggers"      yaml:"triggers"      toml:"triggers"`
 }
 
 type Config struct {
-	AlgorithmPluginDir string `json:"algorithmPluginDir" yaml:"algorithmPluginDir" toml:"algorithmPluginDir"`
+	AlgorithmPluginDir string              `json:"algorithmPluginDir" yaml:"algorithmPluginDir" toml:"algorithmPluginDir"`
+	Algorithms         []*AlgorithmConfig  `json:"algorithms"         yaml:"algorithms"         toml:"algorithms"`
+	ProcessPlugins     []*rpcplugin.Config `json:"processPlugins"     yaml:"processPlugins"     toml:"processPlugins"`
+	StateFile          string              `json:"stateFile"          yaml:"stateFile"          toml:"stateFile"`
+	// 約定を確認する間隔(秒)
+	FillPollInterval   int                 `json:"fillPollInterval"   yaml:"fillPollInterval"   toml:"fillPollInterval"`
+	// アルゴリズムの注文の制限
+	Risk               *risk.Config        `json:"risk"               yaml:"risk"               toml:"risk"`
+	KillSwitch         *KillSwitchConfig   `json:"killSwitch"         yaml:"killSwitch"         toml:"killSwitch"`
+	Reconcile          *ReconcileConfig    `json:"reconcile"          yaml:"reconcile"          toml:"reconcile"`
+	// 注文と約定の記録。省略時はメモリ上にだけ保持する
+	JournalFile        string              `json:"journalFile"        yaml:"journalFile"        toml:"journalFile"`
+}
+
+func (c *Config) validate() (error) {
+	names := make(map[string]bool)
+	for _, algorithmConfig := range c.Algorithms {
+		if algorithmConfig.Algorithm == "" {
+			return errors.Errorf("no algorithm in algorithm config (name = %v)", algorithmConfig.Name)
+		}
+		name := algorithmConfig.Name
+		if name == "" {
+			name = algorithmConfig.Algorithm
+		}
+		if names[name] {
+			return errors.Errorf("duplicate algorithm instance name (name = %v)", name)
+		}
+		_, err := getMailboxPolicy(algorithmConfig.MailboxPolicy)
+		if err != nil {
+			return errors.Wrapf(err, "invalid mailbox policy (name = %v)", name)
+		}
+		names[name] = true
+	}
+	pluginNames := make(map[string]bool)
+	for _, processPluginConfig := range c.ProcessPlugins {
+		err := processPluginConfig.Validate()
+		if err != nil {
+			return errors.Wrap(err, "invalid process plugin config")
+		}
+		if pluginNames[processPluginConfig.Name] {
+			return errors.Errorf("duplicate process plugin name (name = %v)", processPluginConfig.Name)
+		}
+		pluginNames[processPluginConfig.Name] = true
+	}
+	if c.Risk != nil {
+		err := c.Risk.Validate()
+		if err != nil {
+			return errors.Wrap(err, "invalid risk config")
+		}
+	}
+	if c.Reconcile != nil {
+		err := c.Reconcile.validate()
+		if err != nil {
+			return errors.Wrap(err, "invalid reconcile config")
+		}
+	}
+	return nil
 }
 
 func NewRobot(config *Config, configDir string, notifier *notifier.Notifier) (*Robot, error) {
+	if config != nil {
+		err := config.validate()
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid robot config")
+		}
+	}
 	r := &Robot{
-		config:                  config,
-		configDir:               configDir,
-		notifier:                notifier,
-		internalTradeAlgorithms: make([]algorithm.InternalTradeAlgorithm, 0),
-		externalTradeAlgorithms: make([]algorithm.ExternalTradeAlgorithm, 0),
+		config:                       config,
+		configDir:                    configDir,
+		algorithmConfigsMutex:        new(sync.Mutex),
+		notifier:                     notifier,
+		internalTradeAlgorithms:      make(map[string][]*internalTradeAlgorithmInstance),
+		internalExchanges:            make(map[string]exchange.Exchange),
+		internalTradeAlgorithmsMutex: new(sync.Mutex),
+		externalTradeAlgorithms:      make([]*externalTradeAlgorithmInstance, 0),
+		externalTradeAlgorithmsMutex: new(sync.Mutex),
+		loadedPluginFiles:            make(map[string]time.Time),
+		processPluginHosts:           make([]*rpcplugin.Host, 0),
+		killSwitch:                   newKillSwitch(),
+		reconcileResults:             make(map[string]*ReconcileResult),
+		reconcileMutex:               new(sync.Mutex),
+		pausedAlgorithms:             make(map[string]bool),
+		pauseMutex:                   new(sync.Mutex),
+		clock:                        clock.Default(),
+		reloadMutex:                  new(sync.Mutex),
+		orderMutexes:                 make(map[string]*sync.Mutex),
+		orderMutexesMutex:            new(sync.Mutex),
+		closeStoresOnce:              new(sync.Once),
+		logger:                       logger.Get("robot"),
+	}
+	var riskConfig *risk.Config
+	if config != nil {
+		r.algorithmConfigs = config.Algorithms
+		riskConfig = config.Risk
+	}
+	r.riskManager = risk.NewManager(riskConfig, r.clock)
+	stateStore, err := state.NewStore(r.stateFilePath())
+	if err != nil {
+		return nil, errors.Wrap(err, "can not create state store")
+	}
+	r.stateStore = stateStore
+	err = r.riskManager.SetStore(stateStore.Namespace(riskNamespace))
+	if err != nil {
+		r.stateStore.Close()
+		return nil, errors.Wrap(err, "can not restore risk state")
+	}
+	positionLedger, err := position.NewLedger(stateStore.Namespace(positionNamespace))
+	if err != nil {
+		r.stateStore.Close()
+		return nil, errors.Wrap(err, "can not create position ledger")
+	}
+	r.positionLedger = positionLedger
+	orderTracker, err := newOrderTracker(stateStore.Namespace(orderNamespace))
+	if err != nil {
+		r.stateStore.Close()
+		return nil, errors.Wrap(err, "can not create order tracker")
+	}
+	r.orderTracker = orderTracker
+	orderJournal, err := journal.NewJournal(r.journalFilePath())
+	if err != nil {
+		r.stateStore.Close()
+		return nil, errors.Wrap(err, "can not open journal")
 	}
+	r.journal = orderJournal
 	r.loadPluginFiles()
+	err = r.startProcessPlugins()
+	if err != nil {
+		r.journal.Close()
+		r.stateStore.Close()
+		return nil, err
+	}
 	return r, nil
 }
+
+// SetClock is set clock passed to algorithms. it should be called before creating algorithms
+func (r *Robot) SetClock(clock clock.Clock) {
+	r.clock = clock
+	r.riskManager.SetClock(clock)
+}
+
+// Finalize is stop plugin processes and close state store
+func (r *Robot) Finalize() (error) {
+	r.stopProcessPlugins()
+	return r.CloseStores()
+}
+
+// CloseStores is close journal and state store. it can be called before Finalize when finalize of algorithms does not finish
+func (r *Robot) CloseStores() (error) {
+	r.closeStoresOnce.Do(func() {
+		err := r.journal.Close()
+		if err != nil {
+			r.logger.Errorf("can not close journal (reason = %v)", err)
+		}
+		err = r.stateStore.Close()
+		if err != nil {
+			r.closeStoresErr = errors.Wrap(err, "can not close state store")
+		}
+	})
+	return r.closeStoresErr
+}
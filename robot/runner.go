@@ -0,0 +1,322 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type MailboxPolicy string
+
+const (
+	// 同じ通貨ペアの更新がまだ処理されていなければ新しい更新は捨てる
+	MailboxPolicyCoalesce MailboxPolicy = "coalesce"
+	// mailboxが一杯なら一番古い更新を捨てる
+	MailboxPolicyDropOldest MailboxPolicy = "dropOldest"
+	// mailboxが一杯なら新しい更新を捨てる
+	MailboxPolicyDropNewest MailboxPolicy = "dropNewest"
+)
+
+const (
+	AlgorithmStatusRunning  = "running"
+	AlgorithmStatusPaused   = "paused"
+	AlgorithmStatusDraining = "draining"
+	AlgorithmStatusDisabled = "disabled"
+)
+
+const (
+	AlgorithmKindInternal = "internal"
+	AlgorithmKindExternal = "external"
+)
+
+const (
+	defaultMailboxSize   = 64
+	defaultMailboxPolicy = MailboxPolicyCoalesce
+)
+
+func getMailboxPolicy(policy string) (MailboxPolicy, error) {
+	switch MailboxPolicy(policy) {
+	case "":
+		return defaultMailboxPolicy, nil
+	case MailboxPolicyCoalesce, MailboxPolicyDropOldest, MailboxPolicyDropNewest:
+		return MailboxPolicy(policy), nil
+	default:
+		return "", errors.Errorf("unexpected mailbox policy (%v)", policy)
+	}
+}
+
+// AlgorithmStats is statistics of algorithm instance
+type AlgorithmStats struct {
+	Name           string        `json:"name"`
+	Algorithm      string        `json:"algorithm"`
+	Kind           string        `json:"kind"`
+	Exchange       string        `json:"exchange"`
+	Status         string        `json:"status"`
+	Updates        uint64        `json:"updates"`
+	Errors         uint64        `json:"errors"`
+	Dropped        uint64        `json:"dropped"`
+	Panics         uint64        `json:"panics"`
+	Pending        int           `json:"pending"`
+	LastLatency    time.Duration `json:"lastLatency"`
+	MaxLatency     time.Duration `json:"maxLatency"`
+	AverageLatency time.Duration `json:"averageLatency"`
+	LastUpdateTime time.Time     `json:"lastUpdateTime"`
+	LastError      string        `json:"lastError"`
+	Disabled       bool          `json:"disabled"`
+	Paused         bool          `json:"paused"`
+}
+
+type updateFunc func(key string) (error)
+
+type panicCallback func(runner *algorithmRunner, reason interface{}, stack []byte)
+
+// algorithmRunner runs updates of one algorithm instance in its own goroutine
+type algorithmRunner struct {
+	name          string
+	algorithmName string
+	exchangeName  string
+	update        updateFunc
+	panicCallback panicCallback
+	policy        MailboxPolicy
+	mailbox       chan string
+	pending       map[string]bool
+	stopChan      chan bool
+	drainChan     chan bool
+	draining      bool
+	finishChan    chan bool
+	mutex         *sync.Mutex
+	stats         AlgorithmStats
+	totalLatency  time.Duration
+	clock         clock.Clock
+	logger        *logger.Logger
+}
+
+// dropped is must be called with mutex locked
+func (a *algorithmRunner) dropped() {
+	a.stats.Dropped++
+	metrics.IncAlgorithmUpdateDropped(a.name, a.exchangeName)
+}
+
+func (a *algorithmRunner) post(key string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.stats.Disabled || a.stats.Paused || a.draining {
+		return
+	}
+	if a.policy == MailboxPolicyCoalesce {
+		if a.pending[key] {
+			a.dropped()
+			return
+		}
+	}
+	for {
+		select {
+		case a.mailbox <- key:
+			a.pending[key] = true
+			return
+		default:
+		}
+		switch a.policy {
+		case MailboxPolicyDropOldest:
+			select {
+			case oldKey := <-a.mailbox:
+				delete(a.pending, oldKey)
+				a.dropped()
+			default:
+			}
+			continue
+		default:
+			a.dropped()
+			return
+		}
+	}
+}
+
+func (a *algorithmRunner) safeUpdate(key string) (err error) {
+	defer func() {
+		if reason := recover(); reason != nil {
+			err = errors.Errorf("panic in algorithm (name = %v, reason = %v)", a.name, reason)
+			a.mutex.Lock()
+			a.stats.Panics++
+			a.stats.Disabled = true
+			a.mutex.Unlock()
+			if a.panicCallback != nil {
+				a.panicCallback(a, reason, debug.Stack())
+			}
+		}
+	}()
+	return a.update(key)
+}
+
+func (a *algorithmRunner) process(key string) {
+	a.mutex.Lock()
+	delete(a.pending, key)
+	// 止める前にmailboxに入っていた更新も捨てる
+	skip := a.stats.Disabled || a.stats.Paused
+	a.mutex.Unlock()
+	if skip {
+		return
+	}
+	start := a.clock.Now()
+	err := a.safeUpdate(key)
+	latency := a.clock.Since(start)
+	a.mutex.Lock()
+	a.stats.Updates++
+	a.stats.LastLatency = latency
+	if latency > a.stats.MaxLatency {
+		a.stats.MaxLatency = latency
+	}
+	a.totalLatency += latency
+	a.stats.LastUpdateTime = start
+	if err != nil {
+		a.stats.Errors++
+		a.stats.LastError = err.Error()
+	}
+	a.mutex.Unlock()
+	metrics.ObserveAlgorithmUpdate(a.name, a.exchangeName, latency, err)
+	if err != nil {
+		updateLogger := a.logger
+		if key != "" {
+			// 取引所を跨いだ取引のアルゴリズムは通貨ペアを持たない
+			updateLogger = updateLogger.WithField(logger.FieldCurrencyPair, key)
+		}
+		updateLogger.Warnf("algorithm update error (reason = %v)", err)
+	}
+}
+
+func (a *algorithmRunner) run() {
+	defer close(a.finishChan)
+	for {
+		select {
+		case <-a.stopChan:
+			return
+		case <-a.drainChan:
+			// 残っている更新を全て処理してから終わる
+			for {
+				select {
+				case <-a.stopChan:
+					return
+				case key := <-a.mailbox:
+					a.process(key)
+				default:
+					return
+				}
+			}
+		case key := <-a.mailbox:
+			a.process(key)
+		}
+	}
+}
+
+func (a *algorithmRunner) start() {
+	go a.run()
+}
+
+// stop is stop runner and wait for current update
+func (a *algorithmRunner) stop() {
+	close(a.stopChan)
+	<-a.finishChan
+}
+
+// drain is stop accepting updates and wait until pending updates are processed
+func (a *algorithmRunner) drain() {
+	a.mutex.Lock()
+	if a.draining {
+		a.mutex.Unlock()
+		<-a.finishChan
+		return
+	}
+	a.draining = true
+	a.mutex.Unlock()
+	close(a.drainChan)
+	<-a.finishChan
+}
+
+// setPaused is pause or resume updates. market data is still received but not passed to algorithm
+func (a *algorithmRunner) setPaused(paused bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.stats.Paused = paused
+}
+
+func (a *algorithmRunner) getStats() (*AlgorithmStats) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	stats := a.stats
+	stats.Pending = len(a.mailbox)
+	switch {
+	case stats.Disabled:
+		stats.Status = AlgorithmStatusDisabled
+	case a.draining:
+		stats.Status = AlgorithmStatusDraining
+	case stats.Paused:
+		stats.Status = AlgorithmStatusPaused
+	default:
+		stats.Status = AlgorithmStatusRunning
+	}
+	if stats.Updates > 0 {
+		stats.AverageLatency = a.totalLatency / time.Duration(stats.Updates)
+	}
+	return &stats
+}
+
+func newAlgorithmRunner(name string, algorithmName string, exchangeName string, mailboxSize int, policy MailboxPolicy, update updateFunc, panicCallback panicCallback, clock clock.Clock) (*algorithmRunner) {
+	if mailboxSize <= 0 {
+		mailboxSize = defaultMailboxSize
+	}
+	// 取引所を跨いだ取引のアルゴリズムは取引所を持たない
+	kind := AlgorithmKindInternal
+	runnerLogger := logger.Get("robot").WithField(logger.FieldAlgorithm, name)
+	if exchangeName == "" {
+		kind = AlgorithmKindExternal
+	} else {
+		runnerLogger = runnerLogger.WithField(logger.FieldExchange, exchangeName)
+	}
+	return &algorithmRunner{
+		name:          name,
+		algorithmName: algorithmName,
+		exchangeName:  exchangeName,
+		update:        update,
+		panicCallback: panicCallback,
+		policy:        policy,
+		mailbox:       make(chan string, mailboxSize),
+		pending:       make(map[string]bool),
+		stopChan:      make(chan bool),
+		drainChan:     make(chan bool),
+		finishChan:    make(chan bool),
+		mutex:         new(sync.Mutex),
+		clock:         clock,
+		logger:        runnerLogger,
+		stats: AlgorithmStats{
+			Name:      name,
+			Algorithm: algorithmName,
+			Kind:      kind,
+			Exchange:  exchangeName,
+		},
+	}
+}
+
+func (r *Robot) onAlgorithmPanic(runner *algorithmRunner, reason interface{}, stack []byte) {
+	runner.logger.Errorf("algorithm panic, disabled (reason = %v)\n%s", reason, stack)
+	if r.notifier == nil {
+		return
+	}
+	subject := fmt.Sprintf("[ACT] algorithm %v is disabled", runner.name)
+	body := fmt.Sprintf("algorithm panic occurred and disabled.\nname = %v\nalgorithm = %v\nexchange = %v\nreason = %v\n\n%s",
+		runner.name, runner.algorithmName, runner.exchangeName, reason, stack)
+	err := r.notifier.Notify(&notifier.Event{
+		Severity: notifier.SeverityCritical,
+		Subject:  subject,
+		Body:     body,
+		Source:   runner.name,
+	})
+	if err != nil {
+		r.logger.Errorf("can not send notification (reason = %v)", err)
+	}
+}
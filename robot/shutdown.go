@@ -0,0 +1,46 @@
+package robot
+
+import (
+	"sync"
+)
+
+func (r *Robot) getAllRunners() ([]*algorithmRunner) {
+	runners := make([]*algorithmRunner, 0)
+	r.internalTradeAlgorithmsMutex.Lock()
+	for _, instances := range r.internalTradeAlgorithms {
+		for _, instance := range instances {
+			runners = append(runners, instance.runner)
+		}
+	}
+	r.internalTradeAlgorithmsMutex.Unlock()
+	for _, instance := range r.getExternalTradeAlgorithms() {
+		runners = append(runners, instance.runner)
+	}
+	return runners
+}
+
+// DrainAlgorithms is stop accepting updates and wait until all pending updates of algorithms are processed
+func (r *Robot) DrainAlgorithms() {
+	runners := r.getAllRunners()
+	wg := new(sync.WaitGroup)
+	for _, runner := range runners {
+		wg.Add(1)
+		go func(runner *algorithmRunner) {
+			defer wg.Done()
+			runner.drain()
+		}(runner)
+	}
+	wg.Wait()
+	r.logger.Infof("algorithms are drained (instances = %v)", len(runners))
+}
+
+// GetUndrainedAlgorithms is get names of algorithm instances that still have pending updates
+func (r *Robot) GetUndrainedAlgorithms() ([]string) {
+	names := make([]string, 0)
+	for _, stats := range r.GetAlgorithmStats() {
+		if stats.Pending > 0 {
+			names = append(names, stats.Name)
+		}
+	}
+	return names
+}
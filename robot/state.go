@@ -0,0 +1,57 @@
+package robot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+func (r *Robot) stateFilePath() (string) {
+	if r.config == nil || r.config.StateFile == "" {
+		// 設定がなければメモリ上にだけ保持する
+		return ""
+	}
+	if filepath.IsAbs(r.config.StateFile) {
+		return r.config.StateFile
+	}
+	return path.Join(r.configDir, r.config.StateFile)
+}
+
+func (r *Robot) internalStateNamespace(name string, exchangeName string) (algorithm.StateStore) {
+	// 取引所内取引のアルゴリズムは取引所ごとにインスタンスが作られる
+	return r.stateStore.Namespace(fmt.Sprintf("internal/%v@%v", name, exchangeName))
+}
+
+func (r *Robot) externalStateNamespace(name string) (algorithm.StateStore) {
+	return r.stateStore.Namespace(fmt.Sprintf("external/%v", name))
+}
+
+func (r *Robot) restoreAlgorithmState(name string, alg interface{}, store algorithm.StateStore) (error) {
+	statefulAlgorithm, ok := alg.(algorithm.StatefulAlgorithm)
+	if !ok {
+		return nil
+	}
+	err := r.callAlgorithm(name, func() (error) {
+		return statefulAlgorithm.RestoreState(store)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "can not restore algorithm state (name = %v)", name)
+	}
+	return nil
+}
+
+func (r *Robot) snapshotAlgorithmState(name string, alg interface{}, store algorithm.StateStore) {
+	statefulAlgorithm, ok := alg.(algorithm.StatefulAlgorithm)
+	if !ok {
+		return
+	}
+	err := r.callAlgorithm(name, func() (error) {
+		return statefulAlgorithm.SnapshotState(store)
+	})
+	if err != nil {
+		r.logger.WithField(logger.FieldAlgorithm, name).Errorf("can not snapshot algorithm state (reason = %v)", err)
+	}
+}
@@ -0,0 +1,75 @@
+package robot
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"time"
+)
+
+// intervalTrigger wakes algorithm periodically
+type intervalTrigger struct {
+	stopChan   chan bool
+	finishChan chan bool
+}
+
+func (i *intervalTrigger) stop() {
+	close(i.stopChan)
+	<-i.finishChan
+}
+
+func (r *Robot) startIntervalTrigger(interval time.Duration, runner *algorithmRunner) (*intervalTrigger) {
+	i := &intervalTrigger{
+		stopChan:   make(chan bool),
+		finishChan: make(chan bool),
+	}
+	ticker := r.clock.NewTicker(interval)
+	go func() {
+		defer close(i.finishChan)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-i.stopChan:
+				return
+			case <-ticker.C():
+				runner.post("")
+			}
+		}
+	}()
+	return i
+}
+
+func (r *Robot) getTriggers(info *algorithmInstanceInfo, alg algorithm.ExternalTradeAlgorithmV2) (*algorithm.Triggers) {
+	// 設定が優先
+	if info.triggers != nil {
+		return info.triggers
+	}
+	triggeredAlgorithm, ok := alg.(algorithm.TriggeredAlgorithm)
+	if ok {
+		triggers := triggeredAlgorithm.GetTriggers()
+		if triggers != nil {
+			return triggers
+		}
+	}
+	return algorithm.NewDefaultTriggers()
+}
+
+// UpdateExternalTradeAlgorithmsByBoard is wake external algorithms that have board trigger of exchange and currency pair
+func (r *Robot) UpdateExternalTradeAlgorithmsByBoard(currencyPair string, ex exchange.Exchange) (error) {
+	for _, instance := range r.getExternalTradeAlgorithms() {
+		if !instance.scope.inExchange(ex.GetName()) || !instance.triggers.MatchBoard(ex.GetName(), currencyPair) {
+			continue
+		}
+		// 同じインスタンスへの未処理の更新はまとめられる
+		instance.runner.post("")
+	}
+	return nil
+}
+
+func (r *Robot) updateExternalTradeAlgorithmsByFill(fill *Fill) {
+	for _, instance := range r.getExternalTradeAlgorithms() {
+		if instance.name != fill.Name || !instance.triggers.Fills {
+			continue
+		}
+		instance.runner.post("")
+	}
+}
@@ -0,0 +1,1648 @@
+package robottest
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/configurator"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/journal"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"github.com/AutomaticCoinTrader/ACT/risk"
+	"github.com/AutomaticCoinTrader/ACT/robot"
+	"github.com/AutomaticCoinTrader/ACT/rpcplugin"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dummyExchange struct {
+	name          string
+	currencyPairs []string
+}
+
+func (d *dummyExchange) GetName() (string) {
+	return d.name
+}
+
+func (d *dummyExchange) GetCurrencyPairs() ([]string) {
+	return d.currencyPairs
+}
+
+func (d *dummyExchange) Buy(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	return 1, price, amount, nil
+}
+
+func (d *dummyExchange) Sell(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	return 1, price, amount, nil
+}
+
+func (d *dummyExchange) Cancel(orderID int64, currencyPair string) (error) {
+	return nil
+}
+
+func (d *dummyExchange) GetFunds() (map[string]float64, error) {
+	return map[string]float64{}, nil
+}
+
+func (d *dummyExchange) GetLastPrice(currencyPair string) (float64, error) {
+	return 0, nil
+}
+
+func (d *dummyExchange) GetSellBoardCursor(currencyPair string) (exchange.BoardCursor, error) {
+	return nil, nil
+}
+
+func (d *dummyExchange) GetBuyBoardCursor(currencyPair string) (exchange.BoardCursor, error) {
+	return nil, nil
+}
+
+func (d *dummyExchange) GetSellBuyBoardCursor(currencyPair string) (exchange.BoardCursor, exchange.BoardCursor, error) {
+	return nil, nil, nil
+}
+
+func (d *dummyExchange) GetTradesCursor(currencyPair string) (exchange.TradesCursor, error) {
+	return nil, nil
+}
+
+func (d *dummyExchange) GetOrderHistoryCursor(count int64) (exchange.OrderCursor, error) {
+	return nil, nil
+}
+
+func (d *dummyExchange) GetActiveOrderCursor() (exchange.OrderCursor, error) {
+	return nil, nil
+}
+
+func (d *dummyExchange) GetMinPriceUnit(currencyPair string) (float64) {
+	return 1
+}
+
+func (d *dummyExchange) GetMinAmountUnit(currencyPair string) (float64) {
+	return 1
+}
+
+func (d *dummyExchange) GetTradeFeeRate(currencyPair string) (float64) {
+	return 0
+}
+
+func (d *dummyExchange) FixPrice(currencyPair string, price float64) (float64) {
+	return price
+}
+
+func (d *dummyExchange) FixAmount(currencyPair string, amount float64) (float64) {
+	return amount
+}
+
+func (d *dummyExchange) Initialize(streamingCallback exchange.StreamingCallback) (error) {
+	return nil
+}
+
+func (d *dummyExchange) Finalize() (error) {
+	return nil
+}
+
+func (d *dummyExchange) StartStreamings() (error) {
+	return nil
+}
+
+func (d *dummyExchange) StopStreamings() (error) {
+	return nil
+}
+
+type countingAlgorithm struct {
+	mutex     *sync.Mutex
+	updates   map[string]int
+	finalized map[string]int
+}
+
+func (c *countingAlgorithm) GetName() (string) {
+	return "counting"
+}
+
+func (c *countingAlgorithm) Initialize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func (c *countingAlgorithm) Update(currencyPair string, ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.updates[ex.GetName()+"/"+currencyPair]++
+	return nil
+}
+
+func (c *countingAlgorithm) Finalize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.finalized[ex.GetName()]++
+	return nil
+}
+
+func findStats(r *robot.Robot, name string) (*robot.AlgorithmStats) {
+	for _, stats := range r.GetAlgorithmStats() {
+		if stats.Name == name {
+			return stats
+		}
+	}
+	return nil
+}
+
+func waitUpdates(t *testing.T, r *robot.Robot, name string, updates uint64) {
+	for i := 0; i < 100; i++ {
+		stats := findStats(r, name)
+		if stats != nil && stats.Updates >= updates {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting updates (name = %v)", name)
+}
+
+func TestScopedInternalTradeAlgorithms(t *testing.T) {
+	counter := &countingAlgorithm{
+		mutex:     new(sync.Mutex),
+		updates:   make(map[string]int),
+		finalized: make(map[string]int),
+	}
+	algorithm.RegisterAlgorithm("robottest-counting", func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+		return counter, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{
+				Name:          "counting-btc",
+				Algorithm:     "robottest-counting",
+				Exchanges:     []string{"a"},
+				CurrencyPairs: []string{"btc_jpy"},
+			},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	exA := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy", "xem_jpy"}}
+	exB := &dummyExchange{name: "b", currencyPairs: []string{"btc_jpy", "xem_jpy"}}
+	for _, ex := range []exchange.Exchange{exA, exB} {
+		err = r.CreateInternalTradeAlgorithms(ex)
+		if err != nil {
+			t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+		}
+	}
+	for _, ex := range []exchange.Exchange{exA, exB} {
+		for _, currencyPair := range ex.GetCurrencyPairs() {
+			r.UpdateInternalTradeAlgorithms(currencyPair, ex)
+		}
+	}
+	waitUpdates(t, r, "counting-btc", 1)
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	if counter.updates["a/btc_jpy"] != 1 {
+		t.Fatalf("unexpected update count of a/btc_jpy (%v)", counter.updates["a/btc_jpy"])
+	}
+	if len(counter.updates) != 1 {
+		t.Fatalf("out of scope update reached algorithm (%v)", counter.updates)
+	}
+	counter.mutex.Unlock()
+	r.DestroyInternalTradeAlgorithms(exB)
+	counter.mutex.Lock()
+	if counter.finalized["a"] != 0 {
+		t.Fatalf("finalized instance of other exchange (%v)", counter.finalized)
+	}
+	counter.mutex.Unlock()
+	r.DestroyInternalTradeAlgorithms(exA)
+	counter.mutex.Lock()
+	if counter.finalized["a"] != 1 {
+		t.Fatalf("unexpected finalize count (%v)", counter.finalized)
+	}
+}
+
+func TestAlgorithmScopeMatching(t *testing.T) {
+	counters := make(map[string]*countingAlgorithm)
+	for _, name := range []string{"robottest-scope-upper", "robottest-scope-all"} {
+		counter := &countingAlgorithm{
+			mutex:     new(sync.Mutex),
+			updates:   make(map[string]int),
+			finalized: make(map[string]int),
+		}
+		counters[name] = counter
+		algorithm.RegisterAlgorithm(name, func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+			return counter, nil
+		}, nil)
+	}
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			// 大文字小文字は区別しない
+			{Name: "upper", Algorithm: "robottest-scope-upper", Exchanges: []string{"A"}, CurrencyPairs: []string{"BTC_JPY", "Eth_Jpy"}},
+			// 省略時は全ての取引所、全ての通貨ペア
+			{Name: "all", Algorithm: "robottest-scope-all"},
+			{Name: "disabled", Algorithm: "robottest-scope-upper", Disable: true},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	exA := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy", "eth_jpy", "xem_jpy"}}
+	exB := &dummyExchange{name: "b", currencyPairs: []string{"btc_jpy"}}
+	for _, ex := range []*dummyExchange{exA, exB} {
+		err = r.CreateInternalTradeAlgorithms(ex)
+		if err != nil {
+			t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+		}
+		defer r.DestroyInternalTradeAlgorithms(ex)
+		for _, currencyPair := range ex.GetCurrencyPairs() {
+			r.UpdateInternalTradeAlgorithms(currencyPair, ex)
+		}
+	}
+	waitUpdates(t, r, "upper", 2)
+	all := counters["robottest-scope-all"]
+	// 統計は取引所ごとなので更新の数を直接待つ
+	for i := 0; i < 100; i++ {
+		all.mutex.Lock()
+		updates := len(all.updates)
+		all.mutex.Unlock()
+		if updates >= 4 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if findStats(r, "disabled") != nil {
+		t.Fatalf("disabled instance is created")
+	}
+	upper := counters["robottest-scope-upper"]
+	upper.mutex.Lock()
+	defer upper.mutex.Unlock()
+	if len(upper.updates) != 2 || upper.updates["a/btc_jpy"] != 1 || upper.updates["a/eth_jpy"] != 1 {
+		t.Fatalf("unexpected updates of scoped instance (%v)", upper.updates)
+	}
+	all.mutex.Lock()
+	defer all.mutex.Unlock()
+	if len(all.updates) != 4 || all.updates["b/btc_jpy"] != 1 {
+		t.Fatalf("unexpected updates of unscoped instance (%v)", all.updates)
+	}
+}
+
+func TestInvalidRobotConfig(t *testing.T) {
+	configs := []*robot.Config{
+		{Algorithms: []*robot.AlgorithmConfig{{Name: "noAlgorithm"}}},
+		// 名前を省略するとアルゴリズム名になる
+		{Algorithms: []*robot.AlgorithmConfig{{Algorithm: "example"}, {Name: "example", Algorithm: "example"}}},
+		{Algorithms: []*robot.AlgorithmConfig{{Algorithm: "example", MailboxPolicy: "unknown"}}},
+		{ProcessPlugins: []*rpcplugin.Config{{Name: "noCommand"}}},
+		{ProcessPlugins: []*rpcplugin.Config{{Name: "dup", Addr: "127.0.0.1:1"}, {Name: "dup", Addr: "127.0.0.1:2"}}},
+		{Risk: &risk.Config{MaxOrderNotional: -1}},
+		{Reconcile: &robot.ReconcileConfig{OrphanPolicy: "ignore"}},
+	}
+	for _, config := range configs {
+		_, err := robot.NewRobot(config, "", nil)
+		if err == nil {
+			t.Fatalf("invalid config is accepted (config = %+v)", config)
+		}
+	}
+}
+
+func TestDuplicateInstanceName(t *testing.T) {
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "dup", Algorithm: "example"},
+			{Name: "dup", Algorithm: "example"},
+		},
+	}
+	_, err := robot.NewRobot(config, "", nil)
+	if err == nil {
+		t.Fatalf("duplicate instance name was accepted")
+	}
+}
+
+type panicAlgorithm struct {
+}
+
+func (p *panicAlgorithm) GetName() (string) {
+	return "panic"
+}
+
+func (p *panicAlgorithm) Initialize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func (p *panicAlgorithm) Update(currencyPair string, ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	panic("broken algorithm")
+}
+
+func (p *panicAlgorithm) Finalize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func TestAlgorithmPanicRecovery(t *testing.T) {
+	algorithm.RegisterAlgorithm("robottest-panic", func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+		return &panicAlgorithm{}, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "panic", Algorithm: "robottest-panic"},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+	waitUpdates(t, r, "panic", 1)
+	stats := findStats(r, "panic")
+	if !stats.Disabled || stats.Panics != 1 {
+		t.Fatalf("algorithm is not disabled after panic (%+v)", stats)
+	}
+	r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+	time.Sleep(50 * time.Millisecond)
+	stats = findStats(r, "panic")
+	if stats.Updates != 1 {
+		t.Fatalf("disabled algorithm was updated (%+v)", stats)
+	}
+	r.DestroyInternalTradeAlgorithms(ex)
+}
+
+func TestInvalidMailboxPolicy(t *testing.T) {
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "example", Algorithm: "example", MailboxPolicy: "unknown"},
+		},
+	}
+	_, err := robot.NewRobot(config, "", nil)
+	if err == nil {
+		t.Fatalf("invalid mailbox policy was accepted")
+	}
+}
+
+type lifecycleAlgorithm struct {
+	initialized *int
+	finalized   *int
+}
+
+func (l *lifecycleAlgorithm) GetName() (string) {
+	return "lifecycle"
+}
+
+func (l *lifecycleAlgorithm) Initialize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	*l.initialized++
+	return nil
+}
+
+func (l *lifecycleAlgorithm) Update(currencyPair string, ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func (l *lifecycleAlgorithm) Finalize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	*l.finalized++
+	return nil
+}
+
+func TestReloadAlgorithms(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "robottest")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(configDir)
+	err = os.MkdirAll(path.Join(configDir, algorithm.AlgorithmConfigDir), 0755)
+	if err != nil {
+		t.Fatalf("can not create algorithm config dir (reason = %v)", err)
+	}
+	configFile := path.Join(configDir, algorithm.AlgorithmConfigDir, "robottest-lifecycle.yaml")
+	err = ioutil.WriteFile(configFile, []byte("message: hello\n"), 0644)
+	if err != nil {
+		t.Fatalf("can not write config (reason = %v)", err)
+	}
+	initialized := 0
+	finalized := 0
+	algorithm.RegisterAlgorithm("robottest-lifecycle", func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+		return &lifecycleAlgorithm{initialized: &initialized, finalized: &finalized}, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "lifecycle", Algorithm: "robottest-lifecycle"},
+		},
+	}
+	r, err := robot.NewRobot(config, configDir, nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+
+	// 設定ファイルが変わっていなければ何もしない
+	result := r.ReloadAlgorithms()
+	if len(result.Reloaded) != 0 || initialized != 1 {
+		t.Fatalf("unchanged algorithm was reloaded (%+v)", result)
+	}
+
+	modTime := time.Now().Add(time.Minute)
+	err = os.Chtimes(configFile, modTime, modTime)
+	if err != nil {
+		t.Fatalf("can not change mod time (reason = %v)", err)
+	}
+	result = r.ReloadAlgorithms()
+	if len(result.Reloaded) != 1 || initialized != 2 || finalized != 1 {
+		t.Fatalf("changed algorithm was not reloaded (%+v)", result)
+	}
+
+	_, err = r.ReloadAlgorithm("lifecycle")
+	if err != nil {
+		t.Fatalf("can not reload algorithm (reason = %v)", err)
+	}
+	if initialized != 3 || finalized != 2 {
+		t.Fatalf("algorithm was not reloaded (initialized = %v, finalized = %v)", initialized, finalized)
+	}
+	_, err = r.ReloadAlgorithm("unknown")
+	if err == nil {
+		t.Fatalf("reload of unknown algorithm was accepted")
+	}
+
+	// 設定のインスタンスの対象が変われば作り直す
+	result = r.ReloadAlgorithmsWithConfig(&robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "lifecycle", Algorithm: "robottest-lifecycle", CurrencyPairs: []string{"btc_jpy"}},
+		},
+	})
+	if len(result.Reloaded) != 1 || initialized != 4 || finalized != 3 {
+		t.Fatalf("algorithm of changed scope was not reloaded (%+v)", result)
+	}
+	// 不正な設定は反映しない
+	result = r.ReloadAlgorithmsWithConfig(&robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{{Name: "lifecycle"}},
+	})
+	if len(result.Errors) != 1 || len(result.Reloaded) != 0 || len(result.Stopped) != 0 || initialized != 4 {
+		t.Fatalf("invalid config was applied (%+v)", result)
+	}
+	// 設定から消えたインスタンスは終了する
+	result = r.ReloadAlgorithmsWithConfig(&robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "lifecycle", Algorithm: "robottest-lifecycle", Disable: true},
+		},
+	})
+	if len(result.Stopped) != 1 || result.Stopped[0] != "lifecycle@a" || finalized != 4 {
+		t.Fatalf("removed algorithm was not stopped (%+v)", result)
+	}
+	if len(r.GetAlgorithmStats()) != 0 {
+		t.Fatalf("removed algorithm is still running (stats = %v)", r.GetAlgorithmStats())
+	}
+	r.DestroyInternalTradeAlgorithms(ex)
+	if finalized != 4 {
+		t.Fatalf("removed algorithm was finalized again (finalized = %v)", finalized)
+	}
+}
+
+// configAlgorithm reads message from its config file on initialize
+type configAlgorithm struct {
+	configDir string
+	messages  *[]string
+	mutex     *sync.Mutex
+}
+
+func (c *configAlgorithm) GetName() (string) {
+	return "config"
+}
+
+func (c *configAlgorithm) Initialize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	cf, err := configurator.NewConfigurator(path.Join(c.configDir, "robottest-control"))
+	if err != nil {
+		return err
+	}
+	config := struct {
+		Message string `yaml:"message"`
+	}{}
+	err = cf.Load(&config)
+	if err != nil {
+		return err
+	}
+	if config.Message == "" {
+		return errors.New("message is empty")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	*c.messages = append(*c.messages, config.Message)
+	return nil
+}
+
+func (c *configAlgorithm) Update(currencyPair string, ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func (c *configAlgorithm) Finalize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func TestAlgorithmControl(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "robottest")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(configDir)
+	messages := make([]string, 0)
+	mutex := new(sync.Mutex)
+	algorithm.RegisterAlgorithm("robottest-control", func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+		return &configAlgorithm{configDir: configDir, messages: &messages, mutex: mutex}, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "control", Algorithm: "robottest-control", ConfigDir: "control"},
+		},
+	}
+	r, err := robot.NewRobot(config, configDir, nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}}
+	// 設定ファイルがなくても設定を送れば作られる
+	_, err = r.ReconfigureAlgorithm("control", map[string]interface{}{"message": "hello"})
+	if err != nil {
+		t.Fatalf("can not reconfigure algorithm (reason = %v)", err)
+	}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyInternalTradeAlgorithms(ex)
+
+	err = r.PauseAlgorithm("control")
+	if err != nil {
+		t.Fatalf("can not pause algorithm (reason = %v)", err)
+	}
+	r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+	stats, err := r.GetAlgorithmStatsByName("control")
+	if err != nil || len(stats) != 1 || stats[0].Status != robot.AlgorithmStatusPaused || stats[0].Pending != 0 || stats[0].Kind != robot.AlgorithmKindInternal {
+		t.Fatalf("unexpected stats of paused algorithm (stats = %v, reason = %v)", stats, err)
+	}
+
+	// 設定を変えて作り直しても一時停止したまま
+	result, err := r.ReconfigureAlgorithm("control", map[string]interface{}{"message": "world"})
+	if err != nil || len(result.Reloaded) != 1 {
+		t.Fatalf("algorithm is not reconfigured (result = %+v, reason = %v)", result, err)
+	}
+	mutex.Lock()
+	if len(messages) != 2 || messages[0] != "hello" || messages[1] != "world" {
+		t.Fatalf("unexpected messages (messages = %v)", messages)
+	}
+	mutex.Unlock()
+	if stats := findStats(r, "control"); stats.Status != robot.AlgorithmStatusPaused {
+		t.Fatalf("reconfigured algorithm is not paused (status = %v)", stats.Status)
+	}
+
+	// 初期化できない設定なら設定ファイルを戻して古い設定で動かし直す
+	_, err = r.ReconfigureAlgorithm("control", map[string]interface{}{"message": ""})
+	if reconfigureError, ok := robot.GetReconfigureError(err); !ok || reconfigureError.Kind != robot.ReconfigureErrorInitialize {
+		t.Fatalf("unexpected reconfigure error (reason = %v)", err)
+	}
+	mutex.Lock()
+	if len(messages) != 3 || messages[2] != "world" {
+		t.Fatalf("algorithm is not restarted with old config (messages = %v)", messages)
+	}
+	mutex.Unlock()
+	data, err := ioutil.ReadFile(path.Join(configDir, algorithm.AlgorithmConfigDir, "control", "robottest-control.yaml"))
+	if err != nil || !strings.Contains(string(data), "world") {
+		t.Fatalf("config file is not restored (data = %s, reason = %v)", data, err)
+	}
+	if stats := findStats(r, "control"); stats.Status != robot.AlgorithmStatusPaused {
+		t.Fatalf("restarted algorithm is not paused (status = %v)", stats.Status)
+	}
+	_, err = r.ReconfigureAlgorithm("unknown", map[string]interface{}{"message": "hello"})
+	if reconfigureError, ok := robot.GetReconfigureError(err); !ok || reconfigureError.Kind != robot.ReconfigureErrorNotFound {
+		t.Fatalf("unexpected reconfigure error (reason = %v)", err)
+	}
+
+	err = r.ResumeAlgorithm("control")
+	if err != nil {
+		t.Fatalf("can not resume algorithm (reason = %v)", err)
+	}
+	r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+	waitUpdates(t, r, "control", 1)
+	if stats := findStats(r, "control"); stats.Status != robot.AlgorithmStatusRunning {
+		t.Fatalf("resumed algorithm is not running (status = %v)", stats.Status)
+	}
+	if r.PauseAlgorithm("unknown") == nil {
+		t.Fatalf("unknown algorithm is paused")
+	}
+}
+
+type statefulAlgorithm struct {
+	count    int
+	restored int
+	store    algorithm.StateStore
+}
+
+func (s *statefulAlgorithm) GetName() (string) {
+	return "stateful"
+}
+
+func (s *statefulAlgorithm) RestoreState(store algorithm.StateStore) (error) {
+	s.store = store
+	_, err := store.GetJSON("count", &s.count)
+	s.restored = s.count
+	return err
+}
+
+func (s *statefulAlgorithm) SnapshotState(store algorithm.StateStore) (error) {
+	return store.PutJSON("count", s.count)
+}
+
+func (s *statefulAlgorithm) Initialize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func (s *statefulAlgorithm) Update(currencyPair string, ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	s.count++
+	return nil
+}
+
+func (s *statefulAlgorithm) Finalize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func TestAlgorithmStatePersistence(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "robottest")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(configDir)
+	var current *statefulAlgorithm
+	algorithm.RegisterAlgorithm("robottest-stateful", func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+		current = &statefulAlgorithm{}
+		return current, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "stateful", Algorithm: "robottest-stateful"},
+		},
+		StateFile: "state.db",
+	}
+	ex := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}}
+	// ACTの再起動を繰り返しても状態が引き継がれる
+	for i := 0; i < 2; i++ {
+		r, err := robot.NewRobot(config, configDir, nil)
+		if err != nil {
+			t.Fatalf("can not create robot (reason = %v)", err)
+		}
+		err = r.CreateInternalTradeAlgorithms(ex)
+		if err != nil {
+			t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+		}
+		if current.restored != i*3 {
+			t.Fatalf("unexpected restored state (expected = %v, actual = %v)", i*3, current.restored)
+		}
+		for j := 0; j < 3; j++ {
+			r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+			waitUpdates(t, r, "stateful", uint64(j+1))
+		}
+		r.DestroyInternalTradeAlgorithms(ex)
+		err = r.Finalize()
+		if err != nil {
+			t.Fatalf("can not finalize robot (reason = %v)", err)
+		}
+	}
+	_, err = os.Stat(path.Join(configDir, "state.db"))
+	if err != nil {
+		t.Fatalf("state file is not created (reason = %v)", err)
+	}
+}
+
+type contextAlgorithm struct {
+	contexts []*algorithm.Context
+	updates  []string
+}
+
+func (c *contextAlgorithm) GetName() (string) {
+	return "context"
+}
+
+func (c *contextAlgorithm) Initialize(ctx *algorithm.Context) (error) {
+	c.contexts = append(c.contexts, ctx)
+	return ctx.State.Put("initialized", []byte("true"))
+}
+
+func (c *contextAlgorithm) Update(ctx *algorithm.Context, currencyPair string) (error) {
+	ctx.Logger.Printf("update %v", currencyPair)
+	c.updates = append(c.updates, currencyPair)
+	return nil
+}
+
+func (c *contextAlgorithm) Finalize(ctx *algorithm.Context) (error) {
+	return nil
+}
+
+func TestAlgorithmContext(t *testing.T) {
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-context", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "context", Algorithm: "robottest-context", ConfigDir: "/tmp/robottest-context"},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+	waitUpdates(t, r, "context", 1)
+	r.DestroyInternalTradeAlgorithms(ex)
+	if len(alg.contexts) != 1 {
+		t.Fatalf("algorithm is not initialized")
+	}
+	ctx := alg.contexts[0]
+	if ctx.Version != algorithm.ContextVersion || ctx.Name != "context" || ctx.AlgorithmName != "robottest-context" {
+		t.Fatalf("unexpected context (%+v)", ctx)
+	}
+	if ctx.Exchange.GetName() != "a" || len(ctx.Exchanges) != 1 || ctx.Exchanges["a"] != ctx.Exchange {
+		t.Fatalf("unexpected exchanges in context (%+v)", ctx)
+	}
+	if ctx.Config.GetConfigDir() != "/tmp/robottest-context" {
+		t.Fatalf("unexpected config dir (%v)", ctx.Config.GetConfigDir())
+	}
+	if ctx.Clock.Now().IsZero() {
+		t.Fatalf("clock is not set")
+	}
+	value, err := ctx.State.Get("initialized")
+	if err != nil || string(value) != "true" {
+		t.Fatalf("unexpected state (value = %v, reason = %v)", string(value), err)
+	}
+	if len(alg.updates) != 1 || alg.updates[0] != "btc_jpy" {
+		t.Fatalf("unexpected updates (%v)", alg.updates)
+	}
+}
+
+type activeOrderCursor struct {
+	orders [][]float64
+	index  int
+}
+
+func (a *activeOrderCursor) Next() (int64, string, exchange.OrderAction, float64, float64, int64, bool) {
+	if a.index >= len(a.orders) {
+		return 0, "", "", 0, 0, 0, false
+	}
+	order := a.orders[a.index]
+	a.index++
+	action := exchange.OrderActBuy
+	if len(order) > 2 && order[2] == 1 {
+		action = exchange.OrderActSell
+	}
+	return int64(order[0]), "btc_jpy", action, 100, order[1], 0, true
+}
+
+func (a *activeOrderCursor) Reset() {
+	a.index = 0
+}
+
+func (a *activeOrderCursor) Len() (int) {
+	return len(a.orders)
+}
+
+// orderExchange returns active orders and order history set by test
+type orderExchange struct {
+	*dummyExchange
+	activeOrders map[int64]float64
+	// 取引IDと数量。価格は100。3つ目が1なら売り
+	history      [][]float64
+	mutex        *sync.Mutex
+}
+
+func (o *orderExchange) addHistory(tradeID int64, amount float64) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.history = append(o.history, []float64{float64(tradeID), amount})
+}
+
+func (o *orderExchange) addSellHistory(tradeID int64, amount float64) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.history = append(o.history, []float64{float64(tradeID), amount, 1})
+}
+
+func (o *orderExchange) GetOrderHistoryCursor(count int64) (exchange.OrderCursor, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return &activeOrderCursor{orders: append([][]float64{}, o.history...)}, nil
+}
+
+func (o *orderExchange) setActiveOrder(orderID int64, amount float64) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if amount == 0 {
+		delete(o.activeOrders, orderID)
+		return
+	}
+	o.activeOrders[orderID] = amount
+}
+
+func (o *orderExchange) GetActiveOrderCursor() (exchange.OrderCursor, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	cursor := &activeOrderCursor{}
+	for orderID, amount := range o.activeOrders {
+		cursor.orders = append(cursor.orders, []float64{float64(orderID), amount})
+	}
+	return cursor, nil
+}
+
+type triggeredAlgorithm struct {
+	contexts map[string]*algorithm.Context
+	mutex    *sync.Mutex
+}
+
+func (t *triggeredAlgorithm) GetName() (string) {
+	return "robottest-trigger"
+}
+
+func (t *triggeredAlgorithm) GetTriggers() (*algorithm.Triggers) {
+	return &algorithm.Triggers{
+		Boards: []*algorithm.BoardTrigger{{Exchange: "a", CurrencyPair: "btc_jpy"}},
+		Fills:  true,
+	}
+}
+
+func (t *triggeredAlgorithm) Initialize(ctx *algorithm.Context) (error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.contexts[ctx.Name] = ctx
+	return nil
+}
+
+func (t *triggeredAlgorithm) Update(ctx *algorithm.Context) (error) {
+	return nil
+}
+
+func (t *triggeredAlgorithm) Finalize(ctx *algorithm.Context) (error) {
+	return nil
+}
+
+func TestExternalTradeAlgorithmTriggers(t *testing.T) {
+	alg := &triggeredAlgorithm{
+		contexts: make(map[string]*algorithm.Context),
+		mutex:    new(sync.Mutex),
+	}
+	algorithm.RegisterAlgorithmV2("robottest-trigger", nil, func(configDir string) (algorithm.ExternalTradeAlgorithmV2, error) {
+		return alg, nil
+	})
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "board", Algorithm: "robottest-trigger"},
+			{Name: "interval", Algorithm: "robottest-trigger", Triggers: &algorithm.Triggers{Interval: 1000}},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	simulatedClock := clock.NewSimulatedClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(simulatedClock)
+	ex := &orderExchange{
+		dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy", "eth_jpy"}},
+		activeOrders:  make(map[int64]float64),
+		mutex:         new(sync.Mutex),
+	}
+	exchanges := map[string]exchange.Exchange{"a": ex}
+	err = r.CreateExternalTradeAlgorithms(exchanges)
+	if err != nil {
+		t.Fatalf("can not create external trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyExternalTradeAlgorithms(exchanges)
+
+	// 板のトリガー
+	r.UpdateExternalTradeAlgorithmsByBoard("eth_jpy", ex)
+	r.UpdateExternalTradeAlgorithmsByBoard("btc_jpy", ex)
+	waitUpdates(t, r, "board", 1)
+
+	// 時間のトリガー
+	simulatedClock.BlockUntil(1)
+	simulatedClock.Advance(time.Second)
+	waitUpdates(t, r, "interval", 1)
+
+	// 約定のトリガー
+	ctx := alg.contexts["board"]
+	orderID, _, _, err := ctx.Exchanges["a"].Buy("btc_jpy", 100, 2, nil, nil)
+	if err != nil {
+		t.Fatalf("can not buy (reason = %v)", err)
+	}
+	ex.setActiveOrder(orderID, 2)
+	r.PollFills(ex)
+	ex.setActiveOrder(orderID, 0)
+	ex.addHistory(1000, 2)
+	r.PollFills(ex)
+	waitUpdates(t, r, "board", 2)
+	p, err := ctx.Positions.GetPosition("a", "btc_jpy")
+	if err != nil || p.Amount != 2 || p.AveragePrice != 100 || p.Fills != 1 {
+		t.Fatalf("unexpected position (position = %+v, reason = %v)", p, err)
+	}
+	if positions := r.GetAllPositions(); len(positions) != 1 || len(positions["board"]) != 1 {
+		t.Fatalf("unexpected positions (%v)", positions)
+	}
+	entries, err := r.GetJournal().Query(&journal.Query{Name: "board"})
+	if err != nil || len(entries) != 3 || entries[0].Type != journal.EntryTypeRequest || entries[1].Type != journal.EntryTypeResponse ||
+		entries[1].OrderID != orderID || entries[2].Type != journal.EntryTypeFill || entries[2].Amount != 2 {
+		t.Fatalf("unexpected journal entries (entries = %v, reason = %v)", entries, err)
+	}
+
+	if stats := findStats(r, "board"); stats.Updates != 2 {
+		t.Fatalf("unexpected updates of board trigger (updates = %v)", stats.Updates)
+	}
+	if stats := findStats(r, "interval"); stats.Updates != 1 {
+		t.Fatalf("unexpected updates of interval trigger (updates = %v)", stats.Updates)
+	}
+}
+
+func TestRiskManager(t *testing.T) {
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-risk", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "risk", Algorithm: "robottest-risk"},
+		},
+		Risk: &risk.Config{MaxOrderNotional: 1000},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyInternalTradeAlgorithms(ex)
+	ctx := alg.contexts[0]
+	_, _, _, err = ctx.Exchange.Buy("btc_jpy", 100, 20, nil, nil)
+	riskError, ok := risk.GetRiskError(err)
+	if !ok || riskError.Rule != risk.RuleMaxOrderNotional {
+		t.Fatalf("order is not rejected (reason = %v)", err)
+	}
+	_, _, _, err = ctx.Exchange.Buy("btc_jpy", 100, 5, nil, nil)
+	if err != nil {
+		t.Fatalf("order is rejected (reason = %v)", err)
+	}
+}
+
+func TestManualOrder(t *testing.T) {
+	config := &robot.Config{
+		Risk: &risk.Config{MaxOrderNotional: 1000},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &orderExchange{
+		dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}},
+		activeOrders:  make(map[int64]float64),
+		mutex:         new(sync.Mutex),
+	}
+	invalidOrders := [][]interface{}{
+		{"eth_jpy", exchange.OrderActBuy, 100.0, 1.0, risk.RuleInvalidOrder},
+		{"btc_jpy", exchange.OrderActUnkown, 100.0, 1.0, risk.RuleInvalidOrder},
+		{"btc_jpy", exchange.OrderActBuy, 100.0, 0.5, risk.RuleInvalidOrder},
+		{"btc_jpy", exchange.OrderActBuy, 100.0, 20.0, risk.RuleMaxOrderNotional},
+	}
+	for _, o := range invalidOrders {
+		_, err := r.PlaceManualOrder("alice", ex, o[0].(string), o[1].(exchange.OrderAction), o[2].(float64), o[3].(float64))
+		riskError, ok := risk.GetRiskError(err)
+		if !ok || riskError.Rule != o[4].(string) {
+			t.Fatalf("manual order is not rejected (order = %v, reason = %v)", o, err)
+		}
+	}
+	order, err := r.PlaceManualOrder("alice", ex, "btc_jpy", exchange.OrderActBuy, 100, 5)
+	if err != nil || order.OrderID != 1 || order.User != "alice" {
+		t.Fatalf("can not place manual order (order = %+v, reason = %v)", order, err)
+	}
+	ex.setActiveOrder(order.OrderID, 3)
+	r.PollFills(ex)
+	positions := r.GetPositions(robot.ManualOrderName)
+	if len(positions) != 1 || positions[0].Amount != 2 {
+		t.Fatalf("unexpected positions of manual orders (positions = %v)", positions)
+	}
+	err = r.CancelManualOrder("alice", ex, order.OrderID, "btc_jpy")
+	if err != nil {
+		t.Fatalf("can not cancel manual order (reason = %v)", err)
+	}
+	entries, _ := r.GetJournal().Query(&journal.Query{Name: robot.ManualOrderName, Types: []journal.EntryType{journal.EntryTypeCancel}})
+	if len(entries) != 1 || entries[0].OrderID != order.OrderID {
+		t.Fatalf("manual cancel is not recorded (entries = %v)", entries)
+	}
+}
+
+type bidBoardCursor struct {
+	prices []float64
+	index  int
+}
+
+func (b *bidBoardCursor) Next() (float64, float64, bool) {
+	if b.index >= len(b.prices) {
+		return 0, 0, false
+	}
+	price := b.prices[b.index]
+	b.index++
+	return price, 1, true
+}
+
+func (b *bidBoardCursor) Reset() {
+	b.index = 0
+}
+
+func (b *bidBoardCursor) Len() (int) {
+	return len(b.prices)
+}
+
+func (b *bidBoardCursor) All() ([][]float64) {
+	values := make([][]float64, 0, len(b.prices))
+	for _, price := range b.prices {
+		values = append(values, []float64{price, 1})
+	}
+	return values
+}
+
+// killExchange records cancels and sells by kill switch
+type killExchange struct {
+	*orderExchange
+	canceled []int64
+	sold     [][]float64
+}
+
+func (k *killExchange) Cancel(orderID int64, currencyPair string) (error) {
+	k.setActiveOrder(orderID, 0)
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.canceled = append(k.canceled, orderID)
+	return nil
+}
+
+func (k *killExchange) Sell(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.sold = append(k.sold, []float64{price, amount})
+	return 10, price, amount, nil
+}
+
+func (k *killExchange) GetFunds() (map[string]float64, error) {
+	return map[string]float64{"btc": 1.5, "eth": 0, "jpy": 1000}, nil
+}
+
+func (k *killExchange) GetBuyBoardCursor(currencyPair string) (exchange.BoardCursor, error) {
+	return &bidBoardCursor{prices: []float64{100, 99}}, nil
+}
+
+// concurrentOrderExchange places orders slowly with unique order ids
+type concurrentOrderExchange struct {
+	*orderExchange
+	nextOrderID int64
+}
+
+func (c *concurrentOrderExchange) Buy(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	// 確認と発注の間に他の注文が確認される時間を作る
+	time.Sleep(10 * time.Millisecond)
+	c.mutex.Lock()
+	c.nextOrderID++
+	orderID := c.nextOrderID
+	c.activeOrders[orderID] = amount
+	c.mutex.Unlock()
+	return orderID, price, amount, nil
+}
+
+func TestRiskConcurrentOrders(t *testing.T) {
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-risk-concurrent", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "concurrent", Algorithm: "robottest-risk-concurrent"},
+		},
+		Risk:       &risk.Config{MaxOpenOrders: 2, MaxPosition: map[string]float64{"btc": 3}},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	defer r.Finalize()
+	ex := &concurrentOrderExchange{
+		orderExchange: &orderExchange{
+			dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}},
+			activeOrders:  make(map[int64]float64),
+			mutex:         new(sync.Mutex),
+		},
+	}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyInternalTradeAlgorithms(ex)
+	// 同時に出しても建玉 (2 + 2 > 3) と注文数の制限を超えない
+	mutex := new(sync.Mutex)
+	placed := 0
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, err := alg.contexts[0].Exchange.Buy("btc_jpy", 100, 2, nil, nil)
+			if err == nil {
+				mutex.Lock()
+				placed++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if placed != 1 {
+		t.Fatalf("unexpected placed orders (placed = %v)", placed)
+	}
+}
+
+// slowOrderExchange waits release in placing order
+type slowOrderExchange struct {
+	*killExchange
+	started chan bool
+	release chan bool
+}
+
+func (s *slowOrderExchange) Buy(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	s.started <- true
+	<-s.release
+	s.setActiveOrder(20, amount)
+	return 20, price, amount, nil
+}
+
+func TestKillSwitchWaitsPlacingOrder(t *testing.T) {
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-kill-placing", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "placing", Algorithm: "robottest-kill-placing"},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	defer r.Finalize()
+	ex := &slowOrderExchange{
+		killExchange: &killExchange{
+			orderExchange: &orderExchange{
+				dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}},
+				activeOrders:  make(map[int64]float64),
+				mutex:         new(sync.Mutex),
+			},
+		},
+		started: make(chan bool, 1),
+		release: make(chan bool),
+	}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyInternalTradeAlgorithms(ex)
+	go alg.contexts[0].Exchange.Buy("btc_jpy", 100, 1, nil, nil)
+	<-ex.started
+	// 確認を通った注文が出されるまでキルスイッチは注文を取り消さない
+	killed := make(chan *robot.KillResult)
+	go func() {
+		killed <- r.Kill("test")
+	}()
+	select {
+	case <-killed:
+		t.Fatalf("kill switch does not wait placing order")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(ex.release)
+	result := <-killed
+	if len(result.CanceledOrders) != 1 || result.CanceledOrders[0].OrderID != 20 {
+		t.Fatalf("placed order is not canceled (canceled orders = %v)", result.CanceledOrders)
+	}
+}
+
+func TestKillSwitchFlattenNetLong(t *testing.T) {
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-flatten", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "short", Algorithm: "robottest-flatten"},
+		},
+		KillSwitch: &robot.KillSwitchConfig{Flatten: true},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	defer r.Finalize()
+	ex := &killExchange{
+		orderExchange: &orderExchange{
+			dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy", "eth_jpy"}},
+			activeOrders:  make(map[int64]float64),
+			mutex:         new(sync.Mutex),
+		},
+	}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyInternalTradeAlgorithms(ex)
+	// 建玉がなければ残高があっても売らない
+	result := r.Kill("test")
+	if len(result.FlattenOrders) != 0 || len(ex.sold) != 0 {
+		t.Fatalf("holdings are sold (orders = %v, sold = %v)", result.FlattenOrders, ex.sold)
+	}
+	err = r.ResetKillSwitch()
+	if err != nil {
+		t.Fatalf("can not reset kill switch (reason = %v)", err)
+	}
+	// アクティブな注文にないので、履歴で約定したことにする
+	_, _, _, err = alg.contexts[0].Exchange.Sell("btc_jpy", 100, 2, nil, nil)
+	if err != nil {
+		t.Fatalf("can not sell (reason = %v)", err)
+	}
+	ex.addSellHistory(1, 2)
+	r.PollFills(ex)
+	_, err = r.PlaceManualOrder("alice", ex, "btc_jpy", exchange.OrderActBuy, 100, 3)
+	if err != nil {
+		t.Fatalf("can not place manual order (reason = %v)", err)
+	}
+	ex.addHistory(1, 3)
+	r.PollFills(ex)
+	// アルゴリズムの売りも記録しているので消す
+	ex.sold = nil
+	// manualは3買っているが、shortが2売っているので買い越しは1
+	result = r.Kill("test")
+	if len(result.FlattenOrders) != 1 || len(ex.sold) != 1 || ex.sold[0][1] != 1 {
+		t.Fatalf("unexpected flatten orders (orders = %v, sold = %v)", result.FlattenOrders, ex.sold)
+	}
+	err = r.ResetKillSwitch()
+	if err != nil {
+		t.Fatalf("can not reset kill switch (reason = %v)", err)
+	}
+	// 残高 (1.5) より多くは売らない
+	_, err = r.PlaceManualOrder("alice", ex, "btc_jpy", exchange.OrderActBuy, 100, 2)
+	if err != nil {
+		t.Fatalf("can not place manual order (reason = %v)", err)
+	}
+	ex.addHistory(1, 2)
+	r.PollFills(ex)
+	result = r.Kill("test")
+	if len(result.FlattenOrders) != 1 || len(ex.sold) != 2 || ex.sold[1][1] != 1.5 {
+		t.Fatalf("unexpected flatten orders (orders = %v, sold = %v)", result.FlattenOrders, ex.sold)
+	}
+}
+
+func TestKillSwitch(t *testing.T) {
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-kill", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "kill", Algorithm: "robottest-kill"},
+		},
+		Risk:       &risk.Config{MaxOrderNotional: 1000},
+		KillSwitch: &robot.KillSwitchConfig{Flatten: true, RiskRules: []string{risk.RuleMaxOrderNotional}},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &killExchange{
+		orderExchange: &orderExchange{
+			dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy", "eth_jpy"}},
+			activeOrders:  make(map[int64]float64),
+			mutex:         new(sync.Mutex),
+		},
+	}
+	ex.setActiveOrder(5, 1)
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyInternalTradeAlgorithms(ex)
+	ctx := alg.contexts[0]
+	// アルゴリズムが1買っている。残高の残りの0.5は売らない
+	_, _, _, err = ctx.Exchange.Buy("btc_jpy", 100, 1, nil, nil)
+	if err != nil {
+		t.Fatalf("can not buy (reason = %v)", err)
+	}
+	ex.addHistory(1, 1)
+	r.PollFills(ex)
+
+	// リスク管理の違反でキルスイッチが入る
+	ctx.Exchange.Buy("btc_jpy", 100, 20, nil, nil)
+	var status *robot.KillSwitchStatus
+	for i := 0; i < 100; i++ {
+		status = r.GetKillSwitchStatus()
+		if status.LastResult != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !status.Killed || status.LastResult == nil {
+		t.Fatalf("kill switch is not on")
+	}
+	result := status.LastResult
+	if len(result.CanceledOrders) != 1 || result.CanceledOrders[0].OrderID != 5 {
+		t.Fatalf("unexpected canceled orders (%v)", result.CanceledOrders)
+	}
+	if len(result.FlattenOrders) != 1 || result.FlattenOrders[0].CurrencyPair != "btc_jpy" || len(ex.sold) != 1 || ex.sold[0][0] != 100 || ex.sold[0][1] != 1 {
+		t.Fatalf("unexpected flatten orders (orders = %v, sold = %v)", result.FlattenOrders, ex.sold)
+	}
+	// 売り注文の約定は建玉を持っていたインスタンスに付く
+	ex.setActiveOrder(10, 0.4)
+	r.PollFills(ex)
+	positions := r.GetPositions("kill")
+	if len(positions) != 1 || math.Abs(positions[0].Amount-0.4) > 0.000001 {
+		t.Fatalf("fill of flatten order is not recorded (positions = %v)", positions)
+	}
+	_, _, _, err = ctx.Exchange.Buy("btc_jpy", 100, 1, nil, nil)
+	if riskError, ok := risk.GetRiskError(err); !ok || riskError.Rule != risk.RuleKillSwitch {
+		t.Fatalf("order is not rejected by kill switch (reason = %v)", err)
+	}
+	entries, _ := r.GetJournal().Query(&journal.Query{Name: "killswitch"})
+	if len(entries) != 3 || entries[0].Type != journal.EntryTypeCancel || entries[0].OrderID != 5 || entries[2].Type != journal.EntryTypeResponse {
+		t.Fatalf("unexpected journal entries of kill switch (entries = %v)", entries)
+	}
+	entries, _ = r.GetJournal().Query(&journal.Query{Types: []journal.EntryType{journal.EntryTypeReject}})
+	// リスクの制限とキルスイッチで拒否した注文
+	if len(entries) != 2 || entries[1].Error == "" {
+		t.Fatalf("rejected orders are not recorded (entries = %v)", entries)
+	}
+	r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+	waitUpdates(t, r, "kill", 1)
+	if len(alg.updates) != 0 {
+		t.Fatalf("algorithm is updated while kill switch is on")
+	}
+
+	err = r.ResetKillSwitch()
+	if err != nil {
+		t.Fatalf("can not reset kill switch (reason = %v)", err)
+	}
+	_, _, _, err = ctx.Exchange.Buy("btc_jpy", 100, 1, nil, nil)
+	if err != nil {
+		t.Fatalf("order is rejected after reset (reason = %v)", err)
+	}
+}
+
+type blockingAlgorithm struct {
+	release chan bool
+	mutex   *sync.Mutex
+	updates []string
+}
+
+func (b *blockingAlgorithm) GetName() (string) {
+	return "blocking"
+}
+
+func (b *blockingAlgorithm) Initialize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func (b *blockingAlgorithm) Update(currencyPair string, ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	<-b.release
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.updates = append(b.updates, currencyPair)
+	return nil
+}
+
+func (b *blockingAlgorithm) Finalize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return nil
+}
+
+func TestDrainAlgorithms(t *testing.T) {
+	blocking := &blockingAlgorithm{
+		release: make(chan bool),
+		mutex:   new(sync.Mutex),
+	}
+	algorithm.RegisterAlgorithm("robottest-blocking", func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+		return blocking, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "blocking", Algorithm: "robottest-blocking"},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	ex := &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy", "xem_jpy", "eth_jpy"}}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	for _, currencyPair := range ex.GetCurrencyPairs() {
+		r.UpdateInternalTradeAlgorithms(currencyPair, ex)
+	}
+	// 処理中の更新と未処理の更新を全て終わらせる
+	close(blocking.release)
+	r.DrainAlgorithms()
+	blocking.mutex.Lock()
+	if len(blocking.updates) != 3 {
+		t.Fatalf("pending updates are not processed (%v)", blocking.updates)
+	}
+	blocking.mutex.Unlock()
+	if undrained := r.GetUndrainedAlgorithms(); len(undrained) != 0 {
+		t.Fatalf("unexpected undrained algorithms (%v)", undrained)
+	}
+	// drainした後の更新は受け付けない
+	r.UpdateInternalTradeAlgorithms("btc_jpy", ex)
+	r.DestroyInternalTradeAlgorithms(ex)
+	if stats := findStats(r, "blocking"); stats != nil {
+		t.Fatalf("algorithm is not destroyed (%+v)", stats)
+	}
+	blocking.mutex.Lock()
+	defer blocking.mutex.Unlock()
+	if len(blocking.updates) != 3 {
+		t.Fatalf("update after drain is processed (%v)", blocking.updates)
+	}
+}
+
+// reconcileExchange returns sequential order ids
+type reconcileExchange struct {
+	*killExchange
+	nextOrderID int64
+}
+
+func (r *reconcileExchange) Buy(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.nextOrderID++
+	return r.nextOrderID, price, amount, nil
+}
+
+func TestReconcile(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "robottest")
+	if err != nil {
+		t.Fatalf("can not create temp dir (reason = %v)", err)
+	}
+	defer os.RemoveAll(configDir)
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-reconcile", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "reconcile", Algorithm: "robottest-reconcile"},
+		},
+		StateFile: "state.db",
+		Reconcile: &robot.ReconcileConfig{OrphanPolicy: robot.OrphanPolicyCancel},
+	}
+	ex := &reconcileExchange{
+		killExchange: &killExchange{
+			orderExchange: &orderExchange{
+				dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}},
+				activeOrders:  make(map[int64]float64),
+				mutex:         new(sync.Mutex),
+			},
+		},
+	}
+	r, err := robot.NewRobot(config, configDir, nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	ctx := alg.contexts[len(alg.contexts)-1]
+	ctx.Exchange.Buy("btc_jpy", 100, 1, nil, nil)
+	ctx.Exchange.Buy("btc_jpy", 200, 1, nil, nil)
+	ctx.Exchange.Buy("btc_jpy", 300, 2, nil, nil)
+	r.DestroyInternalTradeAlgorithms(ex)
+	r.Finalize()
+
+	// 停止中に1は約定、3は一部約定、9は他で出された注文
+	ex.setActiveOrder(2, 1)
+	ex.setActiveOrder(3, 0.5)
+	ex.setActiveOrder(9, 1)
+	// 履歴のIDは取引ID、価格は100
+	ex.addHistory(1000, 1)
+	r, err = robot.NewRobot(config, configDir, nil)
+	if err != nil {
+		t.Fatalf("can not create robot again (reason = %v)", err)
+	}
+	defer r.Finalize()
+	result := r.Reconcile(ex)
+	if len(result.OwnedOrders) != 2 || len(result.ClosedOrders) != 1 || result.ClosedOrders[0].OrderID != 1 {
+		t.Fatalf("unexpected owned or closed orders (owned = %v, closed = %v)", result.OwnedOrders, result.ClosedOrders)
+	}
+	if len(result.OrphanedOrders) != 1 || len(result.CanceledOrders) != 1 || len(ex.canceled) != 1 || ex.canceled[0] != 9 {
+		t.Fatalf("orphaned order is not canceled (orphaned = %v, canceled = %v)", result.OrphanedOrders, ex.canceled)
+	}
+	if len(result.Fills) != 2 {
+		t.Fatalf("unexpected fills (%v)", result.Fills)
+	}
+	positions := r.GetPositions("reconcile")
+	if len(positions) != 1 || positions[0].Amount != 2.5 {
+		t.Fatalf("unexpected positions (%v)", positions)
+	}
+	// 残高 (1.5) より建玉が多い
+	if len(result.Warnings) != 1 {
+		t.Fatalf("unexpected warnings (%v)", result.Warnings)
+	}
+}
+
+// racingExchange calls onActiveOrders after it takes snapshot of active orders
+type racingExchange struct {
+	*reconcileExchange
+	onActiveOrders func()
+}
+
+func (r *racingExchange) GetActiveOrderCursor() (exchange.OrderCursor, error) {
+	cursor, err := r.reconcileExchange.GetActiveOrderCursor()
+	if r.onActiveOrders != nil {
+		r.onActiveOrders()
+	}
+	return cursor, err
+}
+
+func TestPollFillsRace(t *testing.T) {
+	alg := &contextAlgorithm{}
+	algorithm.RegisterAlgorithmV2("robottest-pollfills", func(configDir string) (algorithm.InternalTradeAlgorithmV2, error) {
+		return alg, nil
+	}, nil)
+	config := &robot.Config{
+		Algorithms: []*robot.AlgorithmConfig{
+			{Name: "pollfills", Algorithm: "robottest-pollfills"},
+		},
+	}
+	r, err := robot.NewRobot(config, "", nil)
+	if err != nil {
+		t.Fatalf("can not create robot (reason = %v)", err)
+	}
+	simulatedClock := clock.NewSimulatedClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(simulatedClock)
+	ex := &racingExchange{
+		reconcileExchange: &reconcileExchange{
+			killExchange: &killExchange{
+				orderExchange: &orderExchange{
+					dummyExchange: &dummyExchange{name: "a", currencyPairs: []string{"btc_jpy"}},
+					activeOrders:  make(map[int64]float64),
+					mutex:         new(sync.Mutex),
+				},
+			},
+		},
+	}
+	err = r.CreateInternalTradeAlgorithms(ex)
+	if err != nil {
+		t.Fatalf("can not create internal trade algorithms (reason = %v)", err)
+	}
+	defer r.DestroyInternalTradeAlgorithms(ex)
+	ctx := alg.contexts[len(alg.contexts)-1]
+	ctx.Exchange.Buy("btc_jpy", 100, 1, nil, nil)
+	ctx.Exchange.Buy("btc_jpy", 100, 1, nil, nil)
+	ex.setActiveOrder(1, 1)
+	// 2は取引所で取り消された
+	ex.onActiveOrders = func() {
+		// 一覧を取得している間に3を出す
+		simulatedClock.Advance(time.Second)
+		ctx.Exchange.Buy("btc_jpy", 100, 3, nil, nil)
+	}
+	err = r.PollFills(ex)
+	if err != nil {
+		t.Fatalf("can not poll fills (reason = %v)", err)
+	}
+	if positions := r.GetPositions("pollfills"); len(positions) != 0 {
+		t.Fatalf("canceled or new order is booked as fill (positions = %v)", positions)
+	}
+
+	// 1は約定した
+	ex.onActiveOrders = nil
+	ex.setActiveOrder(1, 0)
+	ex.setActiveOrder(3, 3)
+	ex.addHistory(1000, 1)
+	err = r.PollFills(ex)
+	if err != nil {
+		t.Fatalf("can not poll fills (reason = %v)", err)
+	}
+	positions := r.GetPositions("pollfills")
+	if len(positions) != 1 || positions[0].Amount != 1 || positions[0].AveragePrice != 100 {
+		t.Fatalf("unexpected positions (positions = %v)", positions)
+	}
+	entries, _ := r.GetJournal().Query(&journal.Query{Name: "pollfills", Types: []journal.EntryType{journal.EntryTypeFill}})
+	if len(entries) != 1 || entries[0].OrderID != 1 {
+		t.Fatalf("unexpected fills (entries = %v)", entries)
+	}
+}
@@ -0,0 +1,169 @@
+package rpcplugin
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"sort"
+)
+
+// session is state of one algorithm instance in plugin process
+type session struct {
+	host       *Host
+	instanceID string
+	kind       AlgorithmKind
+	configDir  string
+	exchanges  []string
+	generation uint64
+}
+
+func (s *session) initialize() (error) {
+	generation, err := s.host.connect()
+	if err != nil {
+		return err
+	}
+	args := &InitializeArgs{
+		InstanceID: s.instanceID,
+		Kind:       s.kind,
+		ConfigDir:  s.configDir,
+		Exchanges:  s.exchanges,
+	}
+	err = s.host.call(generation, AlgorithmServiceName+".Initialize", args, new(Empty))
+	if err != nil {
+		return err
+	}
+	s.generation = generation
+	return nil
+}
+
+func (s *session) call(method string, args interface{}, reply interface{}) (error) {
+	generation, err := s.host.connect()
+	if err != nil {
+		return err
+	}
+	if generation != s.generation {
+		// プラグインプロセスが再起動したので初期化し直す
+		err = s.initialize()
+		if err != nil {
+			return errors.Wrapf(err, "can not initialize again (instance id = %v)", s.instanceID)
+		}
+	}
+	return s.host.call(s.generation, method, args, reply)
+}
+
+func (s *session) finalize() (error) {
+	defer s.host.removeInstance(s.instanceID)
+	s.host.mutex.Lock()
+	restarted := s.host.generation != s.generation || s.host.client == nil
+	s.host.mutex.Unlock()
+	if restarted {
+		// 再起動したプロセスはこのインスタンスを知らない
+		return nil
+	}
+	return s.host.call(s.generation, AlgorithmServiceName+".Finalize", &FinalizeArgs{InstanceID: s.instanceID}, new(Empty))
+}
+
+func newSession(host *Host, kind AlgorithmKind, configDir string) (*session) {
+	return &session{
+		host:       host,
+		instanceID: host.newInstanceID(),
+		kind:       kind,
+		configDir:  configDir,
+	}
+}
+
+type internalTradeAlgorithm struct {
+	session *session
+}
+
+func (i *internalTradeAlgorithm) GetName() (string) {
+	return i.session.host.GetName()
+}
+
+func (i *internalTradeAlgorithm) Initialize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	i.session.host.addExchange(i.session.instanceID, ex)
+	i.session.host.setNotifier(notifier)
+	i.session.exchanges = []string{ex.GetName()}
+	return i.session.initialize()
+}
+
+func (i *internalTradeAlgorithm) Update(currencyPair string, ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	board := new(BoardSnapshot)
+	lastPrice, err := ex.GetLastPrice(currencyPair)
+	if err != nil {
+		return errors.Wrapf(err, "can not get last price (currency pair = %v)", currencyPair)
+	}
+	board.LastPrice = lastPrice
+	sellBoardCursor, buyBoardCursor, err := ex.GetSellBuyBoardCursor(currencyPair)
+	if err != nil {
+		return errors.Wrapf(err, "can not get board (currency pair = %v)", currencyPair)
+	}
+	board.Asks = sellBoardCursor.All()
+	board.Bids = buyBoardCursor.All()
+	args := &UpdateArgs{
+		InstanceID:   i.session.instanceID,
+		Kind:         AlgorithmKindInternal,
+		Exchange:     ex.GetName(),
+		CurrencyPair: currencyPair,
+		Board:        board,
+	}
+	return i.session.call(AlgorithmServiceName+".Update", args, new(Empty))
+}
+
+func (i *internalTradeAlgorithm) Finalize(ex exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return i.session.finalize()
+}
+
+type externalTradeAlgorithm struct {
+	session *session
+}
+
+func (e *externalTradeAlgorithm) GetName() (string) {
+	return e.session.host.GetName()
+}
+
+func (e *externalTradeAlgorithm) Initialize(exchanges map[string]exchange.Exchange, notifier *notifier.Notifier) (error) {
+	names := make([]string, 0, len(exchanges))
+	for name, ex := range exchanges {
+		e.session.host.addExchange(e.session.instanceID, ex)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	e.session.host.setNotifier(notifier)
+	e.session.exchanges = names
+	return e.session.initialize()
+}
+
+func (e *externalTradeAlgorithm) Update(exchanges map[string]exchange.Exchange, notifier *notifier.Notifier) (error) {
+	args := &UpdateArgs{
+		InstanceID: e.session.instanceID,
+		Kind:       AlgorithmKindExternal,
+	}
+	return e.session.call(AlgorithmServiceName+".Update", args, new(Empty))
+}
+
+func (e *externalTradeAlgorithm) Finalize(exchanges map[string]exchange.Exchange, notifier *notifier.Notifier) (error) {
+	return e.session.finalize()
+}
+
+// RegisterAlgorithm is register plugin process as algorithm
+func RegisterAlgorithm(host *Host) {
+	var internalTradeAlgorithmNewFunc algorithm.InternalTradeAlgorithmNewFunc
+	if host.config.hasKind(AlgorithmKindInternal) {
+		internalTradeAlgorithmNewFunc = func(configDir string) (algorithm.InternalTradeAlgorithm, error) {
+			return &internalTradeAlgorithm{
+				session: newSession(host, AlgorithmKindInternal, configDir),
+			}, nil
+		}
+	}
+	var externalTradeAlgorithmNewFunc algorithm.ExternalTradeAlgorithmNewFunc
+	if host.config.hasKind(AlgorithmKindExternal) {
+		externalTradeAlgorithmNewFunc = func(configDir string) (algorithm.ExternalTradeAlgorithm, error) {
+			return &externalTradeAlgorithm{
+				session: newSession(host, AlgorithmKindExternal, configDir),
+			}, nil
+		}
+	}
+	algorithm.RegisterAlgorithm(host.GetName(), internalTradeAlgorithmNewFunc, externalTradeAlgorithmNewFunc)
+}
@@ -0,0 +1,222 @@
+package rpcplugin
+
+import (
+	"github.com/pkg/errors"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+)
+
+// callbackConnection is connection to ACT shared by algorithm instances in plugin process
+type callbackConnection struct {
+	addr   string
+	token  string
+	client *rpc.Client
+	mutex  *sync.Mutex
+}
+
+func (c *callbackConnection) getToken() (string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.token
+}
+
+func (c *callbackConnection) call(method string, args interface{}, reply interface{}) (error) {
+	c.mutex.Lock()
+	if c.client == nil {
+		client, err := jsonrpc.Dial("tcp", c.addr)
+		if err != nil {
+			c.mutex.Unlock()
+			return errors.Wrapf(err, "can not connect to ACT (addr = %v)", c.addr)
+		}
+		c.client = client
+	}
+	client := c.client
+	c.mutex.Unlock()
+	err := client.Call(method, args, reply)
+	if err == rpc.ErrShutdown {
+		c.mutex.Lock()
+		if c.client == client {
+			c.client = nil
+		}
+		c.mutex.Unlock()
+	}
+	return err
+}
+
+// Client is used by plugin process to call back exchange and notifier of ACT as algorithm instance
+type Client struct {
+	connection *callbackConnection
+	instanceID string
+}
+
+// GetInstanceID is get id of algorithm instance which calls back
+func (c *Client) GetInstanceID() (string) {
+	return c.instanceID
+}
+
+func (c *Client) callbackArgs() (CallbackArgs) {
+	return CallbackArgs{
+		InstanceID: c.instanceID,
+		Token:      c.connection.getToken(),
+	}
+}
+
+func (c *Client) call(method string, args interface{}, reply interface{}) (error) {
+	return c.connection.call(method, args, reply)
+}
+
+func (c *Client) GetCurrencyPairs(exchangeName string) ([]string, error) {
+	reply := new(CurrencyPairsReply)
+	err := c.call(ExchangeServiceName+".GetCurrencyPairs", &ExchangeArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName}, reply)
+	return reply.CurrencyPairs, err
+}
+
+func (c *Client) Buy(exchangeName string, currencyPair string, price float64, amount float64, maxRetry int) (int64, float64, float64, error) {
+	args := &OrderArgs{
+		CallbackArgs: c.callbackArgs(),
+		Exchange:     exchangeName,
+		CurrencyPair: currencyPair,
+		Price:        price,
+		Amount:       amount,
+		MaxRetry:     maxRetry,
+	}
+	reply := new(OrderReply)
+	err := c.call(ExchangeServiceName+".Buy", args, reply)
+	return reply.OrderID, reply.Price, reply.Amount, err
+}
+
+func (c *Client) Sell(exchangeName string, currencyPair string, price float64, amount float64, maxRetry int) (int64, float64, float64, error) {
+	args := &OrderArgs{
+		CallbackArgs: c.callbackArgs(),
+		Exchange:     exchangeName,
+		CurrencyPair: currencyPair,
+		Price:        price,
+		Amount:       amount,
+		MaxRetry:     maxRetry,
+	}
+	reply := new(OrderReply)
+	err := c.call(ExchangeServiceName+".Sell", args, reply)
+	return reply.OrderID, reply.Price, reply.Amount, err
+}
+
+func (c *Client) Cancel(exchangeName string, orderID int64, currencyPair string) (error) {
+	args := &CancelArgs{
+		CallbackArgs: c.callbackArgs(),
+		Exchange:     exchangeName,
+		CurrencyPair: currencyPair,
+		OrderID:      orderID,
+	}
+	return c.call(ExchangeServiceName+".Cancel", args, new(Empty))
+}
+
+func (c *Client) GetFunds(exchangeName string) (map[string]float64, error) {
+	reply := new(FundsReply)
+	err := c.call(ExchangeServiceName+".GetFunds", &ExchangeArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName}, reply)
+	return reply.Funds, err
+}
+
+func (c *Client) GetLastPrice(exchangeName string, currencyPair string) (float64, error) {
+	reply := new(FloatReply)
+	err := c.call(ExchangeServiceName+".GetLastPrice", &CurrencyPairArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair}, reply)
+	return reply.Value, err
+}
+
+func (c *Client) GetSellBoard(exchangeName string, currencyPair string) ([][]float64, error) {
+	reply := new(BoardReply)
+	err := c.call(ExchangeServiceName+".GetSellBoard", &CurrencyPairArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair}, reply)
+	return reply.Values, err
+}
+
+func (c *Client) GetBuyBoard(exchangeName string, currencyPair string) ([][]float64, error) {
+	reply := new(BoardReply)
+	err := c.call(ExchangeServiceName+".GetBuyBoard", &CurrencyPairArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair}, reply)
+	return reply.Values, err
+}
+
+func (c *Client) GetTrades(exchangeName string, currencyPair string) ([]*Trade, error) {
+	reply := new(TradesReply)
+	err := c.call(ExchangeServiceName+".GetTrades", &CurrencyPairArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair}, reply)
+	return reply.Trades, err
+}
+
+func (c *Client) GetOrderHistory(exchangeName string, count int64) ([]*Order, error) {
+	reply := new(OrdersReply)
+	err := c.call(ExchangeServiceName+".GetOrderHistory", &OrderHistoryArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, Count: count}, reply)
+	return reply.Orders, err
+}
+
+func (c *Client) GetActiveOrders(exchangeName string) ([]*Order, error) {
+	reply := new(OrdersReply)
+	err := c.call(ExchangeServiceName+".GetActiveOrders", &ExchangeArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName}, reply)
+	return reply.Orders, err
+}
+
+func (c *Client) GetMinPriceUnit(exchangeName string, currencyPair string) (float64, error) {
+	reply := new(FloatReply)
+	err := c.call(ExchangeServiceName+".GetMinPriceUnit", &CurrencyPairArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair}, reply)
+	return reply.Value, err
+}
+
+func (c *Client) GetMinAmountUnit(exchangeName string, currencyPair string) (float64, error) {
+	reply := new(FloatReply)
+	err := c.call(ExchangeServiceName+".GetMinAmountUnit", &CurrencyPairArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair}, reply)
+	return reply.Value, err
+}
+
+func (c *Client) GetTradeFeeRate(exchangeName string, currencyPair string) (float64, error) {
+	reply := new(FloatReply)
+	err := c.call(ExchangeServiceName+".GetTradeFeeRate", &CurrencyPairArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair}, reply)
+	return reply.Value, err
+}
+
+func (c *Client) FixPrice(exchangeName string, currencyPair string, price float64) (float64, error) {
+	reply := new(FloatReply)
+	err := c.call(ExchangeServiceName+".FixPrice", &ValueArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair, Value: price}, reply)
+	return reply.Value, err
+}
+
+func (c *Client) FixAmount(exchangeName string, currencyPair string, amount float64) (float64, error) {
+	reply := new(FloatReply)
+	err := c.call(ExchangeServiceName+".FixAmount", &ValueArgs{CallbackArgs: c.callbackArgs(), Exchange: exchangeName, CurrencyPair: currencyPair, Value: amount}, reply)
+	return reply.Value, err
+}
+
+func (c *Client) SendMail(subject string, body string) (error) {
+	return c.call(NotifierServiceName+".SendMail", &NotifyArgs{CallbackArgs: c.callbackArgs(), Subject: subject, Body: body}, new(Empty))
+}
+
+func (c *Client) Notify(severity string, subject string, body string, source string) (error) {
+	return c.call(NotifierServiceName+".Notify", &NotifyArgs{CallbackArgs: c.callbackArgs(), Subject: subject, Body: body, Severity: severity, Source: source}, new(Empty))
+}
+
+func (c *callbackConnection) setCallback(addr string, token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if token != "" {
+		c.token = token
+	}
+	if addr == "" || c.addr == addr {
+		return
+	}
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	c.addr = addr
+}
+
+func newCallbackConnection(addr string, token string) (*callbackConnection) {
+	return &callbackConnection{
+		addr:  addr,
+		token: token,
+		mutex: new(sync.Mutex),
+	}
+}
+
+func newClient(connection *callbackConnection, instanceID string) (*Client) {
+	return &Client{
+		connection: connection,
+		instanceID: instanceID,
+	}
+}
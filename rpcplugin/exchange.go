@@ -0,0 +1,371 @@
+package rpcplugin
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"crypto/subtle"
+	"runtime/debug"
+	"sync"
+)
+
+// ExchangeService is rpc service that provides exchange.Exchange to plugin process
+type ExchangeService struct {
+	token     string
+	// インスタンスごとに注文を記録する取引所が違うのでインスタンスIDと取引所名で引く
+	exchanges map[string]map[string]exchange.Exchange
+	mutex     *sync.Mutex
+	logger    *logger.Logger
+}
+
+// safeCall is recover panic in exchange so that plugin can not take ACT down
+func safeCall(log *logger.Logger, f func() (error)) (err error) {
+	defer func() {
+		if reason := recover(); reason != nil {
+			err = errors.Errorf("panic in callback (reason = %v)", reason)
+			log.Errorf("panic in plugin callback (reason = %v)\n%s", reason, debug.Stack())
+		}
+	}()
+	return f()
+}
+
+// authenticate is check token of callback. listener of callback accepts any local process
+func authenticate(token string, args *CallbackArgs) (error) {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(args.Token)) != 1 {
+		return errors.Errorf("invalid callback token (instance id = %v)", args.InstanceID)
+	}
+	return nil
+}
+
+func (e *ExchangeService) addExchange(instanceID string, ex exchange.Exchange) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	exchanges, ok := e.exchanges[instanceID]
+	if !ok {
+		exchanges = make(map[string]exchange.Exchange)
+		e.exchanges[instanceID] = exchanges
+	}
+	exchanges[ex.GetName()] = ex
+}
+
+func (e *ExchangeService) removeInstance(instanceID string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.exchanges, instanceID)
+}
+
+func (e *ExchangeService) getExchange(args *CallbackArgs, name string) (exchange.Exchange, error) {
+	err := authenticate(e.token, args)
+	if err != nil {
+		return nil, err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ex, ok := e.exchanges[args.InstanceID][name]
+	if !ok {
+		return nil, errors.Errorf("not found exchange (instance id = %v, name = %v)", args.InstanceID, name)
+	}
+	return ex, nil
+}
+
+func (e *ExchangeService) GetCurrencyPairs(args *ExchangeArgs, reply *CurrencyPairsReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.CurrencyPairs = ex.GetCurrencyPairs()
+		return nil
+	})
+}
+
+func newRetryCallback(maxRetry int) (exchange.RetryCallback) {
+	count := 0
+	return func(price *float64, amount *float64, errMsg string, retryCallbackData interface{}) (bool) {
+		count++
+		return count <= maxRetry
+	}
+}
+
+func (e *ExchangeService) Buy(args *OrderArgs, reply *OrderReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.OrderID, reply.Price, reply.Amount, err = ex.Buy(args.CurrencyPair, args.Price, args.Amount, newRetryCallback(args.MaxRetry), nil)
+		return err
+	})
+}
+
+func (e *ExchangeService) Sell(args *OrderArgs, reply *OrderReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.OrderID, reply.Price, reply.Amount, err = ex.Sell(args.CurrencyPair, args.Price, args.Amount, newRetryCallback(args.MaxRetry), nil)
+		return err
+	})
+}
+
+func (e *ExchangeService) Cancel(args *CancelArgs, reply *Empty) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		return ex.Cancel(args.OrderID, args.CurrencyPair)
+	})
+}
+
+func (e *ExchangeService) GetFunds(args *ExchangeArgs, reply *FundsReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.Funds, err = ex.GetFunds()
+		return err
+	})
+}
+
+func (e *ExchangeService) GetLastPrice(args *CurrencyPairArgs, reply *FloatReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.Value, err = ex.GetLastPrice(args.CurrencyPair)
+		return err
+	})
+}
+
+func (e *ExchangeService) GetSellBoard(args *CurrencyPairArgs, reply *BoardReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		boardCursor, err := ex.GetSellBoardCursor(args.CurrencyPair)
+		if err != nil {
+			return err
+		}
+		reply.Values = boardCursor.All()
+		return nil
+	})
+}
+
+func (e *ExchangeService) GetBuyBoard(args *CurrencyPairArgs, reply *BoardReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		boardCursor, err := ex.GetBuyBoardCursor(args.CurrencyPair)
+		if err != nil {
+			return err
+		}
+		reply.Values = boardCursor.All()
+		return nil
+	})
+}
+
+func (e *ExchangeService) GetTrades(args *CurrencyPairArgs, reply *TradesReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		tradesCursor, err := ex.GetTradesCursor(args.CurrencyPair)
+		if err != nil {
+			return err
+		}
+		reply.Trades = make([]*Trade, 0, tradesCursor.Len())
+		for {
+			time, price, amount, tradeType, ok := tradesCursor.Next()
+			if !ok {
+				break
+			}
+			reply.Trades = append(reply.Trades, &Trade{Time: time, Price: price, Amount: amount, TradeType: tradeType})
+		}
+		return nil
+	})
+}
+
+func orderCursorToOrders(orderCursor exchange.OrderCursor) ([]*Order) {
+	orders := make([]*Order, 0, orderCursor.Len())
+	for {
+		orderID, currencyPair, action, price, amount, timestamp, ok := orderCursor.Next()
+		if !ok {
+			break
+		}
+		orders = append(orders, &Order{
+			OrderID:      orderID,
+			CurrencyPair: currencyPair,
+			Action:       string(action),
+			Price:        price,
+			Amount:       amount,
+			Timestamp:    timestamp,
+		})
+	}
+	return orders
+}
+
+func (e *ExchangeService) GetOrderHistory(args *OrderHistoryArgs, reply *OrdersReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		orderCursor, err := ex.GetOrderHistoryCursor(args.Count)
+		if err != nil {
+			return err
+		}
+		reply.Orders = orderCursorToOrders(orderCursor)
+		return nil
+	})
+}
+
+func (e *ExchangeService) GetActiveOrders(args *ExchangeArgs, reply *OrdersReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		orderCursor, err := ex.GetActiveOrderCursor()
+		if err != nil {
+			return err
+		}
+		reply.Orders = orderCursorToOrders(orderCursor)
+		return nil
+	})
+}
+
+func (e *ExchangeService) GetMinPriceUnit(args *CurrencyPairArgs, reply *FloatReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.Value = ex.GetMinPriceUnit(args.CurrencyPair)
+		return nil
+	})
+}
+
+func (e *ExchangeService) GetMinAmountUnit(args *CurrencyPairArgs, reply *FloatReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.Value = ex.GetMinAmountUnit(args.CurrencyPair)
+		return nil
+	})
+}
+
+func (e *ExchangeService) GetTradeFeeRate(args *CurrencyPairArgs, reply *FloatReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.Value = ex.GetTradeFeeRate(args.CurrencyPair)
+		return nil
+	})
+}
+
+func (e *ExchangeService) FixPrice(args *ValueArgs, reply *FloatReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.Value = ex.FixPrice(args.CurrencyPair, args.Value)
+		return nil
+	})
+}
+
+func (e *ExchangeService) FixAmount(args *ValueArgs, reply *FloatReply) (error) {
+	return safeCall(e.logger, func() (error) {
+		ex, err := e.getExchange(&args.CallbackArgs, args.Exchange)
+		if err != nil {
+			return err
+		}
+		reply.Value = ex.FixAmount(args.CurrencyPair, args.Value)
+		return nil
+	})
+}
+
+func newExchangeService(token string, log *logger.Logger) (*ExchangeService) {
+	return &ExchangeService{
+		token:     token,
+		exchanges: make(map[string]map[string]exchange.Exchange),
+		mutex:     new(sync.Mutex),
+		logger:    log,
+	}
+}
+
+// NotifierService is rpc service that provides notifier to plugin process
+type NotifierService struct {
+	token    string
+	notifier *notifier.Notifier
+	mutex    *sync.Mutex
+	logger   *logger.Logger
+}
+
+func (n *NotifierService) setNotifier(notifier *notifier.Notifier) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.notifier = notifier
+}
+
+func (n *NotifierService) SendMail(args *NotifyArgs, reply *Empty) (error) {
+	return safeCall(n.logger, func() (error) {
+		err := authenticate(n.token, &args.CallbackArgs)
+		if err != nil {
+			return err
+		}
+		n.mutex.Lock()
+		ntf := n.notifier
+		n.mutex.Unlock()
+		if ntf == nil {
+			return nil
+		}
+		return ntf.SendMail(args.Subject, args.Body)
+	})
+}
+
+func (n *NotifierService) Notify(args *NotifyArgs, reply *Empty) (error) {
+	return safeCall(n.logger, func() (error) {
+		err := authenticate(n.token, &args.CallbackArgs)
+		if err != nil {
+			return err
+		}
+		severity, err := notifier.ParseSeverity(args.Severity)
+		if err != nil {
+			return err
+		}
+		n.mutex.Lock()
+		ntf := n.notifier
+		n.mutex.Unlock()
+		if ntf == nil {
+			return nil
+		}
+		return ntf.Notify(&notifier.Event{
+			Severity: severity,
+			Subject:  args.Subject,
+			Body:     args.Body,
+			Source:   args.Source,
+		})
+	})
+}
+
+func newNotifierService(token string, log *logger.Logger) (*NotifierService) {
+	return &NotifierService{
+		token:  token,
+		mutex:  new(sync.Mutex),
+		logger: log,
+	}
+}
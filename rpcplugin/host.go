@@ -0,0 +1,405 @@
+package rpcplugin
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/notifier"
+	"github.com/AutomaticCoinTrader/ACT/logger"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout     = 10
+	defaultRestartWait = 5
+	defaultListenAddr  = "127.0.0.1:0"
+	callbackTokenSize  = 32
+)
+
+// Config is config of out-of-process algorithm plugin
+type Config struct {
+	// アルゴリズム名として登録される
+	Name         string   `json:"name"         yaml:"name"         toml:"name"`
+	// プラグインプロセスを起動する場合のコマンド
+	Command      string   `json:"command"      yaml:"command"      toml:"command"`
+	Args         []string `json:"args"         yaml:"args"         toml:"args"`
+	// 起動済みのプラグインプロセスに接続する場合のアドレス
+	Addr         string   `json:"addr"         yaml:"addr"         toml:"addr"`
+	// プラグインからのコールバックを受けるアドレス
+	CallbackAddr string   `json:"callbackAddr" yaml:"callbackAddr" toml:"callbackAddr"`
+	// internal, external
+	Kinds        []string `json:"kinds"        yaml:"kinds"        toml:"kinds"`
+	Timeout      int      `json:"timeout"      yaml:"timeout"      toml:"timeout"`
+	RestartWait  int      `json:"restartWait"  yaml:"restartWait"  toml:"restartWait"`
+}
+
+// Validate is check config
+func (c *Config) Validate() (error) {
+	if c.Name == "" {
+		return errors.New("no name in process plugin config")
+	}
+	if c.Command == "" && c.Addr == "" {
+		return errors.Errorf("no command and addr in process plugin config (name = %v)", c.Name)
+	}
+	for _, kind := range c.Kinds {
+		switch AlgorithmKind(kind) {
+		case AlgorithmKindInternal, AlgorithmKindExternal:
+		default:
+			return errors.Errorf("unexpected kind in process plugin config (name = %v, kind = %v)", c.Name, kind)
+		}
+	}
+	return nil
+}
+
+func (c *Config) hasKind(kind AlgorithmKind) (bool) {
+	if len(c.Kinds) == 0 {
+		return kind == AlgorithmKindInternal
+	}
+	for _, k := range c.Kinds {
+		if AlgorithmKind(k) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Host manages a plugin process and rpc connections between ACT and the plugin process
+type Host struct {
+	config          *Config
+	timeout         time.Duration
+	restartWait     time.Duration
+	exchangeService *ExchangeService
+	notifierService *NotifierService
+	callbackToken   string
+	listener        net.Listener
+	cmd             *exec.Cmd
+	client          *rpc.Client
+	// 接続中は閉じていないチャンネル
+	connecting      chan bool
+	generation      uint64
+	lastStart       time.Time
+	instanceSeq     uint64
+	stopped         bool
+	mutex           *sync.Mutex
+	logger          *logger.Logger
+}
+
+// GetName is get algorithm name of plugin
+func (h *Host) GetName() (string) {
+	return h.config.Name
+}
+
+func (h *Host) addExchange(instanceID string, ex exchange.Exchange) {
+	h.exchangeService.addExchange(instanceID, ex)
+}
+
+func (h *Host) removeInstance(instanceID string) {
+	h.exchangeService.removeInstance(instanceID)
+}
+
+func (h *Host) setNotifier(notifier *notifier.Notifier) {
+	h.notifierService.setNotifier(notifier)
+}
+
+func (h *Host) newInstanceID() (string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.instanceSeq++
+	return fmt.Sprintf("%v-%v", h.config.Name, h.instanceSeq)
+}
+
+func (h *Host) serveCallback() {
+	server := rpc.NewServer()
+	server.RegisterName(ExchangeServiceName, h.exchangeService)
+	server.RegisterName(NotifierServiceName, h.notifierService)
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			return
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+func (h *Host) pluginAddr() (string, error) {
+	if h.config.Addr != "" {
+		return h.config.Addr, nil
+	}
+	// 空いているポートを探す
+	listener, err := net.Listen("tcp", defaultListenAddr)
+	if err != nil {
+		return "", errors.Wrap(err, "can not find free port")
+	}
+	defer listener.Close()
+	return listener.Addr().String(), nil
+}
+
+func (h *Host) startProcess(addr string) (*exec.Cmd, error) {
+	cmd := exec.Command(h.config.Command, h.config.Args...)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%v=%v", EnvPluginAddr, addr),
+		fmt.Sprintf("%v=%v", EnvCallbackAddr, h.listener.Addr().String()),
+		fmt.Sprintf("%v=%v", EnvCallbackToken, h.callbackToken))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not start plugin process (name = %v, command = %v)", h.config.Name, h.config.Command)
+	}
+	go func() {
+		err := cmd.Wait()
+		h.logger.Warnf("plugin process exited (name = %v, reason = %v)", h.config.Name, err)
+	}()
+	return cmd, nil
+}
+
+func (h *Host) dial(addr string) (*rpc.Client, error) {
+	deadline := time.Now().Add(h.timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, h.timeout)
+		if err == nil {
+			return jsonrpc.NewClient(conn), nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Wrapf(err, "can not connect to plugin process (name = %v, addr = %v)", h.config.Name, addr)
+		}
+		// プロセスがlistenするまで待つ
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (h *Host) handshake(client *rpc.Client) (error) {
+	args := &HandshakeArgs{
+		ProtocolVersion: ProtocolVersion,
+		CallbackAddr:    h.listener.Addr().String(),
+		CallbackToken:   h.callbackToken,
+	}
+	reply := new(HandshakeReply)
+	err := callWithTimeout(client, h.timeout, AlgorithmServiceName+".Handshake", args, reply)
+	if err != nil {
+		return errors.Wrapf(err, "handshake error (name = %v)", h.config.Name)
+	}
+	if reply.ProtocolVersion != ProtocolVersion {
+		return errors.Errorf("protocol version mismatch (name = %v, expected = %v, actual = %v)", h.config.Name, ProtocolVersion, reply.ProtocolVersion)
+	}
+	return nil
+}
+
+// open is start plugin process if needed, dial and handshake. it is called without lock
+func (h *Host) open() (*rpc.Client, *exec.Cmd, error) {
+	addr, err := h.pluginAddr()
+	if err != nil {
+		return nil, nil, err
+	}
+	var cmd *exec.Cmd
+	if h.config.Command != "" {
+		h.logger.Infof("start plugin process (name = %v, command = %v, addr = %v)", h.config.Name, h.config.Command, addr)
+		cmd, err = h.startProcess(addr)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	client, err := h.dial(addr)
+	if err != nil {
+		h.killCmd(cmd)
+		return nil, nil, err
+	}
+	err = h.handshake(client)
+	if err != nil {
+		client.Close()
+		h.killCmd(cmd)
+		return nil, nil, err
+	}
+	return client, cmd, nil
+}
+
+// connect is start or connect plugin process if needed and returns generation of connection
+func (h *Host) connect() (uint64, error) {
+	h.mutex.Lock()
+	for h.connecting != nil {
+		// 他の呼び出しが接続している間は待ってその結果を使う
+		connecting := h.connecting
+		h.mutex.Unlock()
+		<-connecting
+		h.mutex.Lock()
+	}
+	if h.stopped {
+		h.mutex.Unlock()
+		return 0, errors.Errorf("plugin host already stopped (name = %v)", h.config.Name)
+	}
+	if h.client != nil {
+		generation := h.generation
+		h.mutex.Unlock()
+		return generation, nil
+	}
+	if !h.lastStart.IsZero() && time.Since(h.lastStart) < h.restartWait {
+		h.mutex.Unlock()
+		return 0, errors.Errorf("plugin process is waiting for restart (name = %v)", h.config.Name)
+	}
+	h.lastStart = time.Now()
+	connecting := make(chan bool)
+	h.connecting = connecting
+	h.mutex.Unlock()
+
+	// 接続とハンドシェイクは時間がかかるのでロックの外で行う
+	client, cmd, err := h.open()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.connecting = nil
+	close(connecting)
+	if err != nil {
+		return 0, err
+	}
+	if h.stopped {
+		// 接続している間に止められた
+		client.Close()
+		h.killCmd(cmd)
+		return 0, errors.Errorf("plugin host already stopped (name = %v)", h.config.Name)
+	}
+	h.client = client
+	h.cmd = cmd
+	h.generation++
+	return h.generation, nil
+}
+
+func (h *Host) killCmd(cmd *exec.Cmd) {
+	if cmd == nil {
+		return
+	}
+	err := cmd.Process.Kill()
+	if err != nil {
+		h.logger.Errorf("can not kill plugin process (name = %v, reason = %v)", h.config.Name, err)
+	}
+}
+
+func (h *Host) killProcess() {
+	h.killCmd(h.cmd)
+	h.cmd = nil
+}
+
+// disconnect is close connection and kill plugin process. it is restarted on next call
+func (h *Host) disconnect(generation uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.generation != generation || h.client == nil {
+		return
+	}
+	h.client.Close()
+	h.client = nil
+	h.killProcess()
+}
+
+func (h *Host) getClient(generation uint64) (*rpc.Client, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.generation != generation || h.client == nil {
+		return nil, errors.Errorf("plugin process restarted (name = %v)", h.config.Name)
+	}
+	return h.client, nil
+}
+
+func callWithTimeout(client *rpc.Client, timeout time.Duration, method string, args interface{}, reply interface{}) (error) {
+	call := client.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-time.After(timeout):
+		return errors.Errorf("rpc timeout (method = %v)", method)
+	}
+}
+
+func (h *Host) call(generation uint64, method string, args interface{}, reply interface{}) (error) {
+	client, err := h.getClient(generation)
+	if err != nil {
+		return err
+	}
+	err = callWithTimeout(client, h.timeout, method, args, reply)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(rpc.ServerError); ok {
+		// アルゴリズムが返したエラー
+		return err
+	}
+	// 通信できないかタイムアウトしたのでプロセスごと作り直す
+	h.logger.Warnf("plugin process is not responding, restart later (name = %v, reason = %v)", h.config.Name, err)
+	h.disconnect(generation)
+	return errors.Wrapf(err, "can not call plugin (name = %v, method = %v)", h.config.Name, method)
+}
+
+// Stop is close connection and kill plugin process
+func (h *Host) Stop() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.stopped {
+		return
+	}
+	h.stopped = true
+	if h.client != nil {
+		h.client.Close()
+		h.client = nil
+	}
+	h.killProcess()
+	h.listener.Close()
+}
+
+// newCallbackToken is create random token to authenticate callbacks from plugin process
+func newCallbackToken() (string, error) {
+	token := make([]byte, callbackTokenSize)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", errors.Wrap(err, "can not create callback token")
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// NewHost is create host and start callback server
+func NewHost(config *Config) (*Host, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	restartWait := config.RestartWait
+	if restartWait <= 0 {
+		restartWait = defaultRestartWait
+	}
+	callbackAddr := config.CallbackAddr
+	if callbackAddr == "" {
+		callbackAddr = defaultListenAddr
+	}
+	callbackToken, err := newCallbackToken()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", callbackAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not listen callback addr (name = %v, addr = %v)", config.Name, callbackAddr)
+	}
+	hostLogger := logger.Get("plugin").WithField(logger.FieldAlgorithm, config.Name)
+	h := &Host{
+		config:          config,
+		timeout:         time.Duration(timeout) * time.Second,
+		restartWait:     time.Duration(restartWait) * time.Second,
+		exchangeService: newExchangeService(callbackToken, hostLogger),
+		notifierService: newNotifierService(callbackToken, hostLogger),
+		callbackToken:   callbackToken,
+		listener:        listener,
+		mutex:           new(sync.Mutex),
+		logger:          hostLogger,
+	}
+	go h.serveCallback()
+	return h, nil
+}
@@ -0,0 +1,174 @@
+package rpcplugin
+
+// 外部プロセスのアルゴリズムとACTの間のプロトコル
+//
+// ACT -> プラグイン : AlgorithmV1サービス (プラグインがACT_PLUGIN_ADDRでlistenする)
+// プラグイン -> ACT : ExchangeV1サービス, NotifierV1サービス (ACTがACT_CALLBACK_ADDRでlistenする)
+//
+// どちらもnet/rpc/jsonrpc (JSON-RPC 1.0) で通信するので、go以外の言語でもプラグインを作ることができる
+// プラグインからの呼び出しにはInitializeで渡されたinstanceIdとACT_CALLBACK_TOKEN (またはHandshakeのcallbackToken) のtokenを付ける
+// 互換性のない変更をする場合はProtocolVersionを上げて、サービス名もV2にすること
+
+const (
+	ProtocolVersion = 1
+
+	AlgorithmServiceName = "AlgorithmV1"
+	ExchangeServiceName  = "ExchangeV1"
+	NotifierServiceName  = "NotifierV1"
+
+	// プラグインプロセスに渡す環境変数
+	EnvPluginAddr    = "ACT_PLUGIN_ADDR"
+	EnvCallbackAddr  = "ACT_CALLBACK_ADDR"
+	EnvCallbackToken = "ACT_CALLBACK_TOKEN"
+)
+
+type AlgorithmKind string
+
+const (
+	AlgorithmKindInternal AlgorithmKind = "internal"
+	AlgorithmKindExternal AlgorithmKind = "external"
+)
+
+type Empty struct {
+}
+
+type HandshakeArgs struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	CallbackAddr    string `json:"callbackAddr"`
+	CallbackToken   string `json:"callbackToken"`
+}
+
+type HandshakeReply struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	Name            string `json:"name"`
+}
+
+type InitializeArgs struct {
+	InstanceID string        `json:"instanceId"`
+	Kind       AlgorithmKind `json:"kind"`
+	ConfigDir  string        `json:"configDir"`
+	Exchanges  []string      `json:"exchanges"`
+}
+
+type BoardSnapshot struct {
+	LastPrice float64     `json:"lastPrice"`
+	Bids      [][]float64 `json:"bids"`
+	Asks      [][]float64 `json:"asks"`
+}
+
+type UpdateArgs struct {
+	InstanceID   string         `json:"instanceId"`
+	Kind         AlgorithmKind  `json:"kind"`
+	// 以下はinternalの場合のみ
+	Exchange     string         `json:"exchange"`
+	CurrencyPair string         `json:"currencyPair"`
+	Board        *BoardSnapshot `json:"board"`
+}
+
+type FinalizeArgs struct {
+	InstanceID string `json:"instanceId"`
+}
+
+// CallbackArgs is included in all arguments of callback from plugin process
+type CallbackArgs struct {
+	// 呼び出し元のアルゴリズムのインスタンス
+	InstanceID string `json:"instanceId"`
+	// ACTが起動ごとに生成する認証用のトークン
+	Token      string `json:"token"`
+}
+
+type CurrencyPairArgs struct {
+	CallbackArgs
+	Exchange     string `json:"exchange"`
+	CurrencyPair string `json:"currencyPair"`
+}
+
+type ExchangeArgs struct {
+	CallbackArgs
+	Exchange string `json:"exchange"`
+}
+
+type OrderArgs struct {
+	CallbackArgs
+	Exchange     string  `json:"exchange"`
+	CurrencyPair string  `json:"currencyPair"`
+	Price        float64 `json:"price"`
+	Amount       float64 `json:"amount"`
+	// 失敗時にリトライする回数
+	MaxRetry     int     `json:"maxRetry"`
+}
+
+type OrderReply struct {
+	OrderID int64   `json:"orderId"`
+	Price   float64 `json:"price"`
+	Amount  float64 `json:"amount"`
+}
+
+type CancelArgs struct {
+	CallbackArgs
+	Exchange     string `json:"exchange"`
+	CurrencyPair string `json:"currencyPair"`
+	OrderID      int64  `json:"orderId"`
+}
+
+type OrderHistoryArgs struct {
+	CallbackArgs
+	Exchange string `json:"exchange"`
+	Count    int64  `json:"count"`
+}
+
+type ValueArgs struct {
+	CallbackArgs
+	Exchange     string  `json:"exchange"`
+	CurrencyPair string  `json:"currencyPair"`
+	Value        float64 `json:"value"`
+}
+
+type FloatReply struct {
+	Value float64 `json:"value"`
+}
+
+type FundsReply struct {
+	Funds map[string]float64 `json:"funds"`
+}
+
+type CurrencyPairsReply struct {
+	CurrencyPairs []string `json:"currencyPairs"`
+}
+
+type BoardReply struct {
+	Values [][]float64 `json:"values"`
+}
+
+type Trade struct {
+	Time      int64   `json:"time"`
+	Price     float64 `json:"price"`
+	Amount    float64 `json:"amount"`
+	TradeType string  `json:"tradeType"`
+}
+
+type TradesReply struct {
+	Trades []*Trade `json:"trades"`
+}
+
+type Order struct {
+	OrderID      int64   `json:"orderId"`
+	CurrencyPair string  `json:"currencyPair"`
+	Action       string  `json:"action"`
+	Price        float64 `json:"price"`
+	Amount       float64 `json:"amount"`
+	Timestamp    int64   `json:"timestamp"`
+}
+
+type OrdersReply struct {
+	Orders []*Order `json:"orders"`
+}
+
+type NotifyArgs struct {
+	CallbackArgs
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	// Notifyの場合のみ。省略時はinfo
+	Severity string `json:"severity,omitempty"`
+	Source   string `json:"source,omitempty"`
+}
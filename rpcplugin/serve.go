@@ -0,0 +1,113 @@
+package rpcplugin
+
+import (
+	"github.com/pkg/errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"log"
+	"sync"
+)
+
+// Algorithm is algorithm instance implemented in plugin process
+type Algorithm interface {
+	Update(args *UpdateArgs, client *Client) (error)
+	Finalize(client *Client) (error)
+}
+
+// AlgorithmNewFunc is called when ACT initializes algorithm instance
+type AlgorithmNewFunc func(args *InitializeArgs, client *Client) (Algorithm, error)
+
+// AlgorithmService is rpc service served by plugin process
+type AlgorithmService struct {
+	name             string
+	algorithmNewFunc AlgorithmNewFunc
+	connection       *callbackConnection
+	algorithms       map[string]Algorithm
+	mutex            *sync.Mutex
+}
+
+func (a *AlgorithmService) getAlgorithm(instanceID string) (Algorithm, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	alg, ok := a.algorithms[instanceID]
+	if !ok {
+		return nil, errors.Errorf("not found algorithm instance (instance id = %v)", instanceID)
+	}
+	return alg, nil
+}
+
+func (a *AlgorithmService) Handshake(args *HandshakeArgs, reply *HandshakeReply) (error) {
+	a.connection.setCallback(args.CallbackAddr, args.CallbackToken)
+	reply.ProtocolVersion = ProtocolVersion
+	reply.Name = a.name
+	return nil
+}
+
+func (a *AlgorithmService) Initialize(args *InitializeArgs, reply *Empty) (error) {
+	alg, err := a.algorithmNewFunc(args, newClient(a.connection, args.InstanceID))
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.algorithms[args.InstanceID] = alg
+	return nil
+}
+
+func (a *AlgorithmService) Update(args *UpdateArgs, reply *Empty) (error) {
+	alg, err := a.getAlgorithm(args.InstanceID)
+	if err != nil {
+		return err
+	}
+	return alg.Update(args, newClient(a.connection, args.InstanceID))
+}
+
+func (a *AlgorithmService) Finalize(args *FinalizeArgs, reply *Empty) (error) {
+	alg, err := a.getAlgorithm(args.InstanceID)
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	delete(a.algorithms, args.InstanceID)
+	a.mutex.Unlock()
+	return alg.Finalize(newClient(a.connection, args.InstanceID))
+}
+
+// ServeListener is serve algorithm on listener until listener is closed
+func ServeListener(listener net.Listener, name string, algorithmNewFunc AlgorithmNewFunc) (error) {
+	service := &AlgorithmService{
+		name:             name,
+		algorithmNewFunc: algorithmNewFunc,
+		connection:       newCallbackConnection(os.Getenv(EnvCallbackAddr), os.Getenv(EnvCallbackToken)),
+		algorithms:       make(map[string]Algorithm),
+		mutex:            new(sync.Mutex),
+	}
+	server := rpc.NewServer()
+	err := server.RegisterName(AlgorithmServiceName, service)
+	if err != nil {
+		return errors.Wrap(err, "can not register algorithm service")
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return errors.Wrap(err, "can not accept")
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Serve is serve algorithm on address given by ACT. it is called from main of plugin process
+func Serve(name string, algorithmNewFunc AlgorithmNewFunc) (error) {
+	addr := os.Getenv(EnvPluginAddr)
+	if addr == "" {
+		return errors.Errorf("no %v environment variable", EnvPluginAddr)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "can not listen (addr = %v)", addr)
+	}
+	log.Printf("serve %v algorithm plugin (addr = %v)", name, addr)
+	return ServeListener(listener, name, algorithmNewFunc)
+}
@@ -0,0 +1,281 @@
+package rpcplugintest
+
+import (
+	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/algorithm"
+	"github.com/AutomaticCoinTrader/ACT/exchange"
+	"github.com/AutomaticCoinTrader/ACT/rpcplugin"
+	"net"
+	"net/rpc/jsonrpc"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dummyBoardCursor struct {
+	values [][]float64
+}
+
+func (d *dummyBoardCursor) Next() (float64, float64, bool) {
+	return 0, 0, false
+}
+
+func (d *dummyBoardCursor) Reset() {
+}
+
+func (d *dummyBoardCursor) Len() (int) {
+	return len(d.values)
+}
+
+func (d *dummyBoardCursor) All() ([][]float64) {
+	return d.values
+}
+
+// dummyExchange implements only methods used in test
+type dummyExchange struct {
+	exchange.Exchange
+	buyPrices []float64
+	mutex     *sync.Mutex
+}
+
+func (d *dummyExchange) GetName() (string) {
+	return "dummy"
+}
+
+func (d *dummyExchange) GetLastPrice(currencyPair string) (float64, error) {
+	return 100, nil
+}
+
+func (d *dummyExchange) GetSellBuyBoardCursor(currencyPair string) (exchange.BoardCursor, exchange.BoardCursor, error) {
+	return &dummyBoardCursor{values: [][]float64{{101, 1}}}, &dummyBoardCursor{values: [][]float64{{99, 2}}}, nil
+}
+
+func (d *dummyExchange) Buy(currencyPair string, price float64, amount float64, retryCallback exchange.RetryCallback, retryCallbackData interface{}) (int64, float64, float64, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.buyPrices = append(d.buyPrices, price)
+	return int64(len(d.buyPrices)), price, amount, nil
+}
+
+type buyBestBidAlgorithm struct {
+	finalized bool
+}
+
+func (b *buyBestBidAlgorithm) Update(args *rpcplugin.UpdateArgs, client *rpcplugin.Client) (error) {
+	if args.Board == nil || len(args.Board.Bids) == 0 {
+		return errors.New("no board")
+	}
+	if args.CurrencyPair == "fail_jpy" {
+		return errors.New("algorithm error")
+	}
+	_, _, _, err := client.Buy(args.Exchange, args.CurrencyPair, args.Board.Bids[0][0], 1, 0)
+	return err
+}
+
+func (b *buyBestBidAlgorithm) Finalize(client *rpcplugin.Client) (error) {
+	b.finalized = true
+	return nil
+}
+
+func startPlugin(t *testing.T) (string, net.Listener) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not listen (reason = %v)", err)
+	}
+	go rpcplugin.ServeListener(listener, "buyBestBid", func(args *rpcplugin.InitializeArgs, client *rpcplugin.Client) (rpcplugin.Algorithm, error) {
+		return &buyBestBidAlgorithm{}, nil
+	})
+	return listener.Addr().String(), listener
+}
+
+func TestProcessPlugin(t *testing.T) {
+	addr, listener := startPlugin(t)
+	defer listener.Close()
+	host, err := rpcplugin.NewHost(&rpcplugin.Config{
+		Name: "buyBestBid",
+		Addr: addr,
+	})
+	if err != nil {
+		t.Fatalf("can not create host (reason = %v)", err)
+	}
+	defer host.Stop()
+	rpcplugin.RegisterAlgorithm(host)
+	registeredAlgorithm, ok := algorithm.GetRegisterdAlgoriths()["buyBestBid"]
+	if !ok {
+		t.Fatalf("algorithm is not registered")
+	}
+	if registeredAlgorithm.ExternalTradeAlgorithmNewFunc != nil {
+		t.Fatalf("external algorithm should not be registered by default")
+	}
+	alg, err := registeredAlgorithm.InternalTradeAlgorithmNewFunc("")
+	if err != nil {
+		t.Fatalf("can not create algorithm (reason = %v)", err)
+	}
+	ex := &dummyExchange{mutex: new(sync.Mutex)}
+	err = alg.Initialize(ex, nil)
+	if err != nil {
+		t.Fatalf("can not initialize algorithm (reason = %v)", err)
+	}
+	err = alg.Update("btc_jpy", ex, nil)
+	if err != nil {
+		t.Fatalf("can not update algorithm (reason = %v)", err)
+	}
+	ex.mutex.Lock()
+	if len(ex.buyPrices) != 1 || ex.buyPrices[0] != 99 {
+		t.Fatalf("unexpected buy (prices = %v)", ex.buyPrices)
+	}
+	ex.mutex.Unlock()
+	// アルゴリズムのエラーでは接続は切れない
+	err = alg.Update("fail_jpy", ex, nil)
+	if err == nil {
+		t.Fatalf("algorithm error is not returned")
+	}
+	err = alg.Update("btc_jpy", ex, nil)
+	if err != nil {
+		t.Fatalf("can not update algorithm after algorithm error (reason = %v)", err)
+	}
+	err = alg.Finalize(ex, nil)
+	if err != nil {
+		t.Fatalf("can not finalize algorithm (reason = %v)", err)
+	}
+}
+
+func TestProcessPluginInstances(t *testing.T) {
+	addr, listener := startPlugin(t)
+	defer listener.Close()
+	// コールバックを受けるアドレスを決めておく
+	callbackListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not listen (reason = %v)", err)
+	}
+	callbackAddr := callbackListener.Addr().String()
+	callbackListener.Close()
+	host, err := rpcplugin.NewHost(&rpcplugin.Config{
+		Name:         "buyBestBidInstances",
+		Addr:         addr,
+		CallbackAddr: callbackAddr,
+	})
+	if err != nil {
+		t.Fatalf("can not create host (reason = %v)", err)
+	}
+	defer host.Stop()
+	rpcplugin.RegisterAlgorithm(host)
+	registeredAlgorithm := algorithm.GetRegisterdAlgoriths()["buyBestBidInstances"]
+	// 同じ名前の取引所でもインスタンスごとに別の取引所を使う
+	exchanges := []*dummyExchange{{mutex: new(sync.Mutex)}, {mutex: new(sync.Mutex)}}
+	algorithms := make([]algorithm.InternalTradeAlgorithm, 0, len(exchanges))
+	for _, ex := range exchanges {
+		alg, err := registeredAlgorithm.InternalTradeAlgorithmNewFunc("")
+		if err != nil {
+			t.Fatalf("can not create algorithm (reason = %v)", err)
+		}
+		err = alg.Initialize(ex, nil)
+		if err != nil {
+			t.Fatalf("can not initialize algorithm (reason = %v)", err)
+		}
+		algorithms = append(algorithms, alg)
+	}
+	err = algorithms[1].Update("btc_jpy", exchanges[1], nil)
+	if err != nil {
+		t.Fatalf("can not update algorithm (reason = %v)", err)
+	}
+	if len(exchanges[0].buyPrices) != 0 || len(exchanges[1].buyPrices) != 1 {
+		t.Fatalf("order is placed on exchange of other instance (first = %v, second = %v)", exchanges[0].buyPrices, exchanges[1].buyPrices)
+	}
+	// トークンのない呼び出しは受け付けない
+	client, err := jsonrpc.Dial("tcp", callbackAddr)
+	if err != nil {
+		t.Fatalf("can not connect to callback (reason = %v)", err)
+	}
+	defer client.Close()
+	args := &rpcplugin.OrderArgs{
+		CallbackArgs: rpcplugin.CallbackArgs{InstanceID: "buyBestBidInstances-1"},
+		Exchange:     "dummy",
+		CurrencyPair: "btc_jpy",
+		Price:        1,
+		Amount:       1,
+	}
+	err = client.Call(rpcplugin.ExchangeServiceName+".Buy", args, new(rpcplugin.OrderReply))
+	if err == nil || !strings.Contains(err.Error(), "invalid callback token") {
+		t.Fatalf("callback without token is accepted (reason = %v)", err)
+	}
+	if len(exchanges[0].buyPrices) != 0 {
+		t.Fatalf("order is placed without token (prices = %v)", exchanges[0].buyPrices)
+	}
+	for i, alg := range algorithms {
+		err = alg.Finalize(exchanges[i], nil)
+		if err != nil {
+			t.Fatalf("can not finalize algorithm (reason = %v)", err)
+		}
+	}
+}
+
+func TestInvalidProcessPluginConfig(t *testing.T) {
+	configs := []*rpcplugin.Config{
+		{Command: "plugin"},
+		{Name: "noCommand"},
+		{Name: "badKind", Command: "plugin", Kinds: []string{"arbitrage"}},
+	}
+	for _, config := range configs {
+		err := config.Validate()
+		if err == nil {
+			t.Fatalf("invalid config is accepted (config = %v)", config)
+		}
+	}
+}
+
+func TestProcessPluginSlowHandshake(t *testing.T) {
+	// 接続は受けるがハンドシェイクに応答しないプラグイン
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can not listen (reason = %v)", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	host, err := rpcplugin.NewHost(&rpcplugin.Config{
+		Name:    "slowHandshake",
+		Addr:    listener.Addr().String(),
+		Timeout: 2,
+	})
+	if err != nil {
+		t.Fatalf("can not create host (reason = %v)", err)
+	}
+	rpcplugin.RegisterAlgorithm(host)
+	registeredAlgorithm := algorithm.GetRegisterdAlgoriths()["slowHandshake"]
+	alg, err := registeredAlgorithm.InternalTradeAlgorithmNewFunc("")
+	if err != nil {
+		t.Fatalf("can not create algorithm (reason = %v)", err)
+	}
+	initializeErr := make(chan error, 1)
+	go func() {
+		initializeErr <- alg.Initialize(&dummyExchange{mutex: new(sync.Mutex)}, nil)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	// ハンドシェイクを待っている間もインスタンスの作成と停止は待たされない
+	start := time.Now()
+	_, err = registeredAlgorithm.InternalTradeAlgorithmNewFunc("")
+	if err != nil {
+		t.Fatalf("can not create algorithm (reason = %v)", err)
+	}
+	host.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("host is locked while connecting (elapsed = %v)", elapsed)
+	}
+	select {
+	case err := <-initializeErr:
+		if err == nil {
+			t.Fatalf("algorithm is initialized without handshake")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("initialize does not return")
+	}
+}
@@ -0,0 +1,188 @@
+package state
+
+import (
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store is key/value store backed by embedded database. it keeps data in memory if path is empty
+type Store struct {
+	path   string
+	db     *bolt.DB
+	memory map[string]map[string][]byte
+	mutex  *sync.Mutex
+}
+
+// Namespace is view of the store that is limited to one namespace
+type Namespace struct {
+	store *Store
+	name  string
+}
+
+func (n *Namespace) Get(key string) ([]byte, error) {
+	if n.store.db == nil {
+		n.store.mutex.Lock()
+		defer n.store.mutex.Unlock()
+		value, ok := n.store.memory[n.name][key]
+		if !ok {
+			return nil, nil
+		}
+		return append([]byte(nil), value...), nil
+	}
+	var value []byte
+	err := n.store.db.View(func(tx *bolt.Tx) (error) {
+		bucket := tx.Bucket([]byte(n.name))
+		if bucket == nil {
+			return nil
+		}
+		v := bucket.Get([]byte(key))
+		if v != nil {
+			// トランザクションの外では使えないのでコピーする
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not get state (namespace = %v, key = %v)", n.name, key)
+	}
+	return value, nil
+}
+
+func (n *Namespace) Put(key string, value []byte) (error) {
+	if n.store.db == nil {
+		n.store.mutex.Lock()
+		defer n.store.mutex.Unlock()
+		values, ok := n.store.memory[n.name]
+		if !ok {
+			values = make(map[string][]byte)
+			n.store.memory[n.name] = values
+		}
+		values[key] = append([]byte(nil), value...)
+		return nil
+	}
+	err := n.store.db.Update(func(tx *bolt.Tx) (error) {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(n.name))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), value)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "can not put state (namespace = %v, key = %v)", n.name, key)
+	}
+	return nil
+}
+
+func (n *Namespace) Delete(key string) (error) {
+	if n.store.db == nil {
+		n.store.mutex.Lock()
+		defer n.store.mutex.Unlock()
+		delete(n.store.memory[n.name], key)
+		return nil
+	}
+	err := n.store.db.Update(func(tx *bolt.Tx) (error) {
+		bucket := tx.Bucket([]byte(n.name))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil {
+		return errors.Wrapf(err, "can not delete state (namespace = %v, key = %v)", n.name, key)
+	}
+	return nil
+}
+
+func (n *Namespace) Keys() ([]string, error) {
+	keys := make([]string, 0)
+	if n.store.db == nil {
+		n.store.mutex.Lock()
+		defer n.store.mutex.Unlock()
+		for key := range n.store.memory[n.name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys, nil
+	}
+	err := n.store.db.View(func(tx *bolt.Tx) (error) {
+		bucket := tx.Bucket([]byte(n.name))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k []byte, v []byte) (error) {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not get state keys (namespace = %v)", n.name)
+	}
+	return keys, nil
+}
+
+func (n *Namespace) GetJSON(key string, value interface{}) (bool, error) {
+	buf, err := n.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if buf == nil {
+		return false, nil
+	}
+	err = json.Unmarshal(buf, value)
+	if err != nil {
+		return false, errors.Wrapf(err, "can not unmarshal state (namespace = %v, key = %v)", n.name, key)
+	}
+	return true, nil
+}
+
+func (n *Namespace) PutJSON(key string, value interface{}) (error) {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "can not marshal state (namespace = %v, key = %v)", n.name, key)
+	}
+	return n.Put(key, buf)
+}
+
+// Namespace is get namespace of store
+func (s *Store) Namespace(name string) (*Namespace) {
+	return &Namespace{
+		store: s,
+		name:  name,
+	}
+}
+
+// GetPath is get path of database file
+func (s *Store) GetPath() (string) {
+	return s.path
+}
+
+// Close is close database
+func (s *Store) Close() (error) {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// NewStore is create store. if path is empty, data is kept only in memory
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		memory: make(map[string]map[string][]byte),
+		mutex:  new(sync.Mutex),
+	}
+	if path == "" {
+		return s, nil
+	}
+	// 他のプロセスが開いている場合に待ち続けないようにする
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not open state database (path = %v)", path)
+	}
+	s.db = db
+	return s, nil
+}
@@ -1,12 +1,14 @@
 package configurator
 
+import (
+	"github.com/AutomaticCoinTrader/ACT/logger"
+)
+
 type serverConfig struct {
 	AddrPort string `json:"addrPort"  yaml:"addrPort"  toml:"addrPort"`
 }
 
-type loggerConfig struct {
-	Output string `json:"output"    yaml:"output"    toml:"output"`
-}
+type loggerConfig logger.Config
 
 type ZaifProxyConfig struct {
 	Retry               int           `json:"retry"                yaml:"retry"                toml:"retry"`
@@ -2,7 +2,6 @@ package fetcher
 
 import (
 	"sync"
-	"log"
 	"sync/atomic"
 	"time"
 	"path"
@@ -12,6 +11,7 @@ import (
 	"github.com/AutomaticCoinTrader/ACT/exchange/zaif"
 	"net"
 	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 )
 
 type currencyPairsInfo struct {
@@ -41,14 +41,15 @@ type Fetcher struct {
 	fetchRequestCount   uint64
 	fetchSuccessCount   uint64
 	fetchFailCount      uint64
+	logger              *logger.Logger
 }
 
 func (f *Fetcher) pollingLoop(pollingRequestChan chan string, lastBidsMap map[string][][]float64, lastAsksMap map[string][][]float64, lastBidsAsksMutex *sync.Mutex) {
-	log.Printf("start polling loop")
+	f.logger.Debugf("start polling loop")
 	for {
 		currencyPair, ok := <-pollingRequestChan
 		if !ok {
-			log.Printf("finish polling loop")
+			f.logger.Debugf("finish polling loop")
 			return
 		}
 		// select httpClient
@@ -64,7 +65,7 @@ func (f *Fetcher) pollingLoop(pollingRequestChan chan string, lastBidsMap map[st
 		if err != nil {
 			atomic.AddUint64(&f.fetchFailCount, 1)
 			if res != nil && res.StatusCode == 403 {
-				log.Printf("occured 403 Forbidden currency pair = %v", currencyPair)
+				f.logger.WithField(logger.FieldCurrencyPair, currencyPair).Warnf("occured 403 Forbidden currency pair = %v", currencyPair)
 				atomic.StoreInt32(&f.pausePollingRequest, 1)
 			}
 			continue
@@ -76,7 +77,7 @@ func (f *Fetcher) pollingLoop(pollingRequestChan chan string, lastBidsMap map[st
 }
 
 func (f *Fetcher) pollingRequestLoop() {
-	log.Printf("start polling request loop")
+	f.logger.Infof("start polling request loop")
 	atomic.StoreInt32(&f.pollingFinish, 0)
 	lastBidsMap := make(map[string][][]float64)
 	lastAsksMap := make(map[string][][]float64)
@@ -105,7 +106,7 @@ FINISH:
 		}
 	}
 	close(pollingRequestChan)
-	log.Printf("finish polling request loop")
+	f.logger.Infof("finish polling request loop")
 }
 
 func (f *Fetcher) pollingReportLoop() {
@@ -113,7 +114,7 @@ func (f *Fetcher) pollingReportLoop() {
 		if atomic.LoadInt32(&f.pollingFinish) == 1 {
 			return
 		}
-		log.Printf("fetch request count = %v, success count = %v, fail count = %v", atomic.LoadUint64(&f.fetchRequestCount), atomic.LoadUint64(&f.fetchSuccessCount), atomic.LoadUint64(&f.fetchFailCount))
+		f.logger.Infof("fetch request count = %v, success count = %v, fail count = %v", atomic.LoadUint64(&f.fetchRequestCount), atomic.LoadUint64(&f.fetchSuccessCount), atomic.LoadUint64(&f.fetchFailCount))
 		time.Sleep(time.Second)
 	}
 }
@@ -153,6 +154,7 @@ func NewFetcher(config *configurator.ZaifProxyConfig) (*Fetcher, error) {
 		httpClientsMutex: new(sync.Mutex),
 		config:        config,
 		pollingFinish: 0,
+		logger:        logger.Get("proxy"),
 		currencyPairsInfo: &currencyPairsInfo{
 			Bids:      make(map[string][][]float64),
 			Asks:      make(map[string][][]float64),
@@ -9,11 +9,10 @@ import (
 	"path"
 	"os/signal"
 	"syscall"
-	"log/syslog"
 	actConfigurator "github.com/AutomaticCoinTrader/ACT/configurator"
 	"github.com/AutomaticCoinTrader/ACT/tools/proxy/zaif/configurator"
 	"github.com/AutomaticCoinTrader/ACT/tools/proxy/zaif/fetcher"
-	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 )
 
 const (
@@ -42,18 +41,6 @@ Loop:
 	}
 }
 
-func setupLogger(config *configurator.ZaifProxyConfig) (error) {
-	log.SetFlags(log.Ldate|log.Ltime)
-	if config.Logger != nil && config.Logger.Output == "syslog" {
-		logger, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_USER, "ACT")
-		if err != nil {
-			return errors.Wrapf(err, "can not open syslog", err)
-		}
-		log.SetOutput(logger)
-	}
-	return nil
-}
-
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	wd, err := os.Getwd()
@@ -79,11 +66,12 @@ func main() {
 		return
 
 	}
-	err = setupLogger(newConfig)
+	err = logger.Configure((*logger.Config)(newConfig.Logger), *configDir)
 	if err != nil {
 		log.Printf("can not setup logger (config dir = %v, reason = %v)", *configDir, err)
 		return
 	}
+	defer logger.Close()
 	f, err:= fetcher.NewFetcher(newConfig)
 	if err != nil {
 		log.Printf("can not create fetcher (config dir = %v, reason = %v)", *configDir, err)
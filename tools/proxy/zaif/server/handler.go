@@ -14,7 +14,7 @@ func (w *WebsocketServer) handleConnectionBase(writer http.ResponseWriter, reque
 	}
 	// Make sure we close the connection when the function returns
 	defer ws.Close()
-	log.Printf("connected peer address = %v", ws.RemoteAddr().String())
+	w.logger.Infof("connected peer address = %v", ws.RemoteAddr().String())
 	w.clientsMutex.Lock()
 	cs, ok := w.clients[currencyPair]
 	if !ok {
@@ -27,14 +27,14 @@ func (w *WebsocketServer) handleConnectionBase(writer http.ResponseWriter, reque
 		// Read in a new message as JSON and map it to a Message object
 		messageType, message, err := ws.ReadMessage()
 		if err != nil {
-			log.Printf("can not read message (error = %v)", err)
+			w.logger.Infof("can not read message (error = %v)", err)
 			w.clientsMutex.Lock()
 			delete(cs, ws)
 			w.clientsMutex.Unlock()
 			break
 		}
 		if messageType != websocket.TextMessage {
-			log.Printf("unsupported message type (message type = %v, message = %v)", messageType, message)
+			w.logger.Warnf("unsupported message type (message type = %v, message = %v)", messageType, message)
 		}
 		// nop
 	}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"github.com/AutomaticCoinTrader/ACT/tools/proxy/zaif/configurator"
 	"github.com/gorilla/websocket"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"time"
 	"sync"
 )
@@ -18,6 +19,7 @@ type WebsocketServer struct {
 	clientsMutex       *sync.Mutex
 	pingStopChan chan bool
 	pingStopCompleteChan chan bool
+	logger             *logger.Logger
 }
 
 
@@ -43,7 +45,7 @@ func (w *WebsocketServer) pingLoop() {
 
 func (w *WebsocketServer) listenAndServe() {
 	if err := w.server.ListenAndServe(); err != nil {
-		log.Printf("ListenAndServe returns an error (%v)", err)
+		w.logger.Infof("ListenAndServe returns an error (%v)", err)
 		if err != http.ErrServerClosed {
 			log.Fatalf("HTTPServer closed with error (%v)", err)
 		}
@@ -63,7 +65,7 @@ func (w *WebsocketServer) BroadCast(currencyPair string, message []byte) {
 }
 
 func (w *WebsocketServer) Start() {
-	log.Printf("start http server")
+	w.logger.Infof("start http server")
 	go w.listenAndServe()
 	go w.pingLoop()
 }
@@ -72,7 +74,7 @@ func (w *WebsocketServer) Stop() {
 	close(w.pingStopChan)
 	<-w.pingStopCompleteChan
 	w.server.Shutdown(context.Background())
-	log.Printf("stop http server")
+	w.logger.Infof("stop http server")
 }
 
 func NewWsServer(config *configurator.ZaifProxyConfig) (*WebsocketServer) {
@@ -84,6 +86,7 @@ func NewWsServer(config *configurator.ZaifProxyConfig) (*WebsocketServer) {
 		clientsMutex:       new(sync.Mutex),
 		pingStopChan:       make(chan bool),
 		pingStopCompleteChan: make(chan bool),
+		logger:             logger.Get("proxy"),
 	}
 	for _, currencyPair := range config.CurrencyPairs {
 		switch currencyPair {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/AutomaticCoinTrader/ACT/rpcplugin"
+	"log"
+)
+
+// 外部プロセスで動くアルゴリズムのサンプル。板の情報を表示するだけで取引はしない
+type exampleAlgorithm struct {
+	instanceID string
+}
+
+func (e *exampleAlgorithm) Update(args *rpcplugin.UpdateArgs, client *rpcplugin.Client) (error) {
+	if args.Kind == rpcplugin.AlgorithmKindExternal {
+		log.Printf("external update (instance id = %v)", e.instanceID)
+		return nil
+	}
+	log.Printf("internal update (instance id = %v, exchange = %v, currency pair = %v, last price = %v, bids = %v, asks = %v)",
+		e.instanceID, args.Exchange, args.CurrencyPair, args.Board.LastPrice, len(args.Board.Bids), len(args.Board.Asks))
+	return nil
+}
+
+func (e *exampleAlgorithm) Finalize(client *rpcplugin.Client) (error) {
+	log.Printf("finalize (instance id = %v)", e.instanceID)
+	return nil
+}
+
+func main() {
+	err := rpcplugin.Serve("rpcexample", func(args *rpcplugin.InitializeArgs, client *rpcplugin.Client) (rpcplugin.Algorithm, error) {
+		log.Printf("initialize (instance id = %v, kind = %v, exchanges = %v)", args.InstanceID, args.Kind, args.Exchanges)
+		for _, exchangeName := range args.Exchanges {
+			funds, err := client.GetFunds(exchangeName)
+			if err != nil {
+				log.Printf("can not get funds (exchange = %v, reason = %v)", exchangeName, err)
+				continue
+			}
+			log.Printf("funds (exchange = %v, funds = %v)", exchangeName, funds)
+		}
+		return &exampleAlgorithm{instanceID: args.InstanceID}, nil
+	})
+	if err != nil {
+		log.Fatalf("can not serve plugin (reason = %v)", err)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
@@ -14,6 +13,9 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"github.com/viki-org/dnscache"
+	"github.com/AutomaticCoinTrader/ACT/clock"
+	"github.com/AutomaticCoinTrader/ACT/metrics"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"strings"
 	"sync"
 )
@@ -37,9 +39,13 @@ type HTTPRequest struct {
 	RequestMethodString string
 	Headers             map[string]string
 	Body                string
+	// メトリクスのラベル。空ならURLのパス
+	Endpoint            string
 }
 
 type HTTPClient struct {
+	name              string
+	logger            *logger.Logger
 	retry             int
 	retryWait         int
 	timeout           int
@@ -49,6 +55,7 @@ type HTTPClient struct {
 	resolverIdxMutex  *sync.Mutex
 	clientsCache      map[string]*http.Client
 	clientsCacheMutex *sync.Mutex
+	clock             clock.Clock
 }
 
 func (c *HTTPClient) newHTTPTransport(scheme string, host string) (*http.Transport) {
@@ -112,7 +119,38 @@ func (c *HTTPClient) newClient(scheme string, host string) (*http.Client) {
 }
 
 
-func (c *HTTPClient) methodFuncBase(method string, request *HTTPRequest) (*http.Response, []byte, error) {
+// SetClock is set clock used by retry waits
+func (c *HTTPClient) SetClock(clock clock.Clock) {
+	c.clock = clock
+}
+
+// SetName is set exchange name used as label of metrics and field of log. host of url is used if empty
+func (c *HTTPClient) SetName(name string) {
+	c.name = name
+	c.logger = logger.Get("http").WithField(logger.FieldExchange, name)
+}
+
+func (c *HTTPClient) observe(request *HTTPRequest, start time.Time, res *http.Response, err error) {
+	name := c.name
+	if name == "" {
+		name = request.ParsedURL.Host
+	}
+	endpoint := request.Endpoint
+	if endpoint == "" {
+		endpoint = request.ParsedURL.Path
+	}
+	status := 0
+	if res != nil {
+		status = res.StatusCode
+	}
+	metrics.ObserveHTTPRequest(name, endpoint, time.Since(start), status, err)
+}
+
+func (c *HTTPClient) methodFuncBase(method string, request *HTTPRequest) (res *http.Response, resBody []byte, err error) {
+	start := time.Now()
+	defer func() {
+		c.observe(request, start, res, err)
+	}()
 	client := c.newClient(request.ParsedURL.Scheme, request.ParsedURL.Host)
 	req, err := http.NewRequest(method, request.URL, bytes.NewBufferString(request.Body))
 	if err != nil {
@@ -121,11 +159,11 @@ func (c *HTTPClient) methodFuncBase(method string, request *HTTPRequest) (*http.
 	for k, v := range request.Headers {
 		req.Header.Set(k, v)
 	}
-	res, err := client.Do(req)
+	res, err = client.Do(req)
 	if err != nil {
 		return res, nil, errors.Wrap(err, fmt.Sprintf("request of HTTPMethodGET is failure (url = %v, method = %v, request body = %v)", request.URL, request.RequestMethod, request.Body))
 	}
-	resBody, err := ioutil.ReadAll(res.Body)
+	resBody, err = ioutil.ReadAll(res.Body)
 	if err != nil {
 		res.Body.Close()
 		return res, resBody, errors.Wrap(err, fmt.Sprintf("can not read response (url = %v, method = %v, request body = %v)", request.URL, request.RequestMethod, request.Body))
@@ -143,13 +181,13 @@ func (c *HTTPClient) retryRequest(request *HTTPRequest, noRetry bool) (*http.Res
 	for i := 0; i <= c.retry; i++ {
 		res, resBody, err := c.methodFuncBase(request.RequestMethodString, request)
 		if !noRetry && err != nil {
-			log.Printf("request is failure, retry... (url = %v, method = %v, reason = %v)", request.URL, request.RequestMethod, err)
+			c.logger.Warnf("request is failure, retry... (url = %v, method = %v, reason = %v)", request.URL, request.RequestMethod, err)
 			if c.retryWait != 0 {
-				time.Sleep(time.Duration(c.retryWait) * time.Millisecond)
+				c.clock.Sleep(time.Duration(c.retryWait) * time.Millisecond)
 			}
 			continue
 		} else if noRetry && err != nil {
-			log.Printf("request is failure (url = %v, method = %v, reason = %v)", request.URL, request.RequestMethod, err)
+			c.logger.Warnf("request is failure (url = %v, method = %v, reason = %v)", request.URL, request.RequestMethod, err)
 		}
 		return res, resBody, err
 	}
@@ -202,6 +240,8 @@ func NewHTTPClient(retry int, retryWait int, timeout int, localAddr *net.IPAddr)
 		resolverIdxMutex:  new(sync.Mutex),
 		clientsCache:      make(map[string]*http.Client),
 		clientsCacheMutex: new(sync.Mutex),
+		clock:             clock.Default(),
+		logger:            logger.Get("http"),
 	}
 	if localAddr == nil {
 		newHTTPClient.localAddr = nil
@@ -216,6 +256,9 @@ func NewHTTPClient(retry int, retryWait int, timeout int, localAddr *net.IPAddr)
 type WSCallback func(conn *websocket.Conn, data interface{}) error
 
 type WSClient struct {
+	exchangeName          string
+	currencyPair          string
+	reconnectCallback     func()
 	readBufSize           int
 	writeBufSize          int
 	retry                 int
@@ -225,6 +268,8 @@ type WSClient struct {
 	started               bool
 	finished              uint32
 	connectLoopFinishChan chan bool
+	clock                 clock.Clock
+	logger                *logger.Logger
 }
 
 func (w *WSClient) messageLoop(callback WSCallback, callbackData interface{}) (error) {
@@ -234,7 +279,7 @@ func (w *WSClient) messageLoop(callback WSCallback, callbackData interface{}) (e
 			return errors.Wrapf(err, "callback error (reason = %v)", err)
 		}
 		if atomic.LoadUint32(&w.finished) == 1 {
-			log.Printf("message loop finished")
+			w.logger.Debugf("message loop finished")
 			return nil
 		}
 	}
@@ -248,8 +293,8 @@ func (w *WSClient) pingLoop(pingStopChan chan bool, pingStopCompleteChan chan bo
 				close(pingStopCompleteChan)
 				return
 			}
-		case <-time.After(5 * time.Second):
-			deadline := time.Now()
+		case <-w.clock.After(5 * time.Second):
+			deadline := w.clock.Now()
 			deadline.Add(30 * time.Second)
 			w.conn.WriteControl(websocket.PingMessage, []byte("ping"), deadline)
 		}
@@ -294,38 +339,44 @@ func (w *WSClient) connect(callback WSCallback, callbackData interface{}, reques
 		conn, response, err := dialer.Dial(requestURL, header)
 		if err != nil {
 			if response == nil {
-				log.Printf("can not dial, retry ... (url = %v, header = %v, reason = %v)", requestURL, requestHeaders, err)
-				time.Sleep(1 * time.Second)
+				w.logger.Warnf("can not dial, retry ... (url = %v, header = %v, reason = %v)", requestURL, requestHeaders, err)
+				w.clock.Sleep(1 * time.Second)
 				continue
 			}
 			if response.StatusCode < 200 && response.StatusCode <= 300 {
-				log.Printf("can not dial, retry ... (url = %v, header = %v, reason = %v)", requestURL, requestHeaders, err)
-				time.Sleep(1 * time.Second)
+				w.logger.Warnf("can not dial, retry ... (url = %v, header = %v, reason = %v)", requestURL, requestHeaders, err)
+				w.clock.Sleep(1 * time.Second)
 				continue
 			}
-			log.Printf("can not dial (URL = %v, header = %v)", requestURL, requestHeaders)
+			w.logger.Warnf("can not dial (URL = %v, header = %v)", requestURL, requestHeaders)
 			continue
 		}
 		if !w.started {
 			w.connChan <- nil
 			w.started = true
+		} else {
+			metrics.IncWebsocketReconnect(w.exchangeName, w.currencyPair)
+			if w.reconnectCallback != nil {
+				w.reconnectCallback()
+			}
 		}
+		w.logger.Infof("connected (url = %v)", requestURL)
 		w.conn = conn
 		pingStopChan, pingStopCompleteChan := w.startPing()
 		err = w.messageLoop(callback, callbackData)
 		if err != nil {
-			log.Printf("error occuered in message loop: %v", err)
+			w.logger.Warnf("error occuered in message loop: %v", err)
 		}
 		w.stopPing(pingStopChan, pingStopCompleteChan)
 		conn.Close()
 		i = 0
 		if atomic.LoadUint32(&w.finished) == 1 {
-			log.Printf("connect loop finished")
+			w.logger.Debugf("connect loop finished")
 			close(w.connectLoopFinishChan)
 			return
 		}
 	}
-	log.Printf("give up retry (url = %v, header = %v)", requestURL, requestHeaders)
+	w.logger.Errorf("give up retry (url = %v, header = %v)", requestURL, requestHeaders)
 }
 
 func (w *WSClient) Start(callback WSCallback, callbackData interface{}, requestURL string, requestHeaders map[string]string) error {
@@ -345,11 +396,31 @@ func (w *WSClient) Stop() {
 	atomic.StoreUint32(&w.finished, 1)
 	close(w.connChan)
 	select {
-	case <-time.After(1 * time.Second):
+	case <-w.clock.After(1 * time.Second):
 	case <-w.connectLoopFinishChan:
 	}
 }
 
+// SetClock is set clock used by ping loop and retry waits
+func (w *WSClient) SetClock(clock clock.Clock) {
+	w.clock = clock
+}
+
+// SetMetricsLabels is set labels of metrics of reconnections and fields of log
+func (w *WSClient) SetMetricsLabels(exchangeName string, currencyPair string) {
+	w.exchangeName = exchangeName
+	w.currencyPair = currencyPair
+	w.logger = logger.Get("websocket").With(logger.Fields{
+		logger.FieldExchange:     exchangeName,
+		logger.FieldCurrencyPair: currencyPair,
+	})
+}
+
+// SetReconnectCallback is set callback called when reconnected. it should be called before Start
+func (w *WSClient) SetReconnectCallback(callback func()) {
+	w.reconnectCallback = callback
+}
+
 // Send ...
 // TODO: cleanup
 func (w *WSClient) Send(v interface{}) error {
@@ -370,5 +441,7 @@ func NewWSClient(readBufSize int, writeBufSize int, retry int, retryWait int) *W
 		retryWait:             retryWait,
 		connChan:              make(chan error),
 		connectLoopFinishChan: make(chan bool),
+		clock:                 clock.Default(),
+		logger:                logger.Get("websocket"),
 	}
 }
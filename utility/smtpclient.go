@@ -2,13 +2,13 @@ package utility
 
 import (
 	"github.com/pkg/errors"
+	"github.com/AutomaticCoinTrader/ACT/logger"
 	"net"
 	"crypto/tls"
 	"net/mail"
 	"net/smtp"
 	"strings"
 	"fmt"
-	"log"
 )
 
 const (
@@ -44,6 +44,7 @@ type SMTPClient struct {
 	useStartTLS bool
 	from        string
 	to          string
+	logger      *logger.Logger
 }
 
 func (s *SMTPClient) SendMail(subject string, body string) (error) {
@@ -136,12 +137,12 @@ func (s *SMTPClient) SendMail(subject string, body string) (error) {
 
 	err = w.Close()
 	if err != nil {
-		log.Printf("can not close message writer (reason = %v)", err)
+		s.logger.Warnf("can not close message writer (reason = %v)", err)
 	}
 
 	err = client.Quit()
 	if err != nil {
-		log.Printf("can not send QUIT command (reason = %v)", err)
+		s.logger.Warnf("can not send QUIT command (reason = %v)", err)
 	}
 
 	return nil
@@ -158,5 +159,6 @@ func NewSMTPClient(hostPort string, username string, password string, authtype S
 		useStartTLS: useStartTLS,
 		from:        from,
 		to:          to,
+		logger:      logger.Get("smtp"),
 	}
 }
@@ -2,6 +2,7 @@ package utilitytest
 
 import (
 	"testing"
+	"github.com/AutomaticCoinTrader/ACT/clock"
 	"github.com/AutomaticCoinTrader/ACT/utility"
 	"fmt"
 	"net/http"
@@ -343,3 +344,31 @@ func concurrencyRequest(ts *httptest.Server, httpClient *utility.HTTPClient, t *
 	fmt.Printf("finish = %v\n", idx)
 	close(finishChan)
 }
+
+func TestHttpClientRetryWaitClock(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	// リトライの待ちは注入した時計で進む
+	c := clock.NewSimulatedClock(time.Unix(0, 0))
+	httpClient := utility.NewHTTPClient(1, 60000, 1000, nil)
+	httpClient.SetClock(c)
+	errChan := make(chan error, 1)
+	go func() {
+		_, _, err := httpClient.DoRequest(utility.HTTPMethodGET, &utility.HTTPRequest{URL: ts.URL}, false)
+		errChan <- err
+	}()
+	for i := 0; i < 2; i++ {
+		c.BlockUntil(1)
+		c.Advance(time.Minute)
+	}
+	select {
+	case err := <-errChan:
+		if err == nil {
+			t.Fatalf("request is succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("retry wait does not use clock")
+	}
+}